@@ -3,14 +3,19 @@ package storageclient
 import (
 	"calculationServer/pkg/expressionparser"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"io"
 	"math/rand"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -28,6 +33,13 @@ type Client struct {
 	serverName       string
 	connection       *grpc.ClientConn
 	gRPCClient       ExpressionsServiceClient
+	tlsCA            string
+	tlsCert          string
+	tlsKey           string
+	tlsServerName    string
+	enrollmentToken  string
+	credentialFile   string
+	credential       string
 }
 
 /*
@@ -71,11 +83,79 @@ func New() (*Client, error) {
 		randomNumber := rand.Intn(10001)
 		c.serverName = "noname" + strconv.Itoa(randomNumber)
 	}
+
+	c.tlsCA = os.Getenv("STORAGE_TLS_CA")
+	c.tlsCert = os.Getenv("STORAGE_TLS_CERT")
+	c.tlsKey = os.Getenv("STORAGE_TLS_KEY")
+	c.tlsServerName = os.Getenv("STORAGE_TLS_SERVER_NAME")
+
+	c.enrollmentToken = os.Getenv("STORAGE_ENROLLMENT_TOKEN")
+	c.credentialFile = os.Getenv("STORAGE_CREDENTIAL_FILE")
+	if c.credentialFile == "" {
+		c.credentialFile = "worker.credential"
+	}
 	return c, nil
 }
 
+// transportCredentials returns TLS credentials if CA of the storage is set, with client certificate if it is set
+// (mutual TLS), otherwise insecure credentials.
+func (c *Client) transportCredentials() (credentials.TransportCredentials, error) {
+	if c.tlsCA == "" {
+		if c.tlsCert != "" {
+			return nil, errors.New("STORAGE_TLS_CA must be set to use client certificate")
+		}
+		zap.S().Warn("connection to storage is not encrypted")
+		return insecure.NewCredentials(), nil
+	}
+
+	ca, err := os.ReadFile(c.tlsCA)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("no certificates found in CA file")
+	}
+	config := &tls.Config{
+		RootCAs:    pool,
+		ServerName: c.tlsServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if c.tlsCert != "" {
+		cert, err := tls.LoadX509KeyPair(c.tlsCert, c.tlsKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(config), nil
+}
+
+// workerCredential is sent with every call to the storage as "authorization: Bearer <credential>".
+type workerCredential struct {
+	client *Client
+}
+
+func (w workerCredential) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	if w.client.credential == "" {
+		return map[string]string{}, nil
+	}
+	return map[string]string{"authorization": "Bearer " + w.client.credential}, nil
+}
+
+// RequireTransportSecurity returns false, storage may authenticate workers without TLS.
+func (w workerCredential) RequireTransportSecurity() bool {
+	return false
+}
+
 func (c *Client) SetupgRPCServer() error {
-	conn, err := grpc.Dial(c.storageServer, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	creds, err := c.transportCredentials()
+	if err != nil {
+		return err
+	}
+	conn, err := grpc.Dial(c.storageServer, grpc.WithTransportCredentials(creds),
+		grpc.WithPerRPCCredentials(workerCredential{client: c}))
 	if err != nil {
 		return err
 	}
@@ -83,6 +163,42 @@ func (c *Client) SetupgRPCServer() error {
 
 	c.gRPCClient = NewExpressionsServiceClient(conn)
 
+	return c.loadCredential()
+}
+
+// loadCredential reads the credential of the server from the credential file. If there is no file, the server
+// enrolls with STORAGE_ENROLLMENT_TOKEN and saves the credential to the file.
+func (c *Client) loadCredential() error {
+	credential, err := os.ReadFile(c.credentialFile)
+	if err == nil {
+		c.credential = strings.TrimSpace(string(credential))
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if c.enrollmentToken == "" {
+		zap.S().Warn("STORAGE_ENROLLMENT_TOKEN is not set, calls to storage are not authenticated")
+		return nil
+	}
+
+	if err = c.Enroll(c.enrollmentToken); err != nil {
+		return err
+	}
+	return os.WriteFile(c.credentialFile, []byte(c.credential), 0o600)
+}
+
+// Enroll exchanges the enrollment token issued by storage admin for the credential of the server.
+func (c *Client) Enroll(enrollmentToken string) error {
+	ans, err := c.gRPCClient.Enroll(context.Background(), &EnrollRequest{
+		ServerName:      c.serverName,
+		EnrollmentToken: enrollmentToken,
+	})
+	if err != nil {
+		return err
+	}
+	c.credential = ans.Credential
+	zap.S().Info("server is enrolled")
 	return nil
 }
 
@@ -157,7 +273,8 @@ func (c *Client) trySendResult(exp *Expression) {
 	}
 }
 
-func (c *Client) keepAliveExpression(exp *Expression, done <-chan bool, ticker *time.Ticker) {
+// keepAliveExpression sends alive messages until done, calls cancel if storage asks to stop the calculation.
+func (c *Client) keepAliveExpression(exp *Expression, done <-chan bool, ticker *time.Ticker, cancel context.CancelFunc) {
 	for {
 		select {
 		case <-done:
@@ -165,10 +282,14 @@ func (c *Client) keepAliveExpression(exp *Expression, done <-chan bool, ticker *
 			return
 		case <-ticker.C:
 			zap.S().Info("send alive")
-			err := c.KeepAlive(exp)
+			stop, err := c.KeepAlive(exp)
 			if err != nil {
 				zap.S().Error(err)
 			}
+			if stop {
+				zap.S().Info("storage cancelled calculation")
+				cancel()
+			}
 		}
 	}
 }
@@ -189,11 +310,19 @@ func (c *Client) Run() {
 
 		ticker := time.NewTicker(c.keepAlive)
 		done := make(chan bool)
+		ctx, cancel := context.WithCancel(context.Background())
 		// keep this client alive for the server
-		go c.keepAliveExpression(exp, done, ticker)
-		res, logs, err := c.expressionParser.CalculateExpression(exp.Value)
+		go c.keepAliveExpression(exp, done, ticker, cancel)
+		res, logs, err := c.expressionParser.CalculateExpressionContext(ctx, exp.Value)
 		ticker.Stop()
 		done <- true
+		if ctx.Err() != nil {
+			// expression was cancelled or deleted, nobody waits for the result
+			cancel()
+			zap.S().Infof("calculation of %v is cancelled", exp.Value)
+			continue
+		}
+		cancel()
 		if err != nil {
 			zap.S().Error(err)
 			exp.Status = ExpressionError
@@ -308,15 +437,20 @@ func (c *Client) GetOperationsAndTimes(e *Expression) (expressionparser.ExecTime
 	}, nil
 }
 
-func (c *Client) KeepAlive(expression *Expression) error {
+// KeepAlive tells the storage that the server is still calculating the expression. Returns true if the storage
+// asks to stop the calculation (expression is cancelled, deleted or given to another server).
+func (c *Client) KeepAlive(expression *Expression) (bool, error) {
 	var send KeepAliveMsg
 	send.Expression = expression
 	send.StatusWorkers = fmt.Sprintf("%v -> %v from %v workers are runninng to calcualte %v",
 		time.Now().Format("01-02-2006 15:04:05"), c.expressionParser.GetWorkingWorkers(),
 		c.expressionParser.GetTotalNumberOfWorkers(), expression.Value)
-	_, err := c.gRPCClient.KeepAlive(
+	ans, err := c.gRPCClient.KeepAlive(
 		context.Background(),
 		&send,
 	)
-	return err
+	if err != nil {
+		return false, err
+	}
+	return ans.Cancel, nil
 }
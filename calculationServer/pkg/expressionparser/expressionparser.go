@@ -2,6 +2,7 @@ package expressionparser
 
 import (
 	"calculationServer/internal/expressionlogger"
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
@@ -37,7 +38,7 @@ type ExpressionParser struct {
 	execTimeConfig  ExecTimeConfig
 	mu              sync.Mutex
 	logs            *expressionlogger.ExpLogger
-	running         int
+	running         int // workers of all calculations, workers of cancelled ones may still be stopping
 }
 
 func isByteNumberOrPoint(b byte) bool {
@@ -280,6 +281,23 @@ func (e *ExpressionParser) ReadRPN(expressionRPN []string) ([]OperationOrNum, er
 }
 
 func (e *ExpressionParser) CalculateOperation(num1, num2 float64, operator int) (float64, error) {
+	return e.CalculateOperationContext(context.Background(), num1, num2, operator)
+}
+
+// sleep waits for duration, returns error if ctx is done earlier.
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CalculateOperationContext is CalculateOperation that stops waiting when ctx is done.
+func (e *ExpressionParser) CalculateOperationContext(ctx context.Context, num1, num2 float64, operator int) (float64, error) {
 	e.mu.Lock()
 	duration, err := e.getTimeForOperator(operator)
 	e.mu.Unlock()
@@ -287,20 +305,24 @@ func (e *ExpressionParser) CalculateOperation(num1, num2 float64, operator int)
 		return 0, fmt.Errorf("%v is not an operator", operator)
 	}
 
-	res := make(chan float64)
+	res := make(chan float64, 1)
 	switch operator {
 	case ADD:
 		go func() {
 			res <- num1 + num2
 		}()
-		time.Sleep(duration)
+		if err = sleep(ctx, duration); err != nil {
+			return 0, err
+		}
 
 		return <-res, nil
 	case SUBTRACT:
 		go func() {
 			res <- num1 - num2
 		}()
-		time.Sleep(duration)
+		if err = sleep(ctx, duration); err != nil {
+			return 0, err
+		}
 
 		return <-res, nil
 	case DIVIDE:
@@ -311,14 +333,18 @@ func (e *ExpressionParser) CalculateOperation(num1, num2 float64, operator int)
 		go func() {
 			res <- num1 / num2
 		}()
-		time.Sleep(duration)
+		if err = sleep(ctx, duration); err != nil {
+			return 0, err
+		}
 
 		return <-res, nil
 	case MULTIPLY:
 		go func() {
 			res <- num1 * num2
 		}()
-		time.Sleep(duration)
+		if err = sleep(ctx, duration); err != nil {
+			return 0, err
+		}
 
 		return <-res, nil
 	}
@@ -328,14 +354,32 @@ func (e *ExpressionParser) CalculateOperation(num1, num2 float64, operator int)
 
 // CalculateRPNData aka workerPool.
 func (e *ExpressionParser) CalculateRPNData(data []OperationOrNum) (float64, error) {
+	return e.CalculateRPNDataContext(context.Background(), data)
+}
+
+// CalculateRPNDataContext is CalculateRPNData that can be aborted, when ctx is done all workers are stopped.
+func (e *ExpressionParser) CalculateRPNDataContext(ctx context.Context, data []OperationOrNum) (float64, error) {
 	// pool will control number of workers at the same time
 	e.logs.Add("Start of calculations")
 	if e.numberOfWorkers < 1 {
 		return 0, errors.New("number of workers must be bigger than 0")
 	}
 
-	errChan := make(chan error)
-	e.running = 0
+	// buffered, so workers that fail after the pool is stopped do not block
+	errChan := make(chan error, e.numberOfWorkers+1)
+	// workers of this call, workers of a cancelled previous call are not counted
+	running := 0
+	working := func() int {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		return running
+	}
+	workerDone := func() {
+		e.mu.Lock()
+		running--
+		e.running--
+		e.mu.Unlock()
+	}
 	readyChan := make(chan bool, e.numberOfWorkers+1)
 	// to understand, when this goroutine will go through all data elements
 
@@ -347,7 +391,7 @@ func (e *ExpressionParser) CalculateRPNData(data []OperationOrNum) (float64, err
 		}
 		for {
 			e.mu.Lock()
-			if !data[el.OperationID1].IsOperation && !data[el.OperationID2].IsOperation && e.running < e.numberOfWorkers {
+			if !data[el.OperationID1].IsOperation && !data[el.OperationID2].IsOperation && running < e.numberOfWorkers {
 				// we can start new worker
 				e.mu.Unlock()
 				break
@@ -359,10 +403,16 @@ func (e *ExpressionParser) CalculateRPNData(data []OperationOrNum) (float64, err
 				continue
 			case err := <-errChan:
 				return 0, err
+			case <-ctx.Done():
+				e.logs.Add("Calculation is cancelled")
+				return 0, ctx.Err()
 			}
 		}
 
+		e.mu.Lock()
+		running++
 		e.running++
+		e.mu.Unlock()
 		go func() {
 			e.mu.Lock()
 			// take numbers from data
@@ -372,18 +422,20 @@ func (e *ExpressionParser) CalculateRPNData(data []OperationOrNum) (float64, err
 			strOper, err := convertOperatorToString(el.Operator)
 			// if error, write in a channel
 			if err != nil {
+				workerDone()
 				errChan <- err
 				return
 			}
 			e.logs.Add(fmt.Sprintf("Start worker with id %v; work: %v %v %v", ind, num1, strOper, num2))
 
 			// calculate with delays
-			outOper, err := e.CalculateOperation(num1, num2, el.Operator)
+			outOper, err := e.CalculateOperationContext(ctx, num1, num2, el.Operator)
 
 			e.logs.Add(fmt.Sprintf("End of worker with id %v; work was %v %v %v; result is %v",
 				ind, num1, strOper, num2, outOper))
 			// if error, write in a channel
 			if err != nil {
+				workerDone()
 				errChan <- err
 				return
 			}
@@ -392,20 +444,21 @@ func (e *ExpressionParser) CalculateRPNData(data []OperationOrNum) (float64, err
 			data[ind] = OperationOrNum{Data: outOper}
 			e.mu.Unlock()
 
-			e.mu.Lock()
-			e.running--
-			e.mu.Unlock()
+			workerDone()
 			// read one element from pool, so new goroutine can turn on
 			readyChan <- true
 		}()
 	}
 
-	for e.running > 0 {
+	for working() > 0 {
 		select {
 		case <-readyChan:
 			continue
 		case err := <-errChan:
 			return 0, err
+		case <-ctx.Done():
+			e.logs.Add("Calculation is cancelled")
+			return 0, ctx.Err()
 		}
 	}
 
@@ -415,6 +468,11 @@ func (e *ExpressionParser) CalculateRPNData(data []OperationOrNum) (float64, err
 }
 
 func (e *ExpressionParser) CalculateExpression(in string) (float64, string, error) {
+	return e.CalculateExpressionContext(context.Background(), in)
+}
+
+// CalculateExpressionContext is CalculateExpression that can be aborted via ctx.
+func (e *ExpressionParser) CalculateExpressionContext(ctx context.Context, in string) (float64, string, error) {
 	e.logs.Reset()
 	// convert to RPN
 	rpn, err := e.ConvertInRPN(in)
@@ -427,7 +485,7 @@ func (e *ExpressionParser) CalculateExpression(in string) (float64, string, erro
 		return 0, "", err
 	}
 	// calculate
-	res, err := e.CalculateRPNData(data)
+	res, err := e.CalculateRPNDataContext(ctx, data)
 	if err != nil {
 		return 0, "", err
 	}
@@ -435,6 +493,8 @@ func (e *ExpressionParser) CalculateExpression(in string) (float64, string, erro
 }
 
 func (e *ExpressionParser) GetWorkingWorkers() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	return e.running
 }
 
@@ -116,3 +116,21 @@ func TestFullRun(t *testing.T) {
 	assert.Equal(t, int64(0), resExp.UserId)
 	assert.Equal(t, int64(0), resExp.Id)
 }
+
+func TestKeepAliveCancel(t *testing.T) {
+	client, s, conn := ClientAndServerSetup(t)
+	defer s.Stop()
+	defer conn.Close()
+
+	KeepAliveValue = &storageclient.KeepAliveAnswer{Cancel: false}
+	stop, err := client.KeepAlive(&storageclient.Expression{Value: "1+1"})
+	assert.NoError(t, err)
+	assert.False(t, stop)
+
+	KeepAliveValue = &storageclient.KeepAliveAnswer{Cancel: true}
+	stop, err = client.KeepAlive(&storageclient.Expression{Value: "1+1"})
+	assert.NoError(t, err)
+	assert.True(t, stop)
+
+	KeepAliveValue = &storageclient.KeepAliveAnswer{}
+}
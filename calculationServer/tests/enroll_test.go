@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"calculationServer/internal/storageclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnroll(t *testing.T) {
+	s := grpc.NewServer()
+	storageclient.RegisterExpressionsServiceServer(s, &mockServer{})
+	tcpLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = s.Serve(tcpLis)
+	}()
+	defer s.Stop()
+
+	credentialFile := filepath.Join(t.TempDir(), "worker.credential")
+	t.Setenv("NUMBER_OF_CALCULATORS", "1")
+	t.Setenv("SEND_ALIVE_DURATION", "1")
+	t.Setenv("CALCULATION_SERVER_NAME", "worker1")
+	t.Setenv("STORAGE_URL", tcpLis.Addr().String())
+	t.Setenv("STORAGE_CREDENTIAL_FILE", credentialFile)
+	EnrollValue = &storageclient.EnrollAnswer{Credential: "credential1"}
+	ConfirmValue = &storageclient.Confirm{Confirm: true}
+	EnrollRequests = nil
+
+	// wrong enrollment token
+	t.Setenv("STORAGE_ENROLLMENT_TOKEN", "wrong")
+	client, err := storageclient.New()
+	require.NoError(t, err)
+	require.Error(t, client.SetupgRPCServer())
+	client.CloseConn()
+	_, err = os.Stat(credentialFile)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// enrolls and saves credential
+	t.Setenv("STORAGE_ENROLLMENT_TOKEN", "enrollmenttoken")
+	client, err = storageclient.New()
+	require.NoError(t, err)
+	require.NoError(t, client.SetupgRPCServer())
+	_, err = client.TryToConfirm(&storageclient.Expression{ServerName: "worker1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer credential1"}, ConfirmAuthorization)
+	client.CloseConn()
+
+	saved, err := os.ReadFile(credentialFile)
+	require.NoError(t, err)
+	assert.Equal(t, "credential1", string(saved))
+	require.Len(t, EnrollRequests, 2)
+	assert.Equal(t, "worker1", EnrollRequests[1].ServerName)
+
+	// saved credential is used without enrolling again
+	EnrollValue = &storageclient.EnrollAnswer{Credential: "credential2"}
+	client, err = storageclient.New()
+	require.NoError(t, err)
+	require.NoError(t, client.SetupgRPCServer())
+	defer client.CloseConn()
+	_, err = client.TryToConfirm(&storageclient.Expression{ServerName: "worker1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer credential1"}, ConfirmAuthorization)
+	assert.Len(t, EnrollRequests, 2)
+}
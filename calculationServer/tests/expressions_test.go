@@ -2,9 +2,11 @@ package tests
 
 import (
 	"calculationServer/pkg/expressionparser"
+	"context"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestConvertToRPN(t *testing.T) {
@@ -170,3 +172,56 @@ func TestFullProcess(t *testing.T) {
 		})
 	}
 }
+
+func TestCancelCalculation(t *testing.T) {
+	ep := expressionparser.New()
+	err := ep.SetNumberOfWorkers(2)
+	require.NoError(t, err)
+	err = ep.SetExecTimes(expressionparser.ExecTimeConfig{
+		TimeAdd:      10 * time.Second,
+		TimeSubtract: 10 * time.Second,
+		TimeDivide:   10 * time.Second,
+		TimeMultiply: 10 * time.Second,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, _, err = ep.CalculateExpressionContext(ctx, "(1 + 1) * (2 + 2)")
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// workers are freed
+	assert.Eventually(t, func() bool {
+		return ep.GetWorkingWorkers() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCalculationAfterCancel(t *testing.T) {
+	ep := expressionparser.New()
+	require.NoError(t, ep.SetNumberOfWorkers(2))
+	require.NoError(t, ep.SetExecTimes(expressionparser.ExecTimeConfig{
+		TimeAdd:      50 * time.Millisecond,
+		TimeSubtract: 50 * time.Millisecond,
+		TimeDivide:   50 * time.Millisecond,
+		TimeMultiply: 50 * time.Millisecond,
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := ep.CalculateExpressionContext(ctx, "(1 + 1) * (2 + 2)")
+	require.ErrorIs(t, err, context.Canceled)
+
+	// workers of the cancelled calculation do not change the number of workers of the next one
+	actual, _, err := ep.CalculateExpression("(1 + 1) * (2 + 2) + 3 * 3")
+	require.NoError(t, err)
+	assert.InDelta(t, 17, actual, 0.001)
+	assert.Eventually(t, func() bool {
+		return ep.GetWorkingWorkers() == 0
+	}, time.Second, 10*time.Millisecond)
+}
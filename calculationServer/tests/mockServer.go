@@ -5,6 +5,9 @@ import (
 	"context"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 	"net"
 	"testing"
@@ -65,8 +68,11 @@ func (m *mockServer) GetUpdates(_ *storageclient.Empty, stream storageclient.Exp
 }
 
 var ConfirmValue *storageclient.Confirm
+var ConfirmAuthorization []string
 
-func (m *mockServer) ConfirmStartCalculating(_ context.Context, _ *storageclient.Expression) (*storageclient.Confirm, error) {
+func (m *mockServer) ConfirmStartCalculating(ctx context.Context, _ *storageclient.Expression) (*storageclient.Confirm, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ConfirmAuthorization = md.Get("authorization")
 	return ConfirmValue, nil
 }
 
@@ -76,8 +82,10 @@ func (m *mockServer) GetOperationsAndTimes(_ context.Context, _ *storageclient.E
 	return OperationsAndTimesValue, nil
 }
 
-func (m *mockServer) KeepAlive(_ context.Context, _ *storageclient.KeepAliveMsg) (*storageclient.Empty, error) {
-	return &storageclient.Empty{}, nil
+var KeepAliveValue = &storageclient.KeepAliveAnswer{}
+
+func (m *mockServer) KeepAlive(_ context.Context, _ *storageclient.KeepAliveMsg) (*storageclient.KeepAliveAnswer, error) {
+	return KeepAliveValue, nil
 }
 
 var PostResultChannel chan *storageclient.Expression
@@ -87,3 +95,14 @@ func (m *mockServer) PostResult(_ context.Context, exp *storageclient.Expression
 	PostResultChannel <- exp
 	return PostResultValue, nil
 }
+
+var EnrollValue *storageclient.EnrollAnswer
+var EnrollRequests []*storageclient.EnrollRequest
+
+func (m *mockServer) Enroll(_ context.Context, req *storageclient.EnrollRequest) (*storageclient.EnrollAnswer, error) {
+	EnrollRequests = append(EnrollRequests, req)
+	if req.EnrollmentToken != "enrollmenttoken" {
+		return nil, status.Error(codes.Unauthenticated, "enrollment token is not valid")
+	}
+	return EnrollValue, nil
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"calculationServer/internal/storageclient"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type certificate struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+var certificateSerial int64 = 1
+
+// createCertificate creates certificate signed by parent (self-signed if parent is nil) and writes it to dir.
+func createCertificate(t *testing.T, dir string, name string, dnsNames []string, isCA bool,
+	parent *certificate) *certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	certificateSerial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(certificateSerial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if isCA {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	res := &certificate{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	err = os.WriteFile(res.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	require.NoError(t, err)
+	err = os.WriteFile(res.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600)
+	require.NoError(t, err)
+	return res
+}
+
+func TestTLSConnection(t *testing.T) {
+	dir := t.TempDir()
+	ca := createCertificate(t, dir, "ca", nil, true, nil)
+	storageCert := createCertificate(t, dir, "storage", []string{"storage"}, false, ca)
+	workerCert := createCertificate(t, dir, "worker1", nil, false, ca)
+
+	pair, err := tls.LoadX509KeyPair(storageCert.certFile, storageCert.keyFile)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	})))
+	storageclient.RegisterExpressionsServiceServer(s, &mockServer{})
+	tcpLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = s.Serve(tcpLis)
+	}()
+	defer s.Stop()
+
+	t.Setenv("NUMBER_OF_CALCULATORS", "1")
+	t.Setenv("SEND_ALIVE_DURATION", "1")
+	t.Setenv("CALCULATION_SERVER_NAME", "worker1")
+	t.Setenv("STORAGE_URL", tcpLis.Addr().String())
+	t.Setenv("STORAGE_TLS_CA", ca.certFile)
+	t.Setenv("STORAGE_TLS_CERT", workerCert.certFile)
+	t.Setenv("STORAGE_TLS_KEY", workerCert.keyFile)
+	t.Setenv("STORAGE_TLS_SERVER_NAME", "storage")
+	t.Setenv("STORAGE_CREDENTIAL_FILE", filepath.Join(dir, "worker.credential"))
+
+	client, err := storageclient.New()
+	require.NoError(t, err)
+	err = client.SetupgRPCServer()
+	require.NoError(t, err)
+	defer client.CloseConn()
+
+	ConfirmValue = &storageclient.Confirm{Confirm: true}
+	resp, err := client.TryToConfirm(&storageclient.Expression{ServerName: "worker1"})
+	require.NoError(t, err)
+	assert.True(t, resp)
+
+	// client certificate without CA of the storage
+	t.Setenv("STORAGE_TLS_CA", "")
+	client, err = storageclient.New()
+	require.NoError(t, err)
+	require.Error(t, client.SetupgRPCServer())
+}
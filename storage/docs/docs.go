@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/expression": {
+        "/v1/admin/expressions": {
             "get": {
-                "description": "Get all expressions from storage",
+                "description": "Get every expression in storage",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,20 +25,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "expression"
+                    "admin"
                 ],
-                "summary": "Get all expressions",
+                "summary": "Get expressions of all users",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/api.OutGetAllExpressions"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
                     }
                 }
-            },
+            }
+        },
+        "/v1/admin/expressions/{id}/release": {
             "post": {
-                "description": "Add expression to storage",
+                "description": "End the lease of calculation server on expression, the expression is returned to pending and the server is asked to stop",
                 "consumes": [
                     "application/json"
                 ],
@@ -46,39 +54,55 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "expression"
+                    "admin"
                 ],
-                "summary": "Add expression",
+                "summary": "Release expression",
                 "parameters": [
                     {
-                        "description": "Expression",
-                        "name": "expression",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.InPostExpression"
-                        }
+                        "type": "integer",
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.OutPostExpression"
+                            "$ref": "#/definitions/api.OutReleaseExpression"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/api.OutPostExpression"
+                            "$ref": "#/definitions/api.OutReleaseExpression"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutReleaseExpression"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutReleaseExpression"
                         }
                     }
                 }
             }
         },
-        "/expressionById": {
+        "/v1/admin/loginAttempts": {
             "get": {
-                "description": "Get expression from storage by id",
+                "description": "Get failed login attempts, the newest first",
                 "consumes": [
                     "application/json"
                 ],
@@ -86,45 +110,60 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "expression"
+                    "admin"
                 ],
-                "summary": "Get expression by id",
+                "summary": "Get login attempts",
                 "parameters": [
                     {
-                        "description": "Expression ID",
-                        "name": "id",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.InGetExpressionByID"
-                        }
+                        "type": "string",
+                        "description": "Only attempts with this login",
+                        "name": "login",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of attempts (default 100)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.OutGetExpressionByID"
+                            "$ref": "#/definitions/api.OutGetLoginAttempts"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/api.OutGetExpressionByID"
+                            "$ref": "#/definitions/api.OutGetLoginAttempts"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/api.OutGetExpressionByID"
+                            "$ref": "#/definitions/api.OutGetLoginAttempts"
                         }
                     }
                 }
             }
         },
-        "/getComputingPowers": {
+        "/v1/admin/servers": {
             "get": {
-                "description": "Get computing powers from storage",
+                "description": "Get all calculation servers with expressions of all users",
                 "consumes": [
                     "application/json"
                 ],
@@ -132,22 +171,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "computing powers"
+                    "admin"
                 ],
-                "summary": "Get computing powers",
+                "summary": "Get servers",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.OutGetComputingPowers"
+                            "$ref": "#/definitions/api.OutGetServers"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
                         }
                     }
                 }
             }
         },
-        "/getExpressionByServer": {
+        "/v1/admin/signingKeys": {
             "get": {
-                "description": "Get expressions from storage by server name",
+                "description": "Get signing keys of access tokens without private parts, retired_at is 0 for active keys",
                 "consumes": [
                     "application/json"
                 ],
@@ -155,39 +200,40 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "expression"
-                ],
-                "summary": "Get expression by server",
-                "parameters": [
-                    {
-                        "description": "Server name",
-                        "name": "server_name",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.InGetExpressionByServer"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "Get signing keys",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.OutGetExpressionByServer"
+                            "$ref": "#/definitions/api.OutGetSigningKeys"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/api.OutGetExpressionByServer"
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetSigningKeys"
                         }
                     }
                 }
             }
         },
-        "/getOperationsAndTimes": {
-            "get": {
-                "description": "Get operations and times for calculation as a map of operation and time in milliseconds, {\"+\": 100,...}",
+        "/v1/admin/signingKeys/rotate": {
+            "post": {
+                "description": "Create a new signing key for access tokens, other active keys are retired and verify tokens for the grace period (JWT_KEY_GRACE_PERIOD)",
                 "consumes": [
                     "application/json"
                 ],
@@ -195,22 +241,56 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "operations"
+                    "admin"
+                ],
+                "summary": "Rotate signing keys",
+                "parameters": [
+                    {
+                        "description": "RS256 or EdDSA, JWT_ALGORITHM by default",
+                        "name": "algorithm",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.InRotateSigningKeys"
+                        }
+                    }
                 ],
-                "summary": "Get operations and times",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.OutGetOperationsAndTimes"
+                            "$ref": "#/definitions/api.OutRotateSigningKeys"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRotateSigningKeys"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRotateSigningKeys"
                         }
                     }
                 }
             }
         },
-        "/getUser": {
-            "get": {
-                "description": "Get user info",
+        "/v1/admin/signingKeys/{kid}/retire": {
+            "post": {
+                "description": "Stop signing access tokens with the key, it verifies tokens for the grace period (JWT_KEY_GRACE_PERIOD). The last active key can not be retired",
                 "consumes": [
                     "application/json"
                 ],
@@ -218,34 +298,61 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
+                ],
+                "summary": "Retire signing key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key ID",
+                        "name": "kid",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get user",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.OutGetUser"
+                            "$ref": "#/definitions/api.OutRetireSigningKey"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/api.OutGetUser"
+                            "$ref": "#/definitions/api.OutRetireSigningKey"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRetireSigningKey"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/api.OutGetUser"
+                            "$ref": "#/definitions/api.OutRetireSigningKey"
                         }
                     }
                 }
             }
         },
-        "/ping": {
+        "/v1/admin/users": {
             "get": {
-                "description": "Check connection with server",
+                "description": "Get all users",
                 "consumes": [
                     "application/json"
                 ],
@@ -253,22 +360,40 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "ping"
+                    "admin"
                 ],
-                "summary": "Ping",
+                "summary": "Get users",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.OutPing"
+                            "$ref": "#/definitions/api.OutGetUsers"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetUsers"
                         }
                     }
                 }
             }
         },
-        "/postOperationsAndTimes": {
+        "/v1/admin/users/{id}/disable": {
             "post": {
-                "description": "Set operations and times for calculation as a map of operation and time in milliseconds, {\"+\": 100,...}",
+                "description": "Disable account, the user can not login, all sessions, refresh tokens and API keys are revoked",
                 "consumes": [
                     "application/json"
                 ],
@@ -276,42 +401,49 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "operations"
+                    "admin"
                 ],
-                "summary": "Set operations and times",
+                "summary": "Disable user",
                 "parameters": [
                     {
-                        "description": "Operations and times",
-                        "name": "data",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "integer"
-                            }
-                        }
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.OutPostOperationsAndTimes"
+                            "$ref": "#/definitions/api.OutUpdateUserByAdmin"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/api.OutPostOperationsAndTimes"
+                            "$ref": "#/definitions/api.OutUpdateUserByAdmin"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutUpdateUserByAdmin"
                         }
                     }
                 }
             }
         },
-        "/register": {
+        "/v1/admin/users/{id}/enable": {
             "post": {
-                "description": "Register new user",
+                "description": "Enable disabled account",
                 "consumes": [
                     "application/json"
                 ],
@@ -319,54 +451,49 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Register",
+                "summary": "Enable user",
                 "parameters": [
                     {
-                        "description": "Login",
-                        "name": "login",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.InRegister"
-                        }
-                    },
-                    {
-                        "description": "Password",
-                        "name": "password",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.InRegister"
-                        }
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.OutRegister"
+                            "$ref": "#/definitions/api.OutUpdateUserByAdmin"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/api.OutRegister"
+                            "$ref": "#/definitions/api.OutUpdateUserByAdmin"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/api.OutRegister"
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutUpdateUserByAdmin"
                         }
                     }
                 }
             }
         },
-        "/updateUser": {
+        "/v1/admin/users/{id}/role": {
             "post": {
-                "description": "Update user info",
+                "description": "Change role of the user (user or admin)",
                 "consumes": [
                     "application/json"
                 ],
@@ -374,32 +501,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Update user",
+                "summary": "Set user role",
                 "parameters": [
                     {
-                        "description": "New login",
-                        "name": "login",
-                        "in": "body",
-                        "schema": {
-                            "$ref": "#/definitions/api.InUpdateUser"
-                        }
-                    },
-                    {
-                        "description": "New password",
-                        "name": "password",
-                        "in": "body",
-                        "schema": {
-                            "$ref": "#/definitions/api.InUpdateUser"
-                        }
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "description": "Old password",
-                        "name": "old_password",
+                        "description": "Role",
+                        "name": "role",
                         "in": "body",
+                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.InUpdateUser"
+                            "$ref": "#/definitions/api.InSetUserRole"
                         }
                     }
                 ],
@@ -407,255 +526,4922 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.OutRegister"
+                            "$ref": "#/definitions/api.OutUpdateUserByAdmin"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/api.OutRegister"
+                            "$ref": "#/definitions/api.OutUpdateUserByAdmin"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/api.OutRegister"
+                            "$ref": "#/definitions/api.OutAuthData"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/api.OutRegister"
+                            "$ref": "#/definitions/api.OutUpdateUserByAdmin"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "api.InGetExpressionByID": {
-            "type": "object",
-            "required": [
-                "id"
-            ],
-            "properties": {
-                "id": {
-                    "type": "integer"
-                }
-            }
         },
-        "api.InGetExpressionByServer": {
-            "type": "object",
-            "required": [
-                "server_name"
+        "/v1/admin/workers": {
+            "get": {
+                "description": "Get all calculation servers that were allowed to connect",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get workers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetWorkers"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetWorkers"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Issue enrollment token for calculation server, the server exchanges it for its credential. Issuing a token for a revoked worker allows it again",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Add worker",
+                "parameters": [
+                    {
+                        "description": "Name of calculation server",
+                        "name": "name",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InAddWorker"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAddWorker"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAddWorker"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAddWorker"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/workers/{name}": {
+            "delete": {
+                "description": "Revoke credential of calculation server, its leases are ended and its expressions are returned to pending",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Revoke worker",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of calculation server",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRevokeWorker"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRevokeWorker"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRevokeWorker"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/apiKeys": {
+            "get": {
+                "description": "Get API keys of the user that are not revoked",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get API keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetAPIKeys"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetAPIKeys"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create API key for scripts, it is sent in X-API-Key header. Scopes: expressions:read, expressions:write, operations:write",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Add API key",
+                "parameters": [
+                    {
+                        "description": "Name, scopes and lifetime of the key",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InAddAPIKey"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAddAPIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAddAPIKey"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAddAPIKey"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/apiKeys/{id}": {
+            "delete": {
+                "description": "Revoke API key of the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Delete API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteAPIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteAPIKey"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteAPIKey"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteAPIKey"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/batches/{id}": {
+            "get": {
+                "description": "Get numbers of expressions of the batch by status",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Get batch progress",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetBatch"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetBatch"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetBatch"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetBatch"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/errors": {
+            "get": {
+                "description": "Get codes of errors with their HTTP statuses and gRPC codes. Error responses are RFC 7807 problem details (application/problem+json) with type, title, status, detail, instance, code, retryable and message (the same as detail), internal errors also have correlation_id. Every response has X-Correlation-ID header, clients may send their own",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "errors"
+                ],
+                "summary": "Get error catalogue",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetErrors"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/events": {
+            "get": {
+                "description": "Server-Sent Events stream of changes of expressions of the user and of their teams. Event names are created, status, progress (server is alive), finished and deleted, data is {\"id\", \"type\", \"expression\"}. After reconnect the stream is resumed after Last-Event-ID header (or last_event_id parameter), if the events are not kept anymore, reset event is sent and expressions must be reloaded. Comment lines are sent every 15 seconds to keep the connection",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Stream expression events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the last received event",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the last received event",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/expression": {
+            "get": {
+                "description": "Get expressions of the user and of their teams. Without limit all expressions are returned",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Get all expressions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma separated statuses (0 - not ready, 1 - working, 2 - ready, 3 - error, 4 - abandoned, 5 - cancelled)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (2006-01-02 15:04:05)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before (2006-01-02 15:04:05)",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the calculation server",
+                        "name": "server",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Substring of the expression",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "creation_time, end_calculation_time or duration (only finished expressions), - before the name for descending order (default -creation_time)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of expressions on the page (up to 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetAllExpressions"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetAllExpressions"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Add expression to storage",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Add expression",
+                "parameters": [
+                    {
+                        "description": "Expression",
+                        "name": "expression",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InPostExpression"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key of the request, a retried request with the same key gets the same response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostExpression"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostExpression"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostExpression"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/expression/{id}": {
+            "delete": {
+                "description": "Delete expression from storage, if it is being calculated, server will stop calculating it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Delete expression",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteExpression"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteExpression"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteExpression"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteExpression"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteExpression"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/expression/{id}/cancel": {
+            "post": {
+                "description": "Stop calculation of expression, server that calculates it will be notified",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Cancel expression",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutCancelExpression"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutCancelExpression"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutCancelExpression"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutCancelExpression"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/expression/{id}/history": {
+            "get": {
+                "description": "Get previous runs of the expression (results before retries), the oldest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Get expression history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionHistory"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionHistory"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionHistory"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionHistory"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/expression/{id}/retry": {
+            "post": {
+                "description": "Calculate finished expression again, previous result is kept in the history of the expression",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Retry expression",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Operation times for the new run",
+                        "name": "operations",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.InRetryExpression"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRetryExpression"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRetryExpression"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRetryExpression"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRetryExpression"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRetryExpression"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/expressionById": {
+            "get": {
+                "description": "Get expression from storage by id. Deprecated, use GET /api/v2/expressions/{id}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Get expression by id",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InGetExpressionByID"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionByID"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionByID"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionByID"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/expressions/export": {
+            "get": {
+                "description": "Stream expressions of the user and of their teams as CSV (with header) or JSON Lines with all fields of expressions. The filters and sort are the same as in the listing",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Export expressions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv (default) or jsonl",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated statuses (0 - not ready, 1 - working, 2 - ready, 3 - error, 4 - abandoned, 5 - cancelled)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (2006-01-02 15:04:05)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before (2006-01-02 15:04:05)",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the calculation server",
+                        "name": "server",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Substring of the expression",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "creation_time, end_calculation_time or duration (only finished expressions), - before the name for descending order (default -creation_time)",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "expressions",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetAllExpressions"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/expressions/import": {
+            "post": {
+                "description": "Add expressions from CSV (with header) or JSON Lines, e.g. from the export. Rows need value, priority and team_id are optional, other fields are ignored. Correct rows are added as one batch (see /batches/{id}) and calculated again, errors are reported with line numbers. Up to MAX_IMPORT_SIZE rows",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Import expressions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv or jsonl, by default it is chosen by Content-Type",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutImportExpressions"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutImportExpressions"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutImportExpressions"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/expressions/{id}/result": {
+            "get": {
+                "description": "Wait until the expression is calculated, failed or cancelled, or until the wait is over, and return the expression. If it is not finished yet, finished is false and the expression has the current status. The wait is a duration (30s, 1m) or seconds, at most 60 seconds, without it the expression is returned at once",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Wait for result of expression",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "How long to wait, e.g. 30s",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionResult"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/expressions:batch": {
+            "post": {
+                "description": "Add many expressions at once (up to MAX_BATCH_SIZE). Every expression is checked, correct ones are added in one transaction, items of the answer contain ID or error of every expression. If no expression is correct, nothing is added. Progress of the batch is available at /batches/{id}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Post batch of expressions",
+                "parameters": [
+                    {
+                        "description": "Expressions",
+                        "name": "expressions",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InPostBatch"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key of the request, a retried request with the same key gets the same response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostBatch"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostBatch"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostBatch"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/getComputingPowers": {
+            "get": {
+                "description": "Get computing powers from storage",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "computing powers"
+                ],
+                "summary": "Get computing powers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetComputingPowers"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/getExpressionsByServer": {
+            "get": {
+                "description": "Get expressions from storage by server name. Deprecated, use GET /api/v2/servers/{name}/expressions",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Get expression by server",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "description": "Server name",
+                        "name": "server_name",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InGetExpressionByServer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionByServer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionByServer"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/getOperationsAndTimes": {
+            "get": {
+                "description": "Get operations and times for calculation as a map of operation and time in milliseconds, {\"+\": 100,...}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operations"
+                ],
+                "summary": "Get operations and times",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetOperationsAndTimes"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/getUser": {
+            "get": {
+                "description": "Get user info",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetUser"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetUser"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetUser"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/login": {
+            "post": {
+                "description": "Login with login and password. After several failed attempts logins to the account or from the IP are throttled. If the user has second factor, the answer contains challenge for /login/twoFactor instead of tokens",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Login",
+                "parameters": [
+                    {
+                        "description": "Login",
+                        "name": "login",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InLogin"
+                        }
+                    },
+                    {
+                        "description": "Password",
+                        "name": "password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InLogin"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/login/twoFactor": {
+            "post": {
+                "description": "Exchange challenge from /login and TOTP code or recovery code for tokens",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Login with second factor",
+                "parameters": [
+                    {
+                        "description": "Challenge and code",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InLoginTwoFactor"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/logout": {
+            "post": {
+                "description": "Revoke current session, its access and refresh tokens can not be used anymore",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Logout",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogout"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogout"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/logoutEverywhere": {
+            "post": {
+                "description": "Revoke all sessions of the user, including the current one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Logout everywhere",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogout"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogout"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/oidc/callback": {
+            "post": {
+                "description": "Exchange code from the OpenID Connect provider for tokens, cookie oidc_binding of /oidc/login is required. The user is created on the first login, its login is preferred_username or email of the provider. If the user has second factor, the answer contains challenge for /login/twoFactor instead of tokens",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Finish single sign-on",
+                "parameters": [
+                    {
+                        "description": "Code from the provider",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InOIDCCallback"
+                        }
+                    },
+                    {
+                        "description": "State from the provider",
+                        "name": "state",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InOIDCCallback"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogin"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/oidc/login": {
+            "get": {
+                "description": "Get URL of the OpenID Connect provider to which the user must be redirected. The provider redirects the user back to OIDC_REDIRECT_URL with code and state, they are sent to /oidc/callback. The answer sets HttpOnly cookie oidc_binding, it must be sent to /oidc/callback from the same browser",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Start single sign-on",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutOIDCLogin"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutOIDCLogin"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutOIDCLogin"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/ping": {
+            "get": {
+                "description": "Check connection with server",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ping"
+                ],
+                "summary": "Ping",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPing"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/postOperationsAndTimes": {
+            "post": {
+                "description": "Set operations and times for calculation as a map of operation and time in milliseconds, {\"+\": 100,...}. Deprecated, use PUT /api/v2/operations",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operations"
+                ],
+                "summary": "Set operations and times",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "description": "Operations and times",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostOperationsAndTimes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostOperationsAndTimes"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/refresh": {
+            "post": {
+                "description": "Exchange refresh token for new access and refresh tokens. Refresh token can be used only once, reusing it revokes the session",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "refresh",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InRefresh"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRefresh"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRefresh"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRefresh"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRefresh"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/register": {
+            "post": {
+                "description": "Register new user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register",
+                "parameters": [
+                    {
+                        "description": "Login",
+                        "name": "login",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InRegister"
+                        }
+                    },
+                    {
+                        "description": "Password",
+                        "name": "password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InRegister"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRegister"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRegister"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRegister"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/requeueExpression": {
+            "post": {
+                "description": "Return abandoned expression (servers died too many times while calculating it) to pending. Deprecated, use POST /api/v2/expressions/{id}/requeue",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Requeue expression",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InRequeueExpression"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRequeueExpression"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRequeueExpression"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRequeueExpression"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRequeueExpression"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRequeueExpression"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sessions": {
+            "get": {
+                "description": "Get active sessions of the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get sessions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetSessions"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetSessions"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sessions/{id}": {
+            "delete": {
+                "description": "Revoke one of the sessions of the user, e.g. on a lost device",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Delete session",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogout"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogout"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogout"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutLogout"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/teams": {
+            "get": {
+                "description": "Get teams of the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "Get teams",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetTeams"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetTeams"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create team, the user becomes its owner",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "Add team",
+                "parameters": [
+                    {
+                        "description": "Name of the team",
+                        "name": "name",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InAddTeam"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAddTeam"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAddTeam"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAddTeam"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/teams/{id}": {
+            "delete": {
+                "description": "Delete team, its expressions become personal expressions of their authors. Only for owners",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "Delete team",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Team ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteTeam"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteTeam"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteTeam"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteTeam"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/teams/{id}/members": {
+            "get": {
+                "description": "Get members of the team of the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "Get team members",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Team ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetTeamMembers"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetTeamMembers"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetTeamMembers"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Add user to the team or change role of the member. Only for owners",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "Set team member",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Team ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Login and role of the member",
+                        "name": "member",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InSetTeamMember"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutSetTeamMember"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutSetTeamMember"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutSetTeamMember"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutSetTeamMember"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutSetTeamMember"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/teams/{id}/members/{userId}": {
+            "delete": {
+                "description": "Remove user from the team. Owners can remove anyone except themselves, members can only leave",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "Delete team member",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Team ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteTeamMember"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteTeamMember"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteTeamMember"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteTeamMember"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteTeamMember"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/teams/{id}/operationsAndTimes": {
+            "get": {
+                "description": "Get operation times used for expressions of the team as a map of operation and time in milliseconds, {\"+\": 100,...}. If they are not set, operation times of authors are used and data is empty",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "Get operations and times of the team",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Team ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetOperationsAndTimes"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetOperationsAndTimes"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetOperationsAndTimes"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Set operation times for expressions of the team as a map of operation and time in milliseconds, {\"+\": 100,...}. Only for owners",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "Set operations and times of the team",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Team ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Operations and times",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostOperationsAndTimes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostOperationsAndTimes"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostOperationsAndTimes"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostOperationsAndTimes"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostOperationsAndTimes"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/teams/{id}/requireTwoFactor": {
+            "post": {
+                "description": "Members without second factor can not use the team while it is required. Only for owners, owner must have second factor to require it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "Require two-factor authentication in the team",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Team ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Is second factor required",
+                        "name": "required",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InSetTeamTwoFactor"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutSetTeamTwoFactor"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutSetTeamTwoFactor"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutSetTeamTwoFactor"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutSetTeamTwoFactor"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutSetTeamTwoFactor"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/twoFactor": {
+            "get": {
+                "description": "Get whether second factor is enabled and how many recovery codes are left",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get two-factor authentication",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetTwoFactor"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetTwoFactor"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/twoFactor/confirm": {
+            "post": {
+                "description": "Enable second factor with the first TOTP code, returns recovery codes",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Confirm second factor",
+                "parameters": [
+                    {
+                        "description": "TOTP code",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InTwoFactorCode"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRecoveryCodes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRecoveryCodes"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRecoveryCodes"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRecoveryCodes"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/twoFactor/disable": {
+            "post": {
+                "description": "Delete TOTP secret and recovery codes, current TOTP code or recovery code is required",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Disable second factor",
+                "parameters": [
+                    {
+                        "description": "TOTP code or recovery code",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InTwoFactorCode"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDisableTwoFactor"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDisableTwoFactor"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDisableTwoFactor"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/twoFactor/enroll": {
+            "post": {
+                "description": "Create TOTP secret, it is used after it is confirmed with a code (/twoFactor/confirm)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Enroll second factor",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutEnrollTwoFactor"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutEnrollTwoFactor"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutEnrollTwoFactor"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/twoFactor/recoveryCodes": {
+            "post": {
+                "description": "Replace recovery codes with new ones, current TOTP code is required",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Regenerate recovery codes",
+                "parameters": [
+                    {
+                        "description": "TOTP code",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InTwoFactorCode"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRecoveryCodes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRecoveryCodes"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRecoveryCodes"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/updateUser": {
+            "post": {
+                "description": "Update user info, the old password is required unless the user has no password (users of the identity provider)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Update user",
+                "parameters": [
+                    {
+                        "description": "New login",
+                        "name": "login",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.InUpdateUser"
+                        }
+                    },
+                    {
+                        "description": "New password",
+                        "name": "password",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.InUpdateUser"
+                        }
+                    },
+                    {
+                        "description": "Old password",
+                        "name": "old_password",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.InUpdateUser"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRegister"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRegister"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRegister"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRegister"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks": {
+            "get": {
+                "description": "Get webhooks of the user without secrets",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetWebhooks"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetWebhooks"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Register URL that is called (POST) when expressions of the user are calculated, failed or cancelled, or only one expression if expression_id is set. Payloads are signed: X-Webhook-Signature is \"sha256=\" + hex of HMAC-SHA256 of \"\u003cX-Webhook-Timestamp\u003e.\u003cbody\u003e\" with the secret. Failed deliveries are retried with exponential backoff",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Add webhook",
+                "parameters": [
+                    {
+                        "description": "URL and optional expression",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InAddWebhook"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAddWebhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAddWebhook"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAddWebhook"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAddWebhook"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/{id}": {
+            "delete": {
+                "description": "Delete webhook of the user with its deliveries",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteWebhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteWebhook"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteWebhook"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteWebhook"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "Get deliveries of the webhook, the newest first. Status is pending, delivered or failed (all attempts failed)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of deliveries (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetWebhookDeliveries"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetWebhookDeliveries"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetWebhookDeliveries"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetWebhookDeliveries"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/{id}/deliveries/{deliveryId}/redeliver": {
+            "post": {
+                "description": "Send the delivery again, it gets all attempts again",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Redeliver webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Delivery ID",
+                        "name": "deliveryId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRedeliverWebhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRedeliverWebhook"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRedeliverWebhook"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRedeliverWebhook"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRedeliverWebhook"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/events": {
+            "get": {
+                "description": "Server-Sent Events stream of changes of expressions of the user and of their teams. Event names are created, status, progress (server is alive), finished and deleted, data is {\"id\", \"type\", \"expression\"}. After reconnect the stream is resumed after Last-Event-ID header (or last_event_id parameter), if the events are not kept anymore, reset event is sent and expressions must be reloaded. Comment lines are sent every 15 seconds to keep the connection",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Stream expression events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the last received event",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the last received event",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/expressions": {
+            "get": {
+                "description": "Get a page of expressions of the user and of their teams, the next page is requested with next_cursor",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Get expressions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma separated statuses (0 - not ready, 1 - working, 2 - ready, 3 - error, 4 - abandoned, 5 - cancelled)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (2006-01-02 15:04:05)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before (2006-01-02 15:04:05)",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the calculation server",
+                        "name": "server",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Substring of the expression",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "creation_time, end_calculation_time or duration (only finished expressions), - before the name for descending order (default -creation_time)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of expressions on the page (default 100, up to 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetAllExpressions"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetAllExpressions"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Add expression to storage, Location header of the answer is the URL of the expression",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Add expression",
+                "parameters": [
+                    {
+                        "description": "Expression",
+                        "name": "expression",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.InPostExpression"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key of the request, a retried request with the same key gets the same response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostExpression"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostExpression"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostExpression"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutPostExpression"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/expressions/{id}": {
+            "get": {
+                "description": "Get expression by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Get expression",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionByID"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionByID"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionByID"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete expression from storage, if it is being calculated, server will stop calculating it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Delete expression",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteExpression"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteExpression"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteExpression"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutDeleteExpression"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/expressions/{id}/cancel": {
+            "post": {
+                "description": "Stop calculation of expression, server that calculates it will be notified",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Cancel expression",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutCancelExpression"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutCancelExpression"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutCancelExpression"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutCancelExpression"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/expressions/{id}/history": {
+            "get": {
+                "description": "Get previous runs of the expression (results before retries), the oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Get expression history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionHistory"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionHistory"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionHistory"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionHistory"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/expressions/{id}/requeue": {
+            "post": {
+                "description": "Return abandoned expression (servers died too many times while calculating it) to pending",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Requeue expression",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRequeueExpression"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRequeueExpression"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRequeueExpression"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRequeueExpression"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/expressions/{id}/result": {
+            "get": {
+                "description": "Wait until the expression is calculated, failed or cancelled, or until the wait is over, and return the expression. If it is not finished yet, finished is false and the expression has the current status. The wait is a duration (30s, 1m) or seconds, at most 60 seconds, without it the expression is returned at once",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expression"
+                ],
+                "summary": "Wait for result of expression",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "How long to wait, e.g. 30s",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionResult"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/expressions/{id}/retry": {
+            "post": {
+                "description": "Calculate finished expression again, previous result is kept in the history of the expression",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Retry expression",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Expression ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Operation times for the new run",
+                        "name": "operations",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.InRetryExpression"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRetryExpression"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRetryExpression"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRetryExpression"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRetryExpression"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutRetryExpression"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/operations": {
+            "get": {
+                "description": "Get times of operations in milliseconds, {\"+\": 100,...}",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Get operations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetOperationsAndTimes"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetOperationsAndTimes"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace times of all operations in milliseconds, the body must contain all of \"+\", \"-\", \"*\", \"/\" and nothing else",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Set operations",
+                "parameters": [
+                    {
+                        "description": "Operations and times",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetOperationsAndTimes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetOperationsAndTimes"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetOperationsAndTimes"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/servers": {
+            "get": {
+                "description": "Get calculation servers with their status and expressions of the user that they calculate",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Get servers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetComputingPowers"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/servers/{name}/expressions": {
+            "get": {
+                "description": "Get expressions of the user that were calculated by the server",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Get expressions of server",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Server name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutGetExpressionByServer"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.OutAuthData"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.ComputingPower": {
+            "type": "object",
+            "properties": {
+                "calculated_expressions": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "server_name": {
+                    "type": "string"
+                },
+                "server_status": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.InAddAPIKey": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expires_in": {
+                    "description": "seconds, 0 - the key does not expire",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.InAddTeam": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.InAddWebhook": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "expression_id": {
+                    "description": "optional, the webhook is called only for this expression",
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.InAddWorker": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.InGetExpressionByID": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.InGetExpressionByServer": {
+            "type": "object",
+            "required": [
+                "server_name"
+            ],
+            "properties": {
+                "server_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.InLogin": {
+            "type": "object",
+            "required": [
+                "login",
+                "password"
+            ],
+            "properties": {
+                "login": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.InLoginTwoFactor": {
+            "type": "object",
+            "required": [
+                "challenge",
+                "code"
+            ],
+            "properties": {
+                "challenge": {
+                    "type": "string"
+                },
+                "code": {
+                    "description": "TOTP code or recovery code",
+                    "type": "string"
+                }
+            }
+        },
+        "api.InOIDCCallback": {
+            "type": "object",
+            "required": [
+                "code",
+                "state"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.InPostBatch": {
+            "type": "object",
+            "required": [
+                "expressions"
+            ],
+            "properties": {
+                "expressions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.InPostExpression"
+                    }
+                }
+            }
+        },
+        "api.InPostExpression": {
+            "type": "object",
+            "required": [
+                "expression"
+            ],
+            "properties": {
+                "expression": {
+                    "type": "string"
+                },
+                "priority": {
+                    "description": "from 0 to 10, expressions with higher priority are calculated first",
+                    "type": "integer"
+                },
+                "team": {
+                    "description": "optional ID of the team of the user, the expression is shared with the team",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.InRefresh": {
+            "type": "object",
+            "required": [
+                "refresh"
+            ],
+            "properties": {
+                "refresh": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.InRegister": {
+            "type": "object",
+            "required": [
+                "login",
+                "password"
+            ],
+            "properties": {
+                "login": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.InRequeueExpression": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.InRetryExpression": {
+            "type": "object",
+            "properties": {
+                "operations": {
+                    "description": "optional operation times in milliseconds for the new run: {\"+\": 100,...}, not set operations are taken from\nthe user",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "api.InRotateSigningKeys": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.InSetTeamMember": {
+            "type": "object",
+            "required": [
+                "login"
+            ],
+            "properties": {
+                "login": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "owner or member (default)",
+                    "type": "string"
+                }
+            }
+        },
+        "api.InSetTeamTwoFactor": {
+            "type": "object",
+            "properties": {
+                "required": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "api.InSetUserRole": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "description": "user or admin",
+                    "type": "string"
+                }
+            }
+        },
+        "api.InTwoFactorCode": {
+            "type": "object",
+            "required": [
+                "code"
             ],
             "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.InUpdateUser": {
+            "type": "object",
+            "properties": {
+                "login": {
+                    "type": "string"
+                },
+                "old_password": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutAddAPIKey": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "$ref": "#/definitions/db.APIKey"
+                },
+                "key": {
+                    "description": "shown only once",
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutAddTeam": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "team": {
+                    "$ref": "#/definitions/db.Team"
+                }
+            }
+        },
+        "api.OutAddWebhook": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "it is shown only once",
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutAddWorker": {
+            "type": "object",
+            "properties": {
+                "enrollment_token": {
+                    "description": "shown only once",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutAdminUser": {
+            "type": "object",
+            "properties": {
+                "disabled": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutAuthData": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutBatchItem": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.OutCancelExpression": {
+            "type": "object",
+            "properties": {
+                "expression": {
+                    "$ref": "#/definitions/db.Expression"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutDeleteAPIKey": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutDeleteExpression": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutDeleteTeam": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutDeleteTeamMember": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutDeleteWebhook": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutDisableTwoFactor": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutEnrollTwoFactor": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "provisioning_uri": {
+                    "description": "otpauth:// URI for authenticator apps",
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutErrorKind": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "grpc_code": {
+                    "type": "string"
+                },
+                "http_status": {
+                    "type": "integer"
+                },
+                "retryable": {
+                    "type": "boolean"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutGetAPIKeys": {
+            "type": "object",
+            "properties": {
+                "api_keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.APIKey"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutGetAllExpressions": {
+            "type": "object",
+            "properties": {
+                "counts": {
+                    "description": "number of expressions by status, without status filter",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "expressions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.Expression"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "next_cursor": {
+                    "description": "empty on the last page",
+                    "type": "string"
+                },
+                "total": {
+                    "description": "number of expressions that match the filters",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.OutGetBatch": {
+            "type": "object",
+            "properties": {
+                "batch": {
+                    "$ref": "#/definitions/db.Batch"
+                },
+                "counts": {
+                    "description": "number of expressions of the batch by status, deleted ones are not counted",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "done": {
+                    "description": "all expressions of the batch are finished",
+                    "type": "boolean"
+                },
+                "finished": {
+                    "description": "number of calculated, failed, abandoned and cancelled expressions",
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutGetComputingPowers": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "servers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ComputingPower"
+                    }
+                }
+            }
+        },
+        "api.OutGetErrors": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.OutErrorKind"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutGetExpressionByID": {
+            "type": "object",
+            "properties": {
+                "expression": {
+                    "$ref": "#/definitions/db.Expression"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutGetExpressionByServer": {
+            "type": "object",
+            "properties": {
+                "expressions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.Expression"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutGetExpressionHistory": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "runs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.ExpressionRun"
+                    }
+                }
+            }
+        },
+        "api.OutGetExpressionResult": {
+            "type": "object",
+            "properties": {
+                "expression": {
+                    "$ref": "#/definitions/db.Expression"
+                },
+                "finished": {
+                    "description": "false if the wait is over before the expression is finished",
+                    "type": "boolean"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutGetLoginAttempts": {
+            "type": "object",
+            "properties": {
+                "login_attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.LoginAttempt"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutGetOperationsAndTimes": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "executions times in milliseconds: {\"+\": 100,...}",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutGetServers": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "servers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.OutServer"
+                    }
+                }
+            }
+        },
+        "api.OutGetSessions": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "sessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.OutSession"
+                    }
+                }
+            }
+        },
+        "api.OutGetSigningKeys": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "signing_keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.SigningKey"
+                    }
+                }
+            }
+        },
+        "api.OutGetTeamMembers": {
+            "type": "object",
+            "properties": {
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.TeamMember"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutGetTeams": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "teams": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.OutTeam"
+                    }
+                }
+            }
+        },
+        "api.OutGetTwoFactor": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "recovery_codes": {
+                    "description": "number of unused recovery codes",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.OutGetUser": {
+            "type": "object",
+            "properties": {
+                "login": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutGetUsers": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.OutAdminUser"
+                    }
+                }
+            }
+        },
+        "api.OutGetWebhookDeliveries": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.WebhookDelivery"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutGetWebhooks": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.Webhook"
+                    }
+                }
+            }
+        },
+        "api.OutGetWorkers": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "workers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.Worker"
+                    }
+                }
+            }
+        },
+        "api.OutImportError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.OutImportExpressions": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.OutImportError"
+                    }
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutLogin": {
+            "type": "object",
+            "properties": {
+                "access": {
+                    "type": "string"
+                },
+                "challenge": {
+                    "description": "if the user has second factor, tokens are empty and the challenge is exchanged for them with /login/twoFactor",
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "refresh": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutLogout": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutOIDCLogin": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutPing": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutPostBatch": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "description": "in the order of the posted expressions",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.OutBatchItem"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutPostExpression": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutPostOperationsAndTimes": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutRecoveryCodes": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "recovery_codes": {
+                    "description": "shown only once",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.OutRedeliverWebhook": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutRefresh": {
+            "type": "object",
+            "properties": {
+                "access": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "refresh": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutRegister": {
+            "type": "object",
+            "properties": {
+                "access": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "refresh": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutReleaseExpression": {
+            "type": "object",
+            "properties": {
+                "expression": {
+                    "$ref": "#/definitions/db.Expression"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutRequeueExpression": {
+            "type": "object",
+            "properties": {
+                "expression": {
+                    "$ref": "#/definitions/db.Expression"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutRetireSigningKey": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutRetryExpression": {
+            "type": "object",
+            "properties": {
+                "expression": {
+                    "$ref": "#/definitions/db.Expression"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutRevokeWorker": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "released": {
+                    "description": "expressions that were returned to pending",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.Expression"
+                    }
+                }
+            }
+        },
+        "api.OutRotateSigningKeys": {
+            "type": "object",
+            "properties": {
+                "kid": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutServer": {
+            "type": "object",
+            "properties": {
+                "calculated_expressions": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
                 "server_name": {
                     "type": "string"
+                },
+                "server_status": {
+                    "type": "string"
                 }
             }
         },
-        "api.InPostExpression": {
+        "api.OutSession": {
             "type": "object",
-            "required": [
-                "expression"
-            ],
             "properties": {
-                "expression": {
+                "creation_time": {
+                    "type": "string"
+                },
+                "current": {
+                    "description": "the session of the request",
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_seen_time": {
+                    "type": "string"
+                },
+                "revoked": {
+                    "type": "boolean"
+                },
+                "user_agent": {
                     "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
-        "api.InRegister": {
+        "api.OutSetTeamMember": {
             "type": "object",
-            "required": [
-                "login",
-                "password"
-            ],
             "properties": {
-                "login": {
-                    "type": "string"
+                "member": {
+                    "$ref": "#/definitions/db.TeamMember"
                 },
-                "password": {
+                "message": {
                     "type": "string"
                 }
             }
         },
-        "api.InUpdateUser": {
+        "api.OutSetTeamTwoFactor": {
             "type": "object",
             "properties": {
-                "login": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OutTeam": {
+            "type": "object",
+            "properties": {
+                "creation_time": {
                     "type": "string"
                 },
-                "old_password": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
                     "type": "string"
                 },
-                "password": {
+                "require_two_factor": {
+                    "description": "members without second factor can not use the team",
+                    "type": "boolean"
+                },
+                "role": {
+                    "description": "role of the user in the team",
                     "type": "string"
                 }
             }
         },
-        "api.OutGetAllExpressions": {
+        "api.OutUpdateUserByAdmin": {
             "type": "object",
             "properties": {
-                "expressions": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/db.Expression"
-                    }
-                },
                 "message": {
                     "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/api.OutAdminUser"
                 }
             }
         },
-        "api.OutGetComputingPowers": {
+        "db.APIKey": {
             "type": "object",
             "properties": {
-                "message": {
+                "creation_time": {
                     "type": "string"
                 },
-                "servers": {
-                    "type": "array",
-                    "items": {
-                        "type": "object",
-                        "properties": {
-                            "calculated_expressions": {
-                                "type": "array",
-                                "items": {
-                                    "type": "integer"
-                                }
-                            },
-                            "server_name": {
-                                "type": "string"
-                            },
-                            "server_status": {
-                                "type": "string"
-                            }
-                        }
-                    }
+                "expires_at": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_used_time": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked": {
+                    "type": "boolean"
+                },
+                "scopes": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
-        "api.OutGetExpressionByID": {
+        "db.Batch": {
             "type": "object",
             "properties": {
-                "expression": {
-                    "$ref": "#/definitions/db.Expression"
-                },
-                "message": {
+                "creation_time": {
                     "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "size": {
+                    "description": "number of expressions that were added",
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
-        "api.OutGetExpressionByServer": {
+        "db.Expression": {
             "type": "object",
             "properties": {
-                "expressions": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/db.Expression"
-                    }
+                "alive_expires_at": {
+                    "type": "integer"
                 },
-                "message": {
+                "answer": {
+                    "type": "number"
+                },
+                "attempts": {
+                    "description": "how many times servers died while calculating it",
+                    "type": "integer"
+                },
+                "batch_id": {
+                    "description": "0 - expression is not a part of a batch",
+                    "type": "integer"
+                },
+                "creation_time": {
+                    "type": "string"
+                },
+                "end_calculation_time": {
+                    "type": "string"
+                },
+                "failed_servers": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "logs": {
+                    "type": "string"
+                },
+                "priority": {
+                    "description": "expressions with higher priority are calculated first",
+                    "type": "integer"
+                },
+                "ready": {
+                    "description": "0 - not ready, 1 - working, 2 - ready, 3 - error, 4 - abandoned, 5 - cancelled",
+                    "type": "integer"
+                },
+                "server_name": {
+                    "type": "string"
+                },
+                "team_id": {
+                    "description": "0 - personal expression of the user",
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "value": {
                     "type": "string"
                 }
             }
         },
-        "api.OutGetOperationsAndTimes": {
+        "db.ExpressionRun": {
             "type": "object",
             "properties": {
-                "data": {
-                    "description": "executions times in milliseconds: {\"+\": 100,...}",
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "integer"
-                    }
+                "answer": {
+                    "type": "number"
                 },
-                "message": {
+                "creation_time": {
+                    "type": "string"
+                },
+                "end_calculation_time": {
+                    "type": "string"
+                },
+                "expression_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "logs": {
+                    "type": "string"
+                },
+                "ready": {
+                    "type": "integer"
+                },
+                "server_name": {
                     "type": "string"
                 }
             }
         },
-        "api.OutGetUser": {
+        "db.LoginAttempt": {
             "type": "object",
             "properties": {
+                "creation_time": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
                 "login": {
                     "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
-        "api.OutPing": {
+        "db.SigningKey": {
             "type": "object",
             "properties": {
-                "message": {
+                "algorithm": {
+                    "type": "string"
+                },
+                "creation_time": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kid": {
                     "type": "string"
+                },
+                "retired_at": {
+                    "type": "integer"
                 }
             }
         },
-        "api.OutPostExpression": {
+        "db.Team": {
             "type": "object",
             "properties": {
+                "creation_time": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
-                "message": {
+                "name": {
                     "type": "string"
+                },
+                "require_two_factor": {
+                    "description": "members without second factor can not use the team",
+                    "type": "boolean"
                 }
             }
         },
-        "api.OutPostOperationsAndTimes": {
+        "db.TeamMember": {
             "type": "object",
             "properties": {
-                "message": {
+                "id": {
+                    "type": "integer"
+                },
+                "login": {
+                    "description": "login of the user, filled by GetTeamMembers",
+                    "type": "string"
+                },
+                "role": {
                     "type": "string"
+                },
+                "team_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
-        "api.OutRegister": {
+        "db.Webhook": {
             "type": "object",
             "properties": {
-                "access": {
+                "creation_time": {
                     "type": "string"
                 },
-                "message": {
+                "expression_id": {
+                    "description": "0 - all expressions of the user",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "url": {
                     "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
-        "db.Expression": {
+        "db.WebhookDelivery": {
             "type": "object",
             "properties": {
-                "alive_expires_at": {
+                "attempts": {
                     "type": "integer"
                 },
-                "answer": {
-                    "type": "number"
-                },
                 "creation_time": {
                     "type": "string"
                 },
-                "end_calculation_time": {
+                "delivered_time": {
+                    "type": "string"
+                },
+                "event": {
                     "type": "string"
                 },
+                "expression_id": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "integer"
                 },
-                "logs": {
+                "last_error": {
                     "type": "string"
                 },
-                "ready": {
-                    "description": "0 - not ready, 1 - working, 2 - ready, 3 - error",
+                "next_attempt_at": {
                     "type": "integer"
                 },
-                "server_name": {
+                "payload": {
                     "type": "string"
                 },
-                "user_id": {
+                "response_status": {
+                    "description": "HTTP status of the last attempt",
                     "type": "integer"
                 },
-                "value": {
+                "status": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.Worker": {
+            "type": "object",
+            "properties": {
+                "creation_time": {
                     "type": "string"
+                },
+                "enrollment_expires_at": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "revoked": {
+                    "type": "boolean"
                 }
             }
         }
@@ -666,7 +5452,7 @@ const docTemplate = `{
 var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
 	Host:             "localhost:8080",
-	BasePath:         "/api/v1.",
+	BasePath:         "/api",
 	Schemes:          []string{},
 	Title:            "Swagger Storage API",
 	Description:      "This is a server for the storage of expressions and their results",
@@ -8,20 +8,44 @@ import (
 	"go.uber.org/zap"
 	"os"
 	"storage/internal/availableservers"
+	"storage/internal/cryptPasswords"
 	"storage/internal/db"
+	"storage/internal/events"
 	"storage/internal/expressionstorage"
+	"storage/internal/jwtkeys"
+	"storage/internal/loginlimiter"
+	"storage/internal/oidc"
+	"storage/internal/webhooks"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 type API struct {
-	db              *db.APIDb
-	expressions     *expressionstorage.ExpressionStorage
-	servers         *availableservers.AvailableServers
-	statusWorkers   *sync.Map
-	execTimeConfig  *ExecTimeConfig
-	checkAlive      int
-	secretSignature []byte
+	db                  *db.APIDb
+	expressions         *expressionstorage.ExpressionStorage
+	servers             *availableservers.AvailableServers
+	statusWorkers       *sync.Map
+	execTimeConfig      *ExecTimeConfig
+	checkAlive          int
+	secretSignature     []byte
+	keys                *jwtkeys.KeySet
+	adminToken          []byte
+	accessLifetime      time.Duration
+	refreshLifetime     time.Duration
+	passwordPolicy      *cryptPasswords.PasswordPolicy
+	accountLimiter      *loginlimiter.Limiter
+	ipLimiter           *loginlimiter.Limiter
+	totpIssuer          string
+	oidc                *oidc.Client
+	maxBatchSize        int
+	idempotencyLifetime time.Duration // how long responses to requests with Idempotency-Key are kept
+	events              *events.Broker
+	webhooks            *webhooks.Dispatcher
+	maxImportSize       int
+	trustedProxies      []string // client IP is taken from X-Forwarded-For only behind these proxies
+	now                 func() time.Time
 }
 
 func New(_db *db.APIDb, expressions *expressionstorage.ExpressionStorage, statusWorkers *sync.Map, servers *availableservers.AvailableServers, execTimeConfig *ExecTimeConfig) *API {
@@ -30,26 +54,79 @@ func New(_db *db.APIDb, expressions *expressionstorage.ExpressionStorage, status
 		zap.S().Fatal(err)
 	}
 	newAPI := &API{
-		db:              _db,
-		statusWorkers:   statusWorkers,
-		checkAlive:      num,
-		secretSignature: []byte(os.Getenv("SECRET_SIGNATURE")),
-		execTimeConfig:  execTimeConfig,
+		db:                  _db,
+		statusWorkers:       statusWorkers,
+		checkAlive:          num,
+		secretSignature:     []byte(os.Getenv("SECRET_SIGNATURE")),
+		adminToken:          []byte(os.Getenv("ADMIN_TOKEN")),
+		execTimeConfig:      execTimeConfig,
+		accessLifetime:      secondsFromEnv("ACCESS_TOKEN_LIFETIME", defaultAccessLifetime),
+		refreshLifetime:     secondsFromEnv("REFRESH_TOKEN_LIFETIME", defaultRefreshLifetime),
+		maxBatchSize:        numberFromEnv("MAX_BATCH_SIZE", defaultMaxBatchSize),
+		maxImportSize:       numberFromEnv("MAX_IMPORT_SIZE", defaultMaxImportSize),
+		idempotencyLifetime: secondsFromEnv("IDEMPOTENCY_KEY_LIFETIME", defaultIdempotencyKeyLifetime),
 	}
+	newAPI.keys = newKeySet(_db, newAPI.accessLifetime)
 	newAPI.expressions = expressions
+	newAPI.events = events.New(numberFromEnv("EVENTS_BUFFER_SIZE", defaultEventsBufferSize))
+	expressions.AddHook(func(event expressionstorage.Event) {
+		newAPI.events.Publish(event.Type, event.Expression)
+	})
+	newAPI.webhooks = newWebhookDispatcher(_db)
+	expressions.AddHook(newAPI.webhookHook)
 	newAPI.servers = servers
+	newAPI.passwordPolicy = newPasswordPolicy()
+	newAPI.accountLimiter, newAPI.ipLimiter = newLoginLimiters()
+	newAPI.totpIssuer = os.Getenv("TOTP_ISSUER")
+	if newAPI.totpIssuer == "" {
+		newAPI.totpIssuer = defaultTOTPIssuer
+	}
+	newAPI.oidc = newOIDCClient()
+	newAPI.trustedProxies = strings.FieldsFunc(os.Getenv("TRUSTED_PROXIES"), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	newAPI.now = time.Now
 	return newAPI
 }
 
+// numberFromEnv returns positive number from environment variable name, def if it is not set.
+func numberFromEnv(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	num, err := strconv.Atoi(value)
+	if err != nil || num <= 0 {
+		zap.S().Fatalf("%v must be a positive number", name)
+	}
+	return num
+}
+
+// secondsFromEnv returns duration from environment variable name in seconds, def if it is not set.
+func secondsFromEnv(name string, def time.Duration) time.Duration {
+	return time.Duration(numberFromEnv(name, int(def/time.Second))) * time.Second
+}
+
 func (a *API) Start() *gin.Engine {
 	router := gin.Default()
+	// by default no proxy is trusted, otherwise anyone could choose the IP that limits of logins see
+	if err := router.SetTrustedProxies(a.trustedProxies); err != nil {
+		zap.S().Fatal("TRUSTED_PROXIES must be a list of IPs or CIDRs: ", err)
+	}
+	router.Use(a.Problems)
 
 	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
-	config.AllowHeaders = []string{"Authorization", "Content-Type"}
+	// every origin is allowed, credentials are only the cookie that binds single sign-on to the browser, other
+	// requests are authenticated with headers
+	config.AllowOriginFunc = func(string) bool { return true }
+	config.AllowCredentials = true
+	config.AllowHeaders = []string{"Authorization", "Content-Type", "X-API-Key", HeaderCorrelationID}
+	config.ExposeHeaders = []string{HeaderCorrelationID}
 	router.Use(cors.New(config))
 
 	router.GET("/api/v1/ping", a.Ping)
+	router.GET("/api/v1/errors", a.GetErrors)
+	router.GET("/.well-known/jwks.json", a.GetJWKS)
 
 	authorized := router.Group("/api/v1")
 	authorized.Use(a.Auth)
@@ -57,16 +134,98 @@ func (a *API) Start() *gin.Engine {
 	// for users
 	router.POST("/api/v1/register", a.Register)
 	router.POST("/api/v1/login", a.Login)
+	router.POST("/api/v1/refresh", a.Refresh)
+	router.POST("/api/v1/login/twoFactor", a.LoginTwoFactor)
+	router.GET("/api/v1/oidc/login", a.OIDCLogin)
+	router.POST("/api/v1/oidc/callback", a.OIDCCallback)
 
+	// account can be managed only with JWT, other routes can be used with API keys with the right scope
+	authorized.POST("/logout", a.RequireSession, a.Logout)
+	authorized.POST("/logoutEverywhere", a.RequireSession, a.LogoutEverywhere)
+	authorized.GET("/sessions", a.RequireSession, a.GetSessions)
+	authorized.DELETE("/sessions/:id", a.RequireSession, a.DeleteSession)
+	authorized.POST("/apiKeys", a.RequireSession, a.AddAPIKey)
+	authorized.GET("/apiKeys", a.RequireSession, a.GetAPIKeys)
+	authorized.DELETE("/apiKeys/:id", a.RequireSession, a.DeleteAPIKey)
+	authorized.GET("/twoFactor", a.RequireSession, a.GetTwoFactor)
+	authorized.POST("/twoFactor/enroll", a.RequireSession, a.EnrollTwoFactor)
+	authorized.POST("/twoFactor/confirm", a.RequireSession, a.ConfirmTwoFactor)
+	authorized.POST("/twoFactor/disable", a.RequireSession, a.DisableTwoFactor)
+	authorized.POST("/twoFactor/recoveryCodes", a.RequireSession, a.RegenerateRecoveryCodes)
 	authorized.GET("/getUser", a.GetUser)
-	authorized.POST("/updateUser", a.UpdateUser)
-	authorized.POST("/expression", a.PostExpression)
-	authorized.GET("/expression", a.GetAllExpressions)
-	authorized.GET("/expressionById", a.GetExpressionByID)
-	authorized.POST("/postOperationsAndTimes", a.PostOperationsAndTimes)
+	authorized.POST("/updateUser", a.RequireSession, a.UpdateUser)
+	authorized.POST("/expression", a.RequireScope(ScopeExpressionsWrite), a.Idempotent, a.PostExpression)
+	authorized.GET("/expression", a.RequireScope(ScopeExpressionsRead), a.GetAllExpressions)
+	authorized.POST("/expressions:method", a.RequireScope(ScopeExpressionsWrite), a.Idempotent,
+		a.ExpressionsMethod)
+	authorized.GET("/batches/:id", a.RequireScope(ScopeExpressionsRead), a.GetBatch)
+	authorized.GET("/expressionById", a.RequireScope(ScopeExpressionsRead), a.GetExpressionByID)
+	authorized.POST("/requeueExpression", a.RequireScope(ScopeExpressionsWrite), a.RequeueExpression)
+	authorized.DELETE("/expression/:id", a.RequireScope(ScopeExpressionsWrite), a.DeleteExpression)
+	authorized.POST("/expression/:id/cancel", a.RequireScope(ScopeExpressionsWrite), a.CancelExpression)
+	authorized.POST("/expression/:id/retry", a.RequireScope(ScopeExpressionsWrite), a.RetryExpression)
+	authorized.GET("/expression/:id/history", a.RequireScope(ScopeExpressionsRead), a.GetExpressionHistory)
+	authorized.GET("/expressions/export", a.RequireScope(ScopeExpressionsRead), a.ExportExpressions)
+	authorized.POST("/expressions/import", a.RequireScope(ScopeExpressionsWrite), a.ImportExpressions)
+	authorized.GET("/expressions/:id/result", a.RequireScope(ScopeExpressionsRead), a.GetExpressionResult)
+	authorized.GET("/events", a.RequireScope(ScopeExpressionsRead), a.GetEvents)
+	authorized.POST("/webhooks", a.RequireScope(ScopeExpressionsWrite), a.AddWebhook)
+	authorized.GET("/webhooks", a.RequireScope(ScopeExpressionsRead), a.GetWebhooks)
+	authorized.DELETE("/webhooks/:id", a.RequireScope(ScopeExpressionsWrite), a.DeleteWebhook)
+	authorized.GET("/webhooks/:id/deliveries", a.RequireScope(ScopeExpressionsRead), a.GetWebhookDeliveries)
+	authorized.POST("/webhooks/:id/deliveries/:deliveryId/redeliver", a.RequireScope(ScopeExpressionsWrite),
+		a.RedeliverWebhook)
+	authorized.POST("/postOperationsAndTimes", a.RequireScope(ScopeOperationsWrite), a.PostOperationsAndTimes)
 	authorized.GET("/getOperationsAndTimes", a.GetOperationsAndTimes)
-	authorized.GET("/getExpressionsByServer", a.GetExpressionsByServer)
-	authorized.GET("/getComputingPowers", a.GetComputingPowers)
+	authorized.GET("/getExpressionsByServer", a.RequireScope(ScopeExpressionsRead), a.GetExpressionsByServer)
+	authorized.GET("/getComputingPowers", a.RequireScope(ScopeExpressionsRead), a.GetComputingPowers)
+	authorized.POST("/teams", a.RequireSession, a.AddTeam)
+	authorized.GET("/teams", a.RequireSession, a.GetTeams)
+	authorized.DELETE("/teams/:id", a.RequireSession, a.DeleteTeam)
+	authorized.GET("/teams/:id/members", a.RequireSession, a.GetTeamMembers)
+	authorized.POST("/teams/:id/members", a.RequireSession, a.SetTeamMember)
+	authorized.DELETE("/teams/:id/members/:userId", a.RequireSession, a.DeleteTeamMember)
+	authorized.GET("/teams/:id/operationsAndTimes", a.RequireSession, a.GetTeamOperationsAndTimes)
+	authorized.POST("/teams/:id/requireTwoFactor", a.RequireSession, a.SetTeamTwoFactor)
+	authorized.POST("/teams/:id/operationsAndTimes", a.RequireSession, a.PostTeamOperationsAndTimes)
+
+	// resource-oriented routes, v1 routes above are kept for old clients
+	v2 := router.Group("/api/v2")
+	v2.Use(a.Auth)
+
+	v2.POST("/expressions", a.RequireScope(ScopeExpressionsWrite), a.Idempotent, a.PostExpressionV2)
+	v2.GET("/expressions", a.RequireScope(ScopeExpressionsRead), a.GetExpressionsV2)
+	v2.GET("/expressions/:id", a.RequireScope(ScopeExpressionsRead), a.GetExpressionV2)
+	v2.DELETE("/expressions/:id", a.RequireScope(ScopeExpressionsWrite), a.DeleteExpressionV2)
+	v2.POST("/expressions/:id/cancel", a.RequireScope(ScopeExpressionsWrite), a.CancelExpressionV2)
+	v2.POST("/expressions/:id/retry", a.RequireScope(ScopeExpressionsWrite), a.RetryExpressionV2)
+	v2.POST("/expressions/:id/requeue", a.RequireScope(ScopeExpressionsWrite), a.RequeueExpressionV2)
+	v2.GET("/expressions/:id/history", a.RequireScope(ScopeExpressionsRead), a.GetExpressionHistoryV2)
+	v2.GET("/expressions/:id/result", a.RequireScope(ScopeExpressionsRead), a.GetExpressionResult)
+	v2.GET("/events", a.RequireScope(ScopeExpressionsRead), a.GetEvents)
+	v2.GET("/servers", a.RequireScope(ScopeExpressionsRead), a.GetServersV2)
+	v2.GET("/servers/:name/expressions", a.RequireScope(ScopeExpressionsRead), a.GetServerExpressionsV2)
+	v2.GET("/operations", a.GetOperationsV2)
+	v2.PUT("/operations", a.RequireScope(ScopeOperationsWrite), a.PutOperationsV2)
+
+	// for admins
+	admin := router.Group("/api/v1/admin")
+	admin.Use(a.AdminAuth, a.RequireAdmin)
+
+	admin.POST("/workers", a.AddWorker)
+	admin.GET("/workers", a.GetWorkers)
+	admin.DELETE("/workers/:name", a.RevokeWorker)
+	admin.GET("/users", a.GetUsers)
+	admin.POST("/users/:id/role", a.SetUserRole)
+	admin.POST("/users/:id/disable", a.DisableUser)
+	admin.POST("/users/:id/enable", a.EnableUser)
+	admin.GET("/expressions", a.GetAllUsersExpressions)
+	admin.POST("/expressions/:id/release", a.ReleaseExpression)
+	admin.GET("/servers", a.GetServers)
+	admin.GET("/loginAttempts", a.GetLoginAttempts)
+	admin.GET("/signingKeys", a.GetSigningKeys)
+	admin.POST("/signingKeys/rotate", a.RotateSigningKeys)
+	admin.POST("/signingKeys/:kid/retire", a.RetireSigningKey)
 
 	// docs
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
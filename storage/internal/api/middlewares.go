@@ -1,12 +1,22 @@
 package api
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
 	"net/http"
+	"storage/internal/apierrors"
+	"storage/internal/db"
+	"storage/internal/jwtkeys"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type OutAuthData struct {
@@ -15,16 +25,16 @@ type OutAuthData struct {
 
 func (a *API) Auth(c *gin.Context) {
 	var out OutAuthData
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		a.authAPIKey(c, apiKey)
+		return
+	}
+
 	access := c.GetHeader("Authorization")
 	access = strings.Replace(access, "Bearer ", "", 1)
 
-	tokenFromString, err := jwt.Parse(access, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			panic(fmt.Errorf("unexpected signing method: %v", token.Header["alg"]))
-		}
-
-		return a.secretSignature, nil
-	})
+	tokenFromString, err := jwt.Parse(access, a.keys.Keyfunc,
+		jwt.WithValidMethods([]string{jwtkeys.RS256, jwtkeys.EdDSA}))
 
 	if err != nil {
 		out.Message = err.Error()
@@ -43,8 +53,22 @@ func (a *API) Auth(c *gin.Context) {
 		return
 	}
 
-	// find user in db
-	user, err := a.db.GetUserByUsername(claims["name"].(string))
+	// find session and user in db, user is found by ID, so renaming does not affect tokens
+	userID, err := strconv.Atoi(fmt.Sprint(claims["sub"]))
+	if err != nil {
+		out.Message = "looks like wrong token"
+		c.JSON(http.StatusUnauthorized, out)
+		c.Abort()
+		return
+	}
+	session, err := a.db.GetSession(fmt.Sprint(claims["jti"]))
+	if err != nil || session.Revoked || session.User != userID {
+		out.Message = "session is not valid"
+		c.JSON(http.StatusUnauthorized, out)
+		c.Abort()
+		return
+	}
+	user, err := a.db.GetUserByID(userID)
 	if err != nil {
 		out.Message = err.Error()
 		zap.S().Error(out)
@@ -52,7 +76,199 @@ func (a *API) Auth(c *gin.Context) {
 		c.Abort()
 		return
 	}
+	if user.Disabled {
+		out.Message = "user is disabled"
+		c.JSON(http.StatusUnauthorized, out)
+		c.Abort()
+		return
+	}
 	c.Set("user", user)
+	c.Set("session", session)
 
 	c.Next()
 }
+
+// AdminAuth allows requests with "Authorization: Bearer <ADMIN_TOKEN>" (if ADMIN_TOKEN is set), other requests
+// are authenticated as users and must pass RequireAdmin.
+func (a *API) AdminAuth(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if len(a.adminToken) != 0 && subtle.ConstantTimeCompare([]byte(token), a.adminToken) == 1 {
+		c.Set("adminToken", true)
+		c.Next()
+		return
+	}
+	a.Auth(c)
+}
+
+// RequireAdmin allows admin token and users with admin role that use JWT.
+func (a *API) RequireAdmin(c *gin.Context) {
+	if c.GetBool("adminToken") {
+		c.Next()
+		return
+	}
+	user, ok := c.Get("user")
+	_, withSession := c.Get("session")
+	if !ok || !withSession || user.(db.User).Role != db.RoleAdmin {
+		c.JSON(http.StatusForbidden, OutAuthData{Message: "admin role is required"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+const (
+	defaultIdempotencyKeyLifetime = 24 * time.Hour
+	maxIdempotencyKeyLength       = 255
+	maxIdempotentBodySize         = 1 << 20 // the body is read to memory to hash it
+)
+
+// idempotencyWriter keeps the response to save it with the Idempotency-Key.
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotent saves responses to requests with Idempotency-Key header for IDEMPOTENCY_KEY_LIFETIME, a retried request
+// of the user with the same key gets the saved response instead of being handled again. The key can not be reused
+// with another request (409). Responses with status 5xx are not saved, such requests can be retried.
+func (a *API) Idempotent(c *gin.Context) {
+	var out OutAuthData
+	key := c.GetHeader("Idempotency-Key")
+	if key == "" {
+		c.Next()
+		return
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		out.Message = fmt.Sprintf("Idempotency-Key must not be longer than %v characters", maxIdempotencyKeyLength)
+		c.JSON(http.StatusBadRequest, out)
+		c.Abort()
+		return
+	}
+
+	// the body is kept in the context, so handlers bind it with ShouldBindBodyWith
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize)
+	body, err := c.GetRawData()
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		out.Message = fmt.Sprintf("body of the request must not be larger than %v bytes", maxIdempotentBodySize)
+		c.JSON(http.StatusRequestEntityTooLarge, out)
+		c.Abort()
+		return
+	}
+	if err != nil {
+		out.Message = "body of the request can not be read"
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, out)
+		c.Abort()
+		return
+	}
+	c.Set(gin.BodyBytesKey, body)
+	hash := sha256.Sum256(append([]byte(c.Request.Method+" "+c.Request.URL.Path+"\n"), body...))
+
+	user := c.MustGet("user").(db.User)
+	now := time.Now()
+	idempotencyKey := db.IdempotencyKey{
+		User:         user.ID,
+		Key:          key,
+		RequestHash:  hex.EncodeToString(hash[:]),
+		ExpiresAt:    int(now.Add(a.idempotencyLifetime).Unix()),
+		CreationTime: now.Format("2006-01-02 15:04:05"),
+	}
+	reserved, err := a.db.ReserveIdempotencyKey(idempotencyKey, int(now.Unix()))
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		c.Abort()
+		return
+	}
+	if !reserved {
+		a.replayIdempotent(c, idempotencyKey)
+		return
+	}
+
+	// the reserved key would answer "being handled" until it expires, so it is deleted if the handler panics
+	defer func() {
+		if r := recover(); r != nil {
+			if err := a.db.DeleteIdempotencyKey(user.ID, key); err != nil {
+				zap.S().Error(err)
+			}
+			panic(r)
+		}
+	}()
+
+	// the response is kept under problem details and error responses are written here, so the answer that is sent
+	// is saved rather than the answer of the handler
+	problems, _ := c.Writer.(*problemWriter)
+	writer := &idempotencyWriter{ResponseWriter: c.Writer}
+	if problems != nil {
+		writer.ResponseWriter = problems.ResponseWriter
+		problems.ResponseWriter = writer
+	} else {
+		c.Writer = writer
+	}
+	c.Next()
+	if problems != nil {
+		writeProblem(c, problems)
+	}
+
+	if writer.Status() >= http.StatusInternalServerError {
+		err = a.db.DeleteIdempotencyKey(user.ID, key)
+	} else {
+		idempotencyKey.Status = writer.Status()
+		idempotencyKey.Response = writer.body.String()
+		idempotencyKey.Location = writer.Header().Get("Location")
+		err = a.db.SetIdempotencyResponse(idempotencyKey)
+	}
+	if err != nil {
+		zap.S().Error(err)
+	}
+}
+
+// replayIdempotent answers with the saved response to the first request with the key.
+func (a *API) replayIdempotent(c *gin.Context, idempotencyKey db.IdempotencyKey) {
+	var out OutAuthData
+	saved, err := a.db.GetIdempotencyKey(idempotencyKey.User, idempotencyKey.Key)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		c.Abort()
+		return
+	}
+	if saved.RequestHash != idempotencyKey.RequestHash {
+		out.Message = "Idempotency-Key is already used with another request"
+		c.JSON(http.StatusConflict, out)
+		c.Abort()
+		return
+	}
+	if saved.Status == 0 {
+		out.Message = "request with this Idempotency-Key is being handled"
+		_ = c.Error(apierrors.New(apierrors.CodeRequestInProgress, out.Message))
+		c.JSON(http.StatusConflict, out)
+		c.Abort()
+		return
+	}
+
+	if saved.Location != "" {
+		c.Header("Location", saved.Location)
+	}
+	c.Header("Idempotent-Replayed", "true")
+	contentType := "application/json; charset=utf-8"
+	if saved.Status >= http.StatusBadRequest {
+		// error responses are saved as problem details
+		contentType = problemJSON
+	}
+	c.Data(saved.Status, contentType, []byte(saved.Response))
+	c.Abort()
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"storage/internal/apierrors"
+	"strings"
+)
+
+const (
+	// HeaderCorrelationID is the header with the correlation ID of the request, clients may send their own
+	HeaderCorrelationID = "X-Correlation-ID"
+	// problemTypePrefix is the prefix of types of problems, the catalogue of codes is at /api/v1/errors
+	problemTypePrefix = "/api/v1/errors#"
+	problemJSON       = "application/problem+json"
+	internalDetail    = "internal error, report the correlation_id to administrators"
+)
+
+// Problem is RFC 7807 problem details of an error response. Fields of the answer of the handler (e.g. per-item
+// errors of a batch) are kept as extension members, Message duplicates Detail for older clients.
+type Problem struct {
+	Type          string `json:"type"`
+	Title         string `json:"title"`
+	Status        int    `json:"status"`
+	Detail        string `json:"detail"`
+	Instance      string `json:"instance"`
+	Code          string `json:"code"`
+	Retryable     bool   `json:"retryable"`
+	CorrelationID string `json:"correlation_id,omitempty"` // only for internal errors, see the log
+	Message       string `json:"message"`
+}
+
+// problemWriter holds back error responses of handlers, so they are written as problem details.
+type problemWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *problemWriter) held() bool {
+	return w.Status() >= http.StatusBadRequest
+}
+
+func (w *problemWriter) WriteHeaderNow() {
+	if !w.held() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *problemWriter) Write(data []byte) (int, error) {
+	if w.held() {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *problemWriter) WriteString(s string) (int, error) {
+	if w.held() {
+		return w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Problems sets the correlation ID of the request and writes error responses (4xx and 5xx) as problem details. The
+// code of the problem is taken from *apierrors.Error added with c.Error, or from the status. Details of internal
+// errors and of other errors added with c.Error are logged with the correlation ID and are not sent.
+func (a *API) Problems(c *gin.Context) {
+	correlationID := c.GetHeader(HeaderCorrelationID)
+	if !apierrors.IsValidCorrelationID(correlationID) {
+		correlationID = apierrors.NewCorrelationID()
+	}
+	c.Set("correlation_id", correlationID)
+	c.Header(HeaderCorrelationID, correlationID)
+
+	writer := &problemWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	c.Next()
+	c.Writer = writer.ResponseWriter
+	writeProblem(c, writer)
+}
+
+// writeProblem writes the error response held by the writer as problem details, if it is not written yet. Problem
+// details that are already written by the handler (e.g. replayed by Idempotent) are sent as is.
+func writeProblem(c *gin.Context, writer *problemWriter) {
+	if !writer.held() || writer.ResponseWriter.Written() {
+		return
+	}
+	if strings.HasPrefix(writer.Header().Get("Content-Type"), problemJSON) {
+		writer.ResponseWriter.WriteHeader(writer.Status())
+		_, _ = writer.ResponseWriter.Write(writer.body.Bytes())
+		return
+	}
+
+	correlationID := c.GetString("correlation_id")
+	status := writer.Status()
+	kind := apierrors.ForHTTPStatus(status)
+	var apiErr *apierrors.Error
+	var unexpected error // error of the handler without code, e.g. of the database
+	for _, err := range c.Errors {
+		if errors.As(err.Err, &apiErr) {
+			kind = apierrors.Lookup(apiErr.Code)
+		} else {
+			unexpected = err.Err
+		}
+	}
+
+	// fields of the answer of the handler
+	members := make(map[string]any)
+	if err := json.Unmarshal(writer.body.Bytes(), &members); err != nil {
+		members = make(map[string]any)
+	}
+	detail, _ := members["message"].(string)
+	if detail == "" && !strings.HasPrefix(writer.Header().Get("Content-Type"), "application/json") {
+		detail = strings.TrimSpace(writer.body.String())
+	}
+	if detail == "" {
+		detail = http.StatusText(status)
+	}
+
+	problem := Problem{
+		Type:      problemTypePrefix + string(kind.Code),
+		Title:     kind.Title,
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      string(kind.Code),
+		Retryable: kind.Retryable,
+	}
+	if kind.Code == apierrors.CodeInternal {
+		zap.S().Errorw("internal error", "correlation_id", correlationID, "method", c.Request.Method,
+			"path", c.Request.URL.Path, "error", detail)
+		problem.Detail = internalDetail
+		problem.CorrelationID = correlationID
+		// answers of internal errors may contain anything
+		members = make(map[string]any)
+	} else if unexpected != nil && apiErr == nil {
+		// only messages of errors from the catalogue are sent, other errors may tell about internals
+		zap.S().Warnw("error of request", "correlation_id", correlationID, "method", c.Request.Method,
+			"path", c.Request.URL.Path, "error", unexpected)
+		problem.Detail = kind.Title
+	}
+	problem.Message = problem.Detail
+
+	data, err := json.Marshal(problem)
+	if err == nil && len(members) > 0 {
+		// fields of the problem replace the fields of the answer
+		err = json.Unmarshal(data, &members)
+		if err == nil {
+			data, err = json.Marshal(members)
+		}
+	}
+	if err != nil {
+		zap.S().Error(err)
+		return
+	}
+	writer.Header().Set("Content-Type", problemJSON)
+	writer.Header().Del("Content-Length")
+	writer.ResponseWriter.WriteHeader(status)
+	_, _ = writer.ResponseWriter.Write(data)
+}
+
+type OutErrorKind struct {
+	Code       string `json:"code"`
+	Title      string `json:"title"`
+	HTTPStatus int    `json:"http_status"`
+	GRPCCode   string `json:"grpc_code"`
+	Retryable  bool   `json:"retryable"`
+}
+
+type OutGetErrors struct {
+	Errors  []OutErrorKind `json:"errors"`
+	Message string         `json:"message"`
+}
+
+// GetErrors godoc
+//
+//	@Summary		Get error catalogue
+//	@Description	Get codes of errors with their HTTP statuses and gRPC codes. Error responses are RFC 7807 problem details (application/problem+json) with type, title, status, detail, instance, code, retryable and message (the same as detail), internal errors also have correlation_id. Every response has X-Correlation-ID header, clients may send their own
+//	@Tags			errors
+//	@Produce		json
+//	@Success		200	{object}	OutGetErrors
+//	@Router			/v1/errors [get]
+func (a *API) GetErrors(c *gin.Context) {
+	var out OutGetErrors
+	for _, kind := range apierrors.Catalogue() {
+		out.Errors = append(out.Errors, OutErrorKind{
+			Code:       string(kind.Code),
+			Title:      kind.Title,
+			HTTPStatus: kind.HTTPStatus,
+			GRPCCode:   kind.GRPCCode.String(),
+			Retryable:  kind.Retryable,
+		})
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
@@ -8,19 +8,22 @@ import (
 	"net/http"
 	"storage/internal/cryptPasswords"
 	"storage/internal/db"
+	"strconv"
+	"strings"
 	"time"
 )
 
-func makeToken(login string, secretSignature []byte) (string, error) {
+// makeToken returns access token of the user for the session with jti, it expires after accessLifetime. Access tokens
+// are signed with the current signing key, so other services can verify them with /.well-known/jwks.json.
+func (a *API) makeToken(userID int, jti string) (string, error) {
 	now := time.Now()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"name": login,
-		"nbf":  now.Unix(),
-		"exp":  now.Add(5 * time.Minute).Unix(),
-		"iat":  now.Unix(),
+	return a.keys.Sign(jwt.MapClaims{
+		"sub": strconv.Itoa(userID),
+		"jti": jti,
+		"nbf": now.Unix(),
+		"exp": now.Add(a.accessLifetime).Unix(),
+		"iat": now.Unix(),
 	})
-
-	return token.SignedString(secretSignature)
 }
 
 type InRegister struct {
@@ -30,6 +33,7 @@ type InRegister struct {
 
 type OutRegister struct {
 	Access  string `json:"access"`
+	Refresh string `json:"refresh"`
 	Message string `json:"message"`
 }
 
@@ -45,7 +49,7 @@ type OutRegister struct {
 //	@Success		200			{object}	OutRegister
 //	@Failure		400			{object}	OutRegister
 //	@Failure		409			{object}	OutRegister
-//	@Router			/register [post]
+//	@Router			/v1/register [post]
 func (a *API) Register(c *gin.Context) {
 	var in InRegister
 	var out OutRegister
@@ -55,6 +59,12 @@ func (a *API) Register(c *gin.Context) {
 		return
 	}
 
+	if err := a.passwordPolicy.Check(in.Login, in.Password); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
 	// check if user already exists
 	_, err := a.db.GetUserByUsername(in.Login)
 	if err == nil {
@@ -63,13 +73,6 @@ func (a *API) Register(c *gin.Context) {
 		return
 	}
 
-	tokenString, err := makeToken(in.Login, a.secretSignature)
-	if err != nil {
-		out.Message = err.Error()
-		zap.S().Error(out)
-		c.JSON(http.StatusInternalServerError, out)
-	}
-
 	// add user to db
 	hash, err := cryptPasswords.GeneratePasswordHash(in.Password)
 	if err != nil {
@@ -101,7 +104,16 @@ func (a *API) Register(c *gin.Context) {
 		return
 	}
 
+	tokenString, refresh, err := a.startSession(c, id)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
 	out.Access = tokenString
+	out.Refresh = refresh
 	out.Message = "ok"
 	c.JSON(http.StatusOK, out)
 }
@@ -113,9 +125,27 @@ type InLogin struct {
 
 type OutLogin struct {
 	Access  string `json:"access"`
-	Message string `json:"message"`
+	Refresh string `json:"refresh"`
+	// if the user has second factor, tokens are empty and the challenge is exchanged for them with /login/twoFactor
+	Challenge string `json:"challenge"`
+	Message   string `json:"message"`
 }
 
+// Login godoc
+//
+//	@Summary		Login
+//	@Description	Login with login and password. After several failed attempts logins to the account or from the IP are throttled. If the user has second factor, the answer contains challenge for /login/twoFactor instead of tokens
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			login		body		InLogin	true	"Login"
+//	@Param			password	body		InLogin	true	"Password"
+//	@Success		200			{object}	OutLogin
+//	@Failure		400			{object}	OutLogin
+//	@Failure		401			{object}	OutLogin
+//	@Failure		403			{object}	OutLogin
+//	@Failure		429			{object}	OutLogin
+//	@Router			/v1/login [post]
 func (a *API) Login(c *gin.Context) {
 	var in InLogin
 	var out OutLogin
@@ -125,31 +155,69 @@ func (a *API) Login(c *gin.Context) {
 		return
 	}
 
-	// check if user exists
+	if ok, wait := a.loginAllowed(in.Login, c.ClientIP()); !ok {
+		a.loginFailed(c, in.Login, 0, loginReasonThrottled)
+		out.Message = retryAfter(c, wait)
+		c.JSON(http.StatusTooManyRequests, out)
+		return
+	}
+
+	// the answer is the same for unknown login and wrong password
 	user, err := a.db.GetUserByUsername(in.Login)
 	if err != nil {
-		out.Message = "user not found"
-		c.JSON(http.StatusBadRequest, out)
+		compareWithDummyHash(in.Password)
+		a.loginFailed(c, in.Login, 0, loginReasonInvalidCredentials)
+		out.Message = invalidCredentialsMessage
+		c.JSON(http.StatusUnauthorized, out)
 		return
 	}
 
 	err = cryptPasswords.ComparePasswordWithHash(user.Password, in.Password)
 	if err != nil {
-		out.Message = "wrong password"
+		a.loginFailed(c, in.Login, user.ID, loginReasonInvalidCredentials)
+		out.Message = invalidCredentialsMessage
 		c.JSON(http.StatusUnauthorized, out)
 		return
 	}
+	a.accountLimiter.Success(strings.ToLower(in.Login))
+	if user.Disabled {
+		a.loginFailed(c, in.Login, user.ID, loginReasonDisabled)
+		out.Message = "user is disabled"
+		c.JSON(http.StatusForbidden, out)
+		return
+	}
+
+	status := a.completeLogin(c, user.ID, &out)
+	c.JSON(status, out)
+}
 
-	tokenString, err := makeToken(in.Login, a.secretSignature)
+// completeLogin fills out with tokens of a new session, or with a challenge if the user has second factor. Returns
+// status of the answer.
+func (a *API) completeLogin(c *gin.Context, userID int, out *OutLogin) int {
+	hasTwoFactor, err := a.db.HasTwoFactor(userID)
 	if err != nil {
 		out.Message = err.Error()
 		zap.S().Error(out)
-		c.JSON(http.StatusInternalServerError, out)
+		return http.StatusInternalServerError
+	}
+	if hasTwoFactor {
+		if out.Challenge, err = a.makeChallenge(userID); err != nil {
+			out.Message = err.Error()
+			zap.S().Error(out)
+			return http.StatusInternalServerError
+		}
+		out.Message = twoFactorRequiredMsg
+		return http.StatusOK
 	}
 
-	out.Access = tokenString
+	out.Access, out.Refresh, err = a.startSession(c, userID)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		return http.StatusInternalServerError
+	}
 	out.Message = "ok"
-	c.JSON(http.StatusOK, out)
+	return http.StatusOK
 }
 
 type OutGetUser struct {
@@ -166,7 +234,7 @@ type OutGetUser struct {
 //	@Success		200	{object}	OutGetUser
 //	@Failure		400	{object}	OutGetUser
 //	@Failure		500	{object}	OutGetUser
-//	@Router			/getUser [get]
+//	@Router			/v1/getUser [get]
 func (a *API) GetUser(c *gin.Context) {
 	user := c.MustGet("user")
 	c.JSON(http.StatusOK, OutGetUser{
@@ -188,7 +256,7 @@ type OutUpdateUser struct {
 // UpdateUser godoc
 //
 //	@Summary		Update user
-//	@Description	Update user info
+//	@Description	Update user info, the old password is required unless the user has no password (users of the identity provider)
 //	@Tags			auth
 //	@Accept			json
 //	@Produce		json
@@ -199,7 +267,7 @@ type OutUpdateUser struct {
 //	@Failure		400				{object}	OutRegister
 //	@Failure		401				{object}	OutRegister
 //	@Failure		500				{object}	OutRegister
-//	@Router			/updateUser [post]
+//	@Router			/v1/updateUser [post]
 func (a *API) UpdateUser(c *gin.Context) {
 	var in InUpdateUser
 	var out OutUpdateUser
@@ -215,20 +283,34 @@ func (a *API) UpdateUser(c *gin.Context) {
 	u := c.MustGet("user")
 	user := u.(db.User)
 
-	if in.OldPassword == "" {
-		out.Message = "old password is empty"
-		c.JSON(http.StatusBadRequest, out)
-		return
-	}
+	// users of the identity provider have no password yet, they set the first one without the old password
+	var err error
+	if user.Password != "" {
+		if in.OldPassword == "" {
+			out.Message = "old password is empty"
+			c.JSON(http.StatusBadRequest, out)
+			return
+		}
 
-	err := cryptPasswords.ComparePasswordWithHash(user.Password, in.OldPassword)
-	if err != nil {
-		out.Message = "wrong password"
-		c.JSON(http.StatusBadRequest, out)
-		return
+		err = cryptPasswords.ComparePasswordWithHash(user.Password, in.OldPassword)
+		if err != nil {
+			out.Message = "wrong password"
+			c.JSON(http.StatusBadRequest, out)
+			return
+		}
 	}
 
 	if in.NewPassword != "" {
+		login := user.Login
+		if in.Login != "" {
+			login = in.Login
+		}
+		if err := a.passwordPolicy.Check(login, in.NewPassword); err != nil {
+			out.Message = err.Error()
+			c.JSON(http.StatusBadRequest, out)
+			return
+		}
+
 		hash, err := cryptPasswords.GeneratePasswordHash(in.NewPassword)
 		if err != nil {
 			out.Message = err.Error()
@@ -246,7 +328,7 @@ func (a *API) UpdateUser(c *gin.Context) {
 		}
 
 		// make new token
-		tokenString, err = makeToken(in.Login, a.secretSignature)
+		tokenString, err = a.makeToken(user.ID, c.MustGet("session").(db.Session).JTI)
 		if err != nil {
 			out.Message = err.Error()
 			zap.S().Error(out)
@@ -265,7 +347,7 @@ func (a *API) UpdateUser(c *gin.Context) {
 		}
 
 		// make new token
-		tokenString, err = makeToken(in.Login, a.secretSignature)
+		tokenString, err = a.makeToken(user.ID, c.MustGet("session").(db.Session).JTI)
 		if err != nil {
 			out.Message = err.Error()
 			zap.S().Error(out)
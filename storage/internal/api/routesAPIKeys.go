@@ -0,0 +1,253 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+	"net/http"
+	"storage/internal/cryptPasswords"
+	"storage/internal/db"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scopes of API keys, requests with JWT are allowed everything.
+const (
+	ScopeExpressionsRead  = "expressions:read"
+	ScopeExpressionsWrite = "expressions:write"
+	ScopeOperationsWrite  = "operations:write"
+)
+
+var allScopes = []string{ScopeExpressionsRead, ScopeExpressionsWrite, ScopeOperationsWrite}
+
+func hasScope(key db.APIKey, scope string) bool {
+	for _, s := range strings.Split(key.Scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func checkScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		known := false
+		for _, s := range allScopes {
+			if s == scope {
+				known = true
+			}
+		}
+		if !known {
+			return errors.New("unknown scope " + scope)
+		}
+	}
+	return nil
+}
+
+// authAPIKey authenticates the request with X-API-Key header.
+func (a *API) authAPIKey(c *gin.Context, apiKey string) {
+	var out OutAuthData
+	key, err := a.db.GetAPIKey(cryptPasswords.HashToken(apiKey))
+	if err != nil || key.Revoked || (key.ExpiresAt != 0 && key.ExpiresAt < int(time.Now().Unix())) {
+		out.Message = "api key is not valid"
+		c.JSON(http.StatusUnauthorized, out)
+		c.Abort()
+		return
+	}
+	user, err := a.db.GetUserByID(key.User)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusUnauthorized, out)
+		c.Abort()
+		return
+	}
+	if user.Disabled {
+		out.Message = "user is disabled"
+		c.JSON(http.StatusUnauthorized, out)
+		c.Abort()
+		return
+	}
+	if err = a.db.SetAPIKeyLastUsed(key.ID, time.Now().Format("2006-01-02 15:04:05")); err != nil {
+		zap.S().Error(err)
+	}
+	c.Set("user", user)
+	c.Set("apiKey", key)
+
+	c.Next()
+}
+
+// RequireScope allows requests with JWT and requests with API key that has the scope.
+func (a *API) RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key, ok := c.Get("apiKey"); ok && !hasScope(key.(db.APIKey), scope) {
+			c.JSON(http.StatusForbidden, OutAuthData{Message: "api key does not have scope " + scope})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession allows only requests with JWT, API keys can not manage the account.
+func (a *API) RequireSession(c *gin.Context) {
+	if _, ok := c.Get("session"); !ok {
+		c.JSON(http.StatusForbidden, OutAuthData{Message: "api key can not be used for this request"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+type InAddAPIKey struct {
+	Name      string   `json:"name" binding:"required"`
+	Scopes    []string `json:"scopes" binding:"required"`
+	ExpiresIn int      `json:"expires_in"` // seconds, 0 - the key does not expire
+}
+
+type OutAddAPIKey struct {
+	Key     string    `json:"key"` // shown only once
+	APIKey  db.APIKey `json:"api_key"`
+	Message string    `json:"message"`
+}
+
+// AddAPIKey godoc
+//
+//	@Summary		Add API key
+//	@Description	Create API key for scripts, it is sent in X-API-Key header. Scopes: expressions:read, expressions:write, operations:write
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			key	body		InAddAPIKey	true	"Name, scopes and lifetime of the key"
+//	@Success		200	{object}	OutAddAPIKey
+//	@Failure		400	{object}	OutAddAPIKey
+//	@Failure		500	{object}	OutAddAPIKey
+//	@Router			/v1/apiKeys [post]
+func (a *API) AddAPIKey(c *gin.Context) {
+	var in InAddAPIKey
+	var out OutAddAPIKey
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	if err := checkScopes(in.Scopes); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	if in.ExpiresIn < 0 {
+		out.Message = "expires_in must not be negative"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	token, err := cryptPasswords.GenerateToken()
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	key := db.APIKey{
+		Name:         in.Name,
+		KeyHash:      cryptPasswords.HashToken(token),
+		Prefix:       token[:6],
+		User:         c.MustGet("user").(db.User).ID,
+		Scopes:       strings.Join(in.Scopes, ","),
+		CreationTime: time.Now().Format("2006-01-02 15:04:05"),
+	}
+	if in.ExpiresIn > 0 {
+		key.ExpiresAt = int(time.Now().Add(time.Duration(in.ExpiresIn) * time.Second).Unix())
+	}
+	key.ID, err = a.db.AddAPIKey(key)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.Key = token
+	out.APIKey = key
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutGetAPIKeys struct {
+	APIKeys []db.APIKey `json:"api_keys"`
+	Message string      `json:"message"`
+}
+
+// GetAPIKeys godoc
+//
+//	@Summary		Get API keys
+//	@Description	Get API keys of the user that are not revoked
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	OutGetAPIKeys
+//	@Failure		500	{object}	OutGetAPIKeys
+//	@Router			/v1/apiKeys [get]
+func (a *API) GetAPIKeys(c *gin.Context) {
+	var out OutGetAPIKeys
+	keys, err := a.db.GetUserAPIKeys(c.MustGet("user").(db.User).ID)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.APIKeys = keys
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutDeleteAPIKey struct {
+	Message string `json:"message"`
+}
+
+// DeleteAPIKey godoc
+//
+//	@Summary		Delete API key
+//	@Description	Revoke API key of the user
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"API key ID"
+//	@Success		200	{object}	OutDeleteAPIKey
+//	@Failure		400	{object}	OutDeleteAPIKey
+//	@Failure		404	{object}	OutDeleteAPIKey
+//	@Failure		500	{object}	OutDeleteAPIKey
+//	@Router			/v1/apiKeys/{id} [delete]
+func (a *API) DeleteAPIKey(c *gin.Context) {
+	var out OutDeleteAPIKey
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	ok, err := a.db.RevokeAPIKey(c.MustGet("user").(db.User).ID, id)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	if !ok {
+		out.Message = "api key is not found"
+		c.JSON(http.StatusNotFound, out)
+		return
+	}
+
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
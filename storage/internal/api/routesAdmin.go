@@ -0,0 +1,324 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+	"net/http"
+	"storage/internal/cryptPasswords"
+	"storage/internal/db"
+	"strconv"
+)
+
+// BootstrapAdmin gives admin role to the user with login if there are no admins yet. If the user does not exist,
+// it is created with password, an existing user gets admin role only if password is its password.
+func (a *API) BootstrapAdmin(login string, password string) error {
+	hasAdmin, err := a.db.HasAdmin()
+	if err != nil {
+		return err
+	}
+	if hasAdmin {
+		return nil
+	}
+
+	user, err := a.db.GetUserByUsername(login)
+	if err == nil {
+		// anyone could register the login before the admin, so the password must match
+		if cryptPasswords.ComparePasswordWithHash(user.Password, password) != nil {
+			return fmt.Errorf("user %v exists and its password is not ADMIN_PASSWORD, admin role is not given", login)
+		}
+		zap.S().Infof("user %v is admin now", login)
+		return a.db.SetUserRole(user.ID, db.RoleAdmin, false)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if password == "" {
+		return errors.New("password is required to create admin")
+	}
+	if err = a.passwordPolicy.Check(login, password); err != nil {
+		return err
+	}
+	hash, err := cryptPasswords.GeneratePasswordHash(password)
+	if err != nil {
+		return err
+	}
+	id, err := a.db.AddUser(db.User{Login: login, Password: hash, Role: db.RoleAdmin})
+	if err != nil {
+		return err
+	}
+	if _, err = a.db.AddOperation(db.Operation{User: id}); err != nil {
+		return err
+	}
+	zap.S().Infof("admin %v is created", login)
+	return nil
+}
+
+type OutAdminUser struct {
+	ID       int    `json:"id"`
+	Login    string `json:"login"`
+	Role     string `json:"role"`
+	Disabled bool   `json:"disabled"`
+}
+
+type OutGetUsers struct {
+	Users   []OutAdminUser `json:"users"`
+	Message string         `json:"message"`
+}
+
+// GetUsers godoc
+//
+//	@Summary		Get users
+//	@Description	Get all users
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	OutGetUsers
+//	@Failure		401	{object}	OutAuthData
+//	@Failure		403	{object}	OutAuthData
+//	@Failure		500	{object}	OutGetUsers
+//	@Router			/v1/admin/users [get]
+func (a *API) GetUsers(c *gin.Context) {
+	var out OutGetUsers
+	users, err := a.db.GetAllUsers()
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.Users = make([]OutAdminUser, 0, len(users))
+	for _, user := range users {
+		out.Users = append(out.Users, OutAdminUser{ID: user.ID, Login: user.Login, Role: user.Role,
+			Disabled: user.Disabled})
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutUpdateUserByAdmin struct {
+	User    OutAdminUser `json:"user"`
+	Message string       `json:"message"`
+}
+
+// updateUserByAdmin changes role and disabled flag of the user from the path. Admins can not change themselves,
+// so there is always at least one admin.
+func (a *API) updateUserByAdmin(c *gin.Context, update func(user *db.User) error) {
+	var out OutUpdateUserByAdmin
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	if admin, ok := c.Get("user"); ok && admin.(db.User).ID == id {
+		out.Message = "admin can not change own role"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	user, err := a.db.GetUserByID(id)
+	if err != nil {
+		out.Message = "user is not found"
+		c.JSON(http.StatusNotFound, out)
+		return
+	}
+	if err = update(&user); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	if user.Disabled {
+		// sessions, refresh tokens and API keys of the user are revoked with the flag
+		err = a.db.DisableUser(user.ID, user.Role)
+	} else {
+		err = a.db.SetUserRole(user.ID, user.Role, user.Disabled)
+	}
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.User = OutAdminUser{ID: user.ID, Login: user.Login, Role: user.Role, Disabled: user.Disabled}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type InSetUserRole struct {
+	Role string `json:"role" binding:"required"` // user or admin
+}
+
+// SetUserRole godoc
+//
+//	@Summary		Set user role
+//	@Description	Change role of the user (user or admin)
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"User ID"
+//	@Param			role	body		InSetUserRole	true	"Role"
+//	@Success		200		{object}	OutUpdateUserByAdmin
+//	@Failure		400		{object}	OutUpdateUserByAdmin
+//	@Failure		403		{object}	OutAuthData
+//	@Failure		404		{object}	OutUpdateUserByAdmin
+//	@Router			/v1/admin/users/{id}/role [post]
+func (a *API) SetUserRole(c *gin.Context) {
+	var in InSetUserRole
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, OutUpdateUserByAdmin{Message: err.Error()})
+		return
+	}
+	a.updateUserByAdmin(c, func(user *db.User) error {
+		if in.Role != db.RoleUser && in.Role != db.RoleAdmin {
+			return errors.New("role must be user or admin")
+		}
+		user.Role = in.Role
+		return nil
+	})
+}
+
+// DisableUser godoc
+//
+//	@Summary		Disable user
+//	@Description	Disable account, the user can not login, all sessions, refresh tokens and API keys are revoked
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	OutUpdateUserByAdmin
+//	@Failure		400	{object}	OutUpdateUserByAdmin
+//	@Failure		403	{object}	OutAuthData
+//	@Failure		404	{object}	OutUpdateUserByAdmin
+//	@Router			/v1/admin/users/{id}/disable [post]
+func (a *API) DisableUser(c *gin.Context) {
+	a.updateUserByAdmin(c, func(user *db.User) error {
+		user.Disabled = true
+		return nil
+	})
+}
+
+// EnableUser godoc
+//
+//	@Summary		Enable user
+//	@Description	Enable disabled account
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	OutUpdateUserByAdmin
+//	@Failure		400	{object}	OutUpdateUserByAdmin
+//	@Failure		403	{object}	OutAuthData
+//	@Failure		404	{object}	OutUpdateUserByAdmin
+//	@Router			/v1/admin/users/{id}/enable [post]
+func (a *API) EnableUser(c *gin.Context) {
+	a.updateUserByAdmin(c, func(user *db.User) error {
+		user.Disabled = false
+		return nil
+	})
+}
+
+// GetAllUsersExpressions godoc
+//
+//	@Summary		Get expressions of all users
+//	@Description	Get every expression in storage
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	OutGetAllExpressions
+//	@Failure		403	{object}	OutAuthData
+//	@Router			/v1/admin/expressions [get]
+func (a *API) GetAllUsersExpressions(c *gin.Context) {
+	c.JSON(http.StatusOK, OutGetAllExpressions{
+		Expressions: a.expressions.GetAllUsersExpressions(),
+		Message:     "ok",
+	})
+}
+
+type OutReleaseExpression struct {
+	Expression db.Expression `json:"expression"`
+	Message    string        `json:"message"`
+}
+
+// ReleaseExpression godoc
+//
+//	@Summary		Release expression
+//	@Description	End the lease of calculation server on expression, the expression is returned to pending and the server is asked to stop
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Expression ID"
+//	@Success		200	{object}	OutReleaseExpression
+//	@Failure		400	{object}	OutReleaseExpression
+//	@Failure		403	{object}	OutAuthData
+//	@Failure		404	{object}	OutReleaseExpression
+//	@Failure		409	{object}	OutReleaseExpression
+//	@Router			/v1/admin/expressions/{id}/release [post]
+func (a *API) ReleaseExpression(c *gin.Context) {
+	var out OutReleaseExpression
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	expression, err := a.expressions.Release(id)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+
+	out.Expression = expression
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutServer struct {
+	ServerName            string `json:"server_name"`
+	CalculatedExpressions []int  `json:"calculated_expressions"`
+	ServerStatus          string `json:"server_status"`
+}
+
+type OutGetServers struct {
+	Servers []OutServer `json:"servers"`
+	Message string      `json:"message"`
+}
+
+// GetServers godoc
+//
+//	@Summary		Get servers
+//	@Description	Get all calculation servers with expressions of all users
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	OutGetServers
+//	@Failure		403	{object}	OutAuthData
+//	@Router			/v1/admin/servers [get]
+func (a *API) GetServers(c *gin.Context) {
+	var out OutGetServers
+	out.Servers = make([]OutServer, 0)
+	for _, server := range a.servers.GetAll() {
+		ids := make([]int, 0)
+		for _, expression := range a.expressions.GetAllByServer(server) {
+			ids = append(ids, expression.ID)
+		}
+
+		val, ok := a.statusWorkers.Load(server)
+		if !ok {
+			val = "unknown"
+		}
+		out.Servers = append(out.Servers, OutServer{ServerName: server, CalculatedExpressions: ids,
+			ServerStatus: val.(string)})
+	}
+
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
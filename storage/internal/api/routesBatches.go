@@ -0,0 +1,175 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+	"net/http"
+	"storage/internal/db"
+	"strconv"
+	"time"
+)
+
+const defaultMaxBatchSize = 500
+
+// ExpressionsMethod serves custom methods of expressions (/expressions:batch). gin can not escape ':' in routes, so
+// the route is /expressions:method and the parameter is the name of the method with leading ':'.
+func (a *API) ExpressionsMethod(c *gin.Context) {
+	switch c.Param("method") {
+	case ":batch":
+		a.PostBatch(c)
+	default:
+		c.AbortWithStatus(http.StatusNotFound)
+	}
+}
+
+type InPostBatch struct {
+	Expressions []InPostExpression `json:"expressions" binding:"required"`
+}
+
+// OutBatchItem is the result of an expression of the batch, either ID or error.
+type OutBatchItem struct {
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type OutPostBatch struct {
+	BatchID int            `json:"batch_id"`
+	Items   []OutBatchItem `json:"items"` // in the order of the posted expressions
+	Message string         `json:"message"`
+}
+
+// PostBatch godoc
+//
+//	@Summary		Post batch of expressions
+//	@Description	Add many expressions at once (up to MAX_BATCH_SIZE). Every expression is checked, correct ones are added in one transaction, items of the answer contain ID or error of every expression. If no expression is correct, nothing is added. Progress of the batch is available at /batches/{id}
+//	@Tags			expression
+//	@Accept			json
+//	@Produce		json
+//	@Param			expressions		body		InPostBatch	true	"Expressions"
+//	@Param			Idempotency-Key	header		string		false	"Key of the request, a retried request with the same key gets the same response"
+//	@Success		200				{object}	OutPostBatch
+//	@Failure		400				{object}	OutPostBatch
+//	@Failure		409				{object}	OutAuthData
+//	@Failure		500				{object}	OutPostBatch
+//	@Router			/v1/expressions:batch [post]
+func (a *API) PostBatch(c *gin.Context) {
+	var in InPostBatch
+	var out OutPostBatch
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	if len(in.Expressions) == 0 || len(in.Expressions) > a.maxBatchSize {
+		out.Message = fmt.Sprintf("batch must have from 1 to %v expressions", a.maxBatchSize)
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	user := c.MustGet("user").(db.User)
+	out.Items = make([]OutBatchItem, len(in.Expressions))
+	expressions := make([]db.Expression, 0, len(in.Expressions))
+	positions := make([]int, 0, len(in.Expressions))
+	for i, item := range in.Expressions {
+		if item.Expression == "" {
+			out.Items[i].Error = "expression is required"
+			continue
+		}
+		expression, status, err := a.newExpression(user, item)
+		if status == http.StatusInternalServerError {
+			out.Message = err.Error()
+			zap.S().Error(out)
+			c.JSON(http.StatusInternalServerError, out)
+			return
+		}
+		if err != nil {
+			out.Items[i].Error = err.Error()
+			continue
+		}
+		expressions = append(expressions, expression)
+		positions = append(positions, i)
+	}
+	if len(expressions) == 0 {
+		out.Message = "no correct expressions in the batch"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	batchID, ids, err := a.expressions.AddBatch(db.Batch{
+		User:         user.ID,
+		CreationTime: time.Now().Format("2006-01-02 15:04:05"),
+	}, expressions)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	for i, position := range positions {
+		out.Items[position].ID = ids[i]
+	}
+	out.BatchID = batchID
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutGetBatch struct {
+	Batch    db.Batch    `json:"batch"`
+	Counts   map[int]int `json:"counts"`   // number of expressions of the batch by status, deleted ones are not counted
+	Finished int         `json:"finished"` // number of calculated, failed, abandoned and cancelled expressions
+	Done     bool        `json:"done"`     // all expressions of the batch are finished
+	Message  string      `json:"message"`
+}
+
+// GetBatch godoc
+//
+//	@Summary		Get batch progress
+//	@Description	Get numbers of expressions of the batch by status
+//	@Tags			expression
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Batch ID"
+//	@Success		200	{object}	OutGetBatch
+//	@Failure		400	{object}	OutGetBatch
+//	@Failure		404	{object}	OutGetBatch
+//	@Failure		500	{object}	OutGetBatch
+//	@Router			/v1/batches/{id} [get]
+func (a *API) GetBatch(c *gin.Context) {
+	var out OutGetBatch
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	user := c.MustGet("user").(db.User)
+	out.Batch, err = a.db.GetBatch(id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && out.Batch.User != user.ID) {
+		out = OutGetBatch{Message: "batch is not found"}
+		c.JSON(http.StatusNotFound, out)
+		return
+	}
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	expressions := a.expressions.GetBatch(id)
+	out.Counts = make(map[int]int)
+	for _, expression := range expressions {
+		out.Counts[expression.Status]++
+		if expression.Status != db.ExpressionNotReady && expression.Status != db.ExpressionWorking {
+			out.Finished++
+		}
+	}
+	out.Done = out.Finished == len(expressions)
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
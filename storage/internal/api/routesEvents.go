@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"storage/internal/db"
+	"storage/internal/events"
+	"time"
+)
+
+const (
+	defaultEventsBufferSize = 1000
+	eventsHeartbeat         = 15 * time.Second
+	// eventReset tells the client that some events are lost, it must reload expressions
+	eventReset = "reset"
+)
+
+// GetEvents godoc
+//
+//	@Summary		Stream expression events
+//	@Description	Server-Sent Events stream of changes of expressions of the user and of their teams. Event names are created, status, progress (server is alive), finished and deleted, data is {"id", "type", "expression"}. After reconnect the stream is resumed after Last-Event-ID header (or last_event_id parameter), if the events are not kept anymore, reset event is sent and expressions must be reloaded. Comment lines are sent every 15 seconds to keep the connection
+//	@Tags			expression
+//	@Produce		text/event-stream
+//	@Param			Last-Event-ID	header		string	false	"ID of the last received event"
+//	@Param			last_event_id	query		string	false	"ID of the last received event"
+//	@Success		200				{string}	string	"event stream"
+//	@Failure		401				{object}	OutAuthData
+//	@Router			/v1/events [get]
+//	@Router			/v2/events [get]
+func (a *API) GetEvents(c *gin.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	user := c.MustGet("user").(db.User)
+	visible := a.expressions.VisibleTo(user.ID)
+	subscription, missed, complete := a.events.Subscribe(lastEventID)
+	defer subscription.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if !complete {
+		fmt.Fprintf(c.Writer, "event: %v\ndata: {}\n\n", eventReset)
+	}
+	for _, event := range missed {
+		if visible(event.Expression) {
+			writeEvent(c, event)
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+		case event, ok := <-subscription.C:
+			if !ok {
+				// the client was too slow, it reconnects with Last-Event-ID
+				return
+			}
+			if !visible(event.Expression) {
+				continue
+			}
+			writeEvent(c, event)
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeEvent(c *gin.Context, event events.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		zap.S().Error(err)
+		return
+	}
+	fmt.Fprintf(c.Writer, "id: %v\nevent: %v\ndata: %s\n\n", event.ID, event.Type, data)
+}
@@ -0,0 +1,342 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"storage/internal/db"
+	"storage/internal/expressionstorage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	formatCSV   = "csv"
+	formatJSONL = "jsonl"
+
+	defaultMaxImportSize = 10000
+	// exportFlushRows is the number of rows that are written before the response is flushed
+	exportFlushRows = 100
+	// maxImportLine is the longest line of JSON Lines
+	maxImportLine = 1 << 20
+)
+
+// expressionColumns are the columns of exported CSV, the same as fields of db.Expression in JSON.
+var expressionColumns = []string{"id", "value", "answer", "logs", "ready", "alive_expires_at", "creation_time",
+	"end_calculation_time", "server_name", "user_id", "priority", "attempts", "failed_servers", "team_id", "batch_id"}
+
+func expressionRecord(expression db.Expression) []string {
+	return []string{
+		strconv.Itoa(expression.ID),
+		expression.Value,
+		strconv.FormatFloat(expression.Answer, 'g', -1, 64),
+		expression.Logs,
+		strconv.Itoa(expression.Status),
+		strconv.Itoa(expression.AliveExpiresAt),
+		expression.CreationTime,
+		expression.EndCalculationTime,
+		expression.Servername,
+		strconv.Itoa(expression.User),
+		strconv.Itoa(expression.Priority),
+		strconv.Itoa(expression.Attempts),
+		expression.FailedServers,
+		strconv.Itoa(expression.Team),
+		strconv.Itoa(expression.Batch),
+	}
+}
+
+// ExportExpressions godoc
+//
+//	@Summary		Export expressions
+//	@Description	Stream expressions of the user and of their teams as CSV (with header) or JSON Lines with all fields of expressions. The filters and sort are the same as in the listing
+//	@Tags			expression
+//	@Produce		text/csv
+//	@Produce		application/x-ndjson
+//	@Param			format			query		string	false	"csv (default) or jsonl"
+//	@Param			status			query		string	false	"Comma separated statuses (0 - not ready, 1 - working, 2 - ready, 3 - error, 4 - abandoned, 5 - cancelled)"
+//	@Param			created_after	query		string	false	"Created at or after (2006-01-02 15:04:05)"
+//	@Param			created_before	query		string	false	"Created before (2006-01-02 15:04:05)"
+//	@Param			server			query		string	false	"Name of the calculation server"
+//	@Param			q				query		string	false	"Substring of the expression"
+//	@Param			sort			query		string	false	"creation_time, end_calculation_time or duration (only finished expressions), - before the name for descending order (default -creation_time)"
+//	@Success		200				{string}	string	"expressions"
+//	@Failure		400				{object}	OutGetAllExpressions
+//	@Failure		401				{object}	OutAuthData
+//	@Router			/v1/expressions/export [get]
+func (a *API) ExportExpressions(c *gin.Context) {
+	var out OutGetAllExpressions
+	format := c.DefaultQuery("format", formatCSV)
+	if format != formatCSV && format != formatJSONL {
+		out.Message = "format must be csv or jsonl"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	query, err := expressionsQuery(c, 0)
+	var page expressionstorage.Page
+	if err == nil {
+		// the whole listing is exported
+		query.Cursor, query.Limit = "", 0
+		page, err = a.expressions.List(c.MustGet("user").(db.User).ID, query)
+	}
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	filename := "expressions-" + time.Now().Format("2006-01-02") + "." + format
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if format == formatCSV {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+	}
+	c.Status(http.StatusOK)
+
+	if err = writeExpressions(c.Writer, format, page.Expressions); err != nil {
+		// headers are sent, the client sees the broken stream
+		zap.S().Error(err)
+	}
+}
+
+// writeExpressions writes the expressions in the format and flushes them by parts.
+func writeExpressions(w gin.ResponseWriter, format string, expressions []db.Expression) error {
+	csvWriter := csv.NewWriter(w)
+	encoder := json.NewEncoder(w)
+	flush := func() error {
+		if format == formatCSV {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return nil
+	}
+
+	if format == formatCSV {
+		if err := csvWriter.Write(expressionColumns); err != nil {
+			return err
+		}
+	}
+	for i, expression := range expressions {
+		var err error
+		if format == formatCSV {
+			err = csvWriter.Write(expressionRecord(expression))
+		} else {
+			err = encoder.Encode(expression)
+		}
+		if err != nil {
+			return err
+		}
+		if (i+1)%exportFlushRows == 0 {
+			if err = flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// importRow is a row of imported file, fields are named as in the export, other fields are ignored.
+type importRow struct {
+	line     int
+	Value    string `json:"value"`
+	Priority int    `json:"priority"`
+	Team     int    `json:"team_id"`
+	err      error
+}
+
+// OutImportError is the error of a line of the imported file.
+type OutImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type OutImportExpressions struct {
+	BatchID  int              `json:"batch_id,omitempty"`
+	Imported int              `json:"imported"`
+	Errors   []OutImportError `json:"errors"`
+	Message  string           `json:"message"`
+}
+
+// ImportExpressions godoc
+//
+//	@Summary		Import expressions
+//	@Description	Add expressions from CSV (with header) or JSON Lines, e.g. from the export. Rows need value, priority and team_id are optional, other fields are ignored. Correct rows are added as one batch (see /batches/{id}) and calculated again, errors are reported with line numbers. Up to MAX_IMPORT_SIZE rows
+//	@Tags			expression
+//	@Accept			text/csv
+//	@Accept			application/x-ndjson
+//	@Produce		json
+//	@Param			format	query		string	false	"csv or jsonl, by default it is chosen by Content-Type"
+//	@Success		200		{object}	OutImportExpressions
+//	@Failure		400		{object}	OutImportExpressions
+//	@Failure		401		{object}	OutAuthData
+//	@Failure		500		{object}	OutImportExpressions
+//	@Router			/v1/expressions/import [post]
+func (a *API) ImportExpressions(c *gin.Context) {
+	var out OutImportExpressions
+	out.Errors = make([]OutImportError, 0)
+	format := c.Query("format")
+	if format == "" {
+		switch contentType := c.ContentType(); {
+		case strings.Contains(contentType, "csv"):
+			format = formatCSV
+		case contentType == "application/x-ndjson" || contentType == "application/jsonl":
+			format = formatJSONL
+		}
+	}
+
+	var rows []importRow
+	var err error
+	switch format {
+	case formatCSV:
+		rows, err = readCSVRows(c.Request.Body, a.maxImportSize)
+	case formatJSONL:
+		rows, err = readJSONLRows(c.Request.Body, a.maxImportSize)
+	default:
+		err = errors.New("format must be csv or jsonl")
+	}
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	user := c.MustGet("user").(db.User)
+	expressions := make([]db.Expression, 0, len(rows))
+	for _, row := range rows {
+		if row.err == nil && row.Value == "" {
+			row.err = errors.New("value is required")
+		}
+		if row.err != nil {
+			out.Errors = append(out.Errors, OutImportError{Line: row.line, Error: row.err.Error()})
+			continue
+		}
+		expression, status, err := a.newExpression(user,
+			InPostExpression{Expression: row.Value, Priority: row.Priority, Team: row.Team})
+		if status == http.StatusInternalServerError {
+			out.Message = err.Error()
+			zap.S().Error(out)
+			c.JSON(http.StatusInternalServerError, out)
+			return
+		}
+		if err != nil {
+			out.Errors = append(out.Errors, OutImportError{Line: row.line, Error: err.Error()})
+			continue
+		}
+		expressions = append(expressions, expression)
+	}
+	if len(expressions) == 0 {
+		out.Message = "no correct rows to import"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	out.BatchID, _, err = a.expressions.AddBatch(db.Batch{
+		User:         user.ID,
+		CreationTime: time.Now().Format("2006-01-02 15:04:05"),
+	}, expressions)
+	if err != nil {
+		out = OutImportExpressions{Errors: out.Errors, Message: err.Error()}
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Imported = len(expressions)
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// readCSVRows reads rows of CSV with header. Errors of rows are saved in the rows, the error is returned only if the
+// file can not be imported at all.
+func readCSVRows(body io.Reader, maxRows int) ([]importRow, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("header of CSV can not be read: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["value"]; !ok {
+		return nil, errors.New("CSV must have value column")
+	}
+
+	rows := make([]importRow, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if len(rows) == maxRows {
+			return nil, fmt.Errorf("at most %v rows can be imported", maxRows)
+		}
+		row := importRow{err: err}
+		if err == nil {
+			row.line, _ = reader.FieldPos(0)
+			row.err = row.fromRecord(columns, record)
+		} else {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				row.line = parseErr.StartLine
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// fromRecord reads fields of the CSV record.
+func (r *importRow) fromRecord(columns map[string]int, record []string) error {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	r.Value = field("value")
+	var err error
+	if value := field("priority"); value != "" {
+		if r.Priority, err = strconv.Atoi(value); err != nil {
+			return errors.New("priority must be a number")
+		}
+	}
+	if value := field("team_id"); value != "" {
+		if r.Team, err = strconv.Atoi(value); err != nil {
+			return errors.New("team_id must be a number")
+		}
+	}
+	return nil
+}
+
+// readJSONLRows reads rows of JSON Lines, empty lines are skipped.
+func readJSONLRows(body io.Reader, maxRows int) ([]importRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+	rows := make([]importRow, 0)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		if len(rows) == maxRows {
+			return nil, fmt.Errorf("at most %v rows can be imported", maxRows)
+		}
+		row := importRow{}
+		row.err = json.Unmarshal(scanner.Bytes(), &row)
+		row.line = line
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -1,11 +1,17 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"go.uber.org/zap"
+	"io"
 	"net/http"
 	"storage/internal/db"
+	"storage/internal/expressionstorage"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,7 +27,7 @@ type OutPing struct {
 //	@Accept			json
 //	@Produce		json
 //	@Success		200	{object}	OutPing
-//	@Router			/ping [get]
+//	@Router			/v1/ping [get]
 func (a *API) Ping(c *gin.Context) {
 	c.JSON(http.StatusOK, OutPing{Message: "pong"})
 }
@@ -30,6 +36,8 @@ func (a *API) Ping(c *gin.Context) {
 
 type InPostExpression struct {
 	Expression string `json:"expression" binding:"required"`
+	Priority   int    `json:"priority"` // from 0 to 10, expressions with higher priority are calculated first
+	Team       int    `json:"team"`     // optional ID of the team of the user, the expression is shared with the team
 }
 
 type OutPostExpression struct {
@@ -44,10 +52,13 @@ type OutPostExpression struct {
 //	@Tags			expression
 //	@Accept			json
 //	@Produce		json
-//	@Param			expression	body		InPostExpression	true	"Expression"
-//	@Success		200			{object}	OutPostExpression
-//	@Failure		400			{object}	OutPostExpression
-//	@Router			/expression [post]
+//	@Param			expression		body		InPostExpression	true	"Expression"
+//	@Param			Idempotency-Key	header		string				false	"Key of the request, a retried request with the same key gets the same response"
+//	@Success		200				{object}	OutPostExpression
+//	@Failure		400				{object}	OutPostExpression
+//	@Failure		403				{object}	OutPostExpression
+//	@Failure		409				{object}	OutAuthData
+//	@Router			/v1/expression [post]
 func (a *API) PostExpression(c *gin.Context) {
 	var in InPostExpression
 	var out OutPostExpression
@@ -58,21 +69,13 @@ func (a *API) PostExpression(c *gin.Context) {
 		return
 	}
 
-	// add expression to storage
-	newExpression := db.Expression{
-		ID:           0,
-		Value:        in.Expression,
-		Answer:       0,
-		Logs:         "",
-		Status:       db.ExpressionNotReady,
-		CreationTime: time.Now().Format("2006-01-02 15:04:05"),
-		User:         c.MustGet("user").(db.User).ID,
-	}
-	newID, err := a.expressions.Add(newExpression)
+	newID, status, err := a.addExpression(c.MustGet("user").(db.User), in)
 	if err != nil {
 		out.Message = err.Error()
-		zap.S().Error(out)
-		c.JSON(http.StatusInternalServerError, out)
+		if status == http.StatusInternalServerError {
+			zap.S().Error(out)
+		}
+		c.JSON(status, out)
 		return
 	}
 
@@ -81,24 +84,147 @@ func (a *API) PostExpression(c *gin.Context) {
 	c.JSON(http.StatusOK, out)
 }
 
+// addExpression adds the expression of the user, returns its ID or status of the error.
+func (a *API) addExpression(user db.User, in InPostExpression) (int, int, error) {
+	newExpression, status, err := a.newExpression(user, in)
+	if err != nil {
+		return 0, status, err
+	}
+	newID, err := a.expressions.Add(newExpression)
+	if err != nil {
+		return 0, http.StatusInternalServerError, err
+	}
+	return newID, http.StatusOK, nil
+}
+
+// newExpression checks the posted expression and returns the expression to be added or status of the error.
+func (a *API) newExpression(user db.User, in InPostExpression) (db.Expression, int, error) {
+	if err := expressionstorage.IsPriorityCorrect(in.Priority); err != nil {
+		return db.Expression{}, http.StatusBadRequest, err
+	}
+
+	if in.Team != 0 {
+		if _, status, err := a.memberRole(in.Team, user.ID); err != nil {
+			if status == http.StatusNotFound {
+				return db.Expression{}, http.StatusForbidden, errors.New("user is not a member of the team")
+			}
+			return db.Expression{}, status, err
+		}
+	}
+
+	return db.Expression{
+		ID:           0,
+		Value:        in.Expression,
+		Answer:       0,
+		Logs:         "",
+		Status:       db.ExpressionNotReady,
+		CreationTime: time.Now().Format("2006-01-02 15:04:05"),
+		User:         user.ID,
+		Priority:     in.Priority,
+		Team:         in.Team,
+	}, http.StatusOK, nil
+}
+
+const maxExpressionsLimit = 1000
+
 type OutGetAllExpressions struct {
 	Expressions []db.Expression `json:"expressions"`
+	NextCursor  string          `json:"next_cursor,omitempty"` // empty on the last page
+	Counts      map[int]int     `json:"counts,omitempty"`      // number of expressions by status, without status filter
+	Total       int             `json:"total,omitempty"`       // number of expressions that match the filters
 	Message     string          `json:"message"`
 }
 
 // GetAllExpressions godoc
 //
 //	@Summary		Get all expressions
-//	@Description	Get all expressions from storage
+//	@Description	Get expressions of the user and of their teams. Without limit all expressions are returned
 //	@Tags			expression
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{object}	OutGetAllExpressions
-//	@Router			/expression [get]
+//	@Param			status			query		string	false	"Comma separated statuses (0 - not ready, 1 - working, 2 - ready, 3 - error, 4 - abandoned, 5 - cancelled)"
+//	@Param			created_after	query		string	false	"Created at or after (2006-01-02 15:04:05)"
+//	@Param			created_before	query		string	false	"Created before (2006-01-02 15:04:05)"
+//	@Param			server			query		string	false	"Name of the calculation server"
+//	@Param			q				query		string	false	"Substring of the expression"
+//	@Param			sort			query		string	false	"creation_time, end_calculation_time or duration (only finished expressions), - before the name for descending order (default -creation_time)"
+//	@Param			cursor			query		string	false	"next_cursor of the previous page"
+//	@Param			limit			query		int		false	"Maximum number of expressions on the page (up to 1000)"
+//	@Success		200				{object}	OutGetAllExpressions
+//	@Failure		400				{object}	OutGetAllExpressions
+//	@Router			/v1/expression [get]
 func (a *API) GetAllExpressions(c *gin.Context) {
+	a.listExpressions(c, 0)
+}
+
+// listExpressions answers with a page of expressions of the user selected by the query parameters.
+func (a *API) listExpressions(c *gin.Context, defaultLimit int) {
+	var out OutGetAllExpressions
 	user := c.MustGet("user").(db.User)
-	expressions := a.expressions.GetAll(user.ID)
-	c.JSON(http.StatusOK, OutGetAllExpressions{Expressions: expressions, Message: "ok"})
+	query, err := expressionsQuery(c, defaultLimit)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	page, err := a.expressions.List(user.ID, query)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	out.Expressions = page.Expressions
+	out.NextCursor = page.NextCursor
+	out.Counts = page.Counts
+	out.Total = page.Total
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// expressionsQuery reads filters, sort and page of the listing from the query parameters.
+func expressionsQuery(c *gin.Context, defaultLimit int) (expressionstorage.Query, error) {
+	query := expressionstorage.Query{
+		Server:   c.Query("server"),
+		Contains: c.Query("q"),
+		Cursor:   c.Query("cursor"),
+		Limit:    defaultLimit,
+	}
+
+	if statuses := c.Query("status"); statuses != "" {
+		for _, value := range strings.Split(statuses, ",") {
+			status, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || status < db.ExpressionNotReady || status > db.ExpressionCancelled {
+				return query, fmt.Errorf("unknown status %q", value)
+			}
+			query.Statuses = append(query.Statuses, status)
+		}
+	}
+
+	var err error
+	if value := c.Query("created_after"); value != "" {
+		if query.CreatedAfter, err = time.ParseInLocation("2006-01-02 15:04:05", value, time.Local); err != nil {
+			return query, errors.New("created_after must be in format 2006-01-02 15:04:05")
+		}
+	}
+	if value := c.Query("created_before"); value != "" {
+		if query.CreatedBefore, err = time.ParseInLocation("2006-01-02 15:04:05", value, time.Local); err != nil {
+			return query, errors.New("created_before must be in format 2006-01-02 15:04:05")
+		}
+	}
+
+	query.Sort = c.DefaultQuery("sort", "-"+expressionstorage.SortCreationTime)
+	if strings.HasPrefix(query.Sort, "-") {
+		query.Sort = strings.TrimPrefix(query.Sort, "-")
+		query.Descending = true
+	}
+
+	if value := c.Query("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit <= 0 || query.Limit > maxExpressionsLimit {
+			return query, fmt.Errorf("limit must be a number from 1 to %v", maxExpressionsLimit)
+		}
+	}
+	return query, nil
 }
 
 type InGetExpressionByID struct {
@@ -113,15 +239,16 @@ type OutGetExpressionByID struct {
 // GetExpressionByID godoc
 //
 //	@Summary		Get expression by id
-//	@Description	Get expression from storage by id
-//	@Tags			expression
-//	@Accept			json
-//	@Produce		json
-//	@Param			id	body		InGetExpressionByID	true	"Expression ID"
-//	@Success		200	{object}	OutGetExpressionByID
-//	@Failure		400	{object}	OutGetExpressionByID
-//	@Failure		500	{object}	OutGetExpressionByID
-//	@Router			/expressionById [get]
+//	@Description	Get expression from storage by id. Deprecated, use GET /api/v2/expressions/{id}
+//	@Deprecated
+//	@Tags		expression
+//	@Accept		json
+//	@Produce	json
+//	@Param		id	body		InGetExpressionByID	true	"Expression ID"
+//	@Success	200	{object}	OutGetExpressionByID
+//	@Failure	400	{object}	OutGetExpressionByID
+//	@Failure	500	{object}	OutGetExpressionByID
+//	@Router		/v1/expressionById [get]
 func (a *API) GetExpressionByID(c *gin.Context) {
 	var in InGetExpressionByID
 	var out OutGetExpressionByID
@@ -150,6 +277,128 @@ func (a *API) GetExpressionByID(c *gin.Context) {
 	c.JSON(http.StatusOK, out)
 }
 
+type InRequeueExpression struct {
+	ID int `json:"id" binding:"required"`
+}
+
+type OutRequeueExpression struct {
+	Expression db.Expression `json:"expression"`
+	Message    string        `json:"message"`
+}
+
+// RequeueExpression godoc
+//
+//	@Summary		Requeue expression
+//	@Description	Return abandoned expression (servers died too many times while calculating it) to pending. Deprecated, use POST /api/v2/expressions/{id}/requeue
+//	@Deprecated
+//	@Tags		expression
+//	@Accept		json
+//	@Produce	json
+//	@Param		id	body		InRequeueExpression	true	"Expression ID"
+//	@Success	200	{object}	OutRequeueExpression
+//	@Failure	400	{object}	OutRequeueExpression
+//	@Failure	404	{object}	OutRequeueExpression
+//	@Failure	409	{object}	OutRequeueExpression
+//	@Failure	500	{object}	OutRequeueExpression
+//	@Router		/v1/requeueExpression [post]
+func (a *API) RequeueExpression(c *gin.Context) {
+	var in InRequeueExpression
+	var out OutRequeueExpression
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	expression, err := a.expressions.Requeue(c.MustGet("user").(db.User).ID, in.ID)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+
+	out.Expression = expression
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutCancelExpression struct {
+	Expression db.Expression `json:"expression"`
+	Message    string        `json:"message"`
+}
+
+// CancelExpression godoc
+//
+//	@Summary		Cancel expression
+//	@Description	Stop calculation of expression, server that calculates it will be notified
+//	@Tags			expression
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Expression ID"
+//	@Success		200	{object}	OutCancelExpression
+//	@Failure		400	{object}	OutCancelExpression
+//	@Failure		404	{object}	OutCancelExpression
+//	@Failure		409	{object}	OutCancelExpression
+//	@Router			/v1/expression/{id}/cancel [post]
+func (a *API) CancelExpression(c *gin.Context) {
+	var out OutCancelExpression
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	expression, err := a.expressions.Cancel(c.MustGet("user").(db.User).ID, id)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+
+	out.Expression = expression
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutDeleteExpression struct {
+	Message string `json:"message"`
+}
+
+// DeleteExpression godoc
+//
+//	@Summary		Delete expression
+//	@Description	Delete expression from storage, if it is being calculated, server will stop calculating it
+//	@Tags			expression
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Expression ID"
+//	@Success		200	{object}	OutDeleteExpression
+//	@Failure		400	{object}	OutDeleteExpression
+//	@Failure		403	{object}	OutDeleteExpression
+//	@Failure		404	{object}	OutDeleteExpression
+//	@Failure		500	{object}	OutDeleteExpression
+//	@Router			/v1/expression/{id} [delete]
+func (a *API) DeleteExpression(c *gin.Context) {
+	var out OutDeleteExpression
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	if err = a.expressions.DeleteByUser(c.MustGet("user").(db.User).ID, id); err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
 type ExecTimeConfig struct {
 	TimeAdd      time.Duration
 	TimeSubtract time.Duration
@@ -170,7 +419,7 @@ type OutGetOperationsAndTimes struct {
 //	@Accept			json
 //	@Produce		json
 //	@Success		200	{object}	OutGetOperationsAndTimes
-//	@Router			/getOperationsAndTimes [get]
+//	@Router			/v1/getOperationsAndTimes [get]
 func (a *API) GetOperationsAndTimes(c *gin.Context) {
 	operations, err := a.db.GetUserOperations(c.MustGet("user").(db.User).ID)
 	if err != nil {
@@ -178,12 +427,17 @@ func (a *API) GetOperationsAndTimes(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, OutGetOperationsAndTimes{Message: err.Error()})
 		return
 	}
-	outMap := make(map[string]int)
-	outMap["+"] = operations.TimeAdd
-	outMap["-"] = operations.TimeSubtract
-	outMap["/"] = operations.TimeDivide
-	outMap["*"] = operations.TimeMultiply
-	c.JSON(http.StatusOK, OutGetOperationsAndTimes{Data: outMap, Message: "ok"})
+	c.JSON(http.StatusOK, OutGetOperationsAndTimes{Data: operationsMap(operations), Message: "ok"})
+}
+
+// operationsMap returns operation times as a map {"+": 100,...}.
+func operationsMap(operations db.Operation) map[string]int {
+	return map[string]int{
+		"+": operations.TimeAdd,
+		"-": operations.TimeSubtract,
+		"/": operations.TimeDivide,
+		"*": operations.TimeMultiply,
+	}
 }
 
 type OutPostOperationsAndTimes struct {
@@ -193,14 +447,15 @@ type OutPostOperationsAndTimes struct {
 // PostOperationsAndTimes godoc
 //
 //	@Summary		Set operations and times
-//	@Description	Set operations and times for calculation as a map of operation and time in milliseconds, {"+": 100,...}
-//	@Tags			operations
-//	@Accept			json
-//	@Produce		json
-//	@Param			data	body		map[string]int	true	"Operations and times"
-//	@Success		200		{object}	OutPostOperationsAndTimes
-//	@Failure		400		{object}	OutPostOperationsAndTimes
-//	@Router			/postOperationsAndTimes [post]
+//	@Description	Set operations and times for calculation as a map of operation and time in milliseconds, {"+": 100,...}. Deprecated, use PUT /api/v2/operations
+//	@Deprecated
+//	@Tags		operations
+//	@Accept		json
+//	@Produce	json
+//	@Param		data	body		map[string]int	true	"Operations and times"
+//	@Success	200		{object}	OutPostOperationsAndTimes
+//	@Failure	400		{object}	OutPostOperationsAndTimes
+//	@Router		/v1/postOperationsAndTimes [post]
 func (a *API) PostOperationsAndTimes(c *gin.Context) {
 	var in map[string]int
 
@@ -211,12 +466,31 @@ func (a *API) PostOperationsAndTimes(c *gin.Context) {
 		return
 	}
 
-	operations, err := a.db.GetUserOperations(c.MustGet("user").(db.User).ID)
+	_, msg, err := a.setOperationsAndTimes(c.MustGet("user").(db.User).ID, in)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, OutPostOperationsAndTimes{Message: err.Error()})
+		out := OutPostOperationsAndTimes{Message: err.Error()}
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
 		return
 	}
 
+	out := OutPostOperationsAndTimes{Message: msg}
+	c.JSON(http.StatusOK, out)
+}
+
+// setOperationsAndTimes changes operation times of the user from map {"+": 100,...}, returns new operation times and
+// what was changed.
+func (a *API) setOperationsAndTimes(userID int, in map[string]int) (db.Operation, string, error) {
+	operations, err := a.db.GetUserOperations(userID)
+	if err != nil {
+		return operations, "", err
+	}
+	msg := applyOperationsAndTimes(&operations, in)
+	return operations, msg, a.db.UpdateOperation(operations)
+}
+
+// applyOperationsAndTimes changes times of operations from map {"+": 100,...}, returns what was changed.
+func applyOperationsAndTimes(operations *db.Operation, in map[string]int) string {
 	msg := ""
 	for key, value := range in {
 		switch key {
@@ -234,10 +508,135 @@ func (a *API) PostOperationsAndTimes(c *gin.Context) {
 			msg += "changed for *;"
 		}
 	}
+	return msg
+}
 
-	err = a.db.UpdateOperation(operations)
+type InRetryExpression struct {
+	// optional operation times in milliseconds for the new run: {"+": 100,...}, not set operations are taken from
+	// the user
+	Operations map[string]int `json:"operations"`
+}
 
-	out := OutPostOperationsAndTimes{Message: msg}
+type OutRetryExpression struct {
+	Expression db.Expression `json:"expression"`
+	Message    string        `json:"message"`
+}
+
+// RetryExpression godoc
+//
+//	@Summary		Retry expression
+//	@Description	Calculate finished expression again, previous result is kept in the history of the expression
+//	@Tags			expression
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int					true	"Expression ID"
+//	@Param			operations	body		InRetryExpression	false	"Operation times for the new run"
+//	@Success		200			{object}	OutRetryExpression
+//	@Failure		400			{object}	OutRetryExpression
+//	@Failure		404			{object}	OutRetryExpression
+//	@Failure		409			{object}	OutRetryExpression
+//	@Failure		500			{object}	OutRetryExpression
+//	@Router			/v1/expression/{id}/retry [post]
+func (a *API) RetryExpression(c *gin.Context) {
+	var in InRetryExpression
+	var out OutRetryExpression
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	// body is optional
+	if err = c.ShouldBindBodyWith(&in, binding.JSON); err != nil && !errors.Is(err, io.EOF) {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	user := c.MustGet("user").(db.User)
+	expression, err := a.expressions.GetByUserAndID(user.ID, id)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+
+	operations, err := a.retryOperations(user.ID, expression, in)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	expression, err = a.expressions.Retry(user.ID, id, operations)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+
+	out.Expression = expression
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// retryOperations returns operation times for the new run of the expression, nil if operation times of the user
+// must be used. Given times are applied over operation times of the team for expressions of a team.
+func (a *API) retryOperations(userID int, expression db.Expression, in InRetryExpression) (*db.Operation, error) {
+	if len(in.Operations) == 0 {
+		return nil, nil
+	}
+	var operations db.Operation
+	var err error
+	if expression.Team != 0 {
+		operations, err = a.db.GetTeamOperations(expression.Team)
+	} else {
+		operations, err = a.db.GetUserOperations(userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	applyOperationsAndTimes(&operations, in.Operations)
+	return &operations, nil
+}
+
+type OutGetExpressionHistory struct {
+	Runs    []db.ExpressionRun `json:"runs"`
+	Message string             `json:"message"`
+}
+
+// GetExpressionHistory godoc
+//
+//	@Summary		Get expression history
+//	@Description	Get previous runs of the expression (results before retries), the oldest first
+//	@Tags			expression
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Expression ID"
+//	@Success		200	{object}	OutGetExpressionHistory
+//	@Failure		400	{object}	OutGetExpressionHistory
+//	@Failure		404	{object}	OutGetExpressionHistory
+//	@Failure		500	{object}	OutGetExpressionHistory
+//	@Router			/v1/expression/{id}/history [get]
+func (a *API) GetExpressionHistory(c *gin.Context) {
+	var out OutGetExpressionHistory
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	runs, err := a.expressions.GetRuns(c.MustGet("user").(db.User).ID, id)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+
+	out.Runs = runs
+	out.Message = "ok"
 	c.JSON(http.StatusOK, out)
 }
 
@@ -253,14 +652,15 @@ type OutGetExpressionByServer struct {
 // GetExpressionsByServer godoc
 //
 //	@Summary		Get expression by server
-//	@Description	Get expressions from storage by server name
-//	@Tags			expression
-//	@Accept			json
-//	@Produce		json
-//	@Param			server_name	body		InGetExpressionByServer	true	"Server name"
-//	@Success		200			{object}	OutGetExpressionByServer
-//	@Failure		400			{object}	OutGetExpressionByServer
-//	@Router			/getExpressionByServer [get]
+//	@Description	Get expressions from storage by server name. Deprecated, use GET /api/v2/servers/{name}/expressions
+//	@Deprecated
+//	@Tags		expression
+//	@Accept		json
+//	@Produce	json
+//	@Param		server_name	body		InGetExpressionByServer	true	"Server name"
+//	@Success	200			{object}	OutGetExpressionByServer
+//	@Failure	400			{object}	OutGetExpressionByServer
+//	@Router		/v1/getExpressionsByServer [get]
 func (a *API) GetExpressionsByServer(c *gin.Context) {
 	var in InGetExpressionByServer
 	var out OutGetExpressionByServer
@@ -279,13 +679,15 @@ func (a *API) GetExpressionsByServer(c *gin.Context) {
 	c.JSON(http.StatusOK, out)
 }
 
+type ComputingPower struct {
+	ServerName            string `json:"server_name"`
+	CalculatedExpressions []int  `json:"calculated_expressions"`
+	ServerStatus          string `json:"server_status"`
+}
+
 type OutGetComputingPowers struct {
-	Servers []struct {
-		ServerName            string `json:"server_name"`
-		CalculatedExpressions []int  `json:"calculated_expressions"`
-		ServerStatus          string `json:"server_status"`
-	} `json:"servers"`
-	Message string `json:"message"`
+	Servers []ComputingPower `json:"servers"`
+	Message string           `json:"message"`
 }
 
 // GetComputingPowers godoc
@@ -296,15 +698,19 @@ type OutGetComputingPowers struct {
 //	@Accept			json
 //	@Produce		json
 //	@Success		200	{object}	OutGetComputingPowers
-//	@Router			/getComputingPowers [get]
+//	@Router			/v1/getComputingPowers [get]
 func (a *API) GetComputingPowers(c *gin.Context) {
 	var out OutGetComputingPowers
+	out.Servers = a.computingPowers(c.MustGet("user").(db.User).ID)
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
 
-	// get computing powers
-	user := c.MustGet("user").(db.User)
-	servers := a.servers.GetAll()
-	for _, server := range servers {
-		operations := a.servers.GetExpressions(user.ID, server)
+// computingPowers returns servers with expressions of the user that they calculate.
+func (a *API) computingPowers(userID int) []ComputingPower {
+	var servers []ComputingPower
+	for _, server := range a.servers.GetAll() {
+		operations := a.servers.GetExpressions(userID, server)
 		ids := make([]int, 0)
 		for _, expression := range operations {
 			ids = append(ids, expression.ID)
@@ -314,13 +720,8 @@ func (a *API) GetComputingPowers(c *gin.Context) {
 		if !ok {
 			val = "unknown"
 		}
-		out.Servers = append(out.Servers, struct {
-			ServerName            string `json:"server_name"`
-			CalculatedExpressions []int  `json:"calculated_expressions"`
-			ServerStatus          string `json:"server_status"`
-		}{ServerName: server, CalculatedExpressions: ids, ServerStatus: val.(string)})
+		servers = append(servers, ComputingPower{ServerName: server, CalculatedExpressions: ids,
+			ServerStatus: val.(string)})
 	}
-
-	out.Message = "ok"
-	c.JSON(http.StatusOK, out)
+	return servers
 }
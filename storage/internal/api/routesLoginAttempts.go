@@ -0,0 +1,173 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"math"
+	"net/http"
+	"os"
+	"storage/internal/cryptPasswords"
+	"storage/internal/db"
+	"storage/internal/loginlimiter"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLoginFreeAttempts      = 3
+	defaultLoginLockoutAttempts   = 10
+	defaultLoginLockoutDuration   = 15 * time.Minute
+	defaultLoginIPLockoutAttempts = 100
+	loginIPFreeAttempts           = 20
+	loginBaseDelay                = time.Second
+	loginMaxDelay                 = time.Minute
+)
+
+// reasons of failed login attempts.
+const (
+	loginReasonInvalidCredentials  = "invalid credentials"
+	loginReasonThrottled           = "throttled"
+	loginReasonLockedOut           = "locked out"
+	loginReasonDisabled            = "user is disabled"
+	loginReasonInvalidSecondFactor = "invalid second factor"
+)
+
+const invalidCredentialsMessage = "invalid login or password"
+
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// compareWithDummyHash takes as long as a check of a real password, so the answer for unknown login comes as late
+// as the answer for a wrong password.
+func compareWithDummyHash(password string) {
+	dummyHashOnce.Do(func() {
+		var err error
+		if dummyHash, err = cryptPasswords.GeneratePasswordHash("dummy password"); err != nil {
+			zap.S().Error(err)
+		}
+	})
+	_ = cryptPasswords.ComparePasswordWithHash(dummyHash, password)
+}
+
+// newPasswordPolicy returns password policy from PASSWORD_MIN_LENGTH and PASSWORD_DENYLIST_FILE.
+func newPasswordPolicy() *cryptPasswords.PasswordPolicy {
+	var denylist []string
+	if path := os.Getenv("PASSWORD_DENYLIST_FILE"); path != "" {
+		var err error
+		if denylist, err = cryptPasswords.LoadDenylist(path); err != nil {
+			zap.S().Fatal(err)
+		}
+	}
+	return cryptPasswords.NewPasswordPolicy(numberFromEnv("PASSWORD_MIN_LENGTH",
+		cryptPasswords.DefaultMinPasswordLength), denylist)
+}
+
+// newLoginLimiters returns limiters of failed logins by account and by IP.
+func newLoginLimiters() (*loginlimiter.Limiter, *loginlimiter.Limiter) {
+	lockoutDuration := secondsFromEnv("LOGIN_LOCKOUT_DURATION", defaultLoginLockoutDuration)
+	account := loginlimiter.New(loginlimiter.Config{
+		FreeAttempts:    defaultLoginFreeAttempts,
+		BaseDelay:       loginBaseDelay,
+		MaxDelay:        loginMaxDelay,
+		LockoutAttempts: numberFromEnv("LOGIN_LOCKOUT_ATTEMPTS", defaultLoginLockoutAttempts),
+		LockoutDuration: lockoutDuration,
+	})
+	ip := loginlimiter.New(loginlimiter.Config{
+		FreeAttempts:    loginIPFreeAttempts,
+		BaseDelay:       loginBaseDelay,
+		MaxDelay:        loginMaxDelay,
+		LockoutAttempts: numberFromEnv("LOGIN_IP_LOCKOUT_ATTEMPTS", defaultLoginIPLockoutAttempts),
+		LockoutDuration: lockoutDuration,
+	})
+	return account, ip
+}
+
+// SetLoginClock replaces time.Now in login limiters and checks of second factor (for tests).
+func (a *API) SetLoginClock(now func() time.Time) {
+	a.accountLimiter.SetClock(now)
+	a.ipLimiter.SetClock(now)
+	a.now = now
+}
+
+// loginAllowed returns false and how long to wait if logins to the account or from the IP are throttled.
+func (a *API) loginAllowed(login string, ip string) (bool, time.Duration) {
+	accountOk, accountWait := a.accountLimiter.Allow(strings.ToLower(login))
+	ipOk, ipWait := a.ipLimiter.Allow(ip)
+	return accountOk && ipOk, max(accountWait, ipWait)
+}
+
+// loginFailed records failed login attempt, reason is changed if the account gets locked out.
+func (a *API) loginFailed(c *gin.Context, login string, userID int, reason string) {
+	if reason == loginReasonInvalidCredentials || reason == loginReasonInvalidSecondFactor {
+		lockedAccount := a.accountLimiter.Fail(strings.ToLower(login))
+		lockedIP := a.ipLimiter.Fail(c.ClientIP())
+		if lockedAccount || lockedIP {
+			reason = loginReasonLockedOut
+			zap.S().Warnf("logins to %v from %v are locked out", login, c.ClientIP())
+		}
+	}
+
+	_, err := a.db.AddLoginAttempt(db.LoginAttempt{
+		Login:        login,
+		User:         userID,
+		IP:           c.ClientIP(),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Reason:       reason,
+		CreationTime: time.Now().Format("2006-01-02 15:04:05"),
+	})
+	if err != nil {
+		zap.S().Error(err)
+	}
+}
+
+// retryAfter sets Retry-After header and returns message for throttled login.
+func retryAfter(c *gin.Context, wait time.Duration) string {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	return fmt.Sprintf("too many login attempts, try again in %v seconds", seconds)
+}
+
+type OutGetLoginAttempts struct {
+	LoginAttempts []db.LoginAttempt `json:"login_attempts"`
+	Message       string            `json:"message"`
+}
+
+// GetLoginAttempts godoc
+//
+//	@Summary		Get login attempts
+//	@Description	Get failed login attempts, the newest first
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			login	query		string	false	"Only attempts with this login"
+//	@Param			limit	query		int		false	"Maximum number of attempts (default 100)"
+//	@Success		200		{object}	OutGetLoginAttempts
+//	@Failure		400		{object}	OutGetLoginAttempts
+//	@Failure		401		{object}	OutAuthData
+//	@Failure		403		{object}	OutAuthData
+//	@Failure		500		{object}	OutGetLoginAttempts
+//	@Router			/v1/admin/loginAttempts [get]
+func (a *API) GetLoginAttempts(c *gin.Context) {
+	var out OutGetLoginAttempts
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		out.Message = "limit must be a positive number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	out.LoginAttempts, err = a.db.GetLoginAttempts(c.Query("login"), limit)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
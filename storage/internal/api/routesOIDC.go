@@ -0,0 +1,202 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"storage/internal/db"
+	"storage/internal/oidc"
+	"strings"
+	"time"
+)
+
+const (
+	defaultOIDCScopes   = "email profile"
+	oidcDisabledMessage = "single sign-on is not configured"
+	// oidcBindingCookie keeps the binding of the state to the browser that started single sign-on
+	oidcBindingCookie = "oidc_binding"
+	oidcCookiePath    = "/api/v1/oidc"
+)
+
+// newOIDCClient returns OpenID Connect client from OIDC_* environment variables, nil if OIDC_ISSUER is not set.
+func newOIDCClient() *oidc.Client {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil
+	}
+	config := oidc.Config{
+		Issuer:       issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+	}
+	if config.ClientID == "" || config.RedirectURL == "" {
+		zap.S().Fatal("OIDC_CLIENT_ID and OIDC_REDIRECT_URL must be set with OIDC_ISSUER")
+	}
+	scopes := os.Getenv("OIDC_SCOPES")
+	if scopes == "" {
+		scopes = defaultOIDCScopes
+	}
+	config.Scopes = strings.Fields(scopes)
+	return oidc.New(config, nil)
+}
+
+// oidcLogin returns login for a new user of the provider.
+func oidcLogin(claims *oidc.Claims) string {
+	if claims.PreferredUsername != "" {
+		return claims.PreferredUsername
+	}
+	if claims.Email != "" {
+		return claims.Email
+	}
+	return claims.Subject
+}
+
+type OutOIDCLogin struct {
+	URL     string `json:"url"`
+	Message string `json:"message"`
+}
+
+// OIDCLogin godoc
+//
+//	@Summary		Start single sign-on
+//	@Description	Get URL of the OpenID Connect provider to which the user must be redirected. The provider redirects the user back to OIDC_REDIRECT_URL with code and state, they are sent to /oidc/callback. The answer sets HttpOnly cookie oidc_binding, it must be sent to /oidc/callback from the same browser
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	OutOIDCLogin
+//	@Failure		404	{object}	OutOIDCLogin
+//	@Failure		502	{object}	OutOIDCLogin
+//	@Router			/v1/oidc/login [get]
+func (a *API) OIDCLogin(c *gin.Context) {
+	var out OutOIDCLogin
+	if a.oidc == nil {
+		out.Message = oidcDisabledMessage
+		c.JSON(http.StatusNotFound, out)
+		return
+	}
+
+	authorizationURL, binding, err := a.oidc.Begin(c.Request.Context())
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusBadGateway, out)
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcBindingCookie, binding, int(oidc.StateLifetime.Seconds()), oidcCookiePath, "", c.Request.TLS != nil,
+		true)
+	out.URL = authorizationURL
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type InOIDCCallback struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// OIDCCallback godoc
+//
+//	@Summary		Finish single sign-on
+//	@Description	Exchange code from the OpenID Connect provider for tokens, cookie oidc_binding of /oidc/login is required. The user is created on the first login, its login is preferred_username or email of the provider. If the user has second factor, the answer contains challenge for /login/twoFactor instead of tokens
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	body		InOIDCCallback	true	"Code from the provider"
+//	@Param			state	body		InOIDCCallback	true	"State from the provider"
+//	@Success		200		{object}	OutLogin
+//	@Failure		400		{object}	OutLogin
+//	@Failure		401		{object}	OutLogin
+//	@Failure		403		{object}	OutLogin
+//	@Failure		404		{object}	OutLogin
+//	@Failure		409		{object}	OutLogin
+//	@Failure		500		{object}	OutLogin
+//	@Router			/v1/oidc/callback [post]
+func (a *API) OIDCCallback(c *gin.Context) {
+	var in InOIDCCallback
+	var out OutLogin
+	if a.oidc == nil {
+		out.Message = oidcDisabledMessage
+		c.JSON(http.StatusNotFound, out)
+		return
+	}
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	// the login must be finished by the browser that started it, so nobody can log the user in as somebody else
+	binding, _ := c.Cookie(oidcBindingCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcBindingCookie, "", -1, oidcCookiePath, "", c.Request.TLS != nil, true)
+	claims, err := a.oidc.Finish(c.Request.Context(), in.State, binding, in.Code)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Warn(out)
+		c.JSON(http.StatusUnauthorized, out)
+		return
+	}
+
+	user, err := a.oidcUser(claims)
+	if errors.Is(err, errLoginTaken) {
+		out.Message = err.Error()
+		c.JSON(http.StatusConflict, out)
+		return
+	}
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	if user.Disabled {
+		a.loginFailed(c, user.Login, user.ID, loginReasonDisabled)
+		out.Message = "user is disabled"
+		c.JSON(http.StatusForbidden, out)
+		return
+	}
+
+	status := a.completeLogin(c, user.ID, &out)
+	c.JSON(status, out)
+}
+
+var errLoginTaken = errors.New("user with this login already exists, it can not be linked to single sign-on")
+
+// oidcUser returns the user linked to the subject of the provider, the user is created on the first login. Users of
+// the provider have no password, so they can log in only with single sign-on.
+func (a *API) oidcUser(claims *oidc.Claims) (db.User, error) {
+	identity, err := a.db.GetOIDCIdentity(a.oidc.Issuer(), claims.Subject)
+	if err == nil {
+		return a.db.GetUserByID(identity.User)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return db.User{}, err
+	}
+
+	// existing users are not linked by login, otherwise anybody who can choose the name at the provider gets the
+	// account
+	login := oidcLogin(claims)
+	if _, err := a.db.GetUserByUsername(login); err == nil {
+		return db.User{}, errLoginTaken
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return db.User{}, err
+	}
+
+	user := db.User{Login: login}
+	user.ID, err = a.db.AddOIDCUser(user, db.OIDCIdentity{
+		Issuer:       a.oidc.Issuer(),
+		Subject:      claims.Subject,
+		Email:        claims.Email,
+		CreationTime: time.Now().Format("2006-01-02 15:04:05"),
+	})
+	if err != nil {
+		return db.User{}, err
+	}
+	return a.db.GetUserByID(user.ID)
+}
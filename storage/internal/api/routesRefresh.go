@@ -0,0 +1,143 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+	"net/http"
+	"storage/internal/cryptPasswords"
+	"storage/internal/db"
+	"time"
+)
+
+const (
+	defaultAccessLifetime  = 5 * time.Minute
+	defaultRefreshLifetime = 30 * 24 * time.Hour
+)
+
+// makeRefreshToken saves a new refresh token of the user and returns it. Family of the token is the JTI of the
+// session, so all refresh tokens of one login are revoked together.
+func (a *API) makeRefreshToken(userID int, family string) (string, error) {
+	token, err := cryptPasswords.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = a.db.AddRefreshToken(db.RefreshToken{
+		TokenHash:    cryptPasswords.HashToken(token),
+		Family:       family,
+		User:         userID,
+		ExpiresAt:    int(time.Now().Add(a.refreshLifetime).Unix()),
+		CreationTime: time.Now().Format("2006-01-02 15:04:05"),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+type InRefresh struct {
+	Refresh string `json:"refresh" binding:"required"`
+}
+
+type OutRefresh struct {
+	Access  string `json:"access"`
+	Refresh string `json:"refresh"`
+	Message string `json:"message"`
+}
+
+// Refresh godoc
+//
+//	@Summary		Refresh
+//	@Description	Exchange refresh token for new access and refresh tokens. Refresh token can be used only once, reusing it revokes the session
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			refresh	body		InRefresh	true	"Refresh token"
+//	@Success		200		{object}	OutRefresh
+//	@Failure		400		{object}	OutRefresh
+//	@Failure		401		{object}	OutRefresh
+//	@Failure		500		{object}	OutRefresh
+//	@Router			/v1/refresh [post]
+func (a *API) Refresh(c *gin.Context) {
+	var in InRefresh
+	var out OutRefresh
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	token, err := a.db.GetRefreshToken(cryptPasswords.HashToken(in.Refresh))
+	if err != nil || token.Revoked || token.ExpiresAt < int(time.Now().Unix()) {
+		out.Message = "refresh token is not valid"
+		c.JSON(http.StatusUnauthorized, out)
+		return
+	}
+	session, err := a.db.GetSession(token.Family)
+	if err != nil || session.Revoked {
+		out.Message = "refresh token is not valid"
+		c.JSON(http.StatusUnauthorized, out)
+		return
+	}
+
+	// disabled users get no new tokens, the token is not rotated for them
+	user, err := a.db.GetUserByID(token.User)
+	if err != nil {
+		out.Message = "refresh token is not valid"
+		c.JSON(http.StatusUnauthorized, out)
+		return
+	}
+	if user.Disabled {
+		out.Message = "user is disabled"
+		c.JSON(http.StatusUnauthorized, out)
+		return
+	}
+
+	ok, err := a.db.UseRefreshToken(token.ID)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	if !ok {
+		// the token was stolen or the client is broken, nobody can use the family anymore
+		zap.S().Warnf("refresh token of user %v is reused, revoking its session", token.User)
+		if err = a.db.RevokeSession(token.Family); err != nil {
+			zap.S().Error(err)
+		}
+		out.Message = "refresh token is already used"
+		c.JSON(http.StatusUnauthorized, out)
+		return
+	}
+
+	session.LastSeenTime = time.Now().Format("2006-01-02 15:04:05")
+	session.ExpiresAt = int(time.Now().Add(a.refreshLifetime).Unix())
+	if err = a.db.UpdateSession(session); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	access, err := a.makeToken(user.ID, session.JTI)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	refresh, err := a.makeRefreshToken(user.ID, token.Family)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.Access = access
+	out.Refresh = refresh
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"storage/internal/db"
+	"storage/internal/expressionstorage"
+	"strconv"
+	"time"
+)
+
+// maxResultWait is the longest wait of GetExpressionResult, so proxies do not close the connection.
+const maxResultWait = 60 * time.Second
+
+type OutGetExpressionResult struct {
+	Expression db.Expression `json:"expression"`
+	Finished   bool          `json:"finished"` // false if the wait is over before the expression is finished
+	Message    string        `json:"message"`
+}
+
+// GetExpressionResult godoc
+//
+//	@Summary		Wait for result of expression
+//	@Description	Wait until the expression is calculated, failed or cancelled, or until the wait is over, and return the expression. If it is not finished yet, finished is false and the expression has the current status. The wait is a duration (30s, 1m) or seconds, at most 60 seconds, without it the expression is returned at once
+//	@Tags			expression
+//	@Produce		json
+//	@Param			id		path		int		true	"Expression ID"
+//	@Param			wait	query		string	false	"How long to wait, e.g. 30s"
+//	@Success		200		{object}	OutGetExpressionResult
+//	@Failure		400		{object}	OutGetExpressionResult
+//	@Failure		401		{object}	OutAuthData
+//	@Failure		404		{object}	OutGetExpressionResult
+//	@Router			/v1/expressions/{id}/result [get]
+//	@Router			/v2/expressions/{id}/result [get]
+func (a *API) GetExpressionResult(c *gin.Context) {
+	var out OutGetExpressionResult
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	wait, err := parseWait(c.Query("wait"))
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), min(wait, maxResultWait))
+	defer cancel()
+	expression, err := a.expressions.Wait(ctx, c.MustGet("user").(db.User).ID, id)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+	out.Expression = expression
+	out.Finished = expressionstorage.IsFinished(expression.Status)
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// parseWait parses the wait as a duration or seconds.
+func parseWait(wait string) (time.Duration, error) {
+	if wait == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(wait)
+	if err != nil {
+		var seconds int
+		seconds, err = strconv.Atoi(wait)
+		duration = time.Duration(seconds) * time.Second
+	}
+	if err != nil || duration < 0 {
+		return 0, errors.New("wait must be a duration (e.g. 30s) or seconds")
+	}
+	return duration, nil
+}
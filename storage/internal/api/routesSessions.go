@@ -0,0 +1,178 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"storage/internal/cryptPasswords"
+	"storage/internal/db"
+	"strconv"
+	"time"
+)
+
+// startSession creates a new session of the user (login) and returns its access and refresh tokens.
+func (a *API) startSession(c *gin.Context, userID int) (string, string, error) {
+	jti, err := cryptPasswords.GenerateToken()
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	_, err = a.db.AddSession(db.Session{
+		JTI:          jti,
+		User:         userID,
+		UserAgent:    c.GetHeader("User-Agent"),
+		CreationTime: now.Format("2006-01-02 15:04:05"),
+		LastSeenTime: now.Format("2006-01-02 15:04:05"),
+		ExpiresAt:    int(now.Add(a.refreshLifetime).Unix()),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	access, err := a.makeToken(userID, jti)
+	if err != nil {
+		return "", "", err
+	}
+	refresh, err := a.makeRefreshToken(userID, jti)
+	if err != nil {
+		return "", "", err
+	}
+	return access, refresh, nil
+}
+
+type OutLogout struct {
+	Message string `json:"message"`
+}
+
+// Logout godoc
+//
+//	@Summary		Logout
+//	@Description	Revoke current session, its access and refresh tokens can not be used anymore
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	OutLogout
+//	@Failure		500	{object}	OutLogout
+//	@Router			/v1/logout [post]
+func (a *API) Logout(c *gin.Context) {
+	var out OutLogout
+	if err := a.db.RevokeSession(c.MustGet("session").(db.Session).JTI); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// LogoutEverywhere godoc
+//
+//	@Summary		Logout everywhere
+//	@Description	Revoke all sessions of the user, including the current one
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	OutLogout
+//	@Failure		500	{object}	OutLogout
+//	@Router			/v1/logoutEverywhere [post]
+func (a *API) LogoutEverywhere(c *gin.Context) {
+	var out OutLogout
+	if err := a.db.RevokeUserSessions(c.MustGet("user").(db.User).ID); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutSession struct {
+	db.Session
+	Current bool `json:"current"` // the session of the request
+}
+
+type OutGetSessions struct {
+	Sessions []OutSession `json:"sessions"`
+	Message  string       `json:"message"`
+}
+
+// GetSessions godoc
+//
+//	@Summary		Get sessions
+//	@Description	Get active sessions of the user
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	OutGetSessions
+//	@Failure		500	{object}	OutGetSessions
+//	@Router			/v1/sessions [get]
+func (a *API) GetSessions(c *gin.Context) {
+	var out OutGetSessions
+	sessions, err := a.db.GetUserSessions(c.MustGet("user").(db.User).ID, int(time.Now().Unix()))
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	current := c.MustGet("session").(db.Session)
+	out.Sessions = make([]OutSession, 0, len(sessions))
+	for _, session := range sessions {
+		out.Sessions = append(out.Sessions, OutSession{Session: session, Current: session.ID == current.ID})
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// DeleteSession godoc
+//
+//	@Summary		Delete session
+//	@Description	Revoke one of the sessions of the user, e.g. on a lost device
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Session ID"
+//	@Success		200	{object}	OutLogout
+//	@Failure		400	{object}	OutLogout
+//	@Failure		404	{object}	OutLogout
+//	@Failure		500	{object}	OutLogout
+//	@Router			/v1/sessions/{id} [delete]
+func (a *API) DeleteSession(c *gin.Context) {
+	var out OutLogout
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	sessions, err := a.db.GetUserSessions(c.MustGet("user").(db.User).ID, int(time.Now().Unix()))
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	for _, session := range sessions {
+		if session.ID != id {
+			continue
+		}
+		if err = a.db.RevokeSession(session.JTI); err != nil {
+			out.Message = err.Error()
+			zap.S().Error(out)
+			c.JSON(http.StatusInternalServerError, out)
+			return
+		}
+		out.Message = "ok"
+		c.JSON(http.StatusOK, out)
+		return
+	}
+
+	out.Message = "session is not found"
+	c.JSON(http.StatusNotFound, out)
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"storage/internal/db"
+	"storage/internal/jwtkeys"
+	"time"
+)
+
+const (
+	defaultJWTAlgorithm      = jwtkeys.RS256
+	defaultKeyGracePeriod    = 24 * time.Hour
+	jwksCacheControl         = "public, max-age=300"
+	unsupportedAlgorithmText = "algorithm must be RS256 or EdDSA"
+)
+
+// newKeySet returns signing keys with JWT_ALGORITHM and JWT_KEY_GRACE_PERIOD. Retired keys must verify tokens at
+// least until the last token signed with them expires, so the grace period is not shorter than accessLifetime.
+// Private keys are encrypted in database with JWT_KEY_ENCRYPTION_KEY.
+func newKeySet(d *db.APIDb, accessLifetime time.Duration) *jwtkeys.KeySet {
+	algorithm := os.Getenv("JWT_ALGORITHM")
+	if algorithm == "" {
+		algorithm = defaultJWTAlgorithm
+	}
+	gracePeriod := max(secondsFromEnv("JWT_KEY_GRACE_PERIOD", defaultKeyGracePeriod), accessLifetime)
+	encryptionKey, err := base64.StdEncoding.DecodeString(os.Getenv("JWT_KEY_ENCRYPTION_KEY"))
+	if err != nil || len(encryptionKey) != jwtkeys.EncryptionKeySize {
+		zap.S().Fatal("JWT_KEY_ENCRYPTION_KEY must be 32 bytes in base64")
+	}
+	keys, err := jwtkeys.New(d, algorithm, gracePeriod, encryptionKey)
+	if err != nil {
+		zap.S().Fatal(err)
+	}
+	return keys
+}
+
+// GetJWKS serves public keys for verification of access tokens (RFC 7517) at /.well-known/jwks.json, outside of the
+// API base path. Keys are found by kid header of the token, retired keys are listed until their grace period ends.
+func (a *API) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", jwksCacheControl)
+	c.JSON(http.StatusOK, a.keys.JWKS())
+}
+
+type OutGetSigningKeys struct {
+	SigningKeys []db.SigningKey `json:"signing_keys"`
+	Message     string          `json:"message"`
+}
+
+// GetSigningKeys godoc
+//
+//	@Summary		Get signing keys
+//	@Description	Get signing keys of access tokens without private parts, retired_at is 0 for active keys
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	OutGetSigningKeys
+//	@Failure		401	{object}	OutAuthData
+//	@Failure		403	{object}	OutAuthData
+//	@Failure		500	{object}	OutGetSigningKeys
+//	@Router			/v1/admin/signingKeys [get]
+func (a *API) GetSigningKeys(c *gin.Context) {
+	var out OutGetSigningKeys
+	var err error
+	out.SigningKeys, err = a.db.GetSigningKeys()
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type InRotateSigningKeys struct {
+	Algorithm string `json:"algorithm"`
+}
+
+type OutRotateSigningKeys struct {
+	KID     string `json:"kid"`
+	Message string `json:"message"`
+}
+
+// RotateSigningKeys godoc
+//
+//	@Summary		Rotate signing keys
+//	@Description	Create a new signing key for access tokens, other active keys are retired and verify tokens for the grace period (JWT_KEY_GRACE_PERIOD)
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			algorithm	body		InRotateSigningKeys	false	"RS256 or EdDSA, JWT_ALGORITHM by default"
+//	@Success		200			{object}	OutRotateSigningKeys
+//	@Failure		400			{object}	OutRotateSigningKeys
+//	@Failure		401			{object}	OutAuthData
+//	@Failure		403			{object}	OutAuthData
+//	@Failure		500			{object}	OutRotateSigningKeys
+//	@Router			/v1/admin/signingKeys/rotate [post]
+func (a *API) RotateSigningKeys(c *gin.Context) {
+	var in InRotateSigningKeys
+	var out OutRotateSigningKeys
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+			out.Message = err.Error()
+			c.JSON(http.StatusBadRequest, out)
+			return
+		}
+	}
+	if in.Algorithm != "" && in.Algorithm != jwtkeys.RS256 && in.Algorithm != jwtkeys.EdDSA {
+		out.Message = unsupportedAlgorithmText
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	key, err := a.keys.Rotate(in.Algorithm)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	zap.S().Infof("signing key %v is created", key.ID)
+	out.KID = key.ID
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutRetireSigningKey struct {
+	Message string `json:"message"`
+}
+
+// RetireSigningKey godoc
+//
+//	@Summary		Retire signing key
+//	@Description	Stop signing access tokens with the key, it verifies tokens for the grace period (JWT_KEY_GRACE_PERIOD). The last active key can not be retired
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			kid	path		string	true	"Key ID"
+//	@Success		200	{object}	OutRetireSigningKey
+//	@Failure		400	{object}	OutRetireSigningKey
+//	@Failure		401	{object}	OutAuthData
+//	@Failure		403	{object}	OutAuthData
+//	@Failure		404	{object}	OutRetireSigningKey
+//	@Failure		500	{object}	OutRetireSigningKey
+//	@Router			/v1/admin/signingKeys/{kid}/retire [post]
+func (a *API) RetireSigningKey(c *gin.Context) {
+	var out OutRetireSigningKey
+	err := a.keys.Retire(c.Param("kid"))
+	if errors.Is(err, jwtkeys.ErrUnknownKey) {
+		out.Message = "active key is not found"
+		c.JSON(http.StatusNotFound, out)
+		return
+	}
+	if errors.Is(err, jwtkeys.ErrLastKey) {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
@@ -0,0 +1,524 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+	"net/http"
+	"storage/internal/db"
+	"strconv"
+	"time"
+)
+
+// memberRole returns the role of the user in the team. If the user is not a member of the team or the team requires
+// second factor that the user does not have, the error and the status of the answer are returned.
+func (a *API) memberRole(teamID int, userID int) (string, int, error) {
+	role, err := a.db.GetTeamMemberRole(teamID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", http.StatusNotFound, errors.New("team is not found")
+	}
+	if err != nil {
+		zap.S().Error(err)
+		return "", http.StatusInternalServerError, err
+	}
+
+	team, err := a.db.GetTeam(teamID)
+	if err != nil {
+		zap.S().Error(err)
+		return "", http.StatusInternalServerError, err
+	}
+	if team.RequireTwoFactor {
+		hasTwoFactor, err := a.db.HasTwoFactor(userID)
+		if err != nil {
+			zap.S().Error(err)
+			return "", http.StatusInternalServerError, err
+		}
+		if !hasTwoFactor {
+			return "", http.StatusForbidden, errors.New("team requires two-factor authentication")
+		}
+	}
+	return role, http.StatusOK, nil
+}
+
+// teamRole returns ID of the team from the path and the role of the user in it (see memberRole).
+func (a *API) teamRole(c *gin.Context) (int, string, int, error) {
+	teamID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return 0, "", http.StatusBadRequest, errors.New("id must be a number")
+	}
+	role, status, err := a.memberRole(teamID, c.MustGet("user").(db.User).ID)
+	if err != nil {
+		return 0, "", status, err
+	}
+	return teamID, role, http.StatusOK, nil
+}
+
+type InAddTeam struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type OutAddTeam struct {
+	Team    db.Team `json:"team"`
+	Message string  `json:"message"`
+}
+
+// AddTeam godoc
+//
+//	@Summary		Add team
+//	@Description	Create team, the user becomes its owner
+//	@Tags			teams
+//	@Accept			json
+//	@Produce		json
+//	@Param			name	body		InAddTeam	true	"Name of the team"
+//	@Success		200		{object}	OutAddTeam
+//	@Failure		400		{object}	OutAddTeam
+//	@Failure		500		{object}	OutAddTeam
+//	@Router			/v1/teams [post]
+func (a *API) AddTeam(c *gin.Context) {
+	var in InAddTeam
+	var out OutAddTeam
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	team := db.Team{
+		Name:         in.Name,
+		CreationTime: time.Now().Format("2006-01-02 15:04:05"),
+	}
+	var err error
+	team.ID, err = a.db.AddTeam(team, c.MustGet("user").(db.User).ID)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.Team = team
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutTeam struct {
+	db.Team
+	Role string `json:"role"` // role of the user in the team
+}
+
+type OutGetTeams struct {
+	Teams   []OutTeam `json:"teams"`
+	Message string    `json:"message"`
+}
+
+// GetTeams godoc
+//
+//	@Summary		Get teams
+//	@Description	Get teams of the user
+//	@Tags			teams
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	OutGetTeams
+//	@Failure		500	{object}	OutGetTeams
+//	@Router			/v1/teams [get]
+func (a *API) GetTeams(c *gin.Context) {
+	var out OutGetTeams
+	members, err := a.db.GetUserTeams(c.MustGet("user").(db.User).ID)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.Teams = make([]OutTeam, 0, len(members))
+	for _, member := range members {
+		team, err := a.db.GetTeam(member.Team)
+		if err != nil {
+			out.Message = err.Error()
+			zap.S().Error(out)
+			c.JSON(http.StatusInternalServerError, out)
+			return
+		}
+		out.Teams = append(out.Teams, OutTeam{Team: team, Role: member.Role})
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutDeleteTeam struct {
+	Message string `json:"message"`
+}
+
+// DeleteTeam godoc
+//
+//	@Summary		Delete team
+//	@Description	Delete team, its expressions become personal expressions of their authors. Only for owners
+//	@Tags			teams
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Team ID"
+//	@Success		200	{object}	OutDeleteTeam
+//	@Failure		403	{object}	OutDeleteTeam
+//	@Failure		404	{object}	OutDeleteTeam
+//	@Failure		500	{object}	OutDeleteTeam
+//	@Router			/v1/teams/{id} [delete]
+func (a *API) DeleteTeam(c *gin.Context) {
+	var out OutDeleteTeam
+	teamID, role, status, err := a.teamRole(c)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(status, out)
+		return
+	}
+	if role != db.TeamRoleOwner {
+		out.Message = "only owners can delete the team"
+		c.JSON(http.StatusForbidden, out)
+		return
+	}
+
+	if err = a.expressions.DeleteTeam(teamID); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutGetTeamMembers struct {
+	Members []db.TeamMember `json:"members"`
+	Message string          `json:"message"`
+}
+
+// GetTeamMembers godoc
+//
+//	@Summary		Get team members
+//	@Description	Get members of the team of the user
+//	@Tags			teams
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Team ID"
+//	@Success		200	{object}	OutGetTeamMembers
+//	@Failure		404	{object}	OutGetTeamMembers
+//	@Failure		500	{object}	OutGetTeamMembers
+//	@Router			/v1/teams/{id}/members [get]
+func (a *API) GetTeamMembers(c *gin.Context) {
+	var out OutGetTeamMembers
+	teamID, _, status, err := a.teamRole(c)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(status, out)
+		return
+	}
+
+	out.Members, err = a.db.GetTeamMembers(teamID)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type InSetTeamMember struct {
+	Login string `json:"login" binding:"required"`
+	Role  string `json:"role"` // owner or member (default)
+}
+
+type OutSetTeamMember struct {
+	Member  db.TeamMember `json:"member"`
+	Message string        `json:"message"`
+}
+
+// SetTeamMember godoc
+//
+//	@Summary		Set team member
+//	@Description	Add user to the team or change role of the member. Only for owners
+//	@Tags			teams
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Team ID"
+//	@Param			member	body		InSetTeamMember	true	"Login and role of the member"
+//	@Success		200		{object}	OutSetTeamMember
+//	@Failure		400		{object}	OutSetTeamMember
+//	@Failure		403		{object}	OutSetTeamMember
+//	@Failure		404		{object}	OutSetTeamMember
+//	@Failure		500		{object}	OutSetTeamMember
+//	@Router			/v1/teams/{id}/members [post]
+func (a *API) SetTeamMember(c *gin.Context) {
+	var in InSetTeamMember
+	var out OutSetTeamMember
+	teamID, role, status, err := a.teamRole(c)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(status, out)
+		return
+	}
+	if err = c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	if role != db.TeamRoleOwner {
+		out.Message = "only owners can change members of the team"
+		c.JSON(http.StatusForbidden, out)
+		return
+	}
+	if in.Role == "" {
+		in.Role = db.TeamRoleMember
+	}
+	if in.Role != db.TeamRoleOwner && in.Role != db.TeamRoleMember {
+		out.Message = "unknown role: " + in.Role
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	user, err := a.db.GetUserByUsername(in.Login)
+	if err != nil {
+		out.Message = "user not found"
+		c.JSON(http.StatusNotFound, out)
+		return
+	}
+	if user.ID == c.MustGet("user").(db.User).ID {
+		out.Message = "you can not change your own role"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	member := db.TeamMember{Team: teamID, User: user.ID, Role: in.Role, Login: user.Login}
+	if err = a.db.SetTeamMember(member); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.Member = member
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutDeleteTeamMember struct {
+	Message string `json:"message"`
+}
+
+// DeleteTeamMember godoc
+//
+//	@Summary		Delete team member
+//	@Description	Remove user from the team. Owners can remove anyone except themselves, members can only leave
+//	@Tags			teams
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"Team ID"
+//	@Param			userId	path		int	true	"User ID"
+//	@Success		200		{object}	OutDeleteTeamMember
+//	@Failure		400		{object}	OutDeleteTeamMember
+//	@Failure		403		{object}	OutDeleteTeamMember
+//	@Failure		404		{object}	OutDeleteTeamMember
+//	@Failure		500		{object}	OutDeleteTeamMember
+//	@Router			/v1/teams/{id}/members/{userId} [delete]
+func (a *API) DeleteTeamMember(c *gin.Context) {
+	var out OutDeleteTeamMember
+	teamID, role, status, err := a.teamRole(c)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(status, out)
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		out.Message = "user id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	self := userID == c.MustGet("user").(db.User).ID
+	if self && role == db.TeamRoleOwner {
+		out.Message = "owner can not leave the team, delete the team or make another owner first"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	if !self && role != db.TeamRoleOwner {
+		out.Message = "only owners can remove members of the team"
+		c.JSON(http.StatusForbidden, out)
+		return
+	}
+
+	if err = a.db.DeleteTeamMember(teamID, userID); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// GetTeamOperationsAndTimes godoc
+//
+//	@Summary		Get operations and times of the team
+//	@Description	Get operation times used for expressions of the team as a map of operation and time in milliseconds, {"+": 100,...}. If they are not set, operation times of authors are used and data is empty
+//	@Tags			teams
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Team ID"
+//	@Success		200	{object}	OutGetOperationsAndTimes
+//	@Failure		404	{object}	OutGetOperationsAndTimes
+//	@Failure		500	{object}	OutGetOperationsAndTimes
+//	@Router			/v1/teams/{id}/operationsAndTimes [get]
+func (a *API) GetTeamOperationsAndTimes(c *gin.Context) {
+	var out OutGetOperationsAndTimes
+	teamID, _, status, err := a.teamRole(c)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(status, out)
+		return
+	}
+
+	out.Data = make(map[string]int)
+	operations, err := a.db.GetTeamOperations(teamID)
+	if errors.Is(err, sql.ErrNoRows) {
+		out.Message = "ok"
+		c.JSON(http.StatusOK, out)
+		return
+	}
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Data["+"] = operations.TimeAdd
+	out.Data["-"] = operations.TimeSubtract
+	out.Data["/"] = operations.TimeDivide
+	out.Data["*"] = operations.TimeMultiply
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// PostTeamOperationsAndTimes godoc
+//
+//	@Summary		Set operations and times of the team
+//	@Description	Set operation times for expressions of the team as a map of operation and time in milliseconds, {"+": 100,...}. Only for owners
+//	@Tags			teams
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Team ID"
+//	@Param			data	body		map[string]int	true	"Operations and times"
+//	@Success		200		{object}	OutPostOperationsAndTimes
+//	@Failure		400		{object}	OutPostOperationsAndTimes
+//	@Failure		403		{object}	OutPostOperationsAndTimes
+//	@Failure		404		{object}	OutPostOperationsAndTimes
+//	@Failure		500		{object}	OutPostOperationsAndTimes
+//	@Router			/v1/teams/{id}/operationsAndTimes [post]
+func (a *API) PostTeamOperationsAndTimes(c *gin.Context) {
+	var in map[string]int
+	var out OutPostOperationsAndTimes
+	teamID, role, status, err := a.teamRole(c)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(status, out)
+		return
+	}
+	if err = c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	if role != db.TeamRoleOwner {
+		out.Message = "only owners can change operation times of the team"
+		c.JSON(http.StatusForbidden, out)
+		return
+	}
+
+	operations, err := a.db.GetTeamOperations(teamID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = applyOperationsAndTimes(&operations, in)
+	if err = a.db.SetTeamOperations(teamID, operations); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+type InSetTeamTwoFactor struct {
+	Required bool `json:"required"`
+}
+
+type OutSetTeamTwoFactor struct {
+	Message string `json:"message"`
+}
+
+// SetTeamTwoFactor godoc
+//
+//	@Summary		Require two-factor authentication in the team
+//	@Description	Members without second factor can not use the team while it is required. Only for owners, owner must have second factor to require it
+//	@Tags			teams
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int					true	"Team ID"
+//	@Param			required	body		InSetTeamTwoFactor	true	"Is second factor required"
+//	@Success		200			{object}	OutSetTeamTwoFactor
+//	@Failure		400			{object}	OutSetTeamTwoFactor
+//	@Failure		403			{object}	OutSetTeamTwoFactor
+//	@Failure		404			{object}	OutSetTeamTwoFactor
+//	@Failure		500			{object}	OutSetTeamTwoFactor
+//	@Router			/v1/teams/{id}/requireTwoFactor [post]
+func (a *API) SetTeamTwoFactor(c *gin.Context) {
+	var in InSetTeamTwoFactor
+	var out OutSetTeamTwoFactor
+	teamID, role, status, err := a.teamRole(c)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(status, out)
+		return
+	}
+	if err = c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	if role != db.TeamRoleOwner {
+		out.Message = "only owners can change settings of the team"
+		c.JSON(http.StatusForbidden, out)
+		return
+	}
+	if in.Required {
+		hasTwoFactor, err := a.db.HasTwoFactor(c.MustGet("user").(db.User).ID)
+		if err != nil {
+			out.Message = err.Error()
+			zap.S().Error(out)
+			c.JSON(http.StatusInternalServerError, out)
+			return
+		}
+		if !hasTwoFactor {
+			out.Message = "enable two-factor authentication first"
+			c.JSON(http.StatusBadRequest, out)
+			return
+		}
+	}
+
+	if err = a.db.SetTeamRequireTwoFactor(teamID, in.Required); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
@@ -0,0 +1,442 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"storage/internal/cryptPasswords"
+	"storage/internal/db"
+	"storage/internal/totp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTOTPIssuer    = "Distributed Calculations"
+	challengeLifetime    = 5 * time.Minute
+	challengeType        = "2fa"
+	recoveryCodesCount   = 10
+	twoFactorRequiredMsg = "two-factor authentication required"
+)
+
+// makeChallenge returns a short-lived token that proves that the password of the user is checked, it is exchanged
+// for tokens with the second factor (see LoginTwoFactor).
+// Challenges are verified only by this server, so they are signed with SECRET_SIGNATURE and not with the published
+// signing keys, other services can not mistake them for access tokens.
+func (a *API) makeChallenge(userID int) (string, error) {
+	now := a.now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": strconv.Itoa(userID),
+		"typ": challengeType,
+		"exp": now.Add(challengeLifetime).Unix(),
+		"iat": now.Unix(),
+	})
+	return token.SignedString(a.secretSignature)
+}
+
+// parseChallenge returns ID of the user of the challenge.
+func (a *API) parseChallenge(challenge string) (int, error) {
+	token, err := jwt.Parse(challenge, func(token *jwt.Token) (interface{}, error) {
+		return a.secretSignature, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithTimeFunc(a.now),
+		jwt.WithExpirationRequired())
+	if err != nil {
+		return 0, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != challengeType {
+		return 0, errors.New("looks like wrong challenge")
+	}
+	return strconv.Atoi(fmt.Sprint(claims["sub"]))
+}
+
+// checkSecondFactor accepts TOTP code or unused recovery code of the user. Every code is accepted only once.
+func (a *API) checkSecondFactor(userID int, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		secret, err := a.db.GetTOTPSecret(userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !secret.Confirmed {
+			return false, nil
+		}
+		step, ok := totp.Validate(secret.Secret, code, a.now())
+		if !ok {
+			return false, nil
+		}
+		return a.db.UseTOTPStep(userID, step)
+	}
+	return a.db.UseRecoveryCode(userID, cryptPasswords.HashToken(totp.NormalizeRecoveryCode(code)))
+}
+
+// newRecoveryCodes replaces recovery codes of the user, the codes are returned only here.
+func (a *API) newRecoveryCodes(userID int) ([]string, error) {
+	codes, err := totp.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, cryptPasswords.HashToken(totp.NormalizeRecoveryCode(code)))
+	}
+	if err = a.db.SetRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+type InLoginTwoFactor struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"` // TOTP code or recovery code
+}
+
+// LoginTwoFactor godoc
+//
+//	@Summary		Login with second factor
+//	@Description	Exchange challenge from /login and TOTP code or recovery code for tokens
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	body		InLoginTwoFactor	true	"Challenge and code"
+//	@Success		200		{object}	OutLogin
+//	@Failure		400		{object}	OutLogin
+//	@Failure		401		{object}	OutLogin
+//	@Failure		429		{object}	OutLogin
+//	@Failure		500		{object}	OutLogin
+//	@Router			/v1/login/twoFactor [post]
+func (a *API) LoginTwoFactor(c *gin.Context) {
+	var in InLoginTwoFactor
+	var out OutLogin
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	userID, err := a.parseChallenge(in.Challenge)
+	if err != nil {
+		out.Message = "challenge is not valid"
+		c.JSON(http.StatusUnauthorized, out)
+		return
+	}
+	user, err := a.db.GetUserByID(userID)
+	if err != nil {
+		out.Message = "challenge is not valid"
+		c.JSON(http.StatusUnauthorized, out)
+		return
+	}
+	if ok, wait := a.loginAllowed(user.Login, c.ClientIP()); !ok {
+		a.loginFailed(c, user.Login, user.ID, loginReasonThrottled)
+		out.Message = retryAfter(c, wait)
+		c.JSON(http.StatusTooManyRequests, out)
+		return
+	}
+
+	ok, err := a.checkSecondFactor(user.ID, in.Code)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	if !ok {
+		a.loginFailed(c, user.Login, user.ID, loginReasonInvalidSecondFactor)
+		out.Message = "code is not valid"
+		c.JSON(http.StatusUnauthorized, out)
+		return
+	}
+	a.accountLimiter.Success(strings.ToLower(user.Login))
+	if user.Disabled {
+		out.Message = "user is disabled"
+		c.JSON(http.StatusForbidden, out)
+		return
+	}
+
+	out.Access, out.Refresh, err = a.startSession(c, user.ID)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutGetTwoFactor struct {
+	Enabled       bool   `json:"enabled"`
+	RecoveryCodes int    `json:"recovery_codes"` // number of unused recovery codes
+	Message       string `json:"message"`
+}
+
+// GetTwoFactor godoc
+//
+//	@Summary		Get two-factor authentication
+//	@Description	Get whether second factor is enabled and how many recovery codes are left
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	OutGetTwoFactor
+//	@Failure		500	{object}	OutGetTwoFactor
+//	@Router			/v1/twoFactor [get]
+func (a *API) GetTwoFactor(c *gin.Context) {
+	var out OutGetTwoFactor
+	userID := c.MustGet("user").(db.User).ID
+	var err error
+	if out.Enabled, err = a.db.HasTwoFactor(userID); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	if out.RecoveryCodes, err = a.db.CountRecoveryCodes(userID); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutEnrollTwoFactor struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI for authenticator apps
+	Message         string `json:"message"`
+}
+
+// EnrollTwoFactor godoc
+//
+//	@Summary		Enroll second factor
+//	@Description	Create TOTP secret, it is used after it is confirmed with a code (/twoFactor/confirm)
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	OutEnrollTwoFactor
+//	@Failure		409	{object}	OutEnrollTwoFactor
+//	@Failure		500	{object}	OutEnrollTwoFactor
+//	@Router			/v1/twoFactor/enroll [post]
+func (a *API) EnrollTwoFactor(c *gin.Context) {
+	var out OutEnrollTwoFactor
+	user := c.MustGet("user").(db.User)
+	enabled, err := a.db.HasTwoFactor(user.ID)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	if enabled {
+		out.Message = "two-factor authentication is already enabled"
+		c.JSON(http.StatusConflict, out)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	err = a.db.SetTOTPSecret(db.TOTPSecret{
+		User:         user.ID,
+		Secret:       secret,
+		CreationTime: time.Now().Format("2006-01-02 15:04:05"),
+	})
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.Secret = secret
+	out.ProvisioningURI = totp.ProvisioningURI(a.totpIssuer, user.Login, secret)
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type InTwoFactorCode struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type OutRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"` // shown only once
+	Message       string   `json:"message"`
+}
+
+// ConfirmTwoFactor godoc
+//
+//	@Summary		Confirm second factor
+//	@Description	Enable second factor with the first TOTP code, returns recovery codes
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	body		InTwoFactorCode	true	"TOTP code"
+//	@Success		200		{object}	OutRecoveryCodes
+//	@Failure		400		{object}	OutRecoveryCodes
+//	@Failure		409		{object}	OutRecoveryCodes
+//	@Failure		500		{object}	OutRecoveryCodes
+//	@Router			/v1/twoFactor/confirm [post]
+func (a *API) ConfirmTwoFactor(c *gin.Context) {
+	var in InTwoFactorCode
+	var out OutRecoveryCodes
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	userID := c.MustGet("user").(db.User).ID
+	secret, err := a.db.GetTOTPSecret(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		out.Message = "enroll second factor first"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	if secret.Confirmed {
+		out.Message = "two-factor authentication is already enabled"
+		c.JSON(http.StatusConflict, out)
+		return
+	}
+
+	step, ok := totp.Validate(secret.Secret, strings.TrimSpace(in.Code), a.now())
+	if ok {
+		ok, err = a.db.UseTOTPStep(userID, step)
+	}
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	if !ok {
+		out.Message = "code is not valid"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	if out.RecoveryCodes, err = a.newRecoveryCodes(userID); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutDisableTwoFactor struct {
+	Message string `json:"message"`
+}
+
+// DisableTwoFactor godoc
+//
+//	@Summary		Disable second factor
+//	@Description	Delete TOTP secret and recovery codes, current TOTP code or recovery code is required
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	body		InTwoFactorCode	true	"TOTP code or recovery code"
+//	@Success		200		{object}	OutDisableTwoFactor
+//	@Failure		400		{object}	OutDisableTwoFactor
+//	@Failure		500		{object}	OutDisableTwoFactor
+//	@Router			/v1/twoFactor/disable [post]
+func (a *API) DisableTwoFactor(c *gin.Context) {
+	var in InTwoFactorCode
+	var out OutDisableTwoFactor
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	userID := c.MustGet("user").(db.User).ID
+	ok, err := a.checkSecondFactor(userID, in.Code)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	if !ok {
+		out.Message = "code is not valid"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	if err = a.db.DeleteTwoFactor(userID); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// RegenerateRecoveryCodes godoc
+//
+//	@Summary		Regenerate recovery codes
+//	@Description	Replace recovery codes with new ones, current TOTP code is required
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	body		InTwoFactorCode	true	"TOTP code"
+//	@Success		200		{object}	OutRecoveryCodes
+//	@Failure		400		{object}	OutRecoveryCodes
+//	@Failure		500		{object}	OutRecoveryCodes
+//	@Router			/v1/twoFactor/recoveryCodes [post]
+func (a *API) RegenerateRecoveryCodes(c *gin.Context) {
+	var in InTwoFactorCode
+	var out OutRecoveryCodes
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	userID := c.MustGet("user").(db.User).ID
+	ok := len(strings.TrimSpace(in.Code)) == totp.Digits
+	var err error
+	if ok {
+		ok, err = a.checkSecondFactor(userID, in.Code)
+	}
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	if !ok {
+		out.Message = "code is not valid"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	if out.RecoveryCodes, err = a.newRecoveryCodes(userID); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
@@ -0,0 +1,412 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"storage/internal/apierrors"
+	"storage/internal/db"
+	"strconv"
+)
+
+// defaultExpressionsLimitV2 is the page size of /api/v2/expressions, v1 returns all expressions by default.
+const defaultExpressionsLimitV2 = 100
+
+// Routes of /api/v2 are resource-oriented: IDs and names are in the path, request bodies are used only with POST and
+// PUT. Missing or invisible expressions are 404, actions that are not possible in the status of the expression are
+// 409. v1 handlers share the logic with them and keep their old status codes.
+
+// expressionErrorStatus returns status of the error of ExpressionStorage. The error is added to the context, so
+// Problems takes the code from it and does not send details of unexpected errors.
+func expressionErrorStatus(c *gin.Context, err error) int {
+	_ = c.Error(err)
+	return apierrors.Lookup(apierrors.CodeOf(err)).HTTPStatus
+}
+
+// PostExpressionV2 godoc
+//
+//	@Summary		Add expression
+//	@Description	Add expression to storage, Location header of the answer is the URL of the expression
+//	@Tags			v2
+//	@Accept			json
+//	@Produce		json
+//	@Param			expression		body		InPostExpression	true	"Expression"
+//	@Param			Idempotency-Key	header		string				false	"Key of the request, a retried request with the same key gets the same response"
+//	@Success		201				{object}	OutPostExpression
+//	@Failure		400				{object}	OutPostExpression
+//	@Failure		401				{object}	OutAuthData
+//	@Failure		403				{object}	OutPostExpression
+//	@Failure		409				{object}	OutAuthData
+//	@Failure		500				{object}	OutPostExpression
+//	@Router			/v2/expressions [post]
+func (a *API) PostExpressionV2(c *gin.Context) {
+	var in InPostExpression
+	var out OutPostExpression
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	id, status, err := a.addExpression(c.MustGet("user").(db.User), in)
+	if err != nil {
+		out.Message = err.Error()
+		if status == http.StatusInternalServerError {
+			zap.S().Error(out)
+		}
+		c.JSON(status, out)
+		return
+	}
+
+	out.ID = id
+	out.Message = "ok"
+	c.Header("Location", fmt.Sprintf("/api/v2/expressions/%v", id))
+	c.JSON(http.StatusCreated, out)
+}
+
+// GetExpressionsV2 godoc
+//
+//	@Summary		Get expressions
+//	@Description	Get a page of expressions of the user and of their teams, the next page is requested with next_cursor
+//	@Tags			v2
+//	@Produce		json
+//	@Param			status			query		string	false	"Comma separated statuses (0 - not ready, 1 - working, 2 - ready, 3 - error, 4 - abandoned, 5 - cancelled)"
+//	@Param			created_after	query		string	false	"Created at or after (2006-01-02 15:04:05)"
+//	@Param			created_before	query		string	false	"Created before (2006-01-02 15:04:05)"
+//	@Param			server			query		string	false	"Name of the calculation server"
+//	@Param			q				query		string	false	"Substring of the expression"
+//	@Param			sort			query		string	false	"creation_time, end_calculation_time or duration (only finished expressions), - before the name for descending order (default -creation_time)"
+//	@Param			cursor			query		string	false	"next_cursor of the previous page"
+//	@Param			limit			query		int		false	"Maximum number of expressions on the page (default 100, up to 1000)"
+//	@Success		200				{object}	OutGetAllExpressions
+//	@Failure		400				{object}	OutGetAllExpressions
+//	@Failure		401				{object}	OutAuthData
+//	@Router			/v2/expressions [get]
+func (a *API) GetExpressionsV2(c *gin.Context) {
+	a.listExpressions(c, defaultExpressionsLimitV2)
+}
+
+// GetExpressionV2 godoc
+//
+//	@Summary		Get expression
+//	@Description	Get expression by id
+//	@Tags			v2
+//	@Produce		json
+//	@Param			id	path		int	true	"Expression ID"
+//	@Success		200	{object}	OutGetExpressionByID
+//	@Failure		400	{object}	OutGetExpressionByID
+//	@Failure		401	{object}	OutAuthData
+//	@Failure		404	{object}	OutGetExpressionByID
+//	@Router			/v2/expressions/{id} [get]
+func (a *API) GetExpressionV2(c *gin.Context) {
+	var out OutGetExpressionByID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	expression, err := a.expressions.GetByUserAndID(c.MustGet("user").(db.User).ID, id)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+	out.Expression = expression
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// DeleteExpressionV2 godoc
+//
+//	@Summary		Delete expression
+//	@Description	Delete expression from storage, if it is being calculated, server will stop calculating it
+//	@Tags			v2
+//	@Produce		json
+//	@Param			id	path	int	true	"Expression ID"
+//	@Success		204
+//	@Failure		400	{object}	OutDeleteExpression
+//	@Failure		401	{object}	OutAuthData
+//	@Failure		403	{object}	OutDeleteExpression
+//	@Failure		404	{object}	OutDeleteExpression
+//	@Failure		500	{object}	OutDeleteExpression
+//	@Router			/v2/expressions/{id} [delete]
+func (a *API) DeleteExpressionV2(c *gin.Context) {
+	var out OutDeleteExpression
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	if err = a.expressions.DeleteByUser(c.MustGet("user").(db.User).ID, id); err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CancelExpressionV2 godoc
+//
+//	@Summary		Cancel expression
+//	@Description	Stop calculation of expression, server that calculates it will be notified
+//	@Tags			v2
+//	@Produce		json
+//	@Param			id	path		int	true	"Expression ID"
+//	@Success		200	{object}	OutCancelExpression
+//	@Failure		400	{object}	OutCancelExpression
+//	@Failure		401	{object}	OutAuthData
+//	@Failure		404	{object}	OutCancelExpression
+//	@Failure		409	{object}	OutCancelExpression
+//	@Router			/v2/expressions/{id}/cancel [post]
+func (a *API) CancelExpressionV2(c *gin.Context) {
+	var out OutCancelExpression
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	expression, err := a.expressions.Cancel(c.MustGet("user").(db.User).ID, id)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+	out.Expression = expression
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// RetryExpressionV2 godoc
+//
+//	@Summary		Retry expression
+//	@Description	Calculate finished expression again, previous result is kept in the history of the expression
+//	@Tags			v2
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int					true	"Expression ID"
+//	@Param			operations	body		InRetryExpression	false	"Operation times for the new run"
+//	@Success		200			{object}	OutRetryExpression
+//	@Failure		400			{object}	OutRetryExpression
+//	@Failure		401			{object}	OutAuthData
+//	@Failure		404			{object}	OutRetryExpression
+//	@Failure		409			{object}	OutRetryExpression
+//	@Failure		500			{object}	OutRetryExpression
+//	@Router			/v2/expressions/{id}/retry [post]
+func (a *API) RetryExpressionV2(c *gin.Context) {
+	var in InRetryExpression
+	var out OutRetryExpression
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	// body is optional
+	if err = c.ShouldBindBodyWith(&in, binding.JSON); err != nil && !errors.Is(err, io.EOF) {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	user := c.MustGet("user").(db.User)
+	expression, err := a.expressions.GetByUserAndID(user.ID, id)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+	operations, err := a.retryOperations(user.ID, expression, in)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	expression, err = a.expressions.Retry(user.ID, id, operations)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+	out.Expression = expression
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// RequeueExpressionV2 godoc
+//
+//	@Summary		Requeue expression
+//	@Description	Return abandoned expression (servers died too many times while calculating it) to pending
+//	@Tags			v2
+//	@Produce		json
+//	@Param			id	path		int	true	"Expression ID"
+//	@Success		200	{object}	OutRequeueExpression
+//	@Failure		400	{object}	OutRequeueExpression
+//	@Failure		401	{object}	OutAuthData
+//	@Failure		404	{object}	OutRequeueExpression
+//	@Failure		409	{object}	OutRequeueExpression
+//	@Router			/v2/expressions/{id}/requeue [post]
+func (a *API) RequeueExpressionV2(c *gin.Context) {
+	var out OutRequeueExpression
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	expression, err := a.expressions.Requeue(c.MustGet("user").(db.User).ID, id)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+	out.Expression = expression
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// GetExpressionHistoryV2 godoc
+//
+//	@Summary		Get expression history
+//	@Description	Get previous runs of the expression (results before retries), the oldest first
+//	@Tags			v2
+//	@Produce		json
+//	@Param			id	path		int	true	"Expression ID"
+//	@Success		200	{object}	OutGetExpressionHistory
+//	@Failure		400	{object}	OutGetExpressionHistory
+//	@Failure		401	{object}	OutAuthData
+//	@Failure		404	{object}	OutGetExpressionHistory
+//	@Failure		500	{object}	OutGetExpressionHistory
+//	@Router			/v2/expressions/{id}/history [get]
+func (a *API) GetExpressionHistoryV2(c *gin.Context) {
+	var out OutGetExpressionHistory
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	runs, err := a.expressions.GetRuns(c.MustGet("user").(db.User).ID, id)
+	if err != nil {
+		out.Message = err.Error()
+		c.JSON(expressionErrorStatus(c, err), out)
+		return
+	}
+	out.Runs = runs
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// GetServersV2 godoc
+//
+//	@Summary		Get servers
+//	@Description	Get calculation servers with their status and expressions of the user that they calculate
+//	@Tags			v2
+//	@Produce		json
+//	@Success		200	{object}	OutGetComputingPowers
+//	@Failure		401	{object}	OutAuthData
+//	@Router			/v2/servers [get]
+func (a *API) GetServersV2(c *gin.Context) {
+	a.GetComputingPowers(c)
+}
+
+// GetServerExpressionsV2 godoc
+//
+//	@Summary		Get expressions of server
+//	@Description	Get expressions of the user that were calculated by the server
+//	@Tags			v2
+//	@Produce		json
+//	@Param			name	path		string	true	"Server name"
+//	@Success		200		{object}	OutGetExpressionByServer
+//	@Failure		401		{object}	OutAuthData
+//	@Router			/v2/servers/{name}/expressions [get]
+func (a *API) GetServerExpressionsV2(c *gin.Context) {
+	var out OutGetExpressionByServer
+	out.Expressions = a.expressions.GetByServer(c.MustGet("user").(db.User).ID, c.Param("name"))
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// GetOperationsV2 godoc
+//
+//	@Summary		Get operations
+//	@Description	Get times of operations in milliseconds, {"+": 100,...}
+//	@Tags			v2
+//	@Produce		json
+//	@Success		200	{object}	OutGetOperationsAndTimes
+//	@Failure		401	{object}	OutAuthData
+//	@Failure		500	{object}	OutGetOperationsAndTimes
+//	@Router			/v2/operations [get]
+func (a *API) GetOperationsV2(c *gin.Context) {
+	a.GetOperationsAndTimes(c)
+}
+
+// PutOperationsV2 godoc
+//
+//	@Summary		Set operations
+//	@Description	Replace times of all operations in milliseconds, the body must contain all of "+", "-", "*", "/" and nothing else
+//	@Tags			v2
+//	@Accept			json
+//	@Produce		json
+//	@Param			data	body		map[string]int	true	"Operations and times"
+//	@Success		200		{object}	OutGetOperationsAndTimes
+//	@Failure		400		{object}	OutGetOperationsAndTimes
+//	@Failure		401		{object}	OutAuthData
+//	@Failure		500		{object}	OutGetOperationsAndTimes
+//	@Router			/v2/operations [put]
+func (a *API) PutOperationsV2(c *gin.Context) {
+	var in map[string]int
+	var out OutGetOperationsAndTimes
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	if err := checkAllOperations(in); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	operations, _, err := a.setOperationsAndTimes(c.MustGet("user").(db.User).ID, in)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Data = operationsMap(operations)
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// checkAllOperations returns error if times of some operations are missing, unknown or negative.
+func checkAllOperations(in map[string]int) error {
+	known := operationsMap(db.Operation{})
+	for operation, value := range in {
+		if _, ok := known[operation]; !ok {
+			return fmt.Errorf("unknown operation %q", operation)
+		}
+		if value < 0 {
+			return fmt.Errorf("time of %q must not be negative", operation)
+		}
+	}
+	for operation := range known {
+		if _, ok := in[operation]; !ok {
+			return fmt.Errorf("time of %q is missing", operation)
+		}
+	}
+	return nil
+}
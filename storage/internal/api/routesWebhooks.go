@@ -0,0 +1,310 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"storage/internal/db"
+	"storage/internal/expressionstorage"
+	"storage/internal/webhooks"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultWebhookMaxAttempts = 8
+	defaultWebhookRetryDelay  = 10 * time.Second
+	defaultWebhookMaxDelay    = time.Hour
+	defaultWebhookTimeout     = 10 * time.Second
+)
+
+// newWebhookDispatcher returns dispatcher of webhooks with WEBHOOK_* environment variables.
+func newWebhookDispatcher(d *db.APIDb) *webhooks.Dispatcher {
+	return webhooks.New(d, webhooks.Config{
+		MaxAttempts:  numberFromEnv("WEBHOOK_MAX_ATTEMPTS", defaultWebhookMaxAttempts),
+		RetryDelay:   secondsFromEnv("WEBHOOK_RETRY_DELAY", defaultWebhookRetryDelay),
+		MaxDelay:     secondsFromEnv("WEBHOOK_MAX_DELAY", defaultWebhookMaxDelay),
+		Timeout:      secondsFromEnv("WEBHOOK_TIMEOUT", defaultWebhookTimeout),
+		AllowHTTP:    os.Getenv("WEBHOOK_ALLOW_HTTP") == "TRUE",
+		AllowPrivate: os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "TRUE",
+	})
+}
+
+// webhookHook adds deliveries when expressions are calculated, failed or cancelled.
+func (a *API) webhookHook(event expressionstorage.Event) {
+	if event.Type != expressionstorage.EventFinished || event.Expression.Status == db.ExpressionAbandoned {
+		return
+	}
+	go func() {
+		if err := a.webhooks.ExpressionFinished(event.Expression); err != nil {
+			zap.S().Error(err)
+		}
+	}()
+}
+
+type InAddWebhook struct {
+	URL        string `json:"url" binding:"required"`
+	Expression int    `json:"expression_id"` // optional, the webhook is called only for this expression
+}
+
+type OutAddWebhook struct {
+	ID      int    `json:"id"`
+	Secret  string `json:"secret"` // it is shown only once
+	Message string `json:"message"`
+}
+
+// AddWebhook godoc
+//
+//	@Summary		Add webhook
+//	@Description	Register URL that is called (POST) when expressions of the user are calculated, failed or cancelled, or only one expression if expression_id is set. Payloads are signed: X-Webhook-Signature is "sha256=" + hex of HMAC-SHA256 of "<X-Webhook-Timestamp>.<body>" with the secret. Failed deliveries are retried with exponential backoff
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			webhook	body		InAddWebhook	true	"URL and optional expression"
+//	@Success		200		{object}	OutAddWebhook
+//	@Failure		400		{object}	OutAddWebhook
+//	@Failure		404		{object}	OutAddWebhook
+//	@Failure		500		{object}	OutAddWebhook
+//	@Router			/v1/webhooks [post]
+func (a *API) AddWebhook(c *gin.Context) {
+	var in InAddWebhook
+	var out OutAddWebhook
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	if err := a.webhooks.CheckURL(in.URL); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	user := c.MustGet("user").(db.User)
+	if in.Expression != 0 {
+		if _, err := a.expressions.GetByUserAndID(user.ID, in.Expression); err != nil {
+			out.Message = err.Error()
+			c.JSON(http.StatusNotFound, out)
+			return
+		}
+	}
+
+	var err error
+	out.Secret, err = webhooks.NewSecret()
+	if err == nil {
+		out.ID, err = a.db.AddWebhook(db.Webhook{
+			User:         user.ID,
+			Expression:   in.Expression,
+			URL:          in.URL,
+			Secret:       out.Secret,
+			CreationTime: time.Now().Format("2006-01-02 15:04:05"),
+		})
+	}
+	if err != nil {
+		out = OutAddWebhook{Message: err.Error()}
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutGetWebhooks struct {
+	Webhooks []db.Webhook `json:"webhooks"`
+	Message  string       `json:"message"`
+}
+
+// GetWebhooks godoc
+//
+//	@Summary		Get webhooks
+//	@Description	Get webhooks of the user without secrets
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	OutGetWebhooks
+//	@Failure		500	{object}	OutGetWebhooks
+//	@Router			/v1/webhooks [get]
+func (a *API) GetWebhooks(c *gin.Context) {
+	var out OutGetWebhooks
+	var err error
+	out.Webhooks, err = a.db.GetWebhooks(c.MustGet("user").(db.User).ID)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutDeleteWebhook struct {
+	Message string `json:"message"`
+}
+
+// DeleteWebhook godoc
+//
+//	@Summary		Delete webhook
+//	@Description	Delete webhook of the user with its deliveries
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Webhook ID"
+//	@Success		200	{object}	OutDeleteWebhook
+//	@Failure		400	{object}	OutDeleteWebhook
+//	@Failure		404	{object}	OutDeleteWebhook
+//	@Failure		500	{object}	OutDeleteWebhook
+//	@Router			/v1/webhooks/{id} [delete]
+func (a *API) DeleteWebhook(c *gin.Context) {
+	var out OutDeleteWebhook
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		out.Message = "id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	deleted, err := a.db.DeleteWebhook(id, c.MustGet("user").(db.User).ID)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	if !deleted {
+		out.Message = "webhook is not found"
+		c.JSON(http.StatusNotFound, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+// userWebhook returns the webhook from the path if it belongs to the user, otherwise status of the error.
+func (a *API) userWebhook(c *gin.Context) (db.Webhook, int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return db.Webhook{}, http.StatusBadRequest, errors.New("id must be a number")
+	}
+	webhook, err := a.db.GetWebhook(id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && webhook.User != c.MustGet("user").(db.User).ID) {
+		return db.Webhook{}, http.StatusNotFound, errors.New("webhook is not found")
+	}
+	if err != nil {
+		return db.Webhook{}, http.StatusInternalServerError, err
+	}
+	return webhook, http.StatusOK, nil
+}
+
+type OutGetWebhookDeliveries struct {
+	Deliveries []db.WebhookDelivery `json:"deliveries"`
+	Message    string               `json:"message"`
+}
+
+// GetWebhookDeliveries godoc
+//
+//	@Summary		Get webhook deliveries
+//	@Description	Get deliveries of the webhook, the newest first. Status is pending, delivered or failed (all attempts failed)
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"Webhook ID"
+//	@Param			limit	query		int	false	"Maximum number of deliveries (default 100)"
+//	@Success		200		{object}	OutGetWebhookDeliveries
+//	@Failure		400		{object}	OutGetWebhookDeliveries
+//	@Failure		404		{object}	OutGetWebhookDeliveries
+//	@Failure		500		{object}	OutGetWebhookDeliveries
+//	@Router			/v1/webhooks/{id}/deliveries [get]
+func (a *API) GetWebhookDeliveries(c *gin.Context) {
+	var out OutGetWebhookDeliveries
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		out.Message = "limit must be a positive number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+	webhook, status, err := a.userWebhook(c)
+	if err != nil {
+		out.Message = err.Error()
+		if status == http.StatusInternalServerError {
+			zap.S().Error(out)
+		}
+		c.JSON(status, out)
+		return
+	}
+
+	out.Deliveries, err = a.db.GetWebhookDeliveries(webhook.ID, limit)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutRedeliverWebhook struct {
+	Message string `json:"message"`
+}
+
+// RedeliverWebhook godoc
+//
+//	@Summary		Redeliver webhook
+//	@Description	Send the delivery again, it gets all attempts again
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int	true	"Webhook ID"
+//	@Param			deliveryId	path		int	true	"Delivery ID"
+//	@Success		200			{object}	OutRedeliverWebhook
+//	@Failure		400			{object}	OutRedeliverWebhook
+//	@Failure		404			{object}	OutRedeliverWebhook
+//	@Failure		409			{object}	OutRedeliverWebhook
+//	@Failure		500			{object}	OutRedeliverWebhook
+//	@Router			/v1/webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (a *API) RedeliverWebhook(c *gin.Context) {
+	var out OutRedeliverWebhook
+	webhook, status, err := a.userWebhook(c)
+	if err != nil {
+		out.Message = err.Error()
+		if status == http.StatusInternalServerError {
+			zap.S().Error(out)
+		}
+		c.JSON(status, out)
+		return
+	}
+	deliveryID, err := strconv.Atoi(c.Param("deliveryId"))
+	if err != nil {
+		out.Message = "delivery id must be a number"
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	delivery, err := a.db.GetWebhookDelivery(deliveryID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && delivery.Webhook != webhook.ID) {
+		out.Message = "delivery is not found"
+		c.JSON(http.StatusNotFound, out)
+		return
+	}
+	if err == nil {
+		err = a.webhooks.Redeliver(delivery)
+	}
+	if errors.Is(err, webhooks.ErrDeliveryBusy) {
+		out.Message = err.Error()
+		_ = c.Error(err)
+		c.JSON(http.StatusConflict, out)
+		return
+	}
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
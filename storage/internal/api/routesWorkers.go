@@ -0,0 +1,162 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+	"net/http"
+	"storage/internal/cryptPasswords"
+	"storage/internal/db"
+	"time"
+)
+
+// enrollmentTokenLifetime is the time a worker has to exchange the enrollment token for its credential.
+const enrollmentTokenLifetime = 24 * time.Hour
+
+type InAddWorker struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type OutAddWorker struct {
+	Name            string `json:"name"`
+	EnrollmentToken string `json:"enrollment_token"` // shown only once
+	ExpiresAt       int    `json:"expires_at"`
+	Message         string `json:"message"`
+}
+
+// AddWorker godoc
+//
+//	@Summary		Add worker
+//	@Description	Issue enrollment token for calculation server, the server exchanges it for its credential. Issuing a token for a revoked worker allows it again
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			name	body		InAddWorker	true	"Name of calculation server"
+//	@Success		200		{object}	OutAddWorker
+//	@Failure		400		{object}	OutAddWorker
+//	@Failure		401		{object}	OutAuthData
+//	@Failure		500		{object}	OutAddWorker
+//	@Router			/v1/admin/workers [post]
+func (a *API) AddWorker(c *gin.Context) {
+	var in InAddWorker
+	var out OutAddWorker
+	if err := c.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		out.Message = err.Error()
+		c.JSON(http.StatusBadRequest, out)
+		return
+	}
+
+	token, err := cryptPasswords.GenerateToken()
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	expiresAt := int(time.Now().Add(enrollmentTokenLifetime).Unix())
+	_, err = a.db.AddWorker(db.Worker{
+		Name:                in.Name,
+		EnrollmentHash:      cryptPasswords.HashToken(token),
+		EnrollmentExpiresAt: expiresAt,
+		CreationTime:        time.Now().Format("2006-01-02 15:04:05"),
+	})
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.Name = in.Name
+	out.EnrollmentToken = token
+	out.ExpiresAt = expiresAt
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutGetWorkers struct {
+	Workers []db.Worker `json:"workers"`
+	Message string      `json:"message"`
+}
+
+// GetWorkers godoc
+//
+//	@Summary		Get workers
+//	@Description	Get all calculation servers that were allowed to connect
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	OutGetWorkers
+//	@Failure		401	{object}	OutAuthData
+//	@Failure		500	{object}	OutGetWorkers
+//	@Router			/v1/admin/workers [get]
+func (a *API) GetWorkers(c *gin.Context) {
+	var out OutGetWorkers
+	workers, err := a.db.GetAllWorkers()
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	out.Workers = workers
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
+
+type OutRevokeWorker struct {
+	Released []db.Expression `json:"released"` // expressions that were returned to pending
+	Message  string          `json:"message"`
+}
+
+// RevokeWorker godoc
+//
+//	@Summary		Revoke worker
+//	@Description	Revoke credential of calculation server, its leases are ended and its expressions are returned to pending
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			name	path		string	true	"Name of calculation server"
+//	@Success		200		{object}	OutRevokeWorker
+//	@Failure		401		{object}	OutAuthData
+//	@Failure		404		{object}	OutRevokeWorker
+//	@Failure		500		{object}	OutRevokeWorker
+//	@Router			/v1/admin/workers/{name} [delete]
+func (a *API) RevokeWorker(c *gin.Context) {
+	var out OutRevokeWorker
+	name := c.Param("name")
+	worker, err := a.db.GetWorkerByName(name)
+	if err != nil {
+		out.Message = "worker is not found"
+		c.JSON(http.StatusNotFound, out)
+		return
+	}
+
+	worker.Revoked = true
+	worker.CredentialHash = ""
+	worker.EnrollmentHash = ""
+	worker.EnrollmentExpiresAt = 0
+	if err = a.db.UpdateWorker(worker); err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+
+	released, err := a.expressions.ReleaseServer(name)
+	if err != nil {
+		out.Message = err.Error()
+		zap.S().Error(out)
+		c.JSON(http.StatusInternalServerError, out)
+		return
+	}
+	a.servers.Remove(name)
+	a.statusWorkers.Store(name, fmt.Sprintf("%v -> server %v is revoked",
+		time.Now().Format("01-02-2006 15:04:05"), name))
+
+	out.Released = released
+	out.Message = "ok"
+	c.JSON(http.StatusOK, out)
+}
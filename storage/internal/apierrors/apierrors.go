@@ -0,0 +1,159 @@
+// Package apierrors is the catalogue of errors that are returned by the REST API (as RFC 7807 problem details) and by
+// the gRPC server (as status with details). Codes are stable, clients should check them instead of messages.
+package apierrors
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strconv"
+)
+
+// Domain is the domain of gRPC ErrorInfo details.
+const Domain = "storage"
+
+type Code string
+
+const (
+	CodeInvalidArgument   Code = "invalid_argument"
+	CodeUnauthenticated   Code = "unauthenticated"
+	CodePermissionDenied  Code = "permission_denied"
+	CodeNotFound          Code = "not_found"
+	CodeConflict          Code = "conflict"            // the state of the resource does not allow the request
+	CodeRequestInProgress Code = "request_in_progress" // the same request is being handled, retry later
+	CodeRateLimited       Code = "rate_limited"
+	CodeInternal          Code = "internal" // details are not exposed, they are logged with the correlation ID
+	CodeUnavailable       Code = "unavailable"
+	CodeTimeout           Code = "timeout"
+)
+
+// Kind describes errors with the code.
+type Kind struct {
+	Code       Code       `json:"code"`
+	Title      string     `json:"title"`
+	HTTPStatus int        `json:"http_status"`
+	GRPCCode   codes.Code `json:"-"`
+	Retryable  bool       `json:"retryable"` // the same request may succeed later
+}
+
+var catalogue = []Kind{
+	{CodeInvalidArgument, "Invalid request", http.StatusBadRequest, codes.InvalidArgument, false},
+	{CodeUnauthenticated, "Not authenticated", http.StatusUnauthorized, codes.Unauthenticated, false},
+	{CodePermissionDenied, "Permission denied", http.StatusForbidden, codes.PermissionDenied, false},
+	{CodeNotFound, "Not found", http.StatusNotFound, codes.NotFound, false},
+	{CodeConflict, "Conflict", http.StatusConflict, codes.FailedPrecondition, false},
+	{CodeRequestInProgress, "Request is in progress", http.StatusConflict, codes.Aborted, true},
+	{CodeRateLimited, "Too many requests", http.StatusTooManyRequests, codes.ResourceExhausted, true},
+	{CodeInternal, "Internal error", http.StatusInternalServerError, codes.Internal, false},
+	{CodeUnavailable, "Service unavailable", http.StatusServiceUnavailable, codes.Unavailable, true},
+	{CodeTimeout, "Timeout", http.StatusGatewayTimeout, codes.DeadlineExceeded, true},
+}
+
+// Catalogue returns all kinds of errors.
+func Catalogue() []Kind {
+	return append([]Kind(nil), catalogue...)
+}
+
+// Lookup returns the kind of the code, unknown codes are internal errors.
+func Lookup(code Code) Kind {
+	for _, kind := range catalogue {
+		if kind.Code == code {
+			return kind
+		}
+	}
+	return Lookup(CodeInternal)
+}
+
+// ForHTTPStatus returns the first kind with the status, or the most general kind of its class.
+func ForHTTPStatus(httpStatus int) Kind {
+	for _, kind := range catalogue {
+		if kind.HTTPStatus == httpStatus {
+			return kind
+		}
+	}
+	if httpStatus < http.StatusInternalServerError {
+		return Lookup(CodeInvalidArgument)
+	}
+	return Lookup(CodeInternal)
+}
+
+// ForGRPCCode returns the first kind with the gRPC code, unknown codes are internal errors.
+func ForGRPCCode(code codes.Code) Kind {
+	for _, kind := range catalogue {
+		if kind.GRPCCode == code {
+			return kind
+		}
+	}
+	return Lookup(CodeInternal)
+}
+
+// Error is an error with the code, the message is shown to clients.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// GRPCStatus converts the error to gRPC status, so handlers of the gRPC server can return it as is.
+func (e *Error) GRPCStatus() *status.Status {
+	return Status(Lookup(e.Code), e.Message, "")
+}
+
+// CodeOf returns the code of the error, errors without code are internal.
+func CodeOf(err error) Code {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return CodeInternal
+}
+
+// Status returns gRPC status of the kind with ErrorInfo details, correlationID is added if it is not empty.
+func Status(kind Kind, message string, correlationID string) *status.Status {
+	info := &errdetails.ErrorInfo{
+		Reason:   string(kind.Code),
+		Domain:   Domain,
+		Metadata: map[string]string{"retryable": strconv.FormatBool(kind.Retryable)},
+	}
+	if correlationID != "" {
+		info.Metadata["correlation_id"] = correlationID
+	}
+	withDetails, err := status.New(kind.GRPCCode, message).WithDetails(info)
+	if err != nil {
+		return status.New(kind.GRPCCode, message)
+	}
+	return withDetails
+}
+
+// NewCorrelationID returns a random ID that connects the response with the log of the error.
+func NewCorrelationID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// IsValidCorrelationID checks correlation IDs that are sent by clients, so they can not break logs.
+func IsValidCorrelationID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
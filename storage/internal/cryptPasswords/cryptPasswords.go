@@ -1,6 +1,10 @@
 package cryptPasswords
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -18,3 +22,19 @@ func GeneratePasswordHash(s string) (string, error) {
 func ComparePasswordWithHash(existing string, incoming string) error {
 	return bcrypt.CompareHashAndPassword([]byte(existing), []byte(incoming))
 }
+
+// GenerateToken returns a random URL-safe token, tokens are used as secrets for machines (workers, API keys...).
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns a hash of the token to store in database. Tokens are random, so unlike passwords they do not
+// need a slow hash.
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,82 @@
+package cryptPasswords
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"storage/internal/apierrors"
+	"strings"
+)
+
+// DefaultMinPasswordLength is used if the length is not set in the policy.
+const DefaultMinPasswordLength = 8
+
+// maxPasswordLength is the limit of bcrypt, longer passwords can not be hashed.
+const maxPasswordLength = 72
+
+// commonPasswords are always denied.
+var commonPasswords = []string{
+	"password", "password1", "password123", "12345678", "123456789", "1234567890", "qwerty123", "qwertyuiop",
+	"11111111", "00000000", "iloveyou", "sunshine", "princess", "football", "baseball", "welcome1", "letmein1",
+	"admin123", "abc12345", "passw0rd", "trustno1", "superman", "1q2w3e4r", "zaq12wsx", "qwerty12",
+}
+
+var ErrWeakPassword = apierrors.New(apierrors.CodeInvalidArgument, "password is too weak")
+
+// PasswordPolicy checks new passwords of users.
+type PasswordPolicy struct {
+	MinLength int
+	denylist  map[string]bool
+}
+
+// NewPasswordPolicy returns policy with minLength (DefaultMinPasswordLength if it is 0), common passwords and
+// passwords from denylist are denied.
+func NewPasswordPolicy(minLength int, denylist []string) *PasswordPolicy {
+	if minLength <= 0 {
+		minLength = DefaultMinPasswordLength
+	}
+	p := &PasswordPolicy{MinLength: minLength, denylist: make(map[string]bool)}
+	for _, password := range commonPasswords {
+		p.denylist[password] = true
+	}
+	for _, password := range denylist {
+		p.denylist[strings.ToLower(password)] = true
+	}
+	return p
+}
+
+// LoadDenylist reads passwords from the file, one password per line.
+func LoadDenylist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	passwords := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" {
+			passwords = append(passwords, password)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return passwords, nil
+}
+
+// Check returns ErrWeakPassword with the reason if the password of the user with login does not satisfy the policy.
+func (p *PasswordPolicy) Check(login string, password string) error {
+	switch {
+	case len([]rune(password)) < p.MinLength:
+		return fmt.Errorf("%w: it must be at least %v characters long", ErrWeakPassword, p.MinLength)
+	case len(password) > maxPasswordLength:
+		return fmt.Errorf("%w: it must be at most %v bytes long", ErrWeakPassword, maxPasswordLength)
+	case p.denylist[strings.ToLower(password)]:
+		return fmt.Errorf("%w: it is too common", ErrWeakPassword)
+	case strings.EqualFold(password, login):
+		return fmt.Errorf("%w: it must not be the same as login", ErrWeakPassword)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package db
+
+// APIKey is a personal key of a user for scripts. Only the hash of the key is stored, Prefix is kept to let users
+// recognize their keys. Scopes are comma separated, ExpiresAt is 0 if the key does not expire.
+type APIKey struct {
+	ID           int    `db:"id" json:"id"`
+	Name         string `db:"name" json:"name"`
+	KeyHash      string `db:"key_hash" json:"-"`
+	Prefix       string `db:"prefix" json:"prefix"`
+	User         int    `db:"user_id" json:"user_id"`
+	Scopes       string `db:"scopes" json:"scopes"`
+	ExpiresAt    int    `db:"expires_at" json:"expires_at"`
+	Revoked      bool   `db:"revoked" json:"revoked"`
+	CreationTime string `db:"creation_time" json:"creation_time"`
+	LastUsedTime string `db:"last_used_time" json:"last_used_time"`
+}
+
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (APIKey, error) {
+	key := APIKey{}
+	err := row.Scan(&key.ID, &key.Name, &key.KeyHash, &key.Prefix, &key.User, &key.Scopes, &key.ExpiresAt,
+		&key.Revoked, &key.CreationTime, &key.LastUsedTime)
+	return key, err
+}
+
+func (a *APIDb) AddAPIKey(key APIKey) (int, error) {
+	var id int
+	err := a.db.QueryRow("INSERT INTO api_keys(name, key_hash, prefix, user_id, scopes, expires_at, revoked,"+
+		" creation_time, last_used_time) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id", key.Name,
+		key.KeyHash, key.Prefix, key.User, key.Scopes, key.ExpiresAt, key.Revoked, key.CreationTime,
+		key.LastUsedTime).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (a *APIDb) GetAPIKey(keyHash string) (APIKey, error) {
+	return scanAPIKey(a.db.QueryRow("SELECT * FROM api_keys WHERE key_hash=$1", keyHash))
+}
+
+// GetUserAPIKeys returns keys of the user that are not revoked, the oldest first.
+func (a *APIDb) GetUserAPIKeys(userID int) ([]APIKey, error) {
+	keys := make([]APIKey, 0)
+	rows, err := a.db.Query("SELECT * FROM api_keys WHERE user_id=$1 AND revoked=FALSE ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (a *APIDb) SetAPIKeyLastUsed(id int, lastUsedTime string) error {
+	_, err := a.db.Exec("UPDATE api_keys SET last_used_time=$1 WHERE id=$2", lastUsedTime, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RevokeAPIKey revokes the key of the user, returns false if the user has no such key.
+func (a *APIDb) RevokeAPIKey(userID int, id int) (bool, error) {
+	res, err := a.db.Exec("UPDATE api_keys SET revoked=TRUE WHERE id=$1 AND user_id=$2 AND revoked=FALSE", id,
+		userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
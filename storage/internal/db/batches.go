@@ -0,0 +1,46 @@
+package db
+
+// Batch is a group of expressions that were posted together.
+type Batch struct {
+	ID           int    `db:"id" json:"id"`
+	User         int    `db:"user_id" json:"user_id"`
+	Size         int    `db:"size" json:"size"` // number of expressions that were added
+	CreationTime string `db:"creation_time" json:"creation_time"`
+}
+
+// AddBatch adds the batch and its expressions in one transaction, returns ID of the batch and IDs of the expressions.
+func (a *APIDb) AddBatch(batch Batch, expressions []Expression) (int, []int, error) {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback() // rollback after commit does nothing
+
+	batch.Size = len(expressions)
+	err = tx.QueryRow("INSERT INTO batches(user_id, size, creation_time) VALUES($1, $2, $3) RETURNING id",
+		batch.User, batch.Size, batch.CreationTime).Scan(&batch.ID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	ids := make([]int, 0, len(expressions))
+	for _, expression := range expressions {
+		expression.Batch = batch.ID
+		var id int
+		if err = tx.QueryRow(insertExpression, insertExpressionArgs(expression)...).Scan(&id); err != nil {
+			return 0, nil, err
+		}
+		ids = append(ids, id)
+	}
+	return batch.ID, ids, tx.Commit()
+}
+
+func (a *APIDb) GetBatch(id int) (Batch, error) {
+	batch := Batch{}
+	err := a.db.QueryRow("SELECT * FROM batches WHERE id=$1", id).
+		Scan(&batch.ID, &batch.User, &batch.Size, &batch.CreationTime)
+	if err != nil {
+		return batch, err
+	}
+	return batch, nil
+}
@@ -66,7 +66,7 @@ func New() (*APIDb, error) {
 func (a *APIDb) ResetDatabase() {
 	for i := 0; i < 5; i++ {
 		zap.S().Warn(fmt.Sprintf("Attempt %d: Resetting database", i+1))
-		command := "DROP TABLE IF EXISTS expressions;\nDROP TABLE IF EXISTS users;\n\nCREATE TABLE users\n(\n    id       SERIAL PRIMARY KEY,\n    login    TEXT,\n    password TEXT\n);\n\nCREATE TABLE expressions\n(\n    id                   SERIAL PRIMARY KEY,\n    value                TEXT,\n    answer               FLOAT,\n    logs                 TEXT,\n    ready                INT,\n    alive_expires_at     BIGINT,\n    creation_time        TEXT,\n    end_calculation_time TEXT,\n    server_name          TEXT,\n    user_id              INT,\n    CONSTRAINT fk_user\n        FOREIGN KEY (user_id)\n            REFERENCES users (id)\n);\n\nCREATE TABLE operations\n(\n    id            SERIAL PRIMARY KEY,\n    time_add      INT,\n    time_subtract INT,\n    time_divide   INT,\n    time_multiply INT,\n    user_id       INT,\n    CONSTRAINT fk_user\n        FOREIGN KEY (user_id)\n            REFERENCES users (id)\n);"
+		command := "DROP TABLE IF EXISTS webhook_deliveries;\nDROP TABLE IF EXISTS webhooks;\nDROP TABLE IF EXISTS idempotency_keys;\nDROP TABLE IF EXISTS signing_keys;\nDROP TABLE IF EXISTS oidc_identities;\nDROP TABLE IF EXISTS recovery_codes;\nDROP TABLE IF EXISTS totp_secrets;\nDROP TABLE IF EXISTS login_attempts;\nDROP TABLE IF EXISTS team_operations;\nDROP TABLE IF EXISTS team_members;\nDROP TABLE IF EXISTS api_keys;\nDROP TABLE IF EXISTS sessions;\nDROP TABLE IF EXISTS refresh_tokens;\nDROP TABLE IF EXISTS workers;\nDROP TABLE IF EXISTS expression_runs;\nDROP TABLE IF EXISTS expression_operations;\nDROP TABLE IF EXISTS expressions;\nDROP TABLE IF EXISTS teams;\nDROP TABLE IF EXISTS batches;\nDROP TABLE IF EXISTS operations;\nDROP TABLE IF EXISTS users;\n\nCREATE TABLE users\n(\n    id       SERIAL PRIMARY KEY,\n    login    TEXT,\n    password TEXT,\n    role     TEXT,\n    disabled BOOLEAN\n);\n\nCREATE TABLE teams\n(\n    id                 SERIAL PRIMARY KEY,\n    name               TEXT,\n    creation_time      TEXT,\n    require_two_factor BOOLEAN\n);\n\nCREATE TABLE batches\n(\n    id            SERIAL PRIMARY KEY,\n    user_id       INT,\n    size          INT,\n    creation_time TEXT,\n    CONSTRAINT fk_user\n        FOREIGN KEY (user_id)\n            REFERENCES users (id)\n            ON DELETE CASCADE\n);\n\nCREATE TABLE expressions\n(\n    id                   SERIAL PRIMARY KEY,\n    value                TEXT,\n    answer               FLOAT,\n    logs                 TEXT,\n    ready                INT,\n    alive_expires_at     BIGINT,\n    creation_time        TEXT,\n    end_calculation_time TEXT,\n    server_name          TEXT,\n    user_id              INT,\n    priority             INT,\n    attempts             INT,\n    failed_servers       TEXT,\n    team_id              INT,\n    batch_id             INT,\n    CONSTRAINT fk_user\n        FOREIGN KEY (user_id)\n            REFERENCES users (id),\n    CONSTRAINT fk_team\n        FOREIGN KEY (team_id)\n            REFERENCES teams (id)\n            ON DELETE SET NULL,\n    CONSTRAINT fk_batch\n        FOREIGN KEY (batch_id)\n            REFERENCES batches (id)\n            ON DELETE SET NULL\n);\n\nCREATE TABLE operations\n(\n    id            SERIAL PRIMARY KEY,\n    time_add      INT,\n    time_subtract INT,\n    time_divide   INT,\n    time_multiply INT,\n    user_id       INT,\n    CONSTRAINT fk_user\n        FOREIGN KEY (user_id)\n            REFERENCES users (id)\n);\n\nCREATE TABLE expression_runs\n(\n    id                   SERIAL PRIMARY KEY,\n    expression_id        INT,\n    answer               FLOAT,\n    logs                 TEXT,\n    ready                INT,\n    creation_time        TEXT,\n    end_calculation_time TEXT,\n    server_name          TEXT,\n    CONSTRAINT fk_expression\n        FOREIGN KEY (expression_id)\n            REFERENCES expressions (id)\n            ON DELETE CASCADE\n);\n\nCREATE TABLE expression_operations\n(\n    id            SERIAL PRIMARY KEY,\n    time_add      INT,\n    time_subtract INT,\n    time_divide   INT,\n    time_multiply INT,\n    expression_id INT,\n    CONSTRAINT fk_expression\n        FOREIGN KEY (expression_id)\n            REFERENCES expressions (id)\n            ON DELETE CASCADE\n);\n\nCREATE TABLE workers\n(\n    id                    SERIAL PRIMARY KEY,\n    name                  TEXT UNIQUE,\n    enrollment_hash       TEXT,\n    enrollment_expires_at BIGINT,\n    credential_hash       TEXT,\n    revoked               BOOLEAN,\n    creation_time         TEXT\n);\n\nCREATE TABLE refresh_tokens\n(\n    id            SERIAL PRIMARY KEY,\n    token_hash    TEXT UNIQUE,\n    family        TEXT,\n    user_id       INT,\n    expires_at    BIGINT,\n    used          BOOLEAN,\n    revoked       BOOLEAN,\n    creation_time TEXT,\n    CONSTRAINT fk_user\n        FOREIGN KEY (user_id)\n            REFERENCES users (id)\n            ON DELETE CASCADE\n);\n\nCREATE TABLE sessions\n(\n    id             SERIAL PRIMARY KEY,\n    jti            TEXT UNIQUE,\n    user_id        INT,\n    user_agent     TEXT,\n    creation_time  TEXT,\n    last_seen_time TEXT,\n    expires_at     BIGINT,\n    revoked        BOOLEAN,\n    CONSTRAINT fk_user\n        FOREIGN KEY (user_id)\n            REFERENCES users (id)\n            ON DELETE CASCADE\n);\n\nCREATE TABLE api_keys\n(\n    id             SERIAL PRIMARY KEY,\n    name           TEXT,\n    key_hash       TEXT UNIQUE,\n    prefix         TEXT,\n    user_id        INT,\n    scopes         TEXT,\n    expires_at     BIGINT,\n    revoked        BOOLEAN,\n    creation_time  TEXT,\n    last_used_time TEXT,\n    CONSTRAINT fk_user\n        FOREIGN KEY (user_id)\n            REFERENCES users (id)\n            ON DELETE CASCADE\n);\n\nCREATE TABLE team_members\n(\n    id      SERIAL PRIMARY KEY,\n    team_id INT,\n    user_id INT,\n    role    TEXT,\n    UNIQUE (team_id, user_id),\n    CONSTRAINT fk_team\n        FOREIGN KEY (team_id)\n            REFERENCES teams (id)\n            ON DELETE CASCADE,\n    CONSTRAINT fk_user\n        FOREIGN KEY (user_id)\n            REFERENCES users (id)\n            ON DELETE CASCADE\n);\n\nCREATE TABLE team_operations\n(\n    id            SERIAL PRIMARY KEY,\n    time_add      INT,\n    time_subtract INT,\n    time_divide   INT,\n    time_multiply INT,\n    team_id       INT UNIQUE,\n    CONSTRAINT fk_team\n        FOREIGN KEY (team_id)\n            REFERENCES teams (id)\n            ON DELETE CASCADE\n);\n\nCREATE TABLE login_attempts\n(\n    id            SERIAL PRIMARY KEY,\n    login         TEXT,\n    user_id       INT,\n    ip            TEXT,\n    user_agent    TEXT,\n    reason        TEXT,\n    creation_time TEXT\n);\n\nCREATE TABLE totp_secrets\n(\n    id            SERIAL PRIMARY KEY,\n    user_id       INT UNIQUE,\n    secret        TEXT,\n    confirmed     BOOLEAN,\n    last_step     BIGINT,\n    creation_time TEXT,\n    CONSTRAINT fk_user\n        FOREIGN KEY (user_id)\n            REFERENCES users (id)\n            ON DELETE CASCADE\n);\n\nCREATE TABLE recovery_codes\n(\n    id        SERIAL PRIMARY KEY,\n    user_id   INT,\n    code_hash TEXT,\n    used      BOOLEAN,\n    CONSTRAINT fk_user\n        FOREIGN KEY (user_id)\n            REFERENCES users (id)\n            ON DELETE CASCADE\n);\n\nCREATE TABLE oidc_identities\n(\n    id            SERIAL PRIMARY KEY,\n    issuer        TEXT,\n    subject       TEXT,\n    user_id       INT,\n    email         TEXT,\n    creation_time TEXT,\n    UNIQUE (issuer, subject),\n    CONSTRAINT fk_user\n        FOREIGN KEY (user_id)\n            REFERENCES users (id)\n            ON DELETE CASCADE\n);\n\nCREATE TABLE signing_keys\n(\n    id            SERIAL PRIMARY KEY,\n    kid           TEXT UNIQUE,\n    algorithm     TEXT,\n    private_key   TEXT,\n    creation_time TEXT,\n    retired_at    BIGINT\n);\n\nCREATE TABLE idempotency_keys\n(\n    id            SERIAL PRIMARY KEY,\n    user_id       INT,\n    request_key   TEXT,\n    request_hash  TEXT,\n    status        INT,\n    response      TEXT,\n    location      TEXT,\n    expires_at    BIGINT,\n    creation_time TEXT,\n    UNIQUE (user_id, request_key),\n    CONSTRAINT fk_user\n        FOREIGN KEY (user_id)\n            REFERENCES users (id)\n            ON DELETE CASCADE\n);\n\nCREATE TABLE webhooks\n(\n    id            SERIAL PRIMARY KEY,\n    user_id       INT,\n    expression_id INT,\n    url           TEXT,\n    secret        TEXT,\n    creation_time TEXT,\n    CONSTRAINT fk_user\n        FOREIGN KEY (user_id)\n            REFERENCES users (id)\n            ON DELETE CASCADE,\n    CONSTRAINT fk_expression\n        FOREIGN KEY (expression_id)\n            REFERENCES expressions (id)\n            ON DELETE CASCADE\n);\n\nCREATE TABLE webhook_deliveries\n(\n    id              SERIAL PRIMARY KEY,\n    webhook_id      INT,\n    expression_id   INT,\n    event           TEXT,\n    payload         TEXT,\n    status          TEXT,\n    attempts        INT,\n    response_status INT,\n    last_error      TEXT,\n    next_attempt_at BIGINT,\n    creation_time   TEXT,\n    delivered_time  TEXT,\n    CONSTRAINT fk_webhook\n        FOREIGN KEY (webhook_id)\n            REFERENCES webhooks (id)\n            ON DELETE CASCADE\n);"
 		_, err := a.db.Exec(command)
 		if err != nil {
 			zap.S().Warn(fmt.Sprintf("Failed to reset database: %v", err))
@@ -127,13 +127,69 @@ func (a *APIDb) IsDBCorrect() (bool, error) {
 
 	correctFieldsExpressions := []string{
 		"id", "value", "answer", "logs", "ready", "alive_expires_at", "creation_time", "end_calculation_time", "server_name", "user_id",
+		"priority", "attempts", "failed_servers", "team_id", "batch_id",
 	}
 	correctFieldsExpressionsUsers := []string{
-		"id", "login", "password",
+		"id", "login", "password", "role", "disabled",
 	}
 	correctFieldsOperarions := []string{
 		"id", "time_add", "time_subtract", "time_divide", "time_multiply", "user_id",
 	}
+	correctFieldsExpressionRuns := []string{
+		"id", "expression_id", "answer", "logs", "ready", "creation_time", "end_calculation_time", "server_name",
+	}
+	correctFieldsExpressionOperations := []string{
+		"id", "time_add", "time_subtract", "time_divide", "time_multiply", "expression_id",
+	}
+	correctFieldsWorkers := []string{
+		"id", "name", "enrollment_hash", "enrollment_expires_at", "credential_hash", "revoked", "creation_time",
+	}
+	correctFieldsRefreshTokens := []string{
+		"id", "token_hash", "family", "user_id", "expires_at", "used", "revoked", "creation_time",
+	}
+	correctFieldsSessions := []string{
+		"id", "jti", "user_id", "user_agent", "creation_time", "last_seen_time", "expires_at", "revoked",
+	}
+	correctFieldsAPIKeys := []string{
+		"id", "name", "key_hash", "prefix", "user_id", "scopes", "expires_at", "revoked", "creation_time",
+		"last_used_time",
+	}
+	correctFieldsTeams := []string{
+		"id", "name", "creation_time", "require_two_factor",
+	}
+	correctFieldsTeamMembers := []string{
+		"id", "team_id", "user_id", "role",
+	}
+	correctFieldsTeamOperations := []string{
+		"id", "time_add", "time_subtract", "time_divide", "time_multiply", "team_id",
+	}
+	correctFieldsLoginAttempts := []string{
+		"id", "login", "user_id", "ip", "user_agent", "reason", "creation_time",
+	}
+	correctFieldsTOTPSecrets := []string{
+		"id", "user_id", "secret", "confirmed", "last_step", "creation_time",
+	}
+	correctFieldsRecoveryCodes := []string{
+		"id", "user_id", "code_hash", "used",
+	}
+	correctFieldsOIDCIdentities := []string{
+		"id", "issuer", "subject", "user_id", "email", "creation_time",
+	}
+	correctFieldsSigningKeys := []string{
+		"id", "kid", "algorithm", "private_key", "creation_time", "retired_at",
+	}
+	correctFieldsBatches := []string{
+		"id", "user_id", "size", "creation_time",
+	}
+	correctFieldsIdempotencyKeys := []string{
+		"id", "user_id", "request_key", "request_hash", "status", "response", "location", "expires_at", "creation_time",
+	}
+	correctFieldsWebhooks := []string{
+		"id", "user_id", "expression_id", "url", "secret", "creation_time",
+	}
+	correctFieldsWebhookDeliveries := []string{
+		"id", "webhook_id", "expression_id", "event", "payload", "status", "attempts", "response_status", "last_error", "next_attempt_at", "creation_time", "delivered_time",
+	}
 
 	err = a.CheckFields("expressions", correctFieldsExpressions)
 	if err != nil {
@@ -147,5 +203,77 @@ func (a *APIDb) IsDBCorrect() (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	err = a.CheckFields("expression_runs", correctFieldsExpressionRuns)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("expression_operations", correctFieldsExpressionOperations)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("workers", correctFieldsWorkers)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("refresh_tokens", correctFieldsRefreshTokens)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("sessions", correctFieldsSessions)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("api_keys", correctFieldsAPIKeys)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("teams", correctFieldsTeams)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("team_members", correctFieldsTeamMembers)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("team_operations", correctFieldsTeamOperations)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("login_attempts", correctFieldsLoginAttempts)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("totp_secrets", correctFieldsTOTPSecrets)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("recovery_codes", correctFieldsRecoveryCodes)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("oidc_identities", correctFieldsOIDCIdentities)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("signing_keys", correctFieldsSigningKeys)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("batches", correctFieldsBatches)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("idempotency_keys", correctFieldsIdempotencyKeys)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("webhooks", correctFieldsWebhooks)
+	if err != nil {
+		return false, err
+	}
+	err = a.CheckFields("webhook_deliveries", correctFieldsWebhookDeliveries)
+	if err != nil {
+		return false, err
+	}
 	return true, nil
 }
@@ -1,10 +1,12 @@
 package db
 
 const (
-	ExpressionNotReady = 0
-	ExpressionWorking  = 1
-	ExpressionReady    = 2
-	ExpressionError    = 3
+	ExpressionNotReady  = 0
+	ExpressionWorking   = 1
+	ExpressionReady     = 2
+	ExpressionError     = 3
+	ExpressionAbandoned = 4
+	ExpressionCancelled = 5
 )
 
 type Expression struct {
@@ -12,17 +14,26 @@ type Expression struct {
 	Value              string  `db:"value" json:"value"`
 	Answer             float64 `db:"answer" json:"answer"`
 	Logs               string  `db:"logs" json:"logs"`
-	Status             int     `db:"ready" json:"ready"` // 0 - not ready, 1 - working, 2 - ready, 3 - error
+	Status             int     `db:"ready" json:"ready"` // 0 - not ready, 1 - working, 2 - ready, 3 - error, 4 - abandoned, 5 - cancelled
 	AliveExpiresAt     int     `db:"alive_expires_at" json:"alive_expires_at"`
 	CreationTime       string  `db:"creation_time" json:"creation_time"`
 	EndCalculationTime string  `db:"end_calculation_time" json:"end_calculation_time"`
 	Servername         string  `db:"server_name" json:"server_name"`
 	User               int     `db:"user_id" json:"user_id"`
+	Priority           int     `db:"priority" json:"priority"` // expressions with higher priority are calculated first
+	Attempts           int     `db:"attempts" json:"attempts"` // how many times servers died while calculating it
+	FailedServers      string  `db:"failed_servers" json:"failed_servers"`
+	Team               int     `db:"team_id" json:"team_id"`   // 0 - personal expression of the user
+	Batch              int     `db:"batch_id" json:"batch_id"` // 0 - expression is not a part of a batch
 }
 
+const selectExpression = "SELECT id, value, answer, logs, ready, alive_expires_at, creation_time," +
+	" end_calculation_time, server_name, user_id, priority, attempts, failed_servers, COALESCE(team_id, 0)," +
+	" COALESCE(batch_id, 0) FROM expressions"
+
 func (a *APIDb) GetAllExpressions() ([]Expression, error) {
 	expressions := make([]Expression, 0)
-	rows, err := a.db.Query("SELECT * FROM expressions")
+	rows, err := a.db.Query(selectExpression)
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +49,8 @@ func (a *APIDb) GetAllExpressions() ([]Expression, error) {
 		expression := Expression{}
 		err = rows.Scan(&expression.ID, &expression.Value, &expression.Answer, &expression.Logs, &expression.Status,
 			&expression.AliveExpiresAt, &expression.CreationTime, &expression.EndCalculationTime, &expression.Servername,
-			&expression.User)
+			&expression.User, &expression.Priority, &expression.Attempts, &expression.FailedServers,
+			&expression.Team, &expression.Batch)
 		if err != nil {
 			return nil, err
 		}
@@ -50,33 +62,52 @@ func (a *APIDb) GetAllExpressions() ([]Expression, error) {
 
 func (a *APIDb) GetExpressionByID(id int) (Expression, error) {
 	expression := Expression{}
-	err := a.db.QueryRow("SELECT * FROM expressions WHERE id=$1", id).
+	err := a.db.QueryRow(selectExpression+" WHERE id=$1", id).
 		Scan(&expression.ID, &expression.Value, &expression.Answer, &expression.Logs, &expression.Status,
 			&expression.AliveExpiresAt, &expression.CreationTime, &expression.EndCalculationTime, &expression.Servername,
-			&expression.User)
+			&expression.User, &expression.Priority, &expression.Attempts, &expression.FailedServers,
+			&expression.Team, &expression.Batch)
 	if err != nil {
 		return expression, err
 	}
 	return expression, nil
 }
 
+// team_id and batch_id are NULL for personal expressions and expressions without batch, they are 0 in Expression
+const insertExpression = "INSERT INTO expressions(value, answer, logs, ready, alive_expires_at, creation_time," +
+	" end_calculation_time, server_name, user_id, priority, attempts, failed_servers, team_id, batch_id)" +
+	" VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, 0), NULLIF($14, 0))" +
+	" RETURNING id"
+
+func insertExpressionArgs(expression Expression) []interface{} {
+	return []interface{}{expression.Value, expression.Answer, expression.Logs, expression.Status,
+		expression.AliveExpiresAt, expression.CreationTime, expression.EndCalculationTime, expression.Servername,
+		expression.User, expression.Priority, expression.Attempts, expression.FailedServers, expression.Team,
+		expression.Batch}
+}
+
 func (a *APIDb) AddExpression(expression Expression) (int, error) {
 	var id int
-	err := a.db.QueryRow("INSERT INTO expressions(value, answer, logs, ready, alive_expires_at, creation_time,"+
-		" end_calculation_time, server_name, user_id) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id",
-		expression.Value, expression.Answer, expression.Logs, expression.Status, expression.AliveExpiresAt,
-		expression.CreationTime, expression.EndCalculationTime, expression.Servername, expression.User).Scan(&id)
+	err := a.db.QueryRow(insertExpression, insertExpressionArgs(expression)...).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
 	return id, nil
 }
 
+const updateExpression = "UPDATE expressions SET value=$1, answer=$2, logs=$3, ready=$4, alive_expires_at=$5," +
+	" creation_time=$6, end_calculation_time=$7, server_name=$8, user_id=$9, priority=$10, attempts=$11," +
+	" failed_servers=$12, team_id=NULLIF($13, 0) WHERE id=$14"
+
+func updateExpressionArgs(expression Expression) []interface{} {
+	return []interface{}{expression.Value, expression.Answer, expression.Logs, expression.Status,
+		expression.AliveExpiresAt, expression.CreationTime, expression.EndCalculationTime, expression.Servername,
+		expression.User, expression.Priority, expression.Attempts, expression.FailedServers, expression.Team,
+		expression.ID}
+}
+
 func (a *APIDb) UpdateExpression(expression Expression) error {
-	_, err := a.db.Exec("UPDATE expressions SET value=$1, answer=$2, logs=$3, ready=$4, alive_expires_at=$5,"+
-		" creation_time=$6, end_calculation_time=$7, server_name=$8, user_id=$9 WHERE id=$10",
-		expression.Value, expression.Answer, expression.Logs, expression.Status, expression.AliveExpiresAt,
-		expression.CreationTime, expression.EndCalculationTime, expression.Servername, expression.User, expression.ID)
+	_, err := a.db.Exec(updateExpression, updateExpressionArgs(expression)...)
 	return err
 }
 
@@ -0,0 +1,60 @@
+package db
+
+// IdempotencyKey is the Idempotency-Key of a request of the user and the response to it. Status is 0 while the first
+// request is being handled.
+type IdempotencyKey struct {
+	ID           int    `db:"id" json:"id"`
+	User         int    `db:"user_id" json:"user_id"`
+	Key          string `db:"request_key" json:"key"`
+	RequestHash  string `db:"request_hash" json:"-"`
+	Status       int    `db:"status" json:"status"`
+	Response     string `db:"response" json:"-"`
+	Location     string `db:"location" json:"-"` // Location header of the response
+	ExpiresAt    int    `db:"expires_at" json:"expires_at"`
+	CreationTime string `db:"creation_time" json:"creation_time"`
+}
+
+// ReserveIdempotencyKey saves the key without response if the user does not have it yet, returns false if the key
+// is already used. Expired keys of the user are deleted.
+func (a *APIDb) ReserveIdempotencyKey(key IdempotencyKey, now int) (bool, error) {
+	_, err := a.db.Exec("DELETE FROM idempotency_keys WHERE user_id=$1 AND expires_at<$2", key.User, now)
+	if err != nil {
+		return false, err
+	}
+	result, err := a.db.Exec("INSERT INTO idempotency_keys(user_id, request_key, request_hash, status, response,"+
+		" location, expires_at, creation_time) VALUES($1, $2, $3, 0, '', '', $4, $5)"+
+		" ON CONFLICT (user_id, request_key) DO NOTHING",
+		key.User, key.Key, key.RequestHash, key.ExpiresAt, key.CreationTime)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return count == 1, nil
+}
+
+func (a *APIDb) GetIdempotencyKey(userID int, key string) (IdempotencyKey, error) {
+	idempotencyKey := IdempotencyKey{}
+	err := a.db.QueryRow("SELECT * FROM idempotency_keys WHERE user_id=$1 AND request_key=$2", userID, key).
+		Scan(&idempotencyKey.ID, &idempotencyKey.User, &idempotencyKey.Key, &idempotencyKey.RequestHash,
+			&idempotencyKey.Status, &idempotencyKey.Response, &idempotencyKey.Location, &idempotencyKey.ExpiresAt,
+			&idempotencyKey.CreationTime)
+	if err != nil {
+		return idempotencyKey, err
+	}
+	return idempotencyKey, nil
+}
+
+// SetIdempotencyResponse saves the response to the request with the key.
+func (a *APIDb) SetIdempotencyResponse(key IdempotencyKey) error {
+	_, err := a.db.Exec("UPDATE idempotency_keys SET status=$1, response=$2, location=$3 WHERE user_id=$4"+
+		" AND request_key=$5", key.Status, key.Response, key.Location, key.User, key.Key)
+	return err
+}
+
+func (a *APIDb) DeleteIdempotencyKey(userID int, key string) error {
+	_, err := a.db.Exec("DELETE FROM idempotency_keys WHERE user_id=$1 AND request_key=$2", userID, key)
+	return err
+}
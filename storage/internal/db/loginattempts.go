@@ -0,0 +1,50 @@
+package db
+
+// LoginAttempt is a failed login attempt, they are kept for audit. User is 0 if the login does not exist.
+type LoginAttempt struct {
+	ID           int    `db:"id" json:"id"`
+	Login        string `db:"login" json:"login"`
+	User         int    `db:"user_id" json:"user_id"`
+	IP           string `db:"ip" json:"ip"`
+	UserAgent    string `db:"user_agent" json:"user_agent"`
+	Reason       string `db:"reason" json:"reason"`
+	CreationTime string `db:"creation_time" json:"creation_time"`
+}
+
+func (a *APIDb) AddLoginAttempt(attempt LoginAttempt) (int, error) {
+	var id int
+	err := a.db.QueryRow("INSERT INTO login_attempts(login, user_id, ip, user_agent, reason, creation_time)"+
+		" VALUES($1, $2, $3, $4, $5, $6) RETURNING id", attempt.Login, attempt.User, attempt.IP, attempt.UserAgent,
+		attempt.Reason, attempt.CreationTime).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetLoginAttempts returns at most limit attempts with login (all attempts if login is empty), the newest first.
+func (a *APIDb) GetLoginAttempts(login string, limit int) ([]LoginAttempt, error) {
+	attempts := make([]LoginAttempt, 0)
+	rows, err := a.db.Query("SELECT * FROM login_attempts WHERE $1='' OR login=$1 ORDER BY id DESC LIMIT $2",
+		login, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		attempt := LoginAttempt{}
+		err = rows.Scan(&attempt.ID, &attempt.Login, &attempt.User, &attempt.IP, &attempt.UserAgent, &attempt.Reason,
+			&attempt.CreationTime)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
@@ -0,0 +1,53 @@
+package db
+
+// OIDCIdentity links a subject of an external OpenID Connect provider to a user.
+type OIDCIdentity struct {
+	ID           int    `db:"id" json:"id"`
+	Issuer       string `db:"issuer" json:"issuer"`
+	Subject      string `db:"subject" json:"subject"`
+	User         int    `db:"user_id" json:"user_id"`
+	Email        string `db:"email" json:"email"`
+	CreationTime string `db:"creation_time" json:"creation_time"`
+}
+
+// GetOIDCIdentity returns the identity of the subject of the issuer, sql.ErrNoRows if the subject has not logged in.
+func (a *APIDb) GetOIDCIdentity(issuer string, subject string) (OIDCIdentity, error) {
+	identity := OIDCIdentity{}
+	err := a.db.QueryRow("SELECT * FROM oidc_identities WHERE issuer=$1 AND subject=$2", issuer, subject).
+		Scan(&identity.ID, &identity.Issuer, &identity.Subject, &identity.User, &identity.Email,
+			&identity.CreationTime)
+	if err != nil {
+		return identity, err
+	}
+	return identity, nil
+}
+
+// AddOIDCUser creates the user with default operations and links the identity to it, returns ID of the user.
+func (a *APIDb) AddOIDCUser(user User, identity OIDCIdentity) (int, error) {
+	if user.Role == "" {
+		user.Role = RoleUser
+	}
+	tx, err := a.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // rollback after commit does nothing
+
+	var id int
+	err = tx.QueryRow("INSERT INTO users(login, password, role, disabled) VALUES ($1, $2, $3, $4) RETURNING id",
+		user.Login, user.Password, user.Role, user.Disabled).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec("INSERT INTO operations(time_add, time_subtract, time_divide, time_multiply, user_id)"+
+		" VALUES (0, 0, 0, 0, $1)", id)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec("INSERT INTO oidc_identities(issuer, subject, user_id, email, creation_time)"+
+		" VALUES($1, $2, $3, $4, $5)", identity.Issuer, identity.Subject, id, identity.Email, identity.CreationTime)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
@@ -51,3 +51,38 @@ func (a *APIDb) DeleteByUserId(userId int) error {
 	}
 	return nil
 }
+
+// GetExpressionOperations returns operation times that were set for one expression (see SetExpressionOperations).
+func (a *APIDb) GetExpressionOperations(expressionID int) (Operation, error) {
+	operation := Operation{}
+	err := a.db.QueryRow("SELECT id, time_add, time_subtract, time_divide, time_multiply FROM expression_operations"+
+		" WHERE expression_id=$1", expressionID).
+		Scan(&operation.ID, &operation.TimeAdd, &operation.TimeSubtract, &operation.TimeDivide, &operation.TimeMultiply)
+	if err != nil {
+		return operation, err
+	}
+	return operation, nil
+}
+
+// SetExpressionOperations sets operation times for one expression, they are used instead of operation times of the
+// user.
+func (a *APIDb) SetExpressionOperations(expressionID int, operation Operation) error {
+	if err := a.DeleteExpressionOperations(expressionID); err != nil {
+		return err
+	}
+	_, err := a.db.Exec("INSERT INTO expression_operations(time_add, time_subtract, time_divide, time_multiply,"+
+		" expression_id) VALUES ($1, $2, $3, $4, $5)", operation.TimeAdd, operation.TimeSubtract, operation.TimeDivide,
+		operation.TimeMultiply, expressionID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *APIDb) DeleteExpressionOperations(expressionID int) error {
+	_, err := a.db.Exec("DELETE FROM expression_operations WHERE expression_id=$1", expressionID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package db
+
+// RefreshToken is a single-use token that is exchanged for a new access token and a new refresh token. Tokens
+// issued one after another starting from one login belong to the same family. Only the hash of the token is stored.
+type RefreshToken struct {
+	ID           int    `db:"id" json:"id"`
+	TokenHash    string `db:"token_hash" json:"-"`
+	Family       string `db:"family" json:"family"`
+	User         int    `db:"user_id" json:"user_id"`
+	ExpiresAt    int    `db:"expires_at" json:"expires_at"`
+	Used         bool   `db:"used" json:"used"`
+	Revoked      bool   `db:"revoked" json:"revoked"`
+	CreationTime string `db:"creation_time" json:"creation_time"`
+}
+
+func (a *APIDb) AddRefreshToken(token RefreshToken) (int, error) {
+	var id int
+	err := a.db.QueryRow("INSERT INTO refresh_tokens(token_hash, family, user_id, expires_at, used, revoked,"+
+		" creation_time) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id", token.TokenHash, token.Family, token.User,
+		token.ExpiresAt, token.Used, token.Revoked, token.CreationTime).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (a *APIDb) GetRefreshToken(tokenHash string) (RefreshToken, error) {
+	token := RefreshToken{}
+	err := a.db.QueryRow("SELECT * FROM refresh_tokens WHERE token_hash=$1", tokenHash).
+		Scan(&token.ID, &token.TokenHash, &token.Family, &token.User, &token.ExpiresAt, &token.Used, &token.Revoked,
+			&token.CreationTime)
+	if err != nil {
+		return token, err
+	}
+	return token, nil
+}
+
+// UseRefreshToken marks the token as used, returns false if it was already used (e.g. by a concurrent request).
+func (a *APIDb) UseRefreshToken(id int) (bool, error) {
+	res, err := a.db.Exec("UPDATE refresh_tokens SET used=TRUE WHERE id=$1 AND used=FALSE", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// RevokeRefreshTokenFamily revokes all tokens of the family.
+func (a *APIDb) RevokeRefreshTokenFamily(family string) error {
+	_, err := a.db.Exec("UPDATE refresh_tokens SET revoked=TRUE WHERE family=$1", family)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package db
+
+// ExpressionRun is a finished calculation of an expression, expressions keep their previous runs when retried.
+type ExpressionRun struct {
+	ID                 int     `db:"id" json:"id"`
+	Expression         int     `db:"expression_id" json:"expression_id"`
+	Answer             float64 `db:"answer" json:"answer"`
+	Logs               string  `db:"logs" json:"logs"`
+	Status             int     `db:"ready" json:"ready"`
+	CreationTime       string  `db:"creation_time" json:"creation_time"`
+	EndCalculationTime string  `db:"end_calculation_time" json:"end_calculation_time"`
+	Servername         string  `db:"server_name" json:"server_name"`
+}
+
+func (a *APIDb) AddExpressionRun(run ExpressionRun) (int, error) {
+	var id int
+	err := a.db.QueryRow("INSERT INTO expression_runs(expression_id, answer, logs, ready, creation_time,"+
+		" end_calculation_time, server_name) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		run.Expression, run.Answer, run.Logs, run.Status, run.CreationTime, run.EndCalculationTime,
+		run.Servername).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (a *APIDb) GetExpressionRuns(expressionID int) ([]ExpressionRun, error) {
+	runs := make([]ExpressionRun, 0)
+	rows, err := a.db.Query("SELECT * FROM expression_runs WHERE expression_id=$1 ORDER BY id", expressionID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		run := ExpressionRun{}
+		err = rows.Scan(&run.ID, &run.Expression, &run.Answer, &run.Logs, &run.Status, &run.CreationTime,
+			&run.EndCalculationTime, &run.Servername)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// RetryExpression saves the finished run and the expression that is calculated again in one transaction. Operation
+// times of the new run are replaced by operations, if operations is nil, times of the team or the user are used.
+func (a *APIDb) RetryExpression(run ExpressionRun, expression Expression, operations *Operation) error {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // rollback after commit does nothing
+
+	_, err = tx.Exec("INSERT INTO expression_runs(expression_id, answer, logs, ready, creation_time,"+
+		" end_calculation_time, server_name) VALUES($1, $2, $3, $4, $5, $6, $7)",
+		run.Expression, run.Answer, run.Logs, run.Status, run.CreationTime, run.EndCalculationTime, run.Servername)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM expression_operations WHERE expression_id=$1", expression.ID); err != nil {
+		return err
+	}
+	if operations != nil {
+		_, err = tx.Exec("INSERT INTO expression_operations(time_add, time_subtract, time_divide, time_multiply,"+
+			" expression_id) VALUES ($1, $2, $3, $4, $5)", operations.TimeAdd, operations.TimeSubtract,
+			operations.TimeDivide, operations.TimeMultiply, expression.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err = tx.Exec(updateExpression, updateExpressionArgs(expression)...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
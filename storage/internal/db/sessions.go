@@ -0,0 +1,91 @@
+package db
+
+// Session is a login of a user. Access tokens and refresh tokens of the session have its JTI, revoked session
+// can not be used anymore.
+type Session struct {
+	ID           int    `db:"id" json:"id"`
+	JTI          string `db:"jti" json:"-"`
+	User         int    `db:"user_id" json:"user_id"`
+	UserAgent    string `db:"user_agent" json:"user_agent"`
+	CreationTime string `db:"creation_time" json:"creation_time"`
+	LastSeenTime string `db:"last_seen_time" json:"last_seen_time"`
+	ExpiresAt    int    `db:"expires_at" json:"expires_at"`
+	Revoked      bool   `db:"revoked" json:"revoked"`
+}
+
+func scanSession(row interface{ Scan(dest ...any) error }) (Session, error) {
+	session := Session{}
+	err := row.Scan(&session.ID, &session.JTI, &session.User, &session.UserAgent, &session.CreationTime,
+		&session.LastSeenTime, &session.ExpiresAt, &session.Revoked)
+	return session, err
+}
+
+func (a *APIDb) AddSession(session Session) (int, error) {
+	var id int
+	err := a.db.QueryRow("INSERT INTO sessions(jti, user_id, user_agent, creation_time, last_seen_time, expires_at,"+
+		" revoked) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id", session.JTI, session.User, session.UserAgent,
+		session.CreationTime, session.LastSeenTime, session.ExpiresAt, session.Revoked).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (a *APIDb) GetSession(jti string) (Session, error) {
+	return scanSession(a.db.QueryRow("SELECT * FROM sessions WHERE jti=$1", jti))
+}
+
+// GetUserSessions returns sessions of the user that are not revoked and not expired, the oldest first.
+func (a *APIDb) GetUserSessions(userID int, now int) ([]Session, error) {
+	sessions := make([]Session, 0)
+	rows, err := a.db.Query("SELECT * FROM sessions WHERE user_id=$1 AND revoked=FALSE AND expires_at>=$2"+
+		" ORDER BY id", userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// UpdateSession updates last seen time, expiration time and revoked flag of the session.
+func (a *APIDb) UpdateSession(session Session) error {
+	_, err := a.db.Exec("UPDATE sessions SET last_seen_time=$1, expires_at=$2, revoked=$3 WHERE id=$4",
+		session.LastSeenTime, session.ExpiresAt, session.Revoked, session.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RevokeSession revokes the session and its refresh tokens.
+func (a *APIDb) RevokeSession(jti string) error {
+	if _, err := a.db.Exec("UPDATE sessions SET revoked=TRUE WHERE jti=$1", jti); err != nil {
+		return err
+	}
+	return a.RevokeRefreshTokenFamily(jti)
+}
+
+// RevokeUserSessions revokes all sessions of the user and their refresh tokens.
+func (a *APIDb) RevokeUserSessions(userID int) error {
+	if _, err := a.db.Exec("UPDATE sessions SET revoked=TRUE WHERE user_id=$1", userID); err != nil {
+		return err
+	}
+	_, err := a.db.Exec("UPDATE refresh_tokens SET revoked=TRUE WHERE user_id=$1", userID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
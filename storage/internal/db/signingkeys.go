@@ -0,0 +1,82 @@
+package db
+
+// SigningKey is a key for signing of access tokens. RetiredAt is 0 for active keys, retired keys are used only to
+// verify tokens for a grace period. PrivateKey is encrypted by jwtkeys.
+type SigningKey struct {
+	ID           int    `db:"id" json:"id"`
+	KID          string `db:"kid" json:"kid"`
+	Algorithm    string `db:"algorithm" json:"algorithm"`
+	PrivateKey   string `db:"private_key" json:"-"`
+	CreationTime string `db:"creation_time" json:"creation_time"`
+	RetiredAt    int    `db:"retired_at" json:"retired_at"`
+}
+
+func (a *APIDb) AddSigningKey(key SigningKey) (int, error) {
+	var id int
+	err := a.db.QueryRow("INSERT INTO signing_keys(kid, algorithm, private_key, creation_time, retired_at)"+
+		" VALUES($1, $2, $3, $4, $5) RETURNING id", key.KID, key.Algorithm, key.PrivateKey, key.CreationTime,
+		key.RetiredAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetSigningKeys returns all keys, the oldest first.
+func (a *APIDb) GetSigningKeys() ([]SigningKey, error) {
+	keys := make([]SigningKey, 0)
+	rows, err := a.db.Query("SELECT * FROM signing_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		key := SigningKey{}
+		err = rows.Scan(&key.ID, &key.KID, &key.Algorithm, &key.PrivateKey, &key.CreationTime, &key.RetiredAt)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// SetSigningKeyPrivateKey replaces the private key of the key with kid if it is still previous, so instances that
+// encrypt the same key at the same time do not overwrite each other.
+func (a *APIDb) SetSigningKeyPrivateKey(kid string, previous string, privateKey string) error {
+	_, err := a.db.Exec("UPDATE signing_keys SET private_key=$1 WHERE kid=$2 AND private_key=$3", privateKey, kid,
+		previous)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RetireSigningKey marks the active key with kid as retired at retiredAt (unix time), returns false if there is no
+// such active key.
+func (a *APIDb) RetireSigningKey(kid string, retiredAt int) (bool, error) {
+	res, err := a.db.Exec("UPDATE signing_keys SET retired_at=$1 WHERE kid=$2 AND retired_at=0", retiredAt, kid)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n != 0, nil
+}
+
+// DeleteSigningKeysRetiredBefore deletes keys retired before t (unix time).
+func (a *APIDb) DeleteSigningKeysRetiredBefore(t int) error {
+	_, err := a.db.Exec("DELETE FROM signing_keys WHERE retired_at<>0 AND retired_at<$1", t)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package db
+
+const (
+	TeamRoleOwner  = "owner"
+	TeamRoleMember = "member"
+)
+
+// Team is a shared workspace, members of the team see and cancel expressions of each other.
+type Team struct {
+	ID           int    `db:"id" json:"id"`
+	Name         string `db:"name" json:"name"`
+	CreationTime string `db:"creation_time" json:"creation_time"`
+	// members without second factor can not use the team
+	RequireTwoFactor bool `db:"require_two_factor" json:"require_two_factor"`
+}
+
+type TeamMember struct {
+	ID    int    `db:"id" json:"id"`
+	Team  int    `db:"team_id" json:"team_id"`
+	User  int    `db:"user_id" json:"user_id"`
+	Role  string `db:"role" json:"role"`
+	Login string `json:"login"` // login of the user, filled by GetTeamMembers
+	// RequireTwoFactor of the team, filled by GetUserTeams and GetTeamMembers
+	RequireTwoFactor bool `json:"-"`
+}
+
+// AddTeam creates the team with owner.
+func (a *APIDb) AddTeam(team Team, owner int) (int, error) {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // rollback after commit does nothing
+
+	var id int
+	err = tx.QueryRow("INSERT INTO teams(name, creation_time, require_two_factor) VALUES($1, $2, $3) RETURNING id",
+		team.Name, team.CreationTime, team.RequireTwoFactor).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec("INSERT INTO team_members(team_id, user_id, role) VALUES($1, $2, $3)", id, owner,
+		TeamRoleOwner)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// SetTeamRequireTwoFactor changes whether members of the team must have second factor.
+func (a *APIDb) SetTeamRequireTwoFactor(id int, required bool) error {
+	_, err := a.db.Exec("UPDATE teams SET require_two_factor=$1 WHERE id=$2", required, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *APIDb) GetTeam(id int) (Team, error) {
+	team := Team{}
+	err := a.db.QueryRow("SELECT * FROM teams WHERE id=$1", id).Scan(&team.ID, &team.Name, &team.CreationTime,
+		&team.RequireTwoFactor)
+	if err != nil {
+		return team, err
+	}
+	return team, nil
+}
+
+// DeleteTeam deletes the team, its expressions become personal expressions of their authors (team_id is set to
+// NULL by the foreign key).
+func (a *APIDb) DeleteTeam(id int) error {
+	_, err := a.db.Exec("DELETE FROM teams WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetUserTeams returns memberships of the user.
+func (a *APIDb) GetUserTeams(userID int) ([]TeamMember, error) {
+	return a.getTeamMembers("SELECT team_members.id, team_id, user_id, role, login, require_two_factor"+
+		" FROM team_members JOIN users ON users.id=user_id JOIN teams ON teams.id=team_id WHERE user_id=$1"+
+		" ORDER BY team_id", userID)
+}
+
+// GetTeamMembers returns members of the team.
+func (a *APIDb) GetTeamMembers(teamID int) ([]TeamMember, error) {
+	return a.getTeamMembers("SELECT team_members.id, team_id, user_id, role, login, require_two_factor"+
+		" FROM team_members JOIN users ON users.id=user_id JOIN teams ON teams.id=team_id WHERE team_id=$1"+
+		" ORDER BY team_members.id", teamID)
+}
+
+func (a *APIDb) getTeamMembers(query string, arg int) ([]TeamMember, error) {
+	members := make([]TeamMember, 0)
+	rows, err := a.db.Query(query, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		member := TeamMember{}
+		err = rows.Scan(&member.ID, &member.Team, &member.User, &member.Role, &member.Login,
+			&member.RequireTwoFactor)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// SetTeamMember adds the user to the team or changes the role of the member.
+func (a *APIDb) SetTeamMember(member TeamMember) error {
+	_, err := a.db.Exec("INSERT INTO team_members(team_id, user_id, role) VALUES($1, $2, $3)"+
+		" ON CONFLICT (team_id, user_id) DO UPDATE SET role=EXCLUDED.role", member.Team, member.User, member.Role)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *APIDb) DeleteTeamMember(teamID int, userID int) error {
+	_, err := a.db.Exec("DELETE FROM team_members WHERE team_id=$1 AND user_id=$2", teamID, userID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetTeamMemberRole returns the role of the user in the team, sql.ErrNoRows if the user is not a member.
+func (a *APIDb) GetTeamMemberRole(teamID int, userID int) (string, error) {
+	var role string
+	err := a.db.QueryRow("SELECT role FROM team_members WHERE team_id=$1 AND user_id=$2", teamID, userID).
+		Scan(&role)
+	if err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+// GetTeamOperations returns operation times of the team, they are used for expressions of the team instead of
+// operation times of the author.
+func (a *APIDb) GetTeamOperations(teamID int) (Operation, error) {
+	operation := Operation{}
+	err := a.db.QueryRow("SELECT id, time_add, time_subtract, time_divide, time_multiply FROM team_operations"+
+		" WHERE team_id=$1", teamID).
+		Scan(&operation.ID, &operation.TimeAdd, &operation.TimeSubtract, &operation.TimeDivide, &operation.TimeMultiply)
+	if err != nil {
+		return operation, err
+	}
+	return operation, nil
+}
+
+func (a *APIDb) SetTeamOperations(teamID int, operation Operation) error {
+	_, err := a.db.Exec("INSERT INTO team_operations(time_add, time_subtract, time_divide, time_multiply,"+
+		" team_id) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (team_id) DO UPDATE SET time_add=EXCLUDED.time_add,"+
+		" time_subtract=EXCLUDED.time_subtract, time_divide=EXCLUDED.time_divide,"+
+		" time_multiply=EXCLUDED.time_multiply", operation.TimeAdd, operation.TimeSubtract, operation.TimeDivide,
+		operation.TimeMultiply, teamID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
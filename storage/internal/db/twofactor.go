@@ -0,0 +1,117 @@
+package db
+
+// TOTPSecret is a second factor of the user. It is used for logins only after it is confirmed with a code. LastStep
+// is the step of the last accepted code, codes of this and previous steps are not accepted again.
+type TOTPSecret struct {
+	ID           int    `db:"id" json:"id"`
+	User         int    `db:"user_id" json:"user_id"`
+	Secret       string `db:"secret" json:"-"`
+	Confirmed    bool   `db:"confirmed" json:"confirmed"`
+	LastStep     int64  `db:"last_step" json:"-"`
+	CreationTime string `db:"creation_time" json:"creation_time"`
+}
+
+// SetTOTPSecret saves new not confirmed secret of the user, the previous secret is replaced.
+func (a *APIDb) SetTOTPSecret(secret TOTPSecret) error {
+	_, err := a.db.Exec("INSERT INTO totp_secrets(user_id, secret, confirmed, last_step, creation_time)"+
+		" VALUES($1, $2, FALSE, 0, $3) ON CONFLICT (user_id) DO UPDATE SET secret=EXCLUDED.secret,"+
+		" confirmed=FALSE, last_step=0, creation_time=EXCLUDED.creation_time", secret.User, secret.Secret,
+		secret.CreationTime)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *APIDb) GetTOTPSecret(userID int) (TOTPSecret, error) {
+	secret := TOTPSecret{}
+	err := a.db.QueryRow("SELECT * FROM totp_secrets WHERE user_id=$1", userID).Scan(&secret.ID, &secret.User,
+		&secret.Secret, &secret.Confirmed, &secret.LastStep, &secret.CreationTime)
+	if err != nil {
+		return secret, err
+	}
+	return secret, nil
+}
+
+// HasTwoFactor returns true if the user has confirmed second factor.
+func (a *APIDb) HasTwoFactor(userID int) (bool, error) {
+	var exists bool
+	err := a.db.QueryRow("SELECT EXISTS (SELECT FROM totp_secrets WHERE user_id=$1 AND confirmed=TRUE)", userID).
+		Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// UseTOTPStep accepts a code of the step for the user, confirms the secret. Returns false if a code of this or
+// a later step was already accepted.
+func (a *APIDb) UseTOTPStep(userID int, step int64) (bool, error) {
+	res, err := a.db.Exec("UPDATE totp_secrets SET last_step=$2, confirmed=TRUE WHERE user_id=$1 AND last_step<$2",
+		userID, step)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// DeleteTwoFactor deletes the secret and recovery codes of the user.
+func (a *APIDb) DeleteTwoFactor(userID int) error {
+	if _, err := a.db.Exec("DELETE FROM recovery_codes WHERE user_id=$1", userID); err != nil {
+		return err
+	}
+	_, err := a.db.Exec("DELETE FROM totp_secrets WHERE user_id=$1", userID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetRecoveryCodes replaces recovery codes of the user with hashes of new codes.
+func (a *APIDb) SetRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // rollback after commit does nothing
+
+	if _, err = tx.Exec("DELETE FROM recovery_codes WHERE user_id=$1", userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		_, err = tx.Exec("INSERT INTO recovery_codes(user_id, code_hash, used) VALUES($1, $2, FALSE)", userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode marks the recovery code of the user as used, returns false if there is no such unused code.
+func (a *APIDb) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	res, err := a.db.Exec("UPDATE recovery_codes SET used=TRUE WHERE user_id=$1 AND code_hash=$2 AND used=FALSE",
+		userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// CountRecoveryCodes returns the number of unused recovery codes of the user.
+func (a *APIDb) CountRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := a.db.QueryRow("SELECT COUNT(*) FROM recovery_codes WHERE user_id=$1 AND used=FALSE", userID).
+		Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
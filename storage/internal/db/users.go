@@ -1,24 +1,35 @@
 package db
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID       int    `db:"id" json:"id"`
 	Login    string `db:"login" json:"login"`
 	Password string `db:"password" json:"password"`
+	Role     string `db:"role" json:"role"`
+	Disabled bool   `db:"disabled" json:"disabled"`
 }
 
-func (a *APIDb) GetUserByUsername(username string) (User, error) {
+func scanUser(row interface{ Scan(dest ...any) error }) (User, error) {
 	user := User{}
-	err := a.db.QueryRow("SELECT * FROM users WHERE login=$1", username).
-		Scan(&user.ID, &user.Login, &user.Password)
-	if err != nil {
-		return user, err
-	}
-	return user, nil
+	err := row.Scan(&user.ID, &user.Login, &user.Password, &user.Role, &user.Disabled)
+	return user, err
+}
+
+func (a *APIDb) GetUserByUsername(username string) (User, error) {
+	return scanUser(a.db.QueryRow("SELECT * FROM users WHERE login=$1", username))
 }
 
 func (a *APIDb) AddUser(user User) (int, error) {
+	if user.Role == "" {
+		user.Role = RoleUser
+	}
 	var id int
-	err := a.db.QueryRow("INSERT INTO users(login, password) VALUES ($1, $2) RETURNING id", user.Login, user.Password).Scan(&id)
+	err := a.db.QueryRow("INSERT INTO users(login, password, role, disabled) VALUES ($1, $2, $3, $4) RETURNING id",
+		user.Login, user.Password, user.Role, user.Disabled).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
@@ -40,3 +51,75 @@ func (a *APIDb) UpdateUser(user User) error {
 	}
 	return nil
 }
+
+func (a *APIDb) GetUserByID(id int) (User, error) {
+	return scanUser(a.db.QueryRow("SELECT * FROM users WHERE id=$1", id))
+}
+
+// GetAllUsers returns all users ordered by ID.
+func (a *APIDb) GetAllUsers() ([]User, error) {
+	users := make([]User, 0)
+	rows, err := a.db.Query("SELECT * FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// SetUserRole changes role and disabled flag of the user, they are not changed by UpdateUser.
+func (a *APIDb) SetUserRole(id int, role string, disabled bool) error {
+	_, err := a.db.Exec("UPDATE users SET role=$1, disabled=$2 WHERE id=$3", role, disabled, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DisableUser disables the user with the role and revokes everything the user can authenticate with: sessions,
+// refresh tokens and API keys, all in one transaction.
+func (a *APIDb) DisableUser(id int, role string) error {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // rollback after commit does nothing
+
+	if _, err = tx.Exec("UPDATE users SET role=$1, disabled=TRUE WHERE id=$2", role, id); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("UPDATE sessions SET revoked=TRUE WHERE user_id=$1", id); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("UPDATE refresh_tokens SET revoked=TRUE WHERE user_id=$1", id); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("UPDATE api_keys SET revoked=TRUE WHERE user_id=$1", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// HasAdmin returns true if there is at least one admin that is not disabled.
+func (a *APIDb) HasAdmin() (bool, error) {
+	var exists bool
+	err := a.db.QueryRow("SELECT EXISTS (SELECT FROM users WHERE role=$1 AND disabled=FALSE)", RoleAdmin).
+		Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
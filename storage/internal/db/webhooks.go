@@ -0,0 +1,183 @@
+package db
+
+// statuses of webhook deliveries
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed" // all attempts failed, the delivery can be redelivered manually
+)
+
+// Webhook is a URL that is called when expressions of the user (or one expression) are finished. Payloads are
+// signed with Secret.
+type Webhook struct {
+	ID           int    `db:"id" json:"id"`
+	User         int    `db:"user_id" json:"user_id"`
+	Expression   int    `db:"expression_id" json:"expression_id"` // 0 - all expressions of the user
+	URL          string `db:"url" json:"url"`
+	Secret       string `db:"secret" json:"-"`
+	CreationTime string `db:"creation_time" json:"creation_time"`
+}
+
+// WebhookDelivery is a call of the webhook with the payload.
+type WebhookDelivery struct {
+	ID             int    `db:"id" json:"id"`
+	Webhook        int    `db:"webhook_id" json:"webhook_id"`
+	Expression     int    `db:"expression_id" json:"expression_id"`
+	Event          string `db:"event" json:"event"`
+	Payload        string `db:"payload" json:"payload"`
+	Status         string `db:"status" json:"status"`
+	Attempts       int    `db:"attempts" json:"attempts"`
+	ResponseStatus int    `db:"response_status" json:"response_status"` // HTTP status of the last attempt
+	LastError      string `db:"last_error" json:"last_error"`
+	NextAttemptAt  int    `db:"next_attempt_at" json:"next_attempt_at"`
+	CreationTime   string `db:"creation_time" json:"creation_time"`
+	DeliveredTime  string `db:"delivered_time" json:"delivered_time"`
+}
+
+// expression_id is NULL for webhooks of all expressions, it is 0 in Webhook
+const selectWebhook = "SELECT id, user_id, COALESCE(expression_id, 0), url, secret, creation_time FROM webhooks"
+
+func scanWebhook(row interface{ Scan(dest ...any) error }) (Webhook, error) {
+	webhook := Webhook{}
+	err := row.Scan(&webhook.ID, &webhook.User, &webhook.Expression, &webhook.URL, &webhook.Secret,
+		&webhook.CreationTime)
+	return webhook, err
+}
+
+func scanWebhookDelivery(row interface{ Scan(dest ...any) error }) (WebhookDelivery, error) {
+	delivery := WebhookDelivery{}
+	err := row.Scan(&delivery.ID, &delivery.Webhook, &delivery.Expression, &delivery.Event, &delivery.Payload,
+		&delivery.Status, &delivery.Attempts, &delivery.ResponseStatus, &delivery.LastError, &delivery.NextAttemptAt,
+		&delivery.CreationTime, &delivery.DeliveredTime)
+	return delivery, err
+}
+
+func (a *APIDb) AddWebhook(webhook Webhook) (int, error) {
+	var id int
+	err := a.db.QueryRow("INSERT INTO webhooks(user_id, expression_id, url, secret, creation_time)"+
+		" VALUES($1, NULLIF($2, 0), $3, $4, $5) RETURNING id", webhook.User, webhook.Expression, webhook.URL, webhook.Secret,
+		webhook.CreationTime).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (a *APIDb) GetWebhook(id int) (Webhook, error) {
+	return scanWebhook(a.db.QueryRow(selectWebhook+" WHERE id=$1", id))
+}
+
+func (a *APIDb) getWebhooks(query string, args ...any) ([]Webhook, error) {
+	webhooks := make([]Webhook, 0)
+	rows, err := a.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetWebhooks returns webhooks of the user, the oldest first.
+func (a *APIDb) GetWebhooks(userID int) ([]Webhook, error) {
+	return a.getWebhooks(selectWebhook+" WHERE user_id=$1 ORDER BY id", userID)
+}
+
+// GetExpressionWebhooks returns webhooks of the user that are called for the expression.
+func (a *APIDb) GetExpressionWebhooks(userID int, expressionID int) ([]Webhook, error) {
+	return a.getWebhooks(selectWebhook+" WHERE user_id=$1 AND (expression_id IS NULL OR expression_id=$2)"+
+		" ORDER BY id", userID, expressionID)
+}
+
+// DeleteWebhook deletes the webhook of the user with its deliveries, returns false if there is no such webhook.
+func (a *APIDb) DeleteWebhook(id int, userID int) (bool, error) {
+	result, err := a.db.Exec("DELETE FROM webhooks WHERE id=$1 AND user_id=$2", id, userID)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return count == 1, nil
+}
+
+func (a *APIDb) AddWebhookDelivery(delivery WebhookDelivery) (int, error) {
+	var id int
+	err := a.db.QueryRow("INSERT INTO webhook_deliveries(webhook_id, expression_id, event, payload, status,"+
+		" attempts, response_status, last_error, next_attempt_at, creation_time, delivered_time)"+
+		" VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id", delivery.Webhook, delivery.Expression,
+		delivery.Event, delivery.Payload, delivery.Status, delivery.Attempts, delivery.ResponseStatus,
+		delivery.LastError, delivery.NextAttemptAt, delivery.CreationTime, delivery.DeliveredTime).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (a *APIDb) GetWebhookDelivery(id int) (WebhookDelivery, error) {
+	return scanWebhookDelivery(a.db.QueryRow("SELECT * FROM webhook_deliveries WHERE id=$1", id))
+}
+
+// GetWebhookDeliveries returns deliveries of the webhook, the newest first.
+func (a *APIDb) GetWebhookDeliveries(webhookID int, limit int) ([]WebhookDelivery, error) {
+	deliveries := make([]WebhookDelivery, 0)
+	rows, err := a.db.Query("SELECT * FROM webhook_deliveries WHERE webhook_id=$1 ORDER BY id DESC LIMIT $2",
+		webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ClaimWebhookDelivery returns the oldest pending delivery that is due at now and postpones it to leaseUntil, so
+// other instances of storage do not deliver it at the same time. It returns sql.ErrNoRows if there is none.
+func (a *APIDb) ClaimWebhookDelivery(now int, leaseUntil int) (WebhookDelivery, error) {
+	return scanWebhookDelivery(a.db.QueryRow("UPDATE webhook_deliveries SET next_attempt_at=$1 WHERE id IN"+
+		" (SELECT id FROM webhook_deliveries WHERE status=$2 AND next_attempt_at<=$3 ORDER BY next_attempt_at"+
+		" LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING *", leaseUntil, DeliveryPending, now))
+}
+
+// UpdateWebhookDelivery saves the result of an attempt if next_attempt_at of the delivery is still lease, the value
+// it was claimed or read with. It returns false if the lease expired and the delivery was claimed again or redelivered
+// meanwhile, so the newer result is kept.
+func (a *APIDb) UpdateWebhookDelivery(delivery WebhookDelivery, lease int) (bool, error) {
+	result, err := a.db.Exec("UPDATE webhook_deliveries SET status=$1, attempts=$2, response_status=$3,"+
+		" last_error=$4, next_attempt_at=$5, delivered_time=$6 WHERE id=$7 AND next_attempt_at=$8", delivery.Status,
+		delivery.Attempts, delivery.ResponseStatus, delivery.LastError, delivery.NextAttemptAt, delivery.DeliveredTime,
+		delivery.ID, lease)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return count == 1, nil
+}
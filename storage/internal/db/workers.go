@@ -0,0 +1,79 @@
+package db
+
+// Worker is a calculation server that is allowed to connect to the storage. Only hashes of the enrollment token and
+// of the credential are stored.
+type Worker struct {
+	ID                  int    `db:"id" json:"id"`
+	Name                string `db:"name" json:"name"`
+	EnrollmentHash      string `db:"enrollment_hash" json:"-"`
+	EnrollmentExpiresAt int    `db:"enrollment_expires_at" json:"enrollment_expires_at"`
+	CredentialHash      string `db:"credential_hash" json:"-"`
+	Revoked             bool   `db:"revoked" json:"revoked"`
+	CreationTime        string `db:"creation_time" json:"creation_time"`
+}
+
+func scanWorker(row interface{ Scan(dest ...any) error }) (Worker, error) {
+	worker := Worker{}
+	err := row.Scan(&worker.ID, &worker.Name, &worker.EnrollmentHash, &worker.EnrollmentExpiresAt,
+		&worker.CredentialHash, &worker.Revoked, &worker.CreationTime)
+	return worker, err
+}
+
+// AddWorker adds the worker or, if the worker with the same name exists, replaces its enrollment token, revoked
+// worker is allowed again.
+func (a *APIDb) AddWorker(worker Worker) (int, error) {
+	var id int
+	err := a.db.QueryRow("INSERT INTO workers(name, enrollment_hash, enrollment_expires_at, credential_hash,"+
+		" revoked, creation_time) VALUES($1, $2, $3, $4, $5, $6) ON CONFLICT (name) DO UPDATE SET"+
+		" enrollment_hash=EXCLUDED.enrollment_hash, enrollment_expires_at=EXCLUDED.enrollment_expires_at,"+
+		" revoked=EXCLUDED.revoked RETURNING id",
+		worker.Name, worker.EnrollmentHash, worker.EnrollmentExpiresAt, worker.CredentialHash, worker.Revoked,
+		worker.CreationTime).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (a *APIDb) GetWorkerByName(name string) (Worker, error) {
+	return scanWorker(a.db.QueryRow("SELECT * FROM workers WHERE name=$1", name))
+}
+
+func (a *APIDb) GetWorkerByCredential(credentialHash string) (Worker, error) {
+	return scanWorker(a.db.QueryRow("SELECT * FROM workers WHERE credential_hash=$1 AND credential_hash<>''",
+		credentialHash))
+}
+
+func (a *APIDb) GetAllWorkers() ([]Worker, error) {
+	workers := make([]Worker, 0)
+	rows, err := a.db.Query("SELECT * FROM workers ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		worker, err := scanWorker(rows)
+		if err != nil {
+			return nil, err
+		}
+		workers = append(workers, worker)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return workers, nil
+}
+
+// UpdateWorker updates tokens and revoked flag of the worker.
+func (a *APIDb) UpdateWorker(worker Worker) error {
+	_, err := a.db.Exec("UPDATE workers SET enrollment_hash=$1, enrollment_expires_at=$2, credential_hash=$3,"+
+		" revoked=$4 WHERE id=$5", worker.EnrollmentHash, worker.EnrollmentExpiresAt, worker.CredentialHash,
+		worker.Revoked, worker.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Package events keeps recent changes of expressions and delivers them to subscribers (Server-Sent Events).
+package events
+
+import (
+	"fmt"
+	"storage/internal/db"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// subscriberBuffer is the number of events that a subscriber may not have read yet, slower subscribers are
+// disconnected and resume with Last-Event-ID.
+const subscriberBuffer = 64
+
+// Event is a change of the expression. ID is "<boot>-<sequence>", boot changes on every start of storage, so IDs
+// from previous starts are recognised.
+type Event struct {
+	ID         string        `json:"id"`
+	Type       string        `json:"type"`
+	Expression db.Expression `json:"expression"`
+	sequence   int64
+}
+
+// Subscription receives events after Subscribe. C is closed if the subscriber is too slow or Close is called.
+type Subscription struct {
+	C      <-chan Event
+	c      chan Event
+	broker *Broker
+}
+
+// Close stops delivery of events.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	if _, ok := s.broker.subscribers[s]; ok {
+		delete(s.broker.subscribers, s)
+		close(s.c)
+	}
+}
+
+// Broker keeps the last events for resuming subscribers.
+type Broker struct {
+	boot        string
+	size        int
+	sequence    int64
+	recent      []Event // the oldest first, at most size events
+	subscribers map[*Subscription]struct{}
+	mu          sync.Mutex
+}
+
+// New returns Broker that keeps size last events.
+func New(size int) *Broker {
+	return &Broker{
+		boot:        strconv.FormatInt(time.Now().UnixNano(), 36),
+		size:        size,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns ID to the event and delivers it to subscribers.
+func (b *Broker) Publish(eventType string, expression db.Expression) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sequence++
+	event := Event{
+		ID:         fmt.Sprintf("%v-%v", b.boot, b.sequence),
+		Type:       eventType,
+		Expression: expression,
+		sequence:   b.sequence,
+	}
+	b.recent = append(b.recent, event)
+	if len(b.recent) > b.size {
+		b.recent = b.recent[len(b.recent)-b.size:]
+	}
+	for subscription := range b.subscribers {
+		select {
+		case subscription.c <- event:
+		default:
+			delete(b.subscribers, subscription)
+			close(subscription.c)
+		}
+	}
+	return event
+}
+
+// Subscribe returns subscription to new events and events after lastEventID. If lastEventID is not empty and the
+// events after it are not kept anymore (or it is from a previous start), complete is false and the subscriber must
+// reload expressions.
+func (b *Broker) Subscribe(lastEventID string) (subscription *Subscription, missed []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := make(chan Event, subscriberBuffer)
+	subscription = &Subscription{C: c, c: c, broker: b}
+	b.subscribers[subscription] = struct{}{}
+	if lastEventID == "" {
+		return subscription, nil, true
+	}
+
+	boot, sequence, ok := strings.Cut(lastEventID, "-")
+	last, err := strconv.ParseInt(sequence, 10, 64)
+	if !ok || err != nil || boot != b.boot || last > b.sequence {
+		return subscription, nil, false
+	}
+	if len(b.recent) != 0 && b.recent[0].sequence > last+1 {
+		return subscription, nil, false
+	}
+	missed = make([]Event, 0)
+	for _, event := range b.recent {
+		if event.sequence > last {
+			missed = append(missed, event)
+		}
+	}
+	return subscription, missed, true
+}
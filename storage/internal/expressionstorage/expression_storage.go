@@ -4,21 +4,52 @@ import (
 	"errors"
 	"fmt"
 	"go.uber.org/zap"
+	"sort"
+	"storage/internal/apierrors"
 	"storage/internal/db"
 	"sync"
 	"time"
 )
 
+// DefaultMaxAttempts is the number of times servers may die while calculating an expression before it is abandoned.
+const DefaultMaxAttempts = 3
+
+var (
+	// ErrNotFound is returned also for expressions that the user can not see.
+	ErrNotFound = apierrors.New(apierrors.CodeNotFound, "expression is not found")
+	// ErrNotAllowed is returned when a member of the team tries to delete an expression of another member.
+	ErrNotAllowed = apierrors.New(apierrors.CodePermissionDenied, "only author or owner of the team can delete the expression")
+	// errors of actions that are not possible in the current status of the expression
+	ErrNotAbandoned = apierrors.New(apierrors.CodeConflict, "expression is not abandoned")
+	ErrFinished     = apierrors.New(apierrors.CodeConflict, "expression is already finished")
+	ErrNotFinished  = apierrors.New(apierrors.CodeConflict, "expression is not finished")
+	ErrNotWorking   = apierrors.New(apierrors.CodeConflict, "expression is not in working")
+	// ErrNotLeased is returned when the server does not calculate the expression anymore (it was cancelled, released
+	// or given to another server).
+	ErrNotLeased = apierrors.New(apierrors.CodeConflict, "expression is not calculated by the server")
+)
+
 type ExpressionStorage struct {
 	expressions  sync.Map
 	db           *db.APIDb
 	checkAlive   time.Duration
 	serverStatus *sync.Map
+	scheduler    *Scheduler
+	maxAttempts  int
+	// mu guards the fields above and serializes changes of expressions (see CompareAndSwap), hooks are notified
+	// before it is unlocked, so they get changes of an expression in the order they are made
+	mu      sync.Mutex
+	hooks   []Hook
+	waiters map[int][]chan db.Expression // see Wait
+	// hooksMu guards hooks and waiters, it is locked while mu is locked and not the other way round
+	hooksMu sync.Mutex
 }
 
 func New(indb *db.APIDb, checkAlive time.Duration, serverStatus *sync.Map) *ExpressionStorage {
 	e := &ExpressionStorage{
-		db: indb,
+		db:          indb,
+		scheduler:   NewScheduler(),
+		maxAttempts: DefaultMaxAttempts,
 	}
 
 	// check saved data in database and uploads it to memory
@@ -44,14 +75,83 @@ func (e *ExpressionStorage) Add(expression db.Expression) (int, error) {
 	}
 	expression.ID = newID
 
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.expressions.Store(newID, expression)
+	e.notify(EventCreated, expression)
 	return newID, nil
 }
 
+// AddBatch adds the expressions in one transaction, returns ID of the batch and IDs of the expressions.
+func (e *ExpressionStorage) AddBatch(batch db.Batch, expressions []db.Expression) (int, []int, error) {
+	batchID, ids, err := e.db.AddBatch(batch, expressions)
+	if err != nil {
+		return 0, nil, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, expression := range expressions {
+		expression.ID = ids[i]
+		expression.Batch = batchID
+		e.expressions.Store(expression.ID, expression)
+		e.notify(EventCreated, expression)
+	}
+	return batchID, ids, nil
+}
+
+// GetBatch returns expressions of the batch that are not deleted.
+func (e *ExpressionStorage) GetBatch(batchID int) []db.Expression {
+	expressions := make([]db.Expression, 0)
+	e.expressions.Range(func(_, value interface{}) bool {
+		if value.(db.Expression).Batch == batchID {
+			expressions = append(expressions, value.(db.Expression))
+		}
+		return true
+	})
+	return expressions
+}
+
+// userTeams returns roles of the user in their teams by team ID. Teams that require second factor are skipped if the
+// user does not have it.
+func (e *ExpressionStorage) userTeams(userID int) map[int]string {
+	teams := make(map[int]string)
+	members, err := e.db.GetUserTeams(userID)
+	if err != nil {
+		zap.S().Error(err)
+		return teams
+	}
+	hasTwoFactor := false
+	for _, member := range members {
+		if member.RequireTwoFactor {
+			if hasTwoFactor, err = e.db.HasTwoFactor(userID); err != nil {
+				zap.S().Error(err)
+			}
+			break
+		}
+	}
+	for _, member := range members {
+		if !member.RequireTwoFactor || hasTwoFactor {
+			teams[member.Team] = member.Role
+		}
+	}
+	return teams
+}
+
+// isVisible returns true if the expression belongs to the user or to one of their teams.
+func isVisible(expression db.Expression, userID int, teams map[int]string) bool {
+	if expression.User == userID {
+		return true
+	}
+	_, ok := teams[expression.Team]
+	return expression.Team != 0 && ok
+}
+
+// GetAll returns expressions of the user and expressions of their teams.
 func (e *ExpressionStorage) GetAll(userID int) []db.Expression {
 	expressions := make([]db.Expression, 0)
+	teams := e.userTeams(userID)
 	e.expressions.Range(func(_, value interface{}) bool {
-		if value.(db.Expression).User == userID {
+		if isVisible(value.(db.Expression), userID, teams) {
 			expressions = append(expressions, value.(db.Expression))
 		}
 		return true
@@ -59,47 +159,97 @@ func (e *ExpressionStorage) GetAll(userID int) []db.Expression {
 	return expressions
 }
 
+// GetAllUsersExpressions returns expressions of all users ordered by ID.
+func (e *ExpressionStorage) GetAllUsersExpressions() []db.Expression {
+	expressions := make([]db.Expression, 0)
+	e.expressions.Range(func(_, value interface{}) bool {
+		expressions = append(expressions, value.(db.Expression))
+		return true
+	})
+	sort.Slice(expressions, func(i, j int) bool {
+		return expressions[i].ID < expressions[j].ID
+	})
+	return expressions
+}
+
 func (e *ExpressionStorage) GetByID(id int) (db.Expression, error) {
 	if expression, ok := e.expressions.Load(id); ok {
 		return expression.(db.Expression), nil
 	}
-	return db.Expression{}, errors.New("expression is not found")
+	return db.Expression{}, ErrNotFound
 }
 
+// GetByUserAndID returns the expression if it belongs to the user or to one of their teams.
 func (e *ExpressionStorage) GetByUserAndID(userID int, id int) (db.Expression, error) {
 	expression, err := e.GetByID(id)
 	if err != nil {
 		return db.Expression{}, err
 	}
-	if expression.User != userID {
-		return db.Expression{}, errors.New("expression is not found")
+	if !isVisible(expression, userID, e.userTeams(userID)) {
+		return db.Expression{}, ErrNotFound
 	}
 	return expression, nil
 }
 
-// GetNotWorkingExpressions returns all expressions that have Status == ExpressionNotReady.
+// GetNotWorkingExpressions returns all expressions that have Status == ExpressionNotReady in the order they should
+// be calculated (see Scheduler).
 func (e *ExpressionStorage) GetNotWorkingExpressions() []db.Expression {
 	expressions := make([]db.Expression, 0)
+	working := make(map[int]int)
 	e.expressions.Range(func(_, value interface{}) bool {
-		if value.(db.Expression).Status == db.ExpressionNotReady {
+		switch value.(db.Expression).Status {
+		case db.ExpressionNotReady:
 			expressions = append(expressions, value.(db.Expression))
+		case db.ExpressionWorking:
+			working[value.(db.Expression).User]++
 		}
 		return true
 	})
-	return expressions
+	return e.scheduler.Order(expressions, working)
 }
 
 // UpdateExpression updates expression in pendingExpressions and sync with database.
 func (e *ExpressionStorage) UpdateExpression(expression db.Expression) error {
-	if _, ok := e.expressions.Load(expression.ID); !ok {
-		return errors.New("expression is not found")
+	_, err := e.CompareAndSwap(expression.ID, func(current *db.Expression) error {
+		*current = expression
+		return nil
+	})
+	return err
+}
+
+// CompareAndSwap changes the expression atomically. swap gets the current expression, checks it (e.g. its status and
+// server) and changes it, the change is saved only if swap returns nil. Check and write are done under the lock, so
+// concurrent changes (e.g. keep alive of the server and cancel by the user) can not overwrite each other. swap must
+// not call methods of ExpressionStorage.
+func (e *ExpressionStorage) CompareAndSwap(id int, swap func(expression *db.Expression) error) (db.Expression,
+	error) {
+	return e.compareAndSwap(id, swap, e.db.UpdateExpression)
+}
+
+// compareAndSwap is CompareAndSwap that saves the changed expression to the database with write.
+func (e *ExpressionStorage) compareAndSwap(id int, swap func(expression *db.Expression) error,
+	write func(expression db.Expression) error) (db.Expression, error) {
+	e.mu.Lock()
+	value, ok := e.expressions.Load(id)
+	if !ok {
+		e.mu.Unlock()
+		return db.Expression{}, ErrNotFound
 	}
-	e.expressions.Store(expression.ID, expression)
-	// sync with database
-	if err := e.db.UpdateExpression(expression); err != nil {
-		return err
+	previous := value.(db.Expression)
+	expression := previous
+	if err := swap(&expression); err != nil {
+		e.mu.Unlock()
+		return db.Expression{}, err
 	}
-	return nil
+	// the database is written first, so memory is not changed if it fails
+	if err := write(expression); err != nil {
+		e.mu.Unlock()
+		return db.Expression{}, err
+	}
+	e.expressions.Store(id, expression)
+	e.notifyUpdate(previous, expression)
+	e.mu.Unlock()
+	return expression, nil
 }
 
 // IsExpressionWorking returns true if expression is in pendingExpressions and has Status == ExpressionWorking.
@@ -107,7 +257,7 @@ func (e *ExpressionStorage) IsExpressionWorking(id int) (bool, error) {
 	if expression, ok := e.expressions.Load(id); ok {
 		return expression.(db.Expression).Status == db.ExpressionWorking, nil
 	}
-	return false, errors.New("expression is not found")
+	return false, ErrNotFound
 }
 
 // IsExpressionNotReady returns true if expression is in pendingExpressions and has Status == ExpressionNotReady.
@@ -115,22 +265,204 @@ func (e *ExpressionStorage) IsExpressionNotReady(id int) (bool, error) {
 	if expression, ok := e.expressions.Load(id); ok {
 		return expression.(db.Expression).Status == db.ExpressionNotReady, nil
 	}
-	return false, errors.New("expression is not found")
+	return false, ErrNotFound
 }
 
 func (e *ExpressionStorage) Delete(id int) error {
-	if _, ok := e.expressions.Load(id); ok {
-		e.expressions.Delete(id)
-	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	expression, ok := e.expressions.LoadAndDelete(id)
 	// sync with database
 	if err := e.db.DeleteExpression(id); err != nil {
 		return err
 	}
+	if ok {
+		e.notify(EventDeleted, expression.(db.Expression))
+	}
 	return nil
 }
 
+// SetMaxAttempts sets the number of times servers may die while calculating an expression, after that the expression
+// gets ExpressionAbandoned status and is not given to servers until it is requeued.
+func (e *ExpressionStorage) SetMaxAttempts(maxAttempts int) error {
+	if maxAttempts < 1 {
+		return errors.New("max attempts must be bigger than 0")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.maxAttempts = maxAttempts
+	return nil
+}
+
+func (e *ExpressionStorage) getMaxAttempts() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.maxAttempts
+}
+
+// Requeue returns abandoned expression of the user to pending, attempts are reset.
+func (e *ExpressionStorage) Requeue(userID int, id int) (db.Expression, error) {
+	if _, err := e.GetByUserAndID(userID, id); err != nil {
+		return db.Expression{}, err
+	}
+	return e.CompareAndSwap(id, func(expression *db.Expression) error {
+		if expression.Status != db.ExpressionAbandoned {
+			return ErrNotAbandoned
+		}
+		expression.Status = db.ExpressionNotReady
+		expression.Attempts = 0
+		expression.FailedServers = ""
+		expression.Logs = ""
+		expression.Servername = ""
+		return nil
+	})
+}
+
+// Cancel stops calculation of the expression of the user. The server that calculates the expression gets to know
+// about it with the next keep alive message.
+func (e *ExpressionStorage) Cancel(userID int, id int) (db.Expression, error) {
+	if _, err := e.GetByUserAndID(userID, id); err != nil {
+		return db.Expression{}, err
+	}
+	return e.CompareAndSwap(id, func(expression *db.Expression) error {
+		switch expression.Status {
+		case db.ExpressionReady, db.ExpressionError, db.ExpressionCancelled:
+			return ErrFinished
+		}
+		expression.Status = db.ExpressionCancelled
+		expression.EndCalculationTime = time.Now().Format("2006-01-02 15:04:05")
+		expression.Logs = "expression is cancelled by user"
+		return nil
+	})
+}
+
+// Retry queues finished expression of the user again. The previous result is saved as a run of the expression
+// (see GetRuns). If operations is not nil, the new run is calculated with these operation times instead of the
+// operation times of the user.
+func (e *ExpressionStorage) Retry(userID int, id int, operations *db.Operation) (db.Expression, error) {
+	if _, err := e.GetByUserAndID(userID, id); err != nil {
+		return db.Expression{}, err
+	}
+
+	var run db.ExpressionRun
+	return e.compareAndSwap(id, func(expression *db.Expression) error {
+		switch expression.Status {
+		case db.ExpressionNotReady, db.ExpressionWorking:
+			return ErrNotFinished
+		}
+		run = db.ExpressionRun{
+			Expression:         expression.ID,
+			Answer:             expression.Answer,
+			Logs:               expression.Logs,
+			Status:             expression.Status,
+			CreationTime:       expression.CreationTime,
+			EndCalculationTime: expression.EndCalculationTime,
+			Servername:         expression.Servername,
+		}
+
+		expression.Answer = 0
+		expression.Logs = ""
+		expression.Status = db.ExpressionNotReady
+		expression.AliveExpiresAt = 0
+		expression.CreationTime = time.Now().Format("2006-01-02 15:04:05")
+		expression.EndCalculationTime = ""
+		expression.Servername = ""
+		expression.Attempts = 0
+		expression.FailedServers = ""
+		return nil
+	}, func(expression db.Expression) error {
+		// the run, operation times and the expression are saved together, memory is changed after the commit
+		return e.db.RetryExpression(run, expression, operations)
+	})
+}
+
+// DeleteTeam deletes the team, its expressions become personal expressions of their authors.
+func (e *ExpressionStorage) DeleteTeam(teamID int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.db.DeleteTeam(teamID); err != nil {
+		return err
+	}
+	e.expressions.Range(func(key, value interface{}) bool {
+		if expression := value.(db.Expression); expression.Team == teamID {
+			expression.Team = 0
+			e.expressions.Store(key, expression)
+		}
+		return true
+	})
+	return nil
+}
+
+// GetRuns returns previous runs of the expression of the user, the oldest first.
+func (e *ExpressionStorage) GetRuns(userID int, id int) ([]db.ExpressionRun, error) {
+	if _, err := e.GetByUserAndID(userID, id); err != nil {
+		return nil, err
+	}
+	return e.db.GetExpressionRuns(id)
+}
+
+// DeleteByUser deletes the expression of the user, if it is being calculated, the server will stop calculating it.
+// Expression of a team can be deleted by its author or by an owner of the team.
+func (e *ExpressionStorage) DeleteByUser(userID int, id int) error {
+	expression, err := e.GetByUserAndID(userID, id)
+	if err != nil {
+		return err
+	}
+	if expression.User != userID && e.userTeams(userID)[expression.Team] != db.TeamRoleOwner {
+		return ErrNotAllowed
+	}
+	return e.Delete(id)
+}
+
+// release returns working expression to pending without counting an attempt, the server that calculated it is
+// asked to stop with the next keep alive message. If server is not empty, only its lease is ended.
+func (e *ExpressionStorage) release(id int, server string) (db.Expression, error) {
+	return e.CompareAndSwap(id, func(expression *db.Expression) error {
+		if expression.Status != db.ExpressionWorking {
+			return ErrNotWorking
+		}
+		if server != "" && expression.Servername != server {
+			return ErrNotLeased
+		}
+		expression.Status = db.ExpressionNotReady
+		expression.AliveExpiresAt = 0
+		expression.Servername = ""
+		return nil
+	})
+}
+
+// Release ends the lease on the expression (force-release by admin).
+func (e *ExpressionStorage) Release(id int) (db.Expression, error) {
+	return e.release(id, "")
+}
+
+// ReleaseServer ends leases of the server (e.g. the server is revoked), its expressions are returned to pending
+// without counting an attempt. Returns released expressions.
+func (e *ExpressionStorage) ReleaseServer(server string) ([]db.Expression, error) {
+	released := make([]db.Expression, 0)
+	var err error
+	e.expressions.Range(func(_, value interface{}) bool {
+		expression := value.(db.Expression)
+		if expression.Status != db.ExpressionWorking || expression.Servername != server {
+			return true
+		}
+		if expression, err = e.release(expression.ID, server); errors.Is(err, ErrNotWorking) ||
+			errors.Is(err, ErrNotLeased) || errors.Is(err, ErrNotFound) {
+			// the lease is already ended
+			err = nil
+			return true
+		}
+		if err != nil {
+			return false
+		}
+		released = append(released, expression)
+		return true
+	})
+	return released, err
+}
+
 // keepAliveExpressions checks all expressions and if aliveExpiresAt is less than now, then change to not ready,
-// so it will be calculated again via getUpdates.
+// so it will be calculated again via getUpdates. If servers died too many times on the expression, it is abandoned.
 func (e *ExpressionStorage) keepAliveExpressions() {
 	// check all expressions and if aliveExpiresAt is less than now, then change to not ready
 	for {
@@ -142,35 +474,77 @@ func (e *ExpressionStorage) keepAliveExpressions() {
 			}
 
 			if expression.Status == db.ExpressionWorking && expression.AliveExpiresAt < int(time.Now().Unix()) {
-				// change to not ready, so it will be calculated again
-				zap.S().Info(fmt.Sprintf("expression ID %v is not alive, change to not ready."+
-					" Dead server: %v", expression.ID, expression.Servername))
-
-				e.serverStatus.Range(func(key, _ interface{}) bool {
-					zap.S().Info(fmt.Sprintf("%v", key))
-					if expression.Servername == key.(string) {
-						e.serverStatus.Store(key, fmt.Sprintf("%v -> server %v is not alive",
-							time.Now().Format("01-02-2006 15:04:05"), expression.Servername))
-					}
-					return true
-				})
-				expression.Status = db.ExpressionNotReady
-				e.expressions.Store(key, expression)
-				// sync with database
-				if err := e.db.UpdateExpression(expression); err != nil {
-					zap.S().Error(err)
-				}
+				e.expireLease(key.(int))
 			}
 			return true
 		})
 	}
 }
 
-// GetByServer returns all expressions that have ServerName == server.
+// expireLease returns the expression with the expired lease to pending, or abandons it if servers died too many
+// times on it. The lease is checked again under the lock, so a keep alive or a cancel that came in between is kept.
+func (e *ExpressionStorage) expireLease(id int) {
+	maxAttempts := e.getMaxAttempts()
+	now := int(time.Now().Unix())
+	var dead string
+	_, err := e.CompareAndSwap(id, func(expression *db.Expression) error {
+		if expression.Status != db.ExpressionWorking || expression.AliveExpiresAt >= now {
+			return ErrNotLeased
+		}
+		dead = expression.Servername
+		// change to not ready, so it will be calculated again
+		zap.S().Info(fmt.Sprintf("expression ID %v is not alive, change to not ready."+
+			" Dead server: %v", expression.ID, expression.Servername))
+		expression.Attempts++
+		if expression.FailedServers != "" {
+			expression.FailedServers += ", "
+		}
+		expression.FailedServers += expression.Servername
+		expression.Status = db.ExpressionNotReady
+		if expression.Attempts >= maxAttempts {
+			zap.S().Warn(fmt.Sprintf("expression ID %v is abandoned after %v attempts. Failed servers: %v",
+				expression.ID, expression.Attempts, expression.FailedServers))
+			expression.Status = db.ExpressionAbandoned
+			expression.Logs = fmt.Sprintf("expression is abandoned after %v attempts, failed servers: %v",
+				expression.Attempts, expression.FailedServers)
+		}
+		return nil
+	})
+	if errors.Is(err, ErrNotLeased) || errors.Is(err, ErrNotFound) {
+		return
+	}
+	if err != nil {
+		zap.S().Error(err)
+		return
+	}
+
+	e.serverStatus.Range(func(key, _ interface{}) bool {
+		if dead == key.(string) {
+			e.serverStatus.Store(key, fmt.Sprintf("%v -> server %v is not alive",
+				time.Now().Format("01-02-2006 15:04:05"), dead))
+		}
+		return true
+	})
+}
+
+// GetAllByServer returns expressions of all users that have ServerName == server.
+func (e *ExpressionStorage) GetAllByServer(server string) []db.Expression {
+	expressions := make([]db.Expression, 0)
+	e.expressions.Range(func(_, value interface{}) bool {
+		if value.(db.Expression).Servername == server {
+			expressions = append(expressions, value.(db.Expression))
+		}
+		return true
+	})
+	return expressions
+}
+
+// GetByServer returns all expressions visible to the user (see GetAll) that have ServerName == server.
 func (e *ExpressionStorage) GetByServer(userID int, server string) []db.Expression {
 	expressions := make([]db.Expression, 0)
+	teams := e.userTeams(userID)
 	e.expressions.Range(func(_, value interface{}) bool {
-		if value.(db.Expression).Servername == server && value.(db.Expression).User == userID {
+		if value.(db.Expression).Servername == server && isVisible(value.(db.Expression), userID, teams) {
 			expressions = append(expressions, value.(db.Expression))
 		}
 		return true
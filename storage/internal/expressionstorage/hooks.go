@@ -0,0 +1,70 @@
+package expressionstorage
+
+import (
+	"storage/internal/db"
+)
+
+// types of changes of expressions
+const (
+	EventCreated  = "created"
+	EventStatus   = "status"   // status is changed, but the expression is not finished
+	EventProgress = "progress" // server that calculates the expression is alive
+	EventFinished = "finished" // expression is calculated, failed, abandoned or cancelled
+	EventDeleted  = "deleted"
+)
+
+// Event is a change of an expression, Expression is the expression after the change.
+type Event struct {
+	Type       string
+	Expression db.Expression
+}
+
+// Hook is called after every change of expressions while changes are locked, it must not block or change expressions.
+type Hook func(event Event)
+
+// AddHook adds the hook that is called after every change of expressions.
+func (e *ExpressionStorage) AddHook(hook Hook) {
+	e.hooksMu.Lock()
+	defer e.hooksMu.Unlock()
+	e.hooks = append(e.hooks, hook)
+}
+
+// notify must be called with e.mu locked, so events of an expression are not reordered.
+func (e *ExpressionStorage) notify(eventType string, expression db.Expression) {
+	e.hooksMu.Lock()
+	hooks := e.hooks
+	e.hooksMu.Unlock()
+	for _, hook := range hooks {
+		hook(Event{Type: eventType, Expression: expression})
+	}
+	if eventType == EventFinished || eventType == EventDeleted {
+		e.wakeWaiters(eventType, expression)
+	}
+}
+
+// notifyUpdate notifies hooks about the change of the expression from previous.
+func (e *ExpressionStorage) notifyUpdate(previous db.Expression, expression db.Expression) {
+	switch {
+	case IsFinished(expression.Status):
+		if !IsFinished(previous.Status) || previous.Answer != expression.Answer || previous.Logs != expression.Logs {
+			e.notify(EventFinished, expression)
+		}
+	case previous.Status != expression.Status:
+		e.notify(EventStatus, expression)
+	case expression.Status == db.ExpressionWorking:
+		e.notify(EventProgress, expression)
+	}
+}
+
+// IsFinished returns true for statuses of expressions that are not calculated anymore.
+func IsFinished(status int) bool {
+	return status != db.ExpressionNotReady && status != db.ExpressionWorking
+}
+
+// VisibleTo returns function that checks if the user can see the expression. Teams of the user are read once.
+func (e *ExpressionStorage) VisibleTo(userID int) func(db.Expression) bool {
+	teams := e.userTeams(userID)
+	return func(expression db.Expression) bool {
+		return isVisible(expression, userID, teams)
+	}
+}
@@ -0,0 +1,224 @@
+package expressionstorage
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"storage/internal/apierrors"
+	"storage/internal/db"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sort fields of listings
+const (
+	SortCreationTime = "creation_time"
+	SortEndTime      = "end_calculation_time"
+	SortDuration     = "duration"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+var (
+	ErrInvalidSort   = apierrors.New(apierrors.CodeInvalidArgument, "sort must be creation_time, end_calculation_time or duration")
+	ErrInvalidCursor = apierrors.New(apierrors.CodeInvalidArgument, "cursor is invalid")
+)
+
+// Query selects a page of expressions. Zero values do not filter, Limit 0 returns all expressions after the cursor.
+type Query struct {
+	Statuses      []int
+	CreatedAfter  time.Time // inclusive
+	CreatedBefore time.Time // exclusive
+	Server        string
+	Contains      string // substring of the value
+	Sort          string // SortCreationTime by default
+	Descending    bool
+	Cursor        string // NextCursor of the previous page
+	Limit         int
+}
+
+// Page is a part of the listing. Counts are numbers of expressions by status that match the query without the status
+// filter, Total is the number of expressions that match the whole query. Expressions that are not finished are not
+// listed when they are sorted by SortEndTime or SortDuration.
+type Page struct {
+	Expressions []db.Expression
+	NextCursor  string
+	Counts      map[int]int
+	Total       int
+}
+
+// List returns a page of expressions of the user and of their teams.
+func (e *ExpressionStorage) List(userID int, query Query) (Page, error) {
+	return Select(e.GetAll(userID), query)
+}
+
+// Select filters and sorts expressions and returns the page after query.Cursor. Expressions with equal sort keys are
+// ordered by ID, so pages do not overlap even if expressions are added or deleted between requests.
+func Select(expressions []db.Expression, query Query) (Page, error) {
+	key, err := sortKey(query.Sort)
+	if err != nil {
+		return Page{}, err
+	}
+	sortName := query.Sort
+	if sortName == "" {
+		sortName = SortCreationTime
+	}
+	less := func(a, b position) bool {
+		if a.key != b.key {
+			return (a.key < b.key) != query.Descending
+		}
+		if a.id != b.id {
+			return (a.id < b.id) != query.Descending
+		}
+		return false
+	}
+	positionOf := func(expression db.Expression) position {
+		value, _ := key(expression)
+		return position{key: value, id: expression.ID}
+	}
+
+	var after *position
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor, sortName, query.Descending)
+		if err != nil {
+			return Page{}, err
+		}
+		after = &cursor
+	}
+
+	page := Page{Expressions: make([]db.Expression, 0), Counts: make(map[int]int)}
+	for _, expression := range expressions {
+		if !query.matches(expression) {
+			continue
+		}
+		if _, ok := key(expression); !ok {
+			continue
+		}
+		page.Counts[expression.Status]++
+		if !query.hasStatus(expression.Status) {
+			continue
+		}
+		page.Total++
+		if after == nil || less(*after, positionOf(expression)) {
+			page.Expressions = append(page.Expressions, expression)
+		}
+	}
+	sort.Slice(page.Expressions, func(i, j int) bool {
+		return less(positionOf(page.Expressions[i]), positionOf(page.Expressions[j]))
+	})
+
+	if query.Limit > 0 && len(page.Expressions) > query.Limit {
+		page.Expressions = page.Expressions[:query.Limit]
+		page.NextCursor = encodeCursor(positionOf(page.Expressions[query.Limit-1]), sortName, query.Descending)
+	}
+	return page, nil
+}
+
+// position of an expression in the sorted listing
+type position struct {
+	key int64
+	id  int
+}
+
+// matches checks all filters except statuses.
+func (q Query) matches(expression db.Expression) bool {
+	if q.Server != "" && expression.Servername != q.Server {
+		return false
+	}
+	if q.Contains != "" && !strings.Contains(expression.Value, q.Contains) {
+		return false
+	}
+	if !q.CreatedAfter.IsZero() || !q.CreatedBefore.IsZero() {
+		created, err := parseTime(expression.CreationTime)
+		if err != nil {
+			return false
+		}
+		if !q.CreatedAfter.IsZero() && created.Before(q.CreatedAfter) {
+			return false
+		}
+		if !q.CreatedBefore.IsZero() && !created.Before(q.CreatedBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q Query) hasStatus(status int) bool {
+	if len(q.Statuses) == 0 {
+		return true
+	}
+	for _, s := range q.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// sortKey returns function that gives the sort key of an expression and false if the expression has no such key.
+// Expressions that are not finished have no end time and duration, they are not listed with such sorts: their keys
+// would change when they finish, so cursors of pages would skip or repeat them.
+func sortKey(name string) (func(db.Expression) (int64, bool), error) {
+	unix := func(value string) (int64, bool) {
+		t, err := parseTime(value)
+		if err != nil {
+			return 0, false
+		}
+		return t.Unix(), true
+	}
+	switch name {
+	case "", SortCreationTime:
+		return func(expression db.Expression) (int64, bool) {
+			if created, ok := unix(expression.CreationTime); ok {
+				return created, true
+			}
+			return -1, true
+		}, nil
+	case SortEndTime:
+		return func(expression db.Expression) (int64, bool) {
+			return unix(expression.EndCalculationTime)
+		}, nil
+	case SortDuration:
+		return func(expression db.Expression) (int64, bool) {
+			end, ok := unix(expression.EndCalculationTime)
+			if !ok {
+				return 0, false
+			}
+			start, ok := unix(expression.CreationTime)
+			if !ok {
+				return 0, false
+			}
+			return end - start, true
+		}, nil
+	}
+	return nil, ErrInvalidSort
+}
+
+func parseTime(value string) (time.Time, error) {
+	return time.ParseInLocation(timeLayout, value, time.Local)
+}
+
+// cursor is "sort:descending:key:id", it is valid only with the same sort and order.
+func encodeCursor(p position, sortName string, descending bool) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%v:%v:%v:%v", sortName, descending, p.key, p.id)))
+}
+
+func decodeCursor(cursor string, sortName string, descending bool) (position, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return position{}, ErrInvalidCursor
+	}
+	parts := strings.Split(string(data), ":")
+	if len(parts) != 4 || parts[0] != sortName || parts[1] != strconv.FormatBool(descending) {
+		return position{}, ErrInvalidCursor
+	}
+	var p position
+	if p.key, err = strconv.ParseInt(parts[2], 10, 64); err != nil {
+		return position{}, ErrInvalidCursor
+	}
+	if p.id, err = strconv.Atoi(parts[3]); err != nil {
+		return position{}, ErrInvalidCursor
+	}
+	return p, nil
+}
@@ -0,0 +1,81 @@
+package expressionstorage
+
+import (
+	"errors"
+	"sort"
+	"storage/internal/db"
+)
+
+const (
+	MinPriority = 0
+	MaxPriority = 10
+)
+
+// Scheduler orders pending expressions for calculation servers. Expressions with higher priority always go first,
+// inside one priority users are served round-robin, so one user with a lot of expressions can not starve everyone
+// else.
+type Scheduler struct{}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+func IsPriorityCorrect(priority int) error {
+	if priority < MinPriority || priority > MaxPriority {
+		return errors.New("priority must be between 0 and 10")
+	}
+	return nil
+}
+
+type scheduledExpression struct {
+	expression db.Expression
+	turn       int
+}
+
+// Order returns pending expressions in the order they should be calculated. working is the number of expressions
+// that are being calculated right now for each user, users that already occupy servers are moved back.
+// The result is deterministic: priority desc, turn of the user asc, user id asc, expression id asc.
+func (s *Scheduler) Order(pending []db.Expression, working map[int]int) []db.Expression {
+	// expressions of every user are taken in FIFO order
+	sorted := make([]db.Expression, len(pending))
+	copy(sorted, pending)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	type queueKey struct {
+		priority int
+		user     int
+	}
+	served := make(map[queueKey]int)
+	scheduled := make([]scheduledExpression, 0, len(sorted))
+	for _, expression := range sorted {
+		key := queueKey{priority: expression.Priority, user: expression.User}
+		served[key]++
+		// n-th expression of the user goes in n-th turn
+		scheduled = append(scheduled, scheduledExpression{
+			expression: expression,
+			turn:       served[key] + working[expression.User],
+		})
+	}
+
+	sort.Slice(scheduled, func(i, j int) bool {
+		a, b := scheduled[i], scheduled[j]
+		if a.expression.Priority != b.expression.Priority {
+			return a.expression.Priority > b.expression.Priority
+		}
+		if a.turn != b.turn {
+			return a.turn < b.turn
+		}
+		if a.expression.User != b.expression.User {
+			return a.expression.User < b.expression.User
+		}
+		return a.expression.ID < b.expression.ID
+	})
+
+	res := make([]db.Expression, 0, len(scheduled))
+	for _, el := range scheduled {
+		res = append(res, el.expression)
+	}
+	return res
+}
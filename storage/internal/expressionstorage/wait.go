@@ -0,0 +1,72 @@
+package expressionstorage
+
+import (
+	"context"
+	"storage/internal/db"
+)
+
+// Wait returns the expression of the user when it is finished, or the current expression when ctx is done. Waiters
+// are woken by UpdateExpression (e.g. when servers post results), so nothing polls.
+func (e *ExpressionStorage) Wait(ctx context.Context, userID int, id int) (db.Expression, error) {
+	// the waiter is added before the expression is read, so the result can not be missed in between
+	waiter := make(chan db.Expression, 1)
+	e.addWaiter(id, waiter)
+	defer e.removeWaiter(id, waiter)
+
+	expression, err := e.GetByUserAndID(userID, id)
+	if err != nil || IsFinished(expression.Status) {
+		return expression, err
+	}
+	select {
+	case expression, ok := <-waiter:
+		if !ok {
+			// the expression is deleted
+			return db.Expression{}, ErrNotFound
+		}
+		return expression, nil
+	case <-ctx.Done():
+		return e.GetByUserAndID(userID, id)
+	}
+}
+
+func (e *ExpressionStorage) addWaiter(id int, waiter chan db.Expression) {
+	e.hooksMu.Lock()
+	defer e.hooksMu.Unlock()
+	if e.waiters == nil {
+		e.waiters = make(map[int][]chan db.Expression)
+	}
+	e.waiters[id] = append(e.waiters[id], waiter)
+}
+
+func (e *ExpressionStorage) removeWaiter(id int, waiter chan db.Expression) {
+	e.hooksMu.Lock()
+	defer e.hooksMu.Unlock()
+	waiters := e.waiters[id]
+	for i := range waiters {
+		if waiters[i] == waiter {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(e.waiters, id)
+	} else {
+		e.waiters[id] = waiters
+	}
+}
+
+// wakeWaiters sends the finished expression to its waiters, or closes them if the expression is deleted.
+func (e *ExpressionStorage) wakeWaiters(eventType string, expression db.Expression) {
+	e.hooksMu.Lock()
+	waiters := e.waiters[expression.ID]
+	delete(e.waiters, expression.ID)
+	e.hooksMu.Unlock()
+	for _, waiter := range waiters {
+		if eventType == EventDeleted {
+			close(waiter)
+		} else {
+			// buffer of waiters is 1 and they are woken once
+			waiter <- expression
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package gRPCServer
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"storage/internal/apierrors"
+)
+
+// correlationIDKey is the metadata key with the correlation ID of the call, calculation servers may send their own.
+const correlationIDKey = "x-correlation-id"
+
+// ErrorsUnaryInterceptor returns errors as status with ErrorInfo details (see apierrors). Unexpected errors are logged
+// with a correlation ID and only the ID is sent. It must be the first interceptor to convert errors of the others.
+func ErrorsUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, statusError(ctx, info.FullMethod, err)
+	}
+	return resp, nil
+}
+
+// ErrorsStreamInterceptor is ErrorsUnaryInterceptor of streams.
+func ErrorsStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	if err := handler(srv, ss); err != nil {
+		return statusError(ss.Context(), info.FullMethod, err)
+	}
+	return nil
+}
+
+// statusError converts the error of the method to status with details.
+func statusError(ctx context.Context, method string, err error) error {
+	var apiErr *apierrors.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.GRPCStatus().Err()
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		err = status.FromContextError(err).Err()
+	}
+	if st, ok := status.FromError(err); ok {
+		// the client does not wait for the answer of cancelled calls
+		if len(st.Details()) > 0 || st.Code() == codes.Canceled {
+			return err
+		}
+		if kind := apierrors.ForGRPCCode(st.Code()); kind.Code != apierrors.CodeInternal {
+			return apierrors.Status(kind, st.Message(), "").Err()
+		}
+	}
+
+	correlationID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(correlationIDKey)) > 0 {
+		correlationID = md.Get(correlationIDKey)[0]
+	}
+	if !apierrors.IsValidCorrelationID(correlationID) {
+		correlationID = apierrors.NewCorrelationID()
+	}
+	zap.S().Errorw("internal error", "correlation_id", correlationID, "method", method, "error", err)
+	return apierrors.Status(apierrors.Lookup(apierrors.CodeInternal),
+		"internal error, report the correlation_id to administrators", correlationID).Err()
+}
@@ -25,8 +25,9 @@ type ExpressionsServiceClient interface {
 	GetUpdates(ctx context.Context, in *Empty, opts ...grpc.CallOption) (ExpressionsService_GetUpdatesClient, error)
 	ConfirmStartCalculating(ctx context.Context, in *Expression, opts ...grpc.CallOption) (*Confirm, error)
 	PostResult(ctx context.Context, in *Expression, opts ...grpc.CallOption) (*Message, error)
-	KeepAlive(ctx context.Context, in *KeepAliveMsg, opts ...grpc.CallOption) (*Empty, error)
+	KeepAlive(ctx context.Context, in *KeepAliveMsg, opts ...grpc.CallOption) (*KeepAliveAnswer, error)
 	GetOperationsAndTimes(ctx context.Context, in *Expression, opts ...grpc.CallOption) (*OperationsAndTimes, error)
+	Enroll(ctx context.Context, in *EnrollRequest, opts ...grpc.CallOption) (*EnrollAnswer, error)
 }
 
 type expressionsServiceClient struct {
@@ -87,8 +88,8 @@ func (c *expressionsServiceClient) PostResult(ctx context.Context, in *Expressio
 	return out, nil
 }
 
-func (c *expressionsServiceClient) KeepAlive(ctx context.Context, in *KeepAliveMsg, opts ...grpc.CallOption) (*Empty, error) {
-	out := new(Empty)
+func (c *expressionsServiceClient) KeepAlive(ctx context.Context, in *KeepAliveMsg, opts ...grpc.CallOption) (*KeepAliveAnswer, error) {
+	out := new(KeepAliveAnswer)
 	err := c.cc.Invoke(ctx, "/storage.ExpressionsService/KeepAlive", in, out, opts...)
 	if err != nil {
 		return nil, err
@@ -105,6 +106,15 @@ func (c *expressionsServiceClient) GetOperationsAndTimes(ctx context.Context, in
 	return out, nil
 }
 
+func (c *expressionsServiceClient) Enroll(ctx context.Context, in *EnrollRequest, opts ...grpc.CallOption) (*EnrollAnswer, error) {
+	out := new(EnrollAnswer)
+	err := c.cc.Invoke(ctx, "/storage.ExpressionsService/Enroll", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ExpressionsServiceServer is the server API for ExpressionsService service.
 // All implementations must embed UnimplementedExpressionsServiceServer
 // for forward compatibility
@@ -112,8 +122,9 @@ type ExpressionsServiceServer interface {
 	GetUpdates(*Empty, ExpressionsService_GetUpdatesServer) error
 	ConfirmStartCalculating(context.Context, *Expression) (*Confirm, error)
 	PostResult(context.Context, *Expression) (*Message, error)
-	KeepAlive(context.Context, *KeepAliveMsg) (*Empty, error)
+	KeepAlive(context.Context, *KeepAliveMsg) (*KeepAliveAnswer, error)
 	GetOperationsAndTimes(context.Context, *Expression) (*OperationsAndTimes, error)
+	Enroll(context.Context, *EnrollRequest) (*EnrollAnswer, error)
 	mustEmbedUnimplementedExpressionsServiceServer()
 }
 
@@ -130,12 +141,15 @@ func (UnimplementedExpressionsServiceServer) ConfirmStartCalculating(context.Con
 func (UnimplementedExpressionsServiceServer) PostResult(context.Context, *Expression) (*Message, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PostResult not implemented")
 }
-func (UnimplementedExpressionsServiceServer) KeepAlive(context.Context, *KeepAliveMsg) (*Empty, error) {
+func (UnimplementedExpressionsServiceServer) KeepAlive(context.Context, *KeepAliveMsg) (*KeepAliveAnswer, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method KeepAlive not implemented")
 }
 func (UnimplementedExpressionsServiceServer) GetOperationsAndTimes(context.Context, *Expression) (*OperationsAndTimes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetOperationsAndTimes not implemented")
 }
+func (UnimplementedExpressionsServiceServer) Enroll(context.Context, *EnrollRequest) (*EnrollAnswer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Enroll not implemented")
+}
 func (UnimplementedExpressionsServiceServer) mustEmbedUnimplementedExpressionsServiceServer() {}
 
 // UnsafeExpressionsServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -242,6 +256,24 @@ func _ExpressionsService_GetOperationsAndTimes_Handler(srv interface{}, ctx cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ExpressionsService_Enroll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnrollRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExpressionsServiceServer).Enroll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storage.ExpressionsService/Enroll",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExpressionsServiceServer).Enroll(ctx, req.(*EnrollRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ExpressionsService_ServiceDesc is the grpc.ServiceDesc for ExpressionsService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -265,6 +297,10 @@ var ExpressionsService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetOperationsAndTimes",
 			Handler:    _ExpressionsService_GetOperationsAndTimes_Handler,
 		},
+		{
+			MethodName: "Enroll",
+			Handler:    _ExpressionsService_Enroll_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
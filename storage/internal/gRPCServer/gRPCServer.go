@@ -2,11 +2,13 @@ package gRPCServer
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"go.uber.org/zap"
 	"os"
 	"storage/internal/api"
+	"storage/internal/apierrors"
 	"storage/internal/availableservers"
 	"storage/internal/db"
 	"storage/internal/expressionstorage"
@@ -52,6 +54,7 @@ func dbExpressionTogRPCExpression(expression db.Expression) *Expression {
 		EndCalculationTime: expression.EndCalculationTime,
 		ServerName:         expression.Servername,
 		UserId:             int64(expression.User),
+		Priority:           int32(expression.Priority),
 	}
 }
 
@@ -67,6 +70,7 @@ func gRPCExpressionTodbExpression(expression *Expression) db.Expression {
 		EndCalculationTime: expression.EndCalculationTime,
 		Servername:         expression.ServerName,
 		User:               int(expression.UserId),
+		Priority:           int(expression.Priority),
 	}
 }
 
@@ -82,23 +86,21 @@ func (s *Server) GetUpdates(_ *Empty, stream ExpressionsService_GetUpdatesServer
 }
 
 func (s *Server) ConfirmStartCalculating(_ context.Context, e *Expression) (*Confirm, error) {
-	expression := gRPCExpressionTodbExpression(e)
-	ok, err := s.expressions.IsExpressionNotReady(expression.ID)
+	// fields that are controlled by storage (attempts, priority...) are taken from storage, not from the server
+	expression, err := s.expressions.CompareAndSwap(int(e.Id), func(expression *db.Expression) error {
+		if expression.Status != db.ExpressionNotReady {
+			return apierrors.New(apierrors.CodeConflict, "expression is not in pending")
+		}
+		expression.Servername = e.ServerName
+
+		// change to working
+		expression.Status = db.ExpressionWorking
+		expression.AliveExpiresAt = int(time.Now().Add(time.Duration(s.checkAlive) * time.Second).Unix())
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	if !ok {
-		return nil, errors.New("expression is not in pending")
-	}
-
-	// change to working
-	expression.Status = db.ExpressionWorking
-	expression.AliveExpiresAt = int(time.Now().Add(time.Duration(s.checkAlive) * time.Second).Unix())
-	if err = s.expressions.UpdateExpression(expression); err != nil {
-		return &Confirm{
-			Confirm: false,
-		}, err
-	}
 
 	// add server
 	s.servers.Add(expression.Servername)
@@ -108,20 +110,32 @@ func (s *Server) ConfirmStartCalculating(_ context.Context, e *Expression) (*Con
 }
 
 func (s *Server) PostResult(_ context.Context, e *Expression) (*Message, error) {
-	// check if expression is in working
-	expression := gRPCExpressionTodbExpression(e)
-	ok, err := s.expressions.IsExpressionWorking(expression.ID)
-	if err != nil {
-		return nil, err
+	result := gRPCExpressionTodbExpression(e)
+	// the result is a finished calculation, the server can not put the expression into another state
+	if result.Status != db.ExpressionReady && result.Status != db.ExpressionError {
+		return nil, apierrors.New(apierrors.CodeInvalidArgument, "status of the result must be ready or error")
 	}
-	if !ok {
+	expression, err := s.expressions.CompareAndSwap(int(e.Id), func(expression *db.Expression) error {
+		// check if expression is in working
+		if expression.Status != db.ExpressionWorking {
+			return expressionstorage.ErrNotWorking
+		}
+		// only the server that holds the lease can post the result
+		if expression.Servername != result.Servername {
+			return expressionstorage.ErrNotLeased
+		}
+		expression.Answer = result.Answer
+		expression.Logs = result.Logs
+		expression.Status = result.Status
+		expression.EndCalculationTime = time.Now().Format("2006-01-02 15:04:05")
+		return nil
+	})
+	if errors.Is(err, expressionstorage.ErrNotWorking) || errors.Is(err, expressionstorage.ErrNotLeased) {
 		return &Message{
-			Message: "expression is not in working",
-		}, err
+			Message: err.Error(),
+		}, nil
 	}
-
-	expression.EndCalculationTime = time.Now().Format("2006-01-02 15:04:05")
-	if err = s.expressions.UpdateExpression(expression); err != nil {
+	if err != nil {
 		return nil, err
 	}
 
@@ -134,25 +148,52 @@ func (s *Server) PostResult(_ context.Context, e *Expression) (*Message, error)
 	}, nil
 }
 
-func (s *Server) KeepAlive(_ context.Context, msg *KeepAliveMsg) (*Empty, error) {
-	expression := gRPCExpressionTodbExpression(msg.Expression)
-	expression, err := s.expressions.GetByID(expression.ID)
-	if err != nil {
-		return nil, err
+// KeepAlive extends the lease of the server on the expression. If the expression was cancelled, deleted or given to
+// another server, the server is asked to stop calculating it.
+func (s *Server) KeepAlive(_ context.Context, msg *KeepAliveMsg) (*KeepAliveAnswer, error) {
+	// the lease is extended only if the server still holds it, a cancel or release in between is not overwritten
+	expression, err := s.expressions.CompareAndSwap(int(msg.Expression.Id), func(expression *db.Expression) error {
+		if expression.Status != db.ExpressionWorking || expression.Servername != msg.Expression.ServerName {
+			return expressionstorage.ErrNotLeased
+		}
+		expression.AliveExpiresAt = int(time.Now().Add(time.Duration(s.checkAlive) * time.Second).Unix())
+		return nil
+	})
+	if errors.Is(err, expressionstorage.ErrNotFound) {
+		zap.S().Info(fmt.Sprintf("expression ID %v is not found, cancel calculation on %v", msg.Expression.Id,
+			msg.Expression.ServerName))
+		return &KeepAliveAnswer{Cancel: true}, nil
+	}
+	if errors.Is(err, expressionstorage.ErrNotLeased) {
+		zap.S().Info(fmt.Sprintf("expression ID %v is not calculated by %v anymore, cancel calculation",
+			msg.Expression.Id, msg.Expression.ServerName))
+		s.statusWorkers.Store(msg.Expression.ServerName, fmt.Sprintf("%v -> server %v cancelled calculating %v",
+			time.Now().Format("01-02-2006 15:04:05"), msg.Expression.ServerName, msg.Expression.Value))
+		return &KeepAliveAnswer{Cancel: true}, nil
 	}
-
-	expression.AliveExpiresAt = int(time.Now().Add(time.Duration(s.checkAlive) * time.Second).Unix())
-	err = s.expressions.UpdateExpression(expression)
 	if err != nil {
 		return nil, err
 	}
 
 	s.statusWorkers.Store(expression.Servername, msg.StatusWorkers)
-	return nil, nil
+	return &KeepAliveAnswer{Cancel: false}, nil
 }
 
+// GetOperationsAndTimes returns operation times for the expression, if they were not set for the expression
+// (see ExpressionStorage.Retry), operation times of the team of the expression or of the user are returned.
 func (s *Server) GetOperationsAndTimes(_ context.Context, e *Expression) (*OperationsAndTimes, error) {
-	operations, err := s.db.GetUserOperations(int(e.UserId))
+	operations, err := s.db.GetExpressionOperations(int(e.Id))
+	if errors.Is(err, sql.ErrNoRows) {
+		var expression db.Expression
+		if expression, err = s.db.GetExpressionByID(int(e.Id)); err == nil && expression.Team != 0 {
+			operations, err = s.db.GetTeamOperations(expression.Team)
+		} else {
+			err = sql.ErrNoRows
+		}
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		operations, err = s.db.GetUserOperations(int(e.UserId))
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,96 @@
+package gRPCServer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"os"
+)
+
+// LoadTLSConfig creates TLS config for the gRPC server from PEM files. If caFile is not empty, calculation servers
+// must present a certificate signed by this CA (mutual TLS).
+func LoadTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("no certificates found in CA file")
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// serverNameFromRequest returns the name of the calculation server that sent the request.
+func serverNameFromRequest(req interface{}) (string, bool) {
+	switch r := req.(type) {
+	case *Expression:
+		return r.GetServerName(), true
+	case *KeepAliveMsg:
+		return r.GetExpression().GetServerName(), true
+	case *EnrollRequest:
+		return r.GetServerName(), true
+	}
+	return "", false
+}
+
+// isCertificateFor returns true if CN or one of DNS SANs of the certificate is name.
+func isCertificateFor(cert *x509.Certificate, name string) bool {
+	if name == "" {
+		return false
+	}
+	if cert.Subject.CommonName == name {
+		return true
+	}
+	for _, dnsName := range cert.DNSNames {
+		if dnsName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ServerNameInterceptor checks that calculation server uses its own name, i.e. the name in the request matches
+// the CN/SAN of the client certificate. Requests without client certificates (no mutual TLS) are not checked.
+func ServerNameInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return handler(ctx, req)
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return handler(ctx, req)
+	}
+
+	name, ok := serverNameFromRequest(req)
+	if !ok {
+		return handler(ctx, req)
+	}
+	cert := tlsInfo.State.VerifiedChains[0][0]
+	if !isCertificateFor(cert, name) {
+		return nil, status.Error(codes.PermissionDenied,
+			fmt.Sprintf("certificate of %v does not allow to use server name %v", cert.Subject.CommonName, name))
+	}
+	return handler(ctx, req)
+}
@@ -0,0 +1,88 @@
+package gRPCServer
+
+import (
+	"context"
+	"crypto/subtle"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"storage/internal/cryptPasswords"
+	"storage/internal/db"
+	"strings"
+	"time"
+)
+
+const enrollMethod = "/storage.ExpressionsService/Enroll"
+
+// Enroll exchanges the enrollment token issued by an admin for the credential of the worker. Enrollment token can
+// be used only once, enrolling again replaces the credential.
+func (s *Server) Enroll(_ context.Context, req *EnrollRequest) (*EnrollAnswer, error) {
+	worker, err := s.db.GetWorkerByName(req.ServerName)
+	if err != nil || worker.Revoked || worker.EnrollmentHash == "" ||
+		worker.EnrollmentExpiresAt < int(time.Now().Unix()) ||
+		subtle.ConstantTimeCompare([]byte(worker.EnrollmentHash),
+			[]byte(cryptPasswords.HashToken(req.EnrollmentToken))) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "enrollment token is not valid")
+	}
+
+	credential, err := cryptPasswords.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	worker.CredentialHash = cryptPasswords.HashToken(credential)
+	worker.EnrollmentHash = ""
+	worker.EnrollmentExpiresAt = 0
+	if err = s.db.UpdateWorker(worker); err != nil {
+		return nil, err
+	}
+	return &EnrollAnswer{Credential: credential}, nil
+}
+
+// WorkerAuth checks credentials of calculation servers, every call except Enroll must have
+// "authorization: Bearer <credential>" metadata of an enrolled and not revoked worker.
+type WorkerAuth struct {
+	db *db.APIDb
+}
+
+func NewWorkerAuth(d *db.APIDb) *WorkerAuth {
+	return &WorkerAuth{db: d}
+}
+
+func (w *WorkerAuth) authenticate(ctx context.Context) (db.Worker, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get("authorization")) == 0 {
+		return db.Worker{}, status.Error(codes.Unauthenticated, "credential is not provided")
+	}
+	credential := strings.TrimPrefix(md.Get("authorization")[0], "Bearer ")
+	worker, err := w.db.GetWorkerByCredential(cryptPasswords.HashToken(credential))
+	if err != nil || worker.Revoked {
+		return db.Worker{}, status.Error(codes.Unauthenticated, "credential is not valid")
+	}
+	return worker, nil
+}
+
+// UnaryInterceptor authenticates the worker and checks that it uses its own name.
+func (w *WorkerAuth) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if info.FullMethod == enrollMethod {
+		return handler(ctx, req)
+	}
+	worker, err := w.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := serverNameFromRequest(req); ok && name != worker.Name {
+		return nil, status.Errorf(codes.PermissionDenied, "worker %v can not use server name %v", worker.Name, name)
+	}
+	return handler(ctx, req)
+}
+
+// StreamInterceptor authenticates the worker.
+func (w *WorkerAuth) StreamInterceptor(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	if _, err := w.authenticate(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,74 @@
+package jwtkeys
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// EncryptionKeySize is the size of the key that encrypts private keys in database (AES-256).
+const EncryptionKeySize = 32
+
+// encryptedPrefix marks encrypted private keys, keys without it are PEM saved by older versions.
+const encryptedPrefix = "aes256gcm:"
+
+var (
+	ErrEncryptionKeySize = errors.New("encryption key of signing keys must be 32 bytes")
+	ErrDecryption        = errors.New("private key can not be decrypted, the encryption key is wrong")
+)
+
+// sealer encrypts private keys with AES-GCM before they are saved to database. The kid is authenticated with the key,
+// so an encrypted key can not be moved to another row.
+type sealer struct {
+	aead cipher.AEAD
+}
+
+func newSealer(key []byte) (sealer, error) {
+	if len(key) != EncryptionKeySize {
+		return sealer{}, ErrEncryptionKeySize
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return sealer{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return sealer{}, err
+	}
+	return sealer{aead: aead}, nil
+}
+
+// encrypted returns true if the private key was saved encrypted.
+func encrypted(stored string) bool {
+	return strings.HasPrefix(stored, encryptedPrefix)
+}
+
+// seal returns the encrypted private key of kid, a random nonce is put before the ciphertext.
+func (s sealer) seal(kid string, private string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(private), []byte(kid))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open returns the private key of kid that was encrypted with seal.
+func (s sealer) open(kid string, stored string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, encryptedPrefix))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < s.aead.NonceSize() {
+		return "", ErrDecryption
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	private, err := s.aead.Open(nil, nonce, ciphertext, []byte(kid))
+	if err != nil {
+		return "", ErrDecryption
+	}
+	return string(private), nil
+}
@@ -0,0 +1,160 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"storage/internal/apierrors"
+	"storage/internal/cryptPasswords"
+)
+
+// supported algorithms.
+const (
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+const rsaBits = 2048
+
+var ErrUnsupportedAlgorithm = apierrors.New(apierrors.CodeInvalidArgument, "unsupported algorithm, use RS256 or EdDSA")
+
+// Key is a private key for signing of tokens.
+type Key struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+}
+
+// GenerateKey returns a new key with a random ID.
+func GenerateKey(algorithm string) (Key, error) {
+	key := Key{Algorithm: algorithm}
+	var err error
+	switch algorithm {
+	case RS256:
+		key.Private, err = rsa.GenerateKey(rand.Reader, rsaBits)
+	case EdDSA:
+		_, key.Private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return key, ErrUnsupportedAlgorithm
+	}
+	if err != nil {
+		return key, err
+	}
+	key.ID, err = cryptPasswords.GenerateToken()
+	if err != nil {
+		return key, err
+	}
+	// 16 characters are enough to tell keys apart
+	key.ID = key.ID[:16]
+	return key, nil
+}
+
+// Method returns JWT signing method of the key.
+func (k Key) Method() jwt.SigningMethod {
+	if k.Algorithm == EdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// Sign returns the token with claims signed with the key, kid header is the ID of the key.
+func (k Key) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.Method(), claims)
+	token.Header["kid"] = k.ID
+	return token.SignedString(k.Private)
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 (RFC 8037)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet is a set of public keys, it is served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK returns the public key.
+func (k Key) JWK() JWK {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+	switch public := k.Private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// PublicKey returns the key of the JWK for verification of tokens.
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch {
+	case j.Kty == "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case j.Kty == "OKP" && j.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("wrong size of Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %v", j.Kty)
+}
+
+// MarshalPrivateKey returns the private key in PKCS #8 PEM.
+func MarshalPrivateKey(private crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// ParsePrivateKey parses the private key from PKCS #8 PEM.
+func ParsePrivateKey(data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM data in the key")
+	}
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key")
+	}
+	return signer, nil
+}
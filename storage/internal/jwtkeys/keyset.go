@@ -0,0 +1,293 @@
+package jwtkeys
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"storage/internal/apierrors"
+	"storage/internal/db"
+	"sync"
+	"time"
+)
+
+const (
+	// reloadPeriod is how often keys are loaded from database, so rotations made by other instances are noticed.
+	reloadPeriod = time.Minute
+	// minReloadPeriod limits reloads caused by tokens with unknown kid.
+	minReloadPeriod = 5 * time.Second
+)
+
+var (
+	ErrUnknownKey = apierrors.New(apierrors.CodeNotFound, "unknown signing key")
+	ErrLastKey    = apierrors.New(apierrors.CodeConflict, "the last active key can not be retired")
+)
+
+type storedKey struct {
+	Key
+	retiredAt int64
+}
+
+// KeySet signs tokens with the newest active key and verifies them with all active keys and with keys retired less
+// than gracePeriod ago. Keys are kept in database, so all instances of the server share them. Private keys are
+// encrypted with the encryption key, all instances must have the same one.
+type KeySet struct {
+	db          *db.APIDb
+	algorithm   string
+	gracePeriod time.Duration
+	sealer      sealer
+
+	mu       sync.Mutex
+	keys     []storedKey // the oldest first
+	loadedAt time.Time
+	now      func() time.Time
+}
+
+// New loads keys from database, a key with algorithm is created if there are no active keys. Private keys are
+// decrypted with encryptionKey (EncryptionKeySize bytes), keys saved without encryption are encrypted with it.
+func New(d *db.APIDb, algorithm string, gracePeriod time.Duration, encryptionKey []byte) (*KeySet, error) {
+	if algorithm != RS256 && algorithm != EdDSA {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	sealer, err := newSealer(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	s := &KeySet{
+		db:          d,
+		algorithm:   algorithm,
+		gracePeriod: gracePeriod,
+		sealer:      sealer,
+		now:         time.Now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if _, ok := s.signingKey(); !ok {
+		if _, err := s.addKey(algorithm); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// SetClock replaces time.Now (for tests).
+func (s *KeySet) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// load deletes keys after their grace period and loads the rest. Must be called with mu locked.
+func (s *KeySet) load() error {
+	now := s.now()
+	if err := s.db.DeleteSigningKeysRetiredBefore(int(now.Add(-s.gracePeriod).Unix())); err != nil {
+		return err
+	}
+	stored, err := s.db.GetSigningKeys()
+	if err != nil {
+		return err
+	}
+	keys := make([]storedKey, 0, len(stored))
+	for _, key := range stored {
+		data, err := s.decrypt(key)
+		if err != nil {
+			return fmt.Errorf("signing key %v: %w", key.KID, err)
+		}
+		private, err := ParsePrivateKey(data)
+		if err != nil {
+			return fmt.Errorf("signing key %v: %w", key.KID, err)
+		}
+		keys = append(keys, storedKey{
+			Key:       Key{ID: key.KID, Algorithm: key.Algorithm, Private: private},
+			retiredAt: int64(key.RetiredAt),
+		})
+	}
+	s.keys = keys
+	s.loadedAt = now
+	return nil
+}
+
+// decrypt returns PEM of the stored key. Keys that were saved before encryption are encrypted in database.
+func (s *KeySet) decrypt(key db.SigningKey) (string, error) {
+	if encrypted(key.PrivateKey) {
+		return s.sealer.open(key.KID, key.PrivateKey)
+	}
+	sealed, err := s.sealer.seal(key.KID, key.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	if err := s.db.SetSigningKeyPrivateKey(key.KID, key.PrivateKey, sealed); err != nil {
+		return "", err
+	}
+	return key.PrivateKey, nil
+}
+
+// reloadIfStale loads keys if they were loaded more than period ago. Must be called with mu locked.
+func (s *KeySet) reloadIfStale(period time.Duration) {
+	if s.now().Sub(s.loadedAt) < period {
+		return
+	}
+	if err := s.load(); err != nil {
+		zap.S().Error(err)
+	}
+}
+
+// signingKey returns the newest active key. Must be called with mu locked.
+func (s *KeySet) signingKey() (Key, bool) {
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if s.keys[i].retiredAt == 0 {
+			return s.keys[i].Key, true
+		}
+	}
+	return Key{}, false
+}
+
+// valid returns true if tokens signed with the key are accepted. Must be called with mu locked.
+func (s *KeySet) valid(key storedKey) bool {
+	return key.retiredAt == 0 || s.now().Before(time.Unix(key.retiredAt, 0).Add(s.gracePeriod))
+}
+
+// find returns the valid key with id. Must be called with mu locked.
+func (s *KeySet) find(id string) (Key, bool) {
+	for _, key := range s.keys {
+		if key.ID == id && s.valid(key) {
+			return key.Key, true
+		}
+	}
+	return Key{}, false
+}
+
+// addKey generates, saves and loads a new key. Must be called with mu locked.
+func (s *KeySet) addKey(algorithm string) (Key, error) {
+	key, err := GenerateKey(algorithm)
+	if err != nil {
+		return key, err
+	}
+	private, err := MarshalPrivateKey(key.Private)
+	if err != nil {
+		return key, err
+	}
+	sealed, err := s.sealer.seal(key.ID, private)
+	if err != nil {
+		return key, err
+	}
+	_, err = s.db.AddSigningKey(db.SigningKey{
+		KID:          key.ID,
+		Algorithm:    key.Algorithm,
+		PrivateKey:   sealed,
+		CreationTime: s.now().Format("2006-01-02 15:04:05"),
+	})
+	if err != nil {
+		return key, err
+	}
+	return key, s.load()
+}
+
+// Sign returns the token with claims signed with the newest active key.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	s.mu.Lock()
+	s.reloadIfStale(reloadPeriod)
+	key, ok := s.signingKey()
+	s.mu.Unlock()
+	if !ok {
+		return "", errors.New("no active signing key")
+	}
+	return key.Sign(claims)
+}
+
+// Keyfunc returns the public key for verification of the token (see jwt.Keyfunc).
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	id, _ := token.Header["kid"].(string)
+
+	s.mu.Lock()
+	s.reloadIfStale(reloadPeriod)
+	key, ok := s.find(id)
+	if !ok {
+		// the key can be added by another instance
+		s.reloadIfStale(minReloadPeriod)
+		key, ok = s.find(id)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Method.Alg())
+	}
+	return key.Private.Public(), nil
+}
+
+// JWKS returns public keys of all valid keys.
+func (s *KeySet) JWKS() JWKSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfStale(reloadPeriod)
+	set := JWKSet{Keys: make([]JWK, 0, len(s.keys))}
+	for _, key := range s.keys {
+		if s.valid(key) {
+			set.Keys = append(set.Keys, key.JWK())
+		}
+	}
+	return set
+}
+
+// Rotate adds a new key with algorithm (the default algorithm if it is empty) that signs tokens from now on, other
+// active keys are retired.
+func (s *KeySet) Rotate(algorithm string) (Key, error) {
+	if algorithm == "" {
+		algorithm = s.algorithm
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return Key{}, err
+	}
+	previous := s.keys
+
+	key, err := s.addKey(algorithm)
+	if err != nil {
+		return key, err
+	}
+	for _, old := range previous {
+		if old.retiredAt != 0 {
+			continue
+		}
+		if _, err := s.db.RetireSigningKey(old.ID, int(s.now().Unix())); err != nil {
+			return key, err
+		}
+	}
+	return key, s.load()
+}
+
+// Retire retires the active key with id, it is accepted for the grace period.
+func (s *KeySet) Retire(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	active := 0
+	found := false
+	for _, key := range s.keys {
+		if key.retiredAt == 0 {
+			active++
+			found = found || key.ID == id
+		}
+	}
+	if !found {
+		return ErrUnknownKey
+	}
+	if active == 1 {
+		return ErrLastKey
+	}
+
+	if _, err := s.db.RetireSigningKey(id, int(s.now().Unix())); err != nil {
+		return err
+	}
+	return s.load()
+}
@@ -0,0 +1,125 @@
+package loginlimiter
+
+import (
+	"sync"
+	"time"
+)
+
+// Config of the Limiter.
+type Config struct {
+	FreeAttempts    int           // failed attempts without delay
+	BaseDelay       time.Duration // delay after the first failed attempt over FreeAttempts, it doubles every attempt
+	MaxDelay        time.Duration
+	LockoutAttempts int           // failed attempts after which the key is locked for LockoutDuration
+	LockoutDuration time.Duration // also failed attempts are forgotten after this time without attempts
+}
+
+// maxEntries is the number of keys after which forgotten keys are deleted.
+const maxEntries = 10000
+
+type entry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// Limiter counts failed login attempts by key (login, IP...). After FreeAttempts every failed attempt blocks the key
+// for an exponentially growing delay, after LockoutAttempts the key is locked.
+type Limiter struct {
+	config  Config
+	entries map[string]*entry
+	mu      sync.Mutex
+	now     func() time.Time
+}
+
+func New(config Config) *Limiter {
+	return &Limiter{
+		config:  config,
+		entries: make(map[string]*entry),
+		now:     time.Now,
+	}
+}
+
+// SetClock replaces time.Now (for tests).
+func (l *Limiter) SetClock(now func() time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.now = now
+}
+
+// get returns entry of the key, forgotten failures are reset. Must be called with mu locked.
+func (l *Limiter) get(key string) *entry {
+	e, ok := l.entries[key]
+	if !ok {
+		return nil
+	}
+	now := l.now()
+	if now.After(e.blockedUntil) && now.Sub(e.lastFailure) > l.config.LockoutDuration {
+		delete(l.entries, key)
+		return nil
+	}
+	return e
+}
+
+// Allow returns false and how long to wait if the key is blocked.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := l.get(key)
+	if e == nil {
+		return true, 0
+	}
+	if wait := e.blockedUntil.Sub(l.now()); wait > 0 {
+		return false, wait
+	}
+	return true, 0
+}
+
+// Fail records a failed attempt of the key, returns true if the key is locked out now.
+func (l *Limiter) Fail(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if len(l.entries) > maxEntries {
+		l.prune(now)
+	}
+	e := l.get(key)
+	if e == nil {
+		e = &entry{}
+		l.entries[key] = e
+	}
+	e.failures++
+	e.lastFailure = now
+
+	if l.config.LockoutAttempts > 0 && e.failures >= l.config.LockoutAttempts {
+		e.blockedUntil = now.Add(l.config.LockoutDuration)
+		return true
+	}
+	if over := e.failures - l.config.FreeAttempts; over > 0 {
+		delay := l.config.BaseDelay
+		for i := 1; i < over && delay < l.config.MaxDelay; i++ {
+			delay *= 2
+		}
+		if delay > l.config.MaxDelay {
+			delay = l.config.MaxDelay
+		}
+		e.blockedUntil = now.Add(delay)
+	}
+	return false
+}
+
+// prune deletes forgotten keys. Must be called with mu locked.
+func (l *Limiter) prune(now time.Time) {
+	for key, e := range l.entries {
+		if now.After(e.blockedUntil) && now.Sub(e.lastFailure) > l.config.LockoutDuration {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// Success forgets failed attempts of the key.
+func (l *Limiter) Success(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
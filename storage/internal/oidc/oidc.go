@@ -0,0 +1,374 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"net/http"
+	"net/url"
+	"storage/internal/apierrors"
+	"storage/internal/cryptPasswords"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// StateLifetime is the time in which the user must come back from the provider.
+	StateLifetime = 10 * time.Minute
+	// KeysRefreshInterval is the minimal time between fetches of keys of the provider for tokens with unknown kid.
+	KeysRefreshInterval = time.Minute
+)
+
+var (
+	ErrUnknownState = apierrors.New(apierrors.CodeInvalidArgument, "unknown or expired state")
+	// ErrForeignState is returned if the login was started in another browser, e.g. the user follows a link with
+	// code and state of an attacker (login CSRF).
+	ErrForeignState = apierrors.New(apierrors.CodeInvalidArgument, "state was issued to another browser")
+	ErrInvalidToken = apierrors.New(apierrors.CodeUnauthenticated, "invalid id token")
+)
+
+// Config of the relying party, the client must be registered at the provider with RedirectURL.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string // "openid" is always requested
+}
+
+// Claims of the ID token that are used to find or create the user.
+type Claims struct {
+	Email             string `json:"email"`
+	PreferredUsername string `json:"preferred_username"`
+	Nonce             string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type pending struct {
+	verifier  string
+	nonce     string
+	binding   string // kept by the browser that started the login
+	expiresAt time.Time
+}
+
+// Client logs users in with authorization code flow with PKCE (RFC 7636). Provider metadata is discovered on the
+// first use, so the server starts even if the provider is not available.
+type Client struct {
+	config     Config
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	metadata      *discovery
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+	pending       map[string]pending
+	now           func() time.Time
+}
+
+func New(config Config, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{
+		config:     config,
+		httpClient: httpClient,
+		keys:       make(map[string]*rsa.PublicKey),
+		pending:    make(map[string]pending),
+		now:        time.Now,
+	}
+}
+
+// SetClock replaces time.Now (for tests).
+func (c *Client) SetClock(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Issuer returns the issuer from the config.
+func (c *Client) Issuer() string {
+	return c.config.Issuer
+}
+
+func (c *Client) getJSON(ctx context.Context, address string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v: unexpected status %v", address, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// discover returns provider metadata, it is fetched once.
+func (c *Client) discover(ctx context.Context) (*discovery, error) {
+	c.mu.Lock()
+	metadata := c.metadata
+	c.mu.Unlock()
+	if metadata != nil {
+		return metadata, nil
+	}
+
+	metadata = &discovery{}
+	address := strings.TrimSuffix(c.config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := c.getJSON(ctx, address, metadata); err != nil {
+		return nil, err
+	}
+	if metadata.Issuer != c.config.Issuer {
+		return nil, fmt.Errorf("issuer %v in provider metadata does not match %v", metadata.Issuer, c.config.Issuer)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metadata = metadata
+	return metadata, nil
+}
+
+// randomString returns a random URL-safe string of 43 characters, it is a valid PKCE verifier.
+func randomString() (string, error) {
+	return cryptPasswords.GenerateToken()
+}
+
+// challengeS256 returns PKCE code challenge of the verifier.
+func challengeS256(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// prune deletes expired states. Must be called with mu locked.
+func (c *Client) prune() {
+	now := c.now()
+	for state, p := range c.pending {
+		if now.After(p.expiresAt) {
+			delete(c.pending, state)
+		}
+	}
+}
+
+// Begin returns URL of the provider to which the user must be redirected and the binding that the browser of the user
+// must keep (e.g. in a cookie) and send to Finish. The verifier, the nonce and the binding are kept until the user
+// comes back with the state or StateLifetime passes.
+func (c *Client) Begin(ctx context.Context) (string, string, error) {
+	metadata, err := c.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	var state, verifier, nonce, binding string
+	for _, value := range []*string{&state, &verifier, &nonce, &binding} {
+		if *value, err = randomString(); err != nil {
+			return "", "", err
+		}
+	}
+
+	c.mu.Lock()
+	c.prune()
+	c.pending[state] = pending{verifier: verifier, nonce: nonce, binding: binding,
+		expiresAt: c.now().Add(StateLifetime)}
+	c.mu.Unlock()
+
+	scopes := append([]string{"openid"}, c.config.Scopes...)
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", c.config.ClientID)
+	values.Set("redirect_uri", c.config.RedirectURL)
+	values.Set("scope", strings.Join(scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+	values.Set("code_challenge", challengeS256(verifier))
+	values.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + values.Encode(), binding, nil
+}
+
+// Finish exchanges the code for ID token and returns its verified claims. The state can be used only once and only
+// with the binding that Begin returned with it.
+func (c *Client) Finish(ctx context.Context, state string, binding string, code string) (*Claims, error) {
+	c.mu.Lock()
+	p, ok := c.pending[state]
+	delete(c.pending, state)
+	expired := c.now().After(p.expiresAt)
+	c.mu.Unlock()
+	if !ok || expired {
+		return nil, ErrUnknownState
+	}
+	if subtle.ConstantTimeCompare([]byte(binding), []byte(p.binding)) != 1 {
+		return nil, ErrForeignState
+	}
+
+	metadata, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, err := c.exchange(ctx, metadata, code, p.verifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := c.verify(ctx, metadata, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Nonce != p.nonce {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// exchange sends the code and PKCE verifier to the token endpoint and returns raw ID token.
+func (c *Client) exchange(ctx context.Context, metadata *discovery, code string, verifier string) (string, error) {
+	values := url.Values{}
+	values.Set("grant_type", "authorization_code")
+	values.Set("code", code)
+	values.Set("redirect_uri", c.config.RedirectURL)
+	values.Set("code_verifier", verifier)
+	values.Set("client_id", c.config.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint,
+		strings.NewReader(values.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.config.ClientSecret != "" {
+		// RFC 6749 2.3.1: client credentials are form-encoded before basic authentication
+		req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("token endpoint: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint: %v %v", out.Error, out.ErrorDescription)
+	}
+	if out.IDToken == "" {
+		return "", fmt.Errorf("token endpoint: no id_token in the answer")
+	}
+	return out.IDToken, nil
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// refreshKeys fetches RSA signing keys of the provider.
+func (c *Client) refreshKeys(ctx context.Context, metadata *discovery) error {
+	var set jwks
+	if err := c.getJSON(ctx, metadata.JWKSURI, &set); err != nil {
+		return err
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return err
+		}
+		keys[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys = keys
+	return nil
+}
+
+// key returns the signing key with kid, keys are fetched again if the provider has rotated them. They are fetched at
+// most once in KeysRefreshInterval, so tokens with made up kid do not make storage flood the provider.
+func (c *Client) key(ctx context.Context, metadata *discovery, kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	key, ok := c.keys[kid]
+	refresh := !ok && c.now().Sub(c.keysFetchedAt) >= KeysRefreshInterval
+	if refresh {
+		c.keysFetchedAt = c.now()
+	}
+	c.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !refresh {
+		return nil, fmt.Errorf("unknown key %v", kid)
+	}
+
+	if err := c.refreshKeys(ctx, metadata); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key, ok = c.keys[kid]; !ok {
+		return nil, fmt.Errorf("unknown key %v", kid)
+	}
+	return key, nil
+}
+
+// verify checks signature, issuer, audience and lifetime of the ID token.
+func (c *Client) verify(ctx context.Context, metadata *discovery, rawIDToken string) (*Claims, error) {
+	c.mu.Lock()
+	now := c.now
+	c.mu.Unlock()
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.key(ctx, metadata, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(c.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	}
+	return claims, nil
+}
@@ -0,0 +1,329 @@
+// Package webhooks calls URLs of users when their expressions are finished. Deliveries are saved in the database
+// and retried with exponential backoff, so they survive restarts of storage.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"net/url"
+	"storage/internal/apierrors"
+	"storage/internal/db"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// EventFinished is the event of deliveries about finished expressions.
+const EventFinished = "expression.finished"
+
+// headers of deliveries
+const (
+	HeaderSignature = "X-Webhook-Signature" // "sha256=" + hex of HMAC-SHA256 of "<timestamp>.<body>" with the secret
+	HeaderTimestamp = "X-Webhook-Timestamp" // unix time of the attempt
+	HeaderDelivery  = "X-Webhook-Delivery"  // ID of the delivery, the same for all attempts
+	HeaderEvent     = "X-Webhook-Event"
+)
+
+const (
+	pollInterval   = time.Second
+	resolveTimeout = 5 * time.Second
+)
+
+var (
+	// ErrDeliveryBusy is returned by Redeliver if the delivery was attempted while it was redelivered.
+	ErrDeliveryBusy = apierrors.New(apierrors.CodeConflict, "delivery is being attempted, try again")
+	ErrInvalidURL   = apierrors.New(apierrors.CodeInvalidArgument, "webhook URL must be an absolute https URL")
+	// ErrPrivateAddress is returned for URLs of the network of storage, e.g. localhost or metadata of the cloud.
+	ErrPrivateAddress = apierrors.New(apierrors.CodeInvalidArgument,
+		"webhook URL must resolve only to public addresses")
+)
+
+// Config of the Dispatcher.
+type Config struct {
+	MaxAttempts int           // attempts before the delivery fails
+	RetryDelay  time.Duration // delay after the first failed attempt, it doubles every attempt
+	MaxDelay    time.Duration
+	Timeout     time.Duration // timeout of one attempt
+	AllowHTTP   bool          // allow http URLs (for local development and tests)
+	// AllowPrivate allows private, loopback, link-local and unspecified addresses (for local development and tests)
+	AllowPrivate bool
+}
+
+// Payload is the body of deliveries.
+type Payload struct {
+	Event      string        `json:"event"`
+	Expression db.Expression `json:"expression"`
+}
+
+// Dispatcher delivers payloads to webhooks.
+type Dispatcher struct {
+	db     *db.APIDb
+	config Config
+	client *http.Client
+	wake   chan struct{}
+	now    func() time.Time
+}
+
+// New starts delivering pending deliveries.
+func New(d *db.APIDb, config Config) *Dispatcher {
+	dispatcher := &Dispatcher{
+		db:     d,
+		config: config,
+		wake:   make(chan struct{}, 1),
+		now:    time.Now,
+	}
+	// the address is checked again when it is dialed, the host may resolve to another address than in CheckURL
+	dialer := &net.Dialer{Timeout: config.Timeout, Control: dispatcher.checkDialed}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	dispatcher.client = &http.Client{
+		Timeout:   config.Timeout,
+		Transport: transport,
+		// redirects are not followed, they could lead to private addresses
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	go dispatcher.run()
+	return dispatcher
+}
+
+// CheckURL returns ErrInvalidURL if the URL can not be used for webhooks, ErrPrivateAddress if its host does not
+// resolve or resolves to an address that is not public.
+func (d *Dispatcher) CheckURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "https" && !(d.config.AllowHTTP && parsed.Scheme == "http")) {
+		return ErrInvalidURL
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, parsed.Hostname())
+	if err != nil || len(addresses) == 0 {
+		return ErrPrivateAddress
+	}
+	for _, address := range addresses {
+		if !d.allowedIP(address.IP) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
+
+// allowedIP returns true if deliveries can be sent to the IP.
+func (d *Dispatcher) allowedIP(ip net.IP) bool {
+	if d.config.AllowPrivate {
+		return true
+	}
+	return !(ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified())
+}
+
+// checkDialed is Control of the dialer, it refuses connections to addresses that are not allowed.
+func (d *Dispatcher) checkDialed(_ string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !d.allowedIP(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// NewSecret returns a random secret for signing payloads.
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// Sign returns the value of HeaderSignature for the body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of the delivery, receivers should also reject old timestamps.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// ExpressionFinished adds deliveries of the expression to its webhooks.
+func (d *Dispatcher) ExpressionFinished(expression db.Expression) error {
+	webhooks, err := d.db.GetExpressionWebhooks(expression.User, expression.ID)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+	payload, err := json.Marshal(Payload{Event: EventFinished, Expression: expression})
+	if err != nil {
+		return err
+	}
+	now := d.now()
+	for _, webhook := range webhooks {
+		_, err = d.db.AddWebhookDelivery(db.WebhookDelivery{
+			Webhook:       webhook.ID,
+			Expression:    expression.ID,
+			Event:         EventFinished,
+			Payload:       string(payload),
+			Status:        db.DeliveryPending,
+			NextAttemptAt: int(now.Unix()),
+			CreationTime:  now.Format("2006-01-02 15:04:05"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	d.Wake()
+	return nil
+}
+
+// Redeliver sends the delivery again with all attempts.
+func (d *Dispatcher) Redeliver(delivery db.WebhookDelivery) error {
+	lease := delivery.NextAttemptAt
+	delivery.Status = db.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = int(d.now().Unix())
+	updated, err := d.db.UpdateWebhookDelivery(delivery, lease)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrDeliveryBusy
+	}
+	d.Wake()
+	return nil
+}
+
+// Wake makes the dispatcher check pending deliveries now.
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) run() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		}
+		d.deliverPending()
+	}
+}
+
+// deliverPending delivers due deliveries until there are none. Deliveries are claimed one by one right before their
+// attempts, so the lease of a delivery does not run out while other deliveries are attempted.
+func (d *Dispatcher) deliverPending() {
+	for {
+		now := d.now()
+		// the lease is longer than an attempt, so the delivery is not claimed by another instance meanwhile
+		delivery, err := d.db.ClaimWebhookDelivery(int(now.Unix()), int(now.Add(2*d.config.Timeout).Unix()))
+		if errors.Is(err, sql.ErrNoRows) {
+			return
+		}
+		if err != nil {
+			zap.S().Error(err)
+			return
+		}
+		d.attempt(delivery)
+	}
+}
+
+// attempt sends the delivery once and saves the result.
+func (d *Dispatcher) attempt(delivery db.WebhookDelivery) {
+	webhook, err := d.db.GetWebhook(delivery.Webhook)
+	if err != nil {
+		// the webhook is deleted with its deliveries
+		zap.S().Warn(err)
+		return
+	}
+
+	lease := delivery.NextAttemptAt
+	delivery.Attempts++
+	delivery.ResponseStatus, err = d.send(webhook, delivery)
+	now := d.now()
+	if err == nil {
+		delivery.Status = db.DeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredTime = now.Format("2006-01-02 15:04:05")
+	} else {
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= d.config.MaxAttempts {
+			delivery.Status = db.DeliveryFailed
+			zap.S().Warnf("delivery %v to webhook %v failed after %v attempts: %v", delivery.ID, webhook.ID,
+				delivery.Attempts, err)
+		} else {
+			delivery.NextAttemptAt = int(now.Add(d.retryDelay(delivery.Attempts)).Unix())
+		}
+	}
+	updated, err := d.db.UpdateWebhookDelivery(delivery, lease)
+	if err != nil {
+		zap.S().Error(err)
+	} else if !updated {
+		zap.S().Warnf("result of delivery %v is dropped, it was claimed again or redelivered meanwhile", delivery.ID)
+	}
+}
+
+// retryDelay returns the delay after the failed attempt.
+func (d *Dispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.config.RetryDelay
+	for i := 1; i < attempts && delay < d.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, d.config.MaxDelay)
+}
+
+// send posts the payload, returns status of the response and error if it is not 2xx.
+func (d *Dispatcher) send(webhook db.Webhook, delivery db.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.Timeout)
+	defer cancel()
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, body))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderEvent, delivery.Event)
+
+	// errors are shown to the user, so they tell nothing about the network of storage and the answer
+	resp, err := d.client.Do(req)
+	if err != nil {
+		zap.S().Infof("delivery %v to webhook %v: %v", delivery.ID, webhook.ID, err)
+		var timeout net.Error
+		switch {
+		case errors.Is(err, ErrPrivateAddress):
+			return 0, ErrPrivateAddress
+		case errors.As(err, &timeout) && timeout.Timeout():
+			return 0, errors.New("webhook did not answer in time")
+		}
+		return 0, errors.New("webhook request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook answered %v", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"net"
 	"os"
 	"storage/internal/availableservers"
@@ -40,7 +41,7 @@ func InitLogger(debug bool) {
 //	@description	This is a server for the storage of expressions and their results
 
 // @host		localhost:8080
-// @BasePath	/api/v1.
+// @BasePath	/api
 func main() {
 	InitLogger(true)
 	gin.SetMode(gin.ReleaseMode)
@@ -63,6 +64,15 @@ func main() {
 	// expression storage
 	num, err := strconv.Atoi(os.Getenv("CHECK_SERVER_DURATION"))
 	expStorage := expressionstorage.New(d, time.Duration(num)*time.Second, &workerStorage)
+	if maxAttempts := os.Getenv("MAX_EXPRESSION_ATTEMPTS"); maxAttempts != "" {
+		num, err = strconv.Atoi(maxAttempts)
+		if err != nil {
+			zap.S().Fatal(err)
+		}
+		if err = expStorage.SetMaxAttempts(num); err != nil {
+			zap.S().Fatal(err)
+		}
+	}
 
 	// servers storage
 	servers := availableservers.New(expStorage)
@@ -79,7 +89,28 @@ func main() {
 		if err != nil {
 			zap.S().Fatal(err)
 		}
-		grpcServer := grpc.NewServer()
+		// errors interceptors are the first ones to convert errors of the others
+		opts := []grpc.ServerOption{
+			grpc.ChainUnaryInterceptor(gRPCServer.ErrorsUnaryInterceptor, gRPCServer.ServerNameInterceptor),
+			grpc.ChainStreamInterceptor(gRPCServer.ErrorsStreamInterceptor),
+		}
+		if os.Getenv("REQUIRE_WORKER_CREDENTIALS") == "TRUE" {
+			workerAuth := gRPCServer.NewWorkerAuth(d)
+			opts = append(opts, grpc.ChainUnaryInterceptor(workerAuth.UnaryInterceptor),
+				grpc.ChainStreamInterceptor(workerAuth.StreamInterceptor))
+		} else {
+			zap.S().Warn("calculation servers are not authenticated, set REQUIRE_WORKER_CREDENTIALS=TRUE")
+		}
+		if certFile := os.Getenv("GRPC_TLS_CERT"); certFile != "" {
+			config, err := gRPCServer.LoadTLSConfig(certFile, os.Getenv("GRPC_TLS_KEY"), os.Getenv("GRPC_TLS_CA"))
+			if err != nil {
+				zap.S().Fatal(err)
+			}
+			opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+		} else {
+			zap.S().Warn("gRPC server is running without TLS")
+		}
+		grpcServer := grpc.NewServer(opts...)
 		gRPCServer.RegisterExpressionsServiceServer(grpcServer, server)
 		if err := grpcServer.Serve(lis); err != nil {
 			zap.S().Fatal(err)
@@ -87,7 +118,13 @@ func main() {
 	}()
 
 	// api
-	err = api.New(d, expStorage, &workerStorage, servers, execTimeConfig).Start().Run(":8080")
+	apiServer := api.New(d, expStorage, &workerStorage, servers, execTimeConfig)
+	if login := os.Getenv("ADMIN_LOGIN"); login != "" {
+		if err = apiServer.BootstrapAdmin(login, os.Getenv("ADMIN_PASSWORD")); err != nil {
+			zap.S().Fatal(err)
+		}
+	}
+	err = apiServer.Start().Run(":8080")
 	if err != nil {
 		zap.S().Fatal(err)
 	}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Requests of this file need the admin token of storage (see WithTokens) or tokens of a user with admin role.
+
+// AddWorker registers the calculation server, the enrollment token is returned only once.
+func (c *Client) AddWorker(ctx context.Context, name string) (WorkerEnrollment, error) {
+	var out WorkerEnrollment
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/admin/workers", body: struct {
+		Name string `json:"name"`
+	}{name}}, &out)
+	return out, err
+}
+
+// Workers returns registered calculation servers.
+func (c *Client) Workers(ctx context.Context) ([]Worker, error) {
+	var out struct {
+		Workers []Worker `json:"workers"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/admin/workers"}, &out)
+	return out.Workers, err
+}
+
+// RevokeWorker revokes credentials of the calculation server and returns expressions that were released to pending.
+func (c *Client) RevokeWorker(ctx context.Context, name string) ([]Expression, error) {
+	var out struct {
+		Released []Expression `json:"released"`
+	}
+	err := c.do(ctx, request{method: http.MethodDelete, path: pathf("/api/v1/admin/workers/%v", name)}, &out)
+	return out.Released, err
+}
+
+// Users returns all users.
+func (c *Client) Users(ctx context.Context) ([]User, error) {
+	var out struct {
+		Users []User `json:"users"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/admin/users"}, &out)
+	return out.Users, err
+}
+
+// SetUserRole sets the role of the user, user or admin.
+func (c *Client) SetUserRole(ctx context.Context, id int, role string) (User, error) {
+	return c.user(ctx, pathf("/api/v1/admin/users/%v/role", id), struct {
+		Role string `json:"role"`
+	}{role})
+}
+
+// DisableUser disables the user and revokes their sessions.
+func (c *Client) DisableUser(ctx context.Context, id int) (User, error) {
+	return c.user(ctx, pathf("/api/v1/admin/users/%v/disable", id), nil)
+}
+
+// EnableUser enables the disabled user.
+func (c *Client) EnableUser(ctx context.Context, id int) (User, error) {
+	return c.user(ctx, pathf("/api/v1/admin/users/%v/enable", id), nil)
+}
+
+func (c *Client) user(ctx context.Context, path string, body any) (User, error) {
+	var out struct {
+		User User `json:"user"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: path, body: body}, &out)
+	return out.User, err
+}
+
+// AllExpressions returns expressions of all users.
+func (c *Client) AllExpressions(ctx context.Context) ([]Expression, error) {
+	var out struct {
+		Expressions []Expression `json:"expressions"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/admin/expressions"}, &out)
+	return out.Expressions, err
+}
+
+// ReleaseExpression ends the lease of the calculation server on the expression, it is returned to pending.
+func (c *Client) ReleaseExpression(ctx context.Context, id int) (Expression, error) {
+	return c.expression(ctx, http.MethodPost, pathf("/api/v1/admin/expressions/%v/release", id), nil)
+}
+
+// AllServers returns all calculation servers.
+func (c *Client) AllServers(ctx context.Context) ([]Server, error) {
+	var out struct {
+		Servers []Server `json:"servers"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/admin/servers"}, &out)
+	return out.Servers, err
+}
+
+// LoginAttempts returns the last failed logins, of the login if it is not empty. Limit 0 means the default of storage.
+func (c *Client) LoginAttempts(ctx context.Context, login string, limit int) ([]LoginAttempt, error) {
+	var out struct {
+		LoginAttempts []LoginAttempt `json:"login_attempts"`
+	}
+	query := url.Values{}
+	if login != "" {
+		query.Set("login", login)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/admin/loginAttempts", query: query}, &out)
+	return out.LoginAttempts, err
+}
+
+// SigningKeys returns keys that sign tokens.
+func (c *Client) SigningKeys(ctx context.Context) ([]SigningKey, error) {
+	var out struct {
+		SigningKeys []SigningKey `json:"signing_keys"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/admin/signingKeys"}, &out)
+	return out.SigningKeys, err
+}
+
+// RotateSigningKeys creates the new signing key with the algorithm (empty for the default) and returns its kid.
+func (c *Client) RotateSigningKeys(ctx context.Context, algorithm string) (string, error) {
+	var out struct {
+		KID string `json:"kid"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/admin/signingKeys/rotate", body: struct {
+		Algorithm string `json:"algorithm,omitempty"`
+	}{algorithm}}, &out)
+	return out.KID, err
+}
+
+// RetireSigningKey stops signing with the key, it verifies tokens for the grace period. The last active key can not be
+// retired.
+func (c *Client) RetireSigningKey(ctx context.Context, kid string) error {
+	return c.do(ctx, request{method: http.MethodPost, path: pathf("/api/v1/admin/signingKeys/%v/retire", kid)}, nil)
+}
@@ -0,0 +1,245 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Tokens are tokens of a login. If the user has second factor, the tokens are empty and Challenge is exchanged for
+// them with LoginTwoFactor.
+type Tokens struct {
+	Access    string `json:"access"`
+	Refresh   string `json:"refresh"`
+	Challenge string `json:"challenge"`
+}
+
+// NeedsTwoFactor returns true if the login must be finished with LoginTwoFactor.
+func (t Tokens) NeedsTwoFactor() bool {
+	return t.Challenge != "" && t.Access == ""
+}
+
+type credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// Register creates the user and logs in.
+func (c *Client) Register(ctx context.Context, login string, password string) (Tokens, error) {
+	return c.login(ctx, "/api/v1/register", credentials{Login: login, Password: password})
+}
+
+// Login logs in with the login and password. The client uses the tokens unless the second factor is needed.
+func (c *Client) Login(ctx context.Context, login string, password string) (Tokens, error) {
+	return c.login(ctx, "/api/v1/login", credentials{Login: login, Password: password})
+}
+
+// LoginTwoFactor finishes the login with the challenge of Login and a code of the authenticator or a recovery code.
+func (c *Client) LoginTwoFactor(ctx context.Context, challenge string, code string) (Tokens, error) {
+	return c.login(ctx, "/api/v1/login/twoFactor", struct {
+		Challenge string `json:"challenge"`
+		Code      string `json:"code"`
+	}{challenge, code})
+}
+
+// oidcBindingCookie binds single sign-on to the client that started it, it is set by /api/v1/oidc/login.
+const oidcBindingCookie = "oidc_binding"
+
+// OIDCLoginURL returns the URL of the identity provider to send the user to. The client keeps the cookie of the
+// answer, the login must be finished with OIDCCallback of the same client.
+func (c *Client) OIDCLoginURL(ctx context.Context) (string, error) {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/api/v1/oidc/login", noAuth: true})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		URL string `json:"url"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("decode answer of %v %v: %w", http.MethodGet, "/api/v1/oidc/login", err)
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == oidcBindingCookie {
+			c.mu.Lock()
+			c.oidcBinding = cookie
+			c.mu.Unlock()
+		}
+	}
+	return out.URL, nil
+}
+
+// OIDCCallback finishes the login with code and state that the identity provider returned.
+func (c *Client) OIDCCallback(ctx context.Context, code string, state string) (Tokens, error) {
+	header := http.Header{}
+	c.mu.Lock()
+	if c.oidcBinding != nil {
+		header.Set("Cookie", (&http.Cookie{Name: c.oidcBinding.Name, Value: c.oidcBinding.Value}).String())
+		c.oidcBinding = nil
+	}
+	c.mu.Unlock()
+
+	var tokens Tokens
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/oidc/callback", body: struct {
+		Code  string `json:"code"`
+		State string `json:"state"`
+	}{code, state}, header: header, noAuth: true}, &tokens)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if tokens.Access != "" {
+		c.setTokens(tokens.Access, tokens.Refresh)
+	}
+	return tokens, nil
+}
+
+func (c *Client) login(ctx context.Context, path string, body any) (Tokens, error) {
+	var tokens Tokens
+	err := c.do(ctx, request{method: http.MethodPost, path: path, body: body, noAuth: true}, &tokens)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if tokens.Access != "" {
+		c.setTokens(tokens.Access, tokens.Refresh)
+	}
+	return tokens, nil
+}
+
+// Refresh exchanges the refresh token for new tokens, the refresh token can be used only once. Requests refresh
+// tokens of the client automatically when the access token is expired.
+func (c *Client) Refresh(ctx context.Context, refresh string) (Tokens, error) {
+	return c.login(ctx, "/api/v1/refresh", struct {
+		Refresh string `json:"refresh"`
+	}{refresh})
+}
+
+// Logout revokes the session of the client and forgets its tokens.
+func (c *Client) Logout(ctx context.Context) error {
+	return c.logout(ctx, "/api/v1/logout")
+}
+
+// LogoutEverywhere revokes all sessions of the user.
+func (c *Client) LogoutEverywhere(ctx context.Context) error {
+	return c.logout(ctx, "/api/v1/logoutEverywhere")
+}
+
+func (c *Client) logout(ctx context.Context, path string) error {
+	if err := c.do(ctx, request{method: http.MethodPost, path: path, noRetry: true}, nil); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.access, c.refreshToken = "", ""
+	c.mu.Unlock()
+	if c.onTokens != nil {
+		c.onTokens("", "")
+	}
+	return nil
+}
+
+// Sessions returns sessions of the user.
+func (c *Client) Sessions(ctx context.Context) ([]Session, error) {
+	var out struct {
+		Sessions []Session `json:"sessions"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/sessions"}, &out)
+	return out.Sessions, err
+}
+
+// DeleteSession revokes the session.
+func (c *Client) DeleteSession(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: pathf("/api/v1/sessions/%v", id)}, nil)
+}
+
+// User returns the login of the user.
+func (c *Client) User(ctx context.Context) (string, error) {
+	var out struct {
+		Login string `json:"login"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/getUser"}, &out)
+	return out.Login, err
+}
+
+// UserUpdate changes the login and (or) the password, the old password is needed unless the user has no password
+// yet (users of single sign-on).
+type UserUpdate struct {
+	Login       string `json:"login,omitempty"`
+	Password    string `json:"password,omitempty"`
+	OldPassword string `json:"old_password,omitempty"`
+}
+
+// UpdateUser changes the user, the client uses the new access token.
+func (c *Client) UpdateUser(ctx context.Context, update UserUpdate) error {
+	var out struct {
+		Access string `json:"access"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/updateUser", body: update}, &out)
+	if err == nil && out.Access != "" {
+		c.setTokens(out.Access, "")
+	}
+	return err
+}
+
+// AddAPIKey creates the API key, the key is returned only once.
+func (c *Client) AddAPIKey(ctx context.Context, key NewAPIKey) (string, APIKey, error) {
+	var out struct {
+		Key    string `json:"key"`
+		APIKey APIKey `json:"api_key"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/apiKeys", body: key}, &out)
+	return out.Key, out.APIKey, err
+}
+
+// APIKeys returns API keys of the user.
+func (c *Client) APIKeys(ctx context.Context) ([]APIKey, error) {
+	var out struct {
+		APIKeys []APIKey `json:"api_keys"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/apiKeys"}, &out)
+	return out.APIKeys, err
+}
+
+// DeleteAPIKey revokes the API key.
+func (c *Client) DeleteAPIKey(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: pathf("/api/v1/apiKeys/%v", id)}, nil)
+}
+
+// TwoFactor returns whether the user has second factor.
+func (c *Client) TwoFactor(ctx context.Context) (TwoFactorStatus, error) {
+	var out TwoFactorStatus
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/twoFactor"}, &out)
+	return out, err
+}
+
+// EnrollTwoFactor starts enrollment of second factor, it is enabled by ConfirmTwoFactor.
+func (c *Client) EnrollTwoFactor(ctx context.Context) (TwoFactorEnrollment, error) {
+	var out TwoFactorEnrollment
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/twoFactor/enroll"}, &out)
+	return out, err
+}
+
+// ConfirmTwoFactor enables second factor with a code of the authenticator and returns recovery codes.
+func (c *Client) ConfirmTwoFactor(ctx context.Context, code string) ([]string, error) {
+	return c.recoveryCodes(ctx, "/api/v1/twoFactor/confirm", code)
+}
+
+// DisableTwoFactor disables second factor.
+func (c *Client) DisableTwoFactor(ctx context.Context, code string) error {
+	_, err := c.recoveryCodes(ctx, "/api/v1/twoFactor/disable", code)
+	return err
+}
+
+// RegenerateRecoveryCodes replaces recovery codes of the user.
+func (c *Client) RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error) {
+	return c.recoveryCodes(ctx, "/api/v1/twoFactor/recoveryCodes", code)
+}
+
+func (c *Client) recoveryCodes(ctx context.Context, path string, code string) ([]string, error) {
+	var out struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: path, body: struct {
+		Code string `json:"code"`
+	}{code}}, &out)
+	return out.RecoveryCodes, err
+}
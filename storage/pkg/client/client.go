@@ -0,0 +1,224 @@
+// Package client is the Go client of the storage REST API.
+//
+// The client logs in with a login and password (or uses tokens or an API key given with options), refreshes the
+// access token when it expires and returns errors of the API as *Error with stable codes. All methods take a context,
+// listings can be iterated page by page with iterators. Expressions are managed with /api/v2 routes, v1 routes that
+// only duplicate them are not wrapped.
+//
+//	c := client.New("http://localhost:8080")
+//	if _, err := c.Login(ctx, "login", "password"); err != nil {
+//		return err
+//	}
+//	id, err := c.PostExpression(ctx, client.NewExpression{Expression: "2+2"})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Client is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+	onTokens   func(access string, refresh string)
+
+	mu           sync.Mutex // guards tokens and the cookie of single sign-on
+	refreshMu    sync.Mutex // only one refresh of tokens at a time
+	access       string
+	refreshToken string
+	oidcBinding  *http.Cookie // see OIDCLoginURL
+}
+
+// Option configures the Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client, http.DefaultClient is used by default.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTokens sets tokens of a previous login. The access token may also be the admin token of storage.
+func WithTokens(access string, refresh string) Option {
+	return func(c *Client) {
+		c.access = access
+		c.refreshToken = refresh
+	}
+}
+
+// WithAPIKey authenticates requests with the API key instead of tokens.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithTokensCallback sets the function that is called when tokens are changed by login or refresh, e.g. to save them.
+func WithTokensCallback(onTokens func(access string, refresh string)) Option {
+	return func(c *Client) {
+		c.onTokens = onTokens
+	}
+}
+
+// New returns the client of storage at baseURL, e.g. http://localhost:8080.
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Tokens returns the current tokens of the client.
+func (c *Client) Tokens() Tokens {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Tokens{Access: c.access, Refresh: c.refreshToken}
+}
+
+func (c *Client) setTokens(access string, refresh string) {
+	c.mu.Lock()
+	c.access = access
+	if refresh != "" {
+		c.refreshToken = refresh
+	}
+	access, refresh = c.access, c.refreshToken
+	c.mu.Unlock()
+	if c.onTokens != nil {
+		c.onTokens(access, refresh)
+	}
+}
+
+// request is a call of the API.
+type request struct {
+	method  string
+	path    string
+	query   url.Values
+	body    any       // encoded as JSON
+	raw     io.Reader // sent as is instead of body, Content-Type is set in header
+	header  http.Header
+	noAuth  bool
+	noRetry bool // the request is not sent again after refresh of tokens
+}
+
+// do sends the request and decodes the JSON answer into out (if it is not nil). If the access token is expired, the
+// tokens are refreshed and the request is sent again.
+func (c *Client) do(ctx context.Context, r request, out any) error {
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode answer of %v %v: %w", r.method, r.path, err)
+	}
+	return nil
+}
+
+// send sends the request and returns the successful response, the caller closes its body.
+func (c *Client) send(ctx context.Context, r request) (*http.Response, error) {
+	var body []byte
+	if r.body != nil {
+		var err error
+		if body, err = json.Marshal(r.body); err != nil {
+			return nil, err
+		}
+	}
+
+	for refreshed := false; ; refreshed = true {
+		req, err := c.newRequest(ctx, r, body)
+		if err != nil {
+			return nil, err
+		}
+		access := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+
+		apiErr := readError(resp)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || refreshed || r.noAuth || r.noRetry || r.raw != nil ||
+			c.apiKey != "" || access == "" {
+			return nil, apiErr
+		}
+		if err = c.refreshTokens(ctx, access); err != nil {
+			return nil, apiErr
+		}
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, r request, body []byte) (*http.Request, error) {
+	target := c.baseURL + r.path
+	if len(r.query) > 0 {
+		target += "?" + r.query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	} else if r.raw != nil {
+		reader = r.raw
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if !r.noAuth {
+		if c.apiKey != "" {
+			req.Header.Set("X-API-Key", c.apiKey)
+		} else if tokens := c.Tokens(); tokens.Access != "" {
+			req.Header.Set("Authorization", "Bearer "+tokens.Access)
+		}
+	}
+	return req, nil
+}
+
+// refreshTokens gets new tokens if the access token is still the expired one, otherwise another request has already
+// refreshed them.
+func (c *Client) refreshTokens(ctx context.Context, expired string) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	tokens := c.Tokens()
+	if tokens.Access != expired {
+		return nil
+	}
+	if tokens.Refresh == "" {
+		return ErrNoRefreshToken
+	}
+	_, err := c.Refresh(ctx, tokens.Refresh)
+	return err
+}
+
+func pathf(format string, args ...any) string {
+	escaped := make([]any, len(args))
+	for i, arg := range args {
+		escaped[i] = url.PathEscape(fmt.Sprint(arg))
+	}
+	return fmt.Sprintf(format, escaped...)
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// codes of errors of the API, the catalogue is returned by Client.ErrorCatalogue
+const (
+	CodeInvalidArgument   = "invalid_argument"
+	CodeUnauthenticated   = "unauthenticated"
+	CodePermissionDenied  = "permission_denied"
+	CodeNotFound          = "not_found"
+	CodeConflict          = "conflict"
+	CodeRequestInProgress = "request_in_progress"
+	CodeRateLimited       = "rate_limited"
+	CodeInternal          = "internal"
+	CodeUnavailable       = "unavailable"
+	CodeTimeout           = "timeout"
+)
+
+// errors to check with errors.Is, they match *Error with the same code
+var (
+	ErrInvalidArgument  = &Error{Code: CodeInvalidArgument}
+	ErrUnauthenticated  = &Error{Code: CodeUnauthenticated}
+	ErrPermissionDenied = &Error{Code: CodePermissionDenied}
+	ErrNotFound         = &Error{Code: CodeNotFound}
+	ErrConflict         = &Error{Code: CodeConflict}
+	ErrRateLimited      = &Error{Code: CodeRateLimited}
+	ErrInternal         = &Error{Code: CodeInternal}
+	ErrUnavailable      = &Error{Code: CodeUnavailable}
+)
+
+// ErrNoRefreshToken is returned when the access token is expired and the client has no refresh token.
+var ErrNoRefreshToken = errors.New("access token is expired and there is no refresh token")
+
+// Error is an error answer of the API (RFC 7807 problem details).
+type Error struct {
+	Status        int    `json:"status"`
+	Code          string `json:"code"`
+	Title         string `json:"title"`
+	Detail        string `json:"detail"`
+	Instance      string `json:"instance"`
+	Retryable     bool   `json:"retryable"` // the same request may succeed later
+	CorrelationID string `json:"correlation_id"`
+	// RetryAfter is the delay from Retry-After header, 0 if there is no header
+	RetryAfter time.Duration `json:"-"`
+	// Body is the whole answer, it has extension members, e.g. errors of items of batches
+	Body json.RawMessage `json:"-"`
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("storage: %v %v", e.Status, e.Code)
+	if e.Detail != "" {
+		message += ": " + e.Detail
+	}
+	if e.CorrelationID != "" {
+		message += " (correlation ID " + e.CorrelationID + ")"
+	}
+	return message
+}
+
+// Is matches errors with the same code, e.g. errors.Is(err, client.ErrNotFound).
+func (e *Error) Is(target error) bool {
+	var other *Error
+	return errors.As(target, &other) && other.Code == e.Code
+}
+
+// IsRetryable returns true if the request failed with an error that may not happen again.
+func IsRetryable(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Retryable
+}
+
+// readError reads the error answer, answers that are not problem details get the code by the status.
+func readError(resp *http.Response) *Error {
+	apiErr := &Error{Status: resp.StatusCode}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(body, apiErr); err == nil {
+		apiErr.Body = body
+		if apiErr.Detail == "" {
+			var message struct {
+				Message string `json:"message"`
+			}
+			_ = json.Unmarshal(body, &message)
+			apiErr.Detail = message.Message
+		}
+	} else {
+		apiErr.Detail = string(body)
+	}
+	apiErr.Status = resp.StatusCode
+	if apiErr.Code == "" {
+		apiErr.Code = codeOfStatus(resp.StatusCode)
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+func codeOfStatus(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodePermissionDenied
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	}
+	if status < http.StatusInternalServerError {
+		return CodeInvalidArgument
+	}
+	return CodeInternal
+}
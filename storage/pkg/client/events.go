@@ -0,0 +1,132 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// types of events
+const (
+	EventCreated  = "created"
+	EventStatus   = "status"   // status is changed, but the expression is not finished
+	EventProgress = "progress" // server that calculates the expression is alive
+	EventFinished = "finished" // expression is calculated, failed, abandoned or cancelled
+	EventDeleted  = "deleted"
+	// EventReset means that some events are lost after reconnect, expressions must be reloaded
+	EventReset = "reset"
+)
+
+// Event is a change of an expression of the user or of their teams.
+type Event struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	Expression Expression `json:"expression"`
+}
+
+// EventStream reads Server-Sent Events of expressions:
+//
+//	stream, err := c.Events(ctx, "")
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+//	for stream.Next() {
+//		event := stream.Event()
+//	}
+//
+// If the stream is broken, a new one is opened with LastEventID, so no events are lost.
+type EventStream struct {
+	body        io.ReadCloser
+	scanner     *bufio.Scanner
+	event       Event
+	lastEventID string
+	err         error
+}
+
+// Events opens the stream of events after lastEventID (empty for new events only).
+func (c *Client) Events(ctx context.Context, lastEventID string) (*EventStream, error) {
+	header := http.Header{"Accept": {"text/event-stream"}}
+	if lastEventID != "" {
+		header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/api/v2/events", header: header})
+	if err != nil {
+		return nil, err
+	}
+	return &EventStream{body: resp.Body, scanner: bufio.NewScanner(resp.Body), lastEventID: lastEventID}, nil
+}
+
+// Next reads the next event, it returns false when the stream is closed or broken.
+func (s *EventStream) Next() bool {
+	var id, name string
+	var data strings.Builder
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		if line == "" {
+			if name == "" && data.Len() == 0 {
+				continue
+			}
+			return s.dispatch(id, name, data.String())
+		}
+		if strings.HasPrefix(line, ":") {
+			// heartbeat
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			id = value
+		case "event":
+			name = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+	}
+	s.err = s.scanner.Err()
+	return false
+}
+
+func (s *EventStream) dispatch(id string, name string, data string) bool {
+	s.event = Event{}
+	if name != EventReset {
+		if err := json.Unmarshal([]byte(data), &s.event); err != nil {
+			s.err = fmt.Errorf("decode event %v: %w", id, err)
+			return false
+		}
+	}
+	s.event.Type = name
+	if id != "" {
+		s.event.ID = id
+		s.lastEventID = id
+	}
+	return true
+}
+
+// Event returns the current event.
+func (s *EventStream) Event() Event {
+	return s.event
+}
+
+// LastEventID returns ID of the last read event, it is used to resume the stream.
+func (s *EventStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Err returns the error that broke the stream, it is nil if the stream was closed by storage or by Close.
+func (s *EventStream) Err() error {
+	return s.err
+}
+
+// Close closes the stream.
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}
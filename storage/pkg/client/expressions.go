@@ -0,0 +1,322 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formats of export and import
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// timeLayout is the format of times of the API, times are in the time zone of storage
+const timeLayout = "2006-01-02 15:04:05"
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns the context that sends Idempotency-Key header with requests that add expressions, so they
+// are not added twice when the request is repeated.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+func idempotencyHeader(ctx context.Context) http.Header {
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	if key == "" {
+		return nil
+	}
+	return http.Header{"Idempotency-Key": {key}}
+}
+
+// PostExpression adds the expression and returns its ID.
+func (c *Client) PostExpression(ctx context.Context, expression NewExpression) (int, error) {
+	var out struct {
+		ID int `json:"id"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v2/expressions", body: expression,
+		header: idempotencyHeader(ctx)}, &out)
+	return out.ID, err
+}
+
+// PostBatch adds the expressions as one batch. Items are in the order of the expressions, expressions with errors are
+// not added.
+func (c *Client) PostBatch(ctx context.Context, expressions []NewExpression) (int, []BatchItem, error) {
+	var out struct {
+		BatchID int         `json:"batch_id"`
+		Items   []BatchItem `json:"items"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/expressions:batch",
+		body: struct {
+			Expressions []NewExpression `json:"expressions"`
+		}{expressions}, header: idempotencyHeader(ctx)}, &out)
+	return out.BatchID, out.Items, err
+}
+
+// Batch returns the progress of the batch.
+func (c *Client) Batch(ctx context.Context, id int) (BatchStatus, error) {
+	var out BatchStatus
+	err := c.do(ctx, request{method: http.MethodGet, path: pathf("/api/v1/batches/%v", id)}, &out)
+	return out, err
+}
+
+// ExpressionsQuery filters and sorts listings of expressions, zero fields are not used.
+type ExpressionsQuery struct {
+	Statuses      []int
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Server        string
+	Contains      string
+	Sort          string // creation_time, end_calculation_time or duration, - before the name for descending order
+	Limit         int    // size of pages
+	Cursor        string // NextCursor of the previous page
+}
+
+func (q ExpressionsQuery) values() url.Values {
+	values := url.Values{}
+	if len(q.Statuses) > 0 {
+		statuses := make([]string, len(q.Statuses))
+		for i, status := range q.Statuses {
+			statuses[i] = strconv.Itoa(status)
+		}
+		values.Set("status", strings.Join(statuses, ","))
+	}
+	if !q.CreatedAfter.IsZero() {
+		values.Set("created_after", q.CreatedAfter.Format(timeLayout))
+	}
+	if !q.CreatedBefore.IsZero() {
+		values.Set("created_before", q.CreatedBefore.Format(timeLayout))
+	}
+	if q.Server != "" {
+		values.Set("server", q.Server)
+	}
+	if q.Contains != "" {
+		values.Set("q", q.Contains)
+	}
+	if q.Sort != "" {
+		values.Set("sort", q.Sort)
+	}
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Cursor != "" {
+		values.Set("cursor", q.Cursor)
+	}
+	return values
+}
+
+// ExpressionsPage is a page of the listing.
+type ExpressionsPage struct {
+	Expressions []Expression `json:"expressions"`
+	NextCursor  string       `json:"next_cursor"` // empty on the last page
+	Counts      map[int]int  `json:"counts"`      // number of expressions by status, without status filter
+	Total       int          `json:"total"`       // number of expressions that match the filters
+}
+
+// ListExpressions returns a page of expressions of the user and of their teams.
+func (c *Client) ListExpressions(ctx context.Context, query ExpressionsQuery) (ExpressionsPage, error) {
+	var out ExpressionsPage
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v2/expressions", query: query.values()}, &out)
+	return out, err
+}
+
+// ExpressionIterator iterates over all pages of the listing:
+//
+//	it := c.Expressions(ctx, client.ExpressionsQuery{})
+//	for it.Next() {
+//		expression := it.Expression()
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type ExpressionIterator struct {
+	ctx    context.Context
+	client *Client
+	query  ExpressionsQuery
+	page   []Expression
+	index  int
+	last   bool
+	err    error
+}
+
+// Expressions returns the iterator over expressions that match the query, pages are requested when they are needed.
+func (c *Client) Expressions(ctx context.Context, query ExpressionsQuery) *ExpressionIterator {
+	return &ExpressionIterator{ctx: ctx, client: c, query: query, index: -1}
+}
+
+// Next moves to the next expression, it returns false at the end of the listing or on error.
+func (it *ExpressionIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	it.index++
+	for it.index >= len(it.page) {
+		if it.last {
+			return false
+		}
+		page, err := it.client.ListExpressions(it.ctx, it.query)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page, it.index = page.Expressions, 0
+		it.query.Cursor = page.NextCursor
+		it.last = page.NextCursor == ""
+	}
+	return true
+}
+
+// Expression returns the current expression.
+func (it *ExpressionIterator) Expression() Expression {
+	return it.page[it.index]
+}
+
+// Err returns the error that stopped the iteration.
+func (it *ExpressionIterator) Err() error {
+	return it.err
+}
+
+// Expression returns the expression by ID.
+func (c *Client) Expression(ctx context.Context, id int) (Expression, error) {
+	return c.expression(ctx, http.MethodGet, pathf("/api/v2/expressions/%v", id), nil)
+}
+
+// DeleteExpression deletes the expression, it is cancelled if it is being calculated.
+func (c *Client) DeleteExpression(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: pathf("/api/v2/expressions/%v", id)}, nil)
+}
+
+// CancelExpression cancels calculation of the expression.
+func (c *Client) CancelExpression(ctx context.Context, id int) (Expression, error) {
+	return c.expression(ctx, http.MethodPost, pathf("/api/v2/expressions/%v/cancel", id), nil)
+}
+
+// RetryExpression calculates the finished expression again, operations that are not set are taken from the user.
+func (c *Client) RetryExpression(ctx context.Context, id int, operations map[string]int) (Expression, error) {
+	return c.expression(ctx, http.MethodPost, pathf("/api/v2/expressions/%v/retry", id), struct {
+		Operations map[string]int `json:"operations,omitempty"`
+	}{operations})
+}
+
+// RequeueExpression returns the failed expression to pending.
+func (c *Client) RequeueExpression(ctx context.Context, id int) (Expression, error) {
+	return c.expression(ctx, http.MethodPost, pathf("/api/v2/expressions/%v/requeue", id), nil)
+}
+
+func (c *Client) expression(ctx context.Context, method string, path string, body any) (Expression, error) {
+	var out struct {
+		Expression Expression `json:"expression"`
+	}
+	err := c.do(ctx, request{method: method, path: path, body: body}, &out)
+	return out.Expression, err
+}
+
+// ExpressionHistory returns previous calculations of the expression.
+func (c *Client) ExpressionHistory(ctx context.Context, id int) ([]ExpressionRun, error) {
+	var out struct {
+		Runs []ExpressionRun `json:"runs"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: pathf("/api/v2/expressions/%v/history", id)}, &out)
+	return out.Runs, err
+}
+
+// ExpressionResult waits up to wait (at most a minute) for the expression to be finished. It returns the expression
+// and whether it is finished.
+func (c *Client) ExpressionResult(ctx context.Context, id int, wait time.Duration) (Expression, bool, error) {
+	var out struct {
+		Expression Expression `json:"expression"`
+		Finished   bool       `json:"finished"`
+	}
+	query := url.Values{}
+	if wait > 0 {
+		query.Set("wait", wait.String())
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: pathf("/api/v2/expressions/%v/result", id),
+		query: query}, &out)
+	return out.Expression, out.Finished, err
+}
+
+// WaitExpression waits until the expression is finished or ctx is done.
+func (c *Client) WaitExpression(ctx context.Context, id int) (Expression, error) {
+	for {
+		expression, finished, err := c.ExpressionResult(ctx, id, time.Minute)
+		if err != nil || finished {
+			return expression, err
+		}
+	}
+}
+
+// ExportExpressions streams expressions that match the query (Limit and Cursor are not used) in the format, the caller
+// closes the reader.
+func (c *Client) ExportExpressions(ctx context.Context, format string, query ExpressionsQuery) (io.ReadCloser,
+	error) {
+	values := query.values()
+	values.Del("limit")
+	values.Del("cursor")
+	values.Set("format", format)
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/api/v1/expressions/export", query: values})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ImportExpressions adds expressions from CSV with header or JSON Lines, e.g. from the export, as one batch. Errors of
+// lines are in the result.
+func (c *Client) ImportExpressions(ctx context.Context, format string, r io.Reader) (ImportResult, error) {
+	var out ImportResult
+	contentType := "text/csv"
+	if format == FormatJSONL {
+		contentType = "application/x-ndjson"
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/expressions/import",
+		query: url.Values{"format": {format}}, raw: r, header: http.Header{"Content-Type": {contentType}}}, &out)
+	return out, err
+}
+
+// Servers returns calculation servers and expressions of the user that they calculate.
+func (c *Client) Servers(ctx context.Context) ([]Server, error) {
+	var out struct {
+		Servers []Server `json:"servers"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v2/servers"}, &out)
+	return out.Servers, err
+}
+
+// ServerExpressions returns expressions of the user that were calculated by the server.
+func (c *Client) ServerExpressions(ctx context.Context, name string) ([]Expression, error) {
+	var out struct {
+		Expressions []Expression `json:"expressions"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: pathf("/api/v2/servers/%v/expressions", name)}, &out)
+	return out.Expressions, err
+}
+
+// Operations returns times of operations of the user in milliseconds, {"+": 100,...}.
+func (c *Client) Operations(ctx context.Context) (map[string]int, error) {
+	return c.operations(ctx, request{method: http.MethodGet, path: "/api/v2/operations"})
+}
+
+// SetOperations replaces times of all operations, all of "+", "-", "*", "/" are needed.
+func (c *Client) SetOperations(ctx context.Context, operations map[string]int) (map[string]int, error) {
+	return c.operations(ctx, request{method: http.MethodPut, path: "/api/v2/operations", body: operations})
+}
+
+func (c *Client) operations(ctx context.Context, r request) (map[string]int, error) {
+	var out struct {
+		Data map[string]int `json:"data"`
+	}
+	err := c.do(ctx, r, &out)
+	return out.Data, err
+}
+
+// UpdateOperations changes times of the given operations, other operations are not changed.
+func (c *Client) UpdateOperations(ctx context.Context, operations map[string]int) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/api/v1/postOperationsAndTimes", body: operations}, nil)
+}
@@ -0,0 +1,27 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Ping checks that storage is available.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodGet, path: "/api/v1/ping", noAuth: true}, nil)
+}
+
+// ErrorCatalogue returns codes of errors of the API.
+func (c *Client) ErrorCatalogue(ctx context.Context) ([]ErrorKind, error) {
+	var out struct {
+		Errors []ErrorKind `json:"errors"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/errors", noAuth: true}, &out)
+	return out.Errors, err
+}
+
+// JWKS returns public keys that verify tokens of storage.
+func (c *Client) JWKS(ctx context.Context) (JWKSet, error) {
+	var out JWKSet
+	err := c.do(ctx, request{method: http.MethodGet, path: "/.well-known/jwks.json", noAuth: true}, &out)
+	return out, err
+}
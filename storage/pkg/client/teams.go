@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// team roles
+const (
+	RoleOwner  = "owner"
+	RoleMember = "member"
+)
+
+// AddTeam creates the team, the user is its owner.
+func (c *Client) AddTeam(ctx context.Context, name string) (Team, error) {
+	var out struct {
+		Team Team `json:"team"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/teams", body: struct {
+		Name string `json:"name"`
+	}{name}}, &out)
+	return out.Team, err
+}
+
+// Teams returns teams of the user with their roles.
+func (c *Client) Teams(ctx context.Context) ([]Team, error) {
+	var out struct {
+		Teams []Team `json:"teams"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/teams"}, &out)
+	return out.Teams, err
+}
+
+// DeleteTeam deletes the team, only owners can do it.
+func (c *Client) DeleteTeam(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: pathf("/api/v1/teams/%v", id)}, nil)
+}
+
+// TeamMembers returns members of the team.
+func (c *Client) TeamMembers(ctx context.Context, id int) ([]TeamMember, error) {
+	var out struct {
+		Members []TeamMember `json:"members"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: pathf("/api/v1/teams/%v/members", id)}, &out)
+	return out.Members, err
+}
+
+// SetTeamMember adds the user with the login to the team or changes their role.
+func (c *Client) SetTeamMember(ctx context.Context, id int, login string, role string) (TeamMember, error) {
+	var out struct {
+		Member TeamMember `json:"member"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: pathf("/api/v1/teams/%v/members", id), body: struct {
+		Login string `json:"login"`
+		Role  string `json:"role,omitempty"`
+	}{login, role}}, &out)
+	return out.Member, err
+}
+
+// DeleteTeamMember removes the user from the team.
+func (c *Client) DeleteTeamMember(ctx context.Context, id int, userID int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: pathf("/api/v1/teams/%v/members/%v", id, userID)}, nil)
+}
+
+// TeamOperations returns times of operations of the team.
+func (c *Client) TeamOperations(ctx context.Context, id int) (map[string]int, error) {
+	return c.operations(ctx, request{method: http.MethodGet, path: pathf("/api/v1/teams/%v/operationsAndTimes", id)})
+}
+
+// UpdateTeamOperations changes times of the given operations of the team.
+func (c *Client) UpdateTeamOperations(ctx context.Context, id int, operations map[string]int) error {
+	return c.do(ctx, request{method: http.MethodPost, path: pathf("/api/v1/teams/%v/operationsAndTimes", id),
+		body: operations}, nil)
+}
+
+// SetTeamTwoFactor sets whether members of the team need second factor.
+func (c *Client) SetTeamTwoFactor(ctx context.Context, id int, required bool) error {
+	return c.do(ctx, request{method: http.MethodPost, path: pathf("/api/v1/teams/%v/requireTwoFactor", id),
+		body: struct {
+			Required bool `json:"required"`
+		}{required}}, nil)
+}
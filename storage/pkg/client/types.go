@@ -0,0 +1,239 @@
+package client
+
+// statuses of expressions
+const (
+	StatusNotReady  = 0
+	StatusWorking   = 1
+	StatusReady     = 2
+	StatusError     = 3
+	StatusAbandoned = 4
+	StatusCancelled = 5
+)
+
+// Expression is an expression of the user or of their team.
+type Expression struct {
+	ID                 int     `json:"id"`
+	Value              string  `json:"value"`
+	Answer             float64 `json:"answer"`
+	Logs               string  `json:"logs"`
+	Status             int     `json:"ready"` // one of Status constants
+	AliveExpiresAt     int     `json:"alive_expires_at"`
+	CreationTime       string  `json:"creation_time"`
+	EndCalculationTime string  `json:"end_calculation_time"`
+	Servername         string  `json:"server_name"`
+	User               int     `json:"user_id"`
+	Priority           int     `json:"priority"`
+	Attempts           int     `json:"attempts"`
+	FailedServers      string  `json:"failed_servers"`
+	Team               int     `json:"team_id"`
+	Batch              int     `json:"batch_id"`
+}
+
+// Finished returns true if the expression is calculated, failed, abandoned or cancelled.
+func (e Expression) Finished() bool {
+	return e.Status >= StatusReady
+}
+
+// NewExpression is an expression to calculate.
+type NewExpression struct {
+	Expression string `json:"expression"`
+	Priority   int    `json:"priority"` // from 0 to 10, expressions with higher priority are calculated first
+	Team       int    `json:"team"`     // optional ID of the team, the expression is shared with the team
+}
+
+// ExpressionRun is a previous calculation of the expression.
+type ExpressionRun struct {
+	ID                 int     `json:"id"`
+	Expression         int     `json:"expression_id"`
+	Answer             float64 `json:"answer"`
+	Logs               string  `json:"logs"`
+	Status             int     `json:"ready"`
+	CreationTime       string  `json:"creation_time"`
+	EndCalculationTime string  `json:"end_calculation_time"`
+	Servername         string  `json:"server_name"`
+}
+
+// BatchItem is the result of adding an expression of the batch, either ID or Error is set.
+type BatchItem struct {
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type Batch struct {
+	ID           int    `json:"id"`
+	User         int    `json:"user_id"`
+	Size         int    `json:"size"`
+	CreationTime string `json:"creation_time"`
+}
+
+// BatchStatus is the progress of the batch.
+type BatchStatus struct {
+	Batch    Batch       `json:"batch"`
+	Counts   map[int]int `json:"counts"`   // number of expressions by status
+	Finished int         `json:"finished"` // number of finished expressions
+	Done     bool        `json:"done"`     // all expressions are finished
+}
+
+// ImportError is the error of a line of the imported file.
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type ImportResult struct {
+	BatchID  int           `json:"batch_id"`
+	Imported int           `json:"imported"`
+	Errors   []ImportError `json:"errors"`
+}
+
+// Server is a calculation server.
+type Server struct {
+	ServerName            string `json:"server_name"`
+	CalculatedExpressions []int  `json:"calculated_expressions"`
+	ServerStatus          string `json:"server_status"`
+}
+
+type Session struct {
+	ID           int    `json:"id"`
+	User         int    `json:"user_id"`
+	UserAgent    string `json:"user_agent"`
+	CreationTime string `json:"creation_time"`
+	LastSeenTime string `json:"last_seen_time"`
+	ExpiresAt    int    `json:"expires_at"`
+	Revoked      bool   `json:"revoked"`
+	Current      bool   `json:"current"` // the session of the client
+}
+
+type APIKey struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Prefix       string `json:"prefix"`
+	User         int    `json:"user_id"`
+	Scopes       string `json:"scopes"` // comma separated
+	ExpiresAt    int    `json:"expires_at"`
+	Revoked      bool   `json:"revoked"`
+	CreationTime string `json:"creation_time"`
+	LastUsedTime string `json:"last_used_time"`
+}
+
+// NewAPIKey is a key to create.
+type NewAPIKey struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn int      `json:"expires_in"` // seconds, 0 - the key does not expire
+}
+
+type TwoFactorStatus struct {
+	Enabled       bool `json:"enabled"`
+	RecoveryCodes int  `json:"recovery_codes"` // number of unused recovery codes
+}
+
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI for authenticator apps
+}
+
+type Team struct {
+	ID               int    `json:"id"`
+	Name             string `json:"name"`
+	CreationTime     string `json:"creation_time"`
+	RequireTwoFactor bool   `json:"require_two_factor"`
+	Role             string `json:"role,omitempty"` // role of the user, set by Teams
+}
+
+type TeamMember struct {
+	ID    int    `json:"id"`
+	Team  int    `json:"team_id"`
+	User  int    `json:"user_id"`
+	Role  string `json:"role"`
+	Login string `json:"login"`
+}
+
+type Webhook struct {
+	ID           int    `json:"id"`
+	User         int    `json:"user_id"`
+	Expression   int    `json:"expression_id"` // 0 - all expressions of the user
+	URL          string `json:"url"`
+	CreationTime string `json:"creation_time"`
+}
+
+type WebhookDelivery struct {
+	ID             int    `json:"id"`
+	Webhook        int    `json:"webhook_id"`
+	Expression     int    `json:"expression_id"`
+	Event          string `json:"event"`
+	Payload        string `json:"payload"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	ResponseStatus int    `json:"response_status"`
+	LastError      string `json:"last_error"`
+	NextAttemptAt  int    `json:"next_attempt_at"`
+	CreationTime   string `json:"creation_time"`
+	DeliveredTime  string `json:"delivered_time"`
+}
+
+// User is a user as seen by administrators.
+type User struct {
+	ID       int    `json:"id"`
+	Login    string `json:"login"`
+	Role     string `json:"role"`
+	Disabled bool   `json:"disabled"`
+}
+
+type Worker struct {
+	ID                  int    `json:"id"`
+	Name                string `json:"name"`
+	EnrollmentExpiresAt int    `json:"enrollment_expires_at"`
+	Revoked             bool   `json:"revoked"`
+	CreationTime        string `json:"creation_time"`
+}
+
+// WorkerEnrollment has the token that the worker exchanges for its credential.
+type WorkerEnrollment struct {
+	Name            string `json:"name"`
+	EnrollmentToken string `json:"enrollment_token"`
+	ExpiresAt       int    `json:"expires_at"`
+}
+
+type LoginAttempt struct {
+	ID           int    `json:"id"`
+	Login        string `json:"login"`
+	User         int    `json:"user_id"`
+	IP           string `json:"ip"`
+	UserAgent    string `json:"user_agent"`
+	Reason       string `json:"reason"`
+	CreationTime string `json:"creation_time"`
+}
+
+type SigningKey struct {
+	ID           int    `json:"id"`
+	KID          string `json:"kid"`
+	Algorithm    string `json:"algorithm"`
+	CreationTime string `json:"creation_time"`
+	RetiredAt    int    `json:"retired_at"`
+}
+
+// JWK is a public key of signing keys of tokens.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ErrorKind is an entry of the error catalogue.
+type ErrorKind struct {
+	Code       string `json:"code"`
+	Title      string `json:"title"`
+	HTTPStatus int    `json:"http_status"`
+	GRPCCode   string `json:"grpc_code"`
+	Retryable  bool   `json:"retryable"`
+}
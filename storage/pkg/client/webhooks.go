@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// AddWebhook adds the webhook that is called when expressions of the user (or only the expression, if it is not 0)
+// are finished. The secret that signs payloads is returned only once.
+func (c *Client) AddWebhook(ctx context.Context, webhookURL string, expression int) (int, string, error) {
+	var out struct {
+		ID     int    `json:"id"`
+		Secret string `json:"secret"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/webhooks", body: struct {
+		URL        string `json:"url"`
+		Expression int    `json:"expression_id,omitempty"`
+	}{webhookURL, expression}}, &out)
+	return out.ID, out.Secret, err
+}
+
+// Webhooks returns webhooks of the user.
+func (c *Client) Webhooks(ctx context.Context) ([]Webhook, error) {
+	var out struct {
+		Webhooks []Webhook `json:"webhooks"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/webhooks"}, &out)
+	return out.Webhooks, err
+}
+
+// DeleteWebhook deletes the webhook with its deliveries.
+func (c *Client) DeleteWebhook(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: pathf("/api/v1/webhooks/%v", id)}, nil)
+}
+
+// WebhookDeliveries returns the last deliveries of the webhook, limit 0 means the default of storage.
+func (c *Client) WebhookDeliveries(ctx context.Context, id int, limit int) ([]WebhookDelivery, error) {
+	var out struct {
+		Deliveries []WebhookDelivery `json:"deliveries"`
+	}
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: pathf("/api/v1/webhooks/%v/deliveries", id),
+		query: query}, &out)
+	return out.Deliveries, err
+}
+
+// RedeliverWebhook sends the payload of the delivery again.
+func (c *Client) RedeliverWebhook(ctx context.Context, id int, delivery int) error {
+	return c.do(ctx, request{method: http.MethodPost,
+		path: pathf("/api/v1/webhooks/%v/deliveries/%v/redeliver", id, delivery)}, nil)
+}
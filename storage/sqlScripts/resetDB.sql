@@ -1,11 +1,52 @@
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS signing_keys;
+DROP TABLE IF EXISTS oidc_identities;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS totp_secrets;
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS team_operations;
+DROP TABLE IF EXISTS team_members;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS workers;
+DROP TABLE IF EXISTS expression_runs;
+DROP TABLE IF EXISTS expression_operations;
 DROP TABLE IF EXISTS expressions;
+DROP TABLE IF EXISTS teams;
+DROP TABLE IF EXISTS batches;
+DROP TABLE IF EXISTS operations;
 DROP TABLE IF EXISTS users;
 
 CREATE TABLE users
 (
     id       SERIAL PRIMARY KEY,
     login    TEXT,
-    password TEXT
+    password TEXT,
+    role     TEXT,
+    disabled BOOLEAN
+);
+
+CREATE TABLE teams
+(
+    id                 SERIAL PRIMARY KEY,
+    name               TEXT,
+    creation_time      TEXT,
+    require_two_factor BOOLEAN
+);
+
+CREATE TABLE batches
+(
+    id            SERIAL PRIMARY KEY,
+    user_id       INT,
+    size          INT,
+    creation_time TEXT,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users (id)
+            ON DELETE CASCADE
 );
 
 CREATE TABLE expressions
@@ -20,9 +61,22 @@ CREATE TABLE expressions
     end_calculation_time TEXT,
     server_name          TEXT,
     user_id              INT,
+    priority             INT,
+    attempts             INT,
+    failed_servers       TEXT,
+    team_id              INT,
+    batch_id             INT,
     CONSTRAINT fk_user
         FOREIGN KEY (user_id)
-            REFERENCES users (id)
+            REFERENCES users (id),
+    CONSTRAINT fk_team
+        FOREIGN KEY (team_id)
+            REFERENCES teams (id)
+            ON DELETE SET NULL,
+    CONSTRAINT fk_batch
+        FOREIGN KEY (batch_id)
+            REFERENCES batches (id)
+            ON DELETE SET NULL
 );
 
 CREATE TABLE operations
@@ -36,4 +90,244 @@ CREATE TABLE operations
     CONSTRAINT fk_user
         FOREIGN KEY (user_id)
             REFERENCES users (id)
+);
+
+CREATE TABLE expression_runs
+(
+    id                   SERIAL PRIMARY KEY,
+    expression_id        INT,
+    answer               FLOAT,
+    logs                 TEXT,
+    ready                INT,
+    creation_time        TEXT,
+    end_calculation_time TEXT,
+    server_name          TEXT,
+    CONSTRAINT fk_expression
+        FOREIGN KEY (expression_id)
+            REFERENCES expressions (id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE expression_operations
+(
+    id            SERIAL PRIMARY KEY,
+    time_add      INT,
+    time_subtract INT,
+    time_divide   INT,
+    time_multiply INT,
+    expression_id INT,
+    CONSTRAINT fk_expression
+        FOREIGN KEY (expression_id)
+            REFERENCES expressions (id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE workers
+(
+    id                    SERIAL PRIMARY KEY,
+    name                  TEXT UNIQUE,
+    enrollment_hash       TEXT,
+    enrollment_expires_at BIGINT,
+    credential_hash       TEXT,
+    revoked               BOOLEAN,
+    creation_time         TEXT
+);
+
+CREATE TABLE refresh_tokens
+(
+    id            SERIAL PRIMARY KEY,
+    token_hash    TEXT UNIQUE,
+    family        TEXT,
+    user_id       INT,
+    expires_at    BIGINT,
+    used          BOOLEAN,
+    revoked       BOOLEAN,
+    creation_time TEXT,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users (id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE sessions
+(
+    id             SERIAL PRIMARY KEY,
+    jti            TEXT UNIQUE,
+    user_id        INT,
+    user_agent     TEXT,
+    creation_time  TEXT,
+    last_seen_time TEXT,
+    expires_at     BIGINT,
+    revoked        BOOLEAN,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users (id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE api_keys
+(
+    id             SERIAL PRIMARY KEY,
+    name           TEXT,
+    key_hash       TEXT UNIQUE,
+    prefix         TEXT,
+    user_id        INT,
+    scopes         TEXT,
+    expires_at     BIGINT,
+    revoked        BOOLEAN,
+    creation_time  TEXT,
+    last_used_time TEXT,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users (id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE team_members
+(
+    id      SERIAL PRIMARY KEY,
+    team_id INT,
+    user_id INT,
+    role    TEXT,
+    UNIQUE (team_id, user_id),
+    CONSTRAINT fk_team
+        FOREIGN KEY (team_id)
+            REFERENCES teams (id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users (id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE team_operations
+(
+    id            SERIAL PRIMARY KEY,
+    time_add      INT,
+    time_subtract INT,
+    time_divide   INT,
+    time_multiply INT,
+    team_id       INT UNIQUE,
+    CONSTRAINT fk_team
+        FOREIGN KEY (team_id)
+            REFERENCES teams (id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE login_attempts
+(
+    id            SERIAL PRIMARY KEY,
+    login         TEXT,
+    user_id       INT,
+    ip            TEXT,
+    user_agent    TEXT,
+    reason        TEXT,
+    creation_time TEXT
+);
+
+CREATE TABLE totp_secrets
+(
+    id            SERIAL PRIMARY KEY,
+    user_id       INT UNIQUE,
+    secret        TEXT,
+    confirmed     BOOLEAN,
+    last_step     BIGINT,
+    creation_time TEXT,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users (id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE recovery_codes
+(
+    id        SERIAL PRIMARY KEY,
+    user_id   INT,
+    code_hash TEXT,
+    used      BOOLEAN,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users (id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE oidc_identities
+(
+    id            SERIAL PRIMARY KEY,
+    issuer        TEXT,
+    subject       TEXT,
+    user_id       INT,
+    email         TEXT,
+    creation_time TEXT,
+    UNIQUE (issuer, subject),
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users (id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE signing_keys
+(
+    id            SERIAL PRIMARY KEY,
+    kid           TEXT UNIQUE,
+    algorithm     TEXT,
+    private_key   TEXT,
+    creation_time TEXT,
+    retired_at    BIGINT
+);
+
+CREATE TABLE idempotency_keys
+(
+    id            SERIAL PRIMARY KEY,
+    user_id       INT,
+    request_key   TEXT,
+    request_hash  TEXT,
+    status        INT,
+    response      TEXT,
+    location      TEXT,
+    expires_at    BIGINT,
+    creation_time TEXT,
+    UNIQUE (user_id, request_key),
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users (id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE webhooks
+(
+    id            SERIAL PRIMARY KEY,
+    user_id       INT,
+    expression_id INT,
+    url           TEXT,
+    secret        TEXT,
+    creation_time TEXT,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users (id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_expression
+        FOREIGN KEY (expression_id)
+            REFERENCES expressions (id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE webhook_deliveries
+(
+    id              SERIAL PRIMARY KEY,
+    webhook_id      INT,
+    expression_id   INT,
+    event           TEXT,
+    payload         TEXT,
+    status          TEXT,
+    attempts        INT,
+    response_status INT,
+    last_error      TEXT,
+    next_attempt_at BIGINT,
+    creation_time   TEXT,
+    delivered_time  TEXT,
+    CONSTRAINT fk_webhook
+        FOREIGN KEY (webhook_id)
+            REFERENCES webhooks (id)
+            ON DELETE CASCADE
 );
\ No newline at end of file
@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"storage/internal/api"
+	"storage/internal/availableservers"
+	"storage/internal/cryptPasswords"
+	"storage/internal/db"
+	"storage/internal/expressionstorage"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAdminRoutes(t *testing.T) {
+	d, err := db.New()
+	require.NoError(t, err)
+	statusWorkers := &sync.Map{}
+	expressions := expressionstorage.New(d, time.Minute, statusWorkers)
+	servers := availableservers.New(expressions)
+	timeConfig := &api.ExecTimeConfig{TimeAdd: 1, TimeSubtract: 1, TimeDivide: 1, TimeMultiply: 1}
+	a := api.New(d, expressions, statusWorkers, servers, timeConfig)
+	router := a.Start()
+
+	request := func(method, url, access string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	register := func(login string) (db.User, string) {
+		w := request(http.MethodPost, "/api/v1/register", "", `{"login":"`+login+`","password":"`+testPassword+`"}`)
+		var out api.OutRegister
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		require.Equal(t, "ok", out.Message)
+		user, err := d.GetUserByUsername(login)
+		require.NoError(t, err)
+		return user, out.Access
+	}
+
+	prefix := fmt.Sprintf("%vadmin", time.Now().UnixNano())
+	admin, adminAccess := register(prefix + "a")
+	require.NoError(t, d.SetUserRole(admin.ID, db.RoleAdmin, false))
+	user, userAccess := register(prefix + "u")
+	assert.Equal(t, db.RoleUser, user.Role)
+
+	// users can not use admin routes
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/api/v1/admin/users", userAccess, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/v1/admin/users", "", "").Code)
+
+	w := request(http.MethodGet, "/api/v1/admin/users", adminAccess, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), user.Login)
+	assert.NotContains(t, w.Body.String(), user.Password)
+
+	// every expression and force-release
+	id, err := expressions.Add(db.Expression{Value: "1+1", User: user.ID})
+	require.NoError(t, err)
+	expression, err := expressions.GetByID(id)
+	require.NoError(t, err)
+	expression.Status = db.ExpressionWorking
+	expression.Servername = prefix + "server"
+	require.NoError(t, expressions.UpdateExpression(expression))
+	servers.Add(expression.Servername)
+
+	w = request(http.MethodGet, "/api/v1/admin/expressions", adminAccess, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var all api.OutGetAllExpressions
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &all))
+	assert.Contains(t, expressionIDs(all.Expressions), id)
+
+	w = request(http.MethodGet, "/api/v1/admin/servers", adminAccess, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var allServers api.OutGetServers
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &allServers))
+	assert.Contains(t, allServers.Servers, api.OutServer{ServerName: expression.Servername,
+		CalculatedExpressions: []int{id}, ServerStatus: "unknown"})
+
+	w = request(http.MethodPost, fmt.Sprintf("/api/v1/admin/expressions/%v/release", id), adminAccess, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	expression, err = expressions.GetByID(id)
+	require.NoError(t, err)
+	assert.Equal(t, db.ExpressionNotReady, expression.Status)
+	assert.Equal(t, 0, expression.Attempts)
+	w = request(http.MethodPost, fmt.Sprintf("/api/v1/admin/expressions/%v/release", id), adminAccess, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// disable and enable
+	w = request(http.MethodPost, "/api/v1/apiKeys", userAccess, `{"name":"script","scopes":["expressions:read"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = request(http.MethodPost, fmt.Sprintf("/api/v1/admin/users/%v/disable", admin.ID), adminAccess, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = request(http.MethodPost, fmt.Sprintf("/api/v1/admin/users/%v/disable", user.ID), adminAccess, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/v1/getUser", userAccess, "").Code)
+	keys, err := d.GetUserAPIKeys(user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	sessions, err := d.GetUserSessions(user.ID, int(time.Now().Unix()))
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+	w = request(http.MethodPost, "/api/v1/login", "", `{"login":"`+user.Login+`","password":"`+testPassword+`"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = request(http.MethodPost, fmt.Sprintf("/api/v1/admin/users/%v/enable", user.ID), adminAccess, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	w = request(http.MethodPost, "/api/v1/login", "", `{"login":"`+user.Login+`","password":"`+testPassword+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// roles
+	w = request(http.MethodPost, fmt.Sprintf("/api/v1/admin/users/%v/role", user.ID), adminAccess,
+		`{"role":"superuser"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = request(http.MethodPost, fmt.Sprintf("/api/v1/admin/users/%v/role", user.ID), adminAccess, `{"role":"admin"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	user, err = d.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.RoleAdmin, user.Role)
+
+	require.NoError(t, expressions.Delete(id))
+	require.NoError(t, d.DeleteByUserId(admin.ID))
+	require.NoError(t, d.DeleteUser(admin.ID))
+	require.NoError(t, d.DeleteByUserId(user.ID))
+	require.NoError(t, d.DeleteUser(user.ID))
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	d, a := CreateApi(t)
+	hasAdmin, err := d.HasAdmin()
+	require.NoError(t, err)
+
+	login := fmt.Sprintf("%vbootstrap", time.Now().UnixNano())
+	require.NoError(t, a.BootstrapAdmin(login, testPassword))
+	user, err := d.GetUserByUsername(login)
+	if hasAdmin {
+		// the first admin already exists, nothing is created
+		require.Error(t, err)
+		return
+	}
+	require.NoError(t, err)
+	assert.Equal(t, db.RoleAdmin, user.Role)
+
+	// the second call does nothing
+	require.NoError(t, a.BootstrapAdmin(login+"second", testPassword))
+	_, err = d.GetUserByUsername(login + "second")
+	require.Error(t, err)
+}
+
+func TestBootstrapAdminExistingUser(t *testing.T) {
+	d, a := CreateApi(t)
+	hasAdmin, err := d.HasAdmin()
+	require.NoError(t, err)
+	if hasAdmin {
+		t.Skip("the first admin already exists")
+	}
+
+	login := fmt.Sprintf("%vbootstrapexisting", time.Now().UnixNano())
+	hash, err := cryptPasswords.GeneratePasswordHash(testPassword)
+	require.NoError(t, err)
+	id, err := d.AddUser(db.User{Login: login, Password: hash})
+	require.NoError(t, err)
+
+	// the user registered the login before the admin, the role is not given without the password
+	require.Error(t, a.BootstrapAdmin(login, "another-password"))
+	user, err := d.GetUserByUsername(login)
+	require.NoError(t, err)
+	assert.NotEqual(t, db.RoleAdmin, user.Role)
+
+	require.NoError(t, a.BootstrapAdmin(login, testPassword))
+	user, err = d.GetUserByUsername(login)
+	require.NoError(t, err)
+	assert.Equal(t, db.RoleAdmin, user.Role)
+
+	require.NoError(t, d.DeleteUser(id))
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"storage/internal/api"
+	"strings"
+	"testing"
+)
+
+func TestAPIV2(t *testing.T) {
+	d, a := CreateApi(t)
+	router := a.Start()
+	access := CreateRegisteredUser(t, router)
+	request := func(method, url string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/v2/expressions", `{"expression":"2+2"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var posted api.OutPostExpression
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &posted))
+	url := fmt.Sprintf("/api/v2/expressions/%v", posted.ID)
+	assert.Equal(t, url, w.Header().Get("Location"))
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/api/v2/expressions", `{}`).Code)
+
+	w = request(http.MethodGet, url, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var got api.OutGetExpressionByID
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, "2+2", got.Expression.Value)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/api/v2/expressions/abc", "").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/api/v2/expressions/0", "").Code)
+
+	// v1 adapter answers the same
+	w = request(http.MethodGet, "/api/v1/expressionById", fmt.Sprintf(`{"id":%v}`, posted.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	var gotV1 api.OutGetExpressionByID
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotV1))
+	assert.Equal(t, got, gotV1)
+
+	// actions that are not possible in the status are conflicts
+	assert.Equal(t, http.StatusConflict, request(http.MethodPost, url+"/requeue", "").Code)
+	assert.Equal(t, http.StatusConflict, request(http.MethodPost, url+"/retry", "").Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, url+"/cancel", "").Code)
+	assert.Equal(t, http.StatusConflict, request(http.MethodPost, url+"/cancel", "").Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, url+"/retry", `{"operations":{"+":5}}`).Code)
+	w = request(http.MethodGet, url+"/history", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var history api.OutGetExpressionHistory
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	assert.Len(t, history.Runs, 1)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/api/v2/expressions/0/history", "").Code)
+
+	w = request(http.MethodGet, "/api/v2/servers/unknown/expressions", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var byServer api.OutGetExpressionByServer
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &byServer))
+	assert.Empty(t, byServer.Expressions)
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/v2/servers", "").Code)
+
+	// PUT replaces all operation times
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPut, "/api/v2/operations", `{"+":1}`).Code)
+	assert.Equal(t, http.StatusBadRequest,
+		request(http.MethodPut, "/api/v2/operations", `{"+":1,"-":2,"*":3,"/":4,"^":5}`).Code)
+	assert.Equal(t, http.StatusBadRequest,
+		request(http.MethodPut, "/api/v2/operations", `{"+":-1,"-":2,"*":3,"/":4}`).Code)
+	w = request(http.MethodPut, "/api/v2/operations", `{"+":1,"-":2,"*":3,"/":4}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = request(http.MethodGet, "/api/v2/operations", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var operations api.OutGetOperationsAndTimes
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &operations))
+	assert.Equal(t, map[string]int{"+": 1, "-": 2, "*": 3, "/": 4}, operations.Data)
+
+	w = request(http.MethodDelete, url, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, http.StatusNotFound, request(http.MethodDelete, url, "").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, url, "").Code)
+
+	var user api.OutGetUser
+	require.NoError(t, json.Unmarshal(request(http.MethodGet, "/api/v1/getUser", "").Body.Bytes(), &user))
+	u, err := d.GetUserByUsername(user.Login)
+	require.NoError(t, err)
+	require.NoError(t, d.DeleteByUserId(u.ID))
+	require.NoError(t, d.DeleteUser(u.ID))
+}
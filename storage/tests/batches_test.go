@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"storage/internal/api"
+	"storage/internal/db"
+	"strings"
+	"testing"
+)
+
+func TestPostBatch(t *testing.T) {
+	t.Setenv("MAX_BATCH_SIZE", "3")
+	d, a := CreateApi(t)
+	router := a.Start()
+	access := CreateRegisteredUser(t, router)
+	request := func(method, url string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/v1/expressions:batch",
+		`{"expressions":[{"expression":"2+2"},{"expression":""},{"expression":"3*3","priority":11},{"expression":"1-1"}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = request(http.MethodPost, "/api/v1/expressions:batch", `{"expressions":[]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, "/api/v1/expressions:unknown", `{}`).Code)
+
+	w = request(http.MethodPost, "/api/v1/expressions:batch",
+		`{"expressions":[{"expression":"2+2"},{"expression":"3*3","priority":11},{"expression":"1-1","team":-1}]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var out api.OutPostBatch
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Len(t, out.Items, 3)
+	assert.NotZero(t, out.Items[0].ID)
+	assert.Empty(t, out.Items[0].Error)
+	assert.Zero(t, out.Items[1].ID)
+	assert.NotEmpty(t, out.Items[1].Error)
+	assert.Zero(t, out.Items[2].ID)
+	assert.NotEmpty(t, out.Items[2].Error)
+
+	w = request(http.MethodGet, fmt.Sprintf("/api/v2/expressions/%v", out.Items[0].ID), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var got api.OutGetExpressionByID
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, "2+2", got.Expression.Value)
+	assert.Equal(t, out.BatchID, got.Expression.Batch)
+
+	w = request(http.MethodGet, fmt.Sprintf("/api/v1/batches/%v", out.BatchID), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var progress api.OutGetBatch
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &progress))
+	assert.Equal(t, 1, progress.Batch.Size)
+	assert.Equal(t, map[int]int{db.ExpressionNotReady: 1}, progress.Counts)
+	assert.False(t, progress.Done)
+
+	w = request(http.MethodPost, fmt.Sprintf("/api/v1/expression/%v/cancel", out.Items[0].ID), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	w = request(http.MethodGet, fmt.Sprintf("/api/v1/batches/%v", out.BatchID), "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &progress))
+	assert.Equal(t, 1, progress.Finished)
+	assert.True(t, progress.Done)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/api/v1/batches/0", "").Code)
+
+	var user api.OutGetUser
+	require.NoError(t, json.Unmarshal(request(http.MethodGet, "/api/v1/getUser", "").Body.Bytes(), &user))
+	u, err := d.GetUserByUsername(user.Login)
+	require.NoError(t, err)
+	require.NoError(t, d.DeleteExpression(out.Items[0].ID))
+	require.NoError(t, d.DeleteByUserId(u.ID))
+	require.NoError(t, d.DeleteUser(u.ID))
+}
@@ -0,0 +1,298 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"storage/internal/api"
+	"storage/internal/apierrors"
+	"storage/pkg/client"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use((&api.API{}).Problems)
+	router.GET("/api/v2/expressions/:id", func(c *gin.Context) {
+		_ = c.Error(apierrors.New(apierrors.CodeNotFound, "expression not found"))
+		c.JSON(http.StatusNotFound, gin.H{"message": "expression not found"})
+	})
+	router.GET("/api/v2/servers", func(c *gin.Context) {
+		c.Header("Retry-After", "3")
+		c.JSON(http.StatusTooManyRequests, gin.H{"message": "too many requests"})
+	})
+	router.GET("/api/v2/operations", func(c *gin.Context) {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "pq: connection refused"})
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+	c := client.New(server.URL)
+	ctx := context.Background()
+
+	_, err := c.Expression(ctx, 1)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+	assert.NotErrorIs(t, err, client.ErrConflict)
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.Status)
+	assert.Equal(t, client.CodeNotFound, apiErr.Code)
+	assert.Equal(t, "expression not found", apiErr.Detail)
+	assert.Equal(t, "/api/v2/expressions/1", apiErr.Instance)
+	assert.False(t, client.IsRetryable(err))
+
+	_, err = c.Servers(ctx)
+	assert.ErrorIs(t, err, client.ErrRateLimited)
+	assert.True(t, client.IsRetryable(err))
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 3*time.Second, apiErr.RetryAfter)
+
+	// details of internal errors are not sent, the correlation ID is in the error
+	_, err = c.Operations(ctx)
+	assert.ErrorIs(t, err, client.ErrInternal)
+	require.ErrorAs(t, err, &apiErr)
+	assert.NotEmpty(t, apiErr.CorrelationID)
+	assert.NotContains(t, err.Error(), "pq:")
+	assert.Contains(t, err.Error(), apiErr.CorrelationID)
+}
+
+// tokenServer issues rotating tokens, a request with an old access token gets 401.
+type tokenServer struct {
+	mu        sync.Mutex
+	access    string
+	refresh   string
+	issued    int
+	refreshes atomic.Int32
+}
+
+func (s *tokenServer) issue() {
+	s.issued++
+	s.access = fmt.Sprintf("access-%v", s.issued)
+	s.refresh = fmt.Sprintf("refresh-%v", s.issued)
+}
+
+func (s *tokenServer) router() *gin.Engine {
+	router := gin.New()
+	router.Use((&api.API{}).Problems)
+	router.POST("/api/v1/refresh", func(c *gin.Context) {
+		var in api.InRefresh
+		_ = c.ShouldBindJSON(&in)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.refreshes.Add(1)
+		if in.Refresh != s.refresh {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "refresh token is already used"})
+			return
+		}
+		s.issue()
+		c.JSON(http.StatusOK, api.OutRefresh{Access: s.access, Refresh: s.refresh, Message: "ok"})
+	})
+	router.GET("/api/v2/operations", func(c *gin.Context) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if c.GetHeader("Authorization") != "Bearer "+s.access {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "token is expired"})
+			return
+		}
+		c.JSON(http.StatusOK, api.OutGetOperationsAndTimes{Data: map[string]int{"+": 1}, Message: "ok"})
+	})
+	return router
+}
+
+func TestClientRefreshesTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokens := &tokenServer{}
+	tokens.issue()
+	server := httptest.NewServer(tokens.router())
+	defer server.Close()
+
+	var saved atomic.Value
+	c := client.New(server.URL, client.WithTokens("expired", tokens.refresh),
+		client.WithTokensCallback(func(access string, refresh string) {
+			saved.Store(access + " " + refresh)
+		}))
+	ctx := context.Background()
+
+	// concurrent requests with the expired token refresh it only once, the refresh token can not be reused
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			operations, err := c.Operations(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]int{"+": 1}, operations)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), tokens.refreshes.Load())
+	assert.Equal(t, client.Tokens{Access: "access-2", Refresh: "refresh-2"}, c.Tokens())
+	assert.Equal(t, "access-2 refresh-2", saved.Load())
+
+	// the refresh fails, the error of the request is returned
+	c = client.New(server.URL, client.WithTokens("expired", "refresh-1"))
+	_, err := c.Operations(ctx)
+	assert.ErrorIs(t, err, client.ErrUnauthenticated)
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "token is expired", apiErr.Detail)
+
+	c = client.New(server.URL, client.WithTokens("expired", ""))
+	_, err = c.Operations(ctx)
+	assert.ErrorIs(t, err, client.ErrUnauthenticated)
+}
+
+func TestClientOIDCBinding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/oidc/login", func(c *gin.Context) {
+		c.SetCookie("oidc_binding", "binding-1", 600, "/api/v1/oidc", "", false, true)
+		c.JSON(http.StatusOK, gin.H{"url": "https://idp.example.com/authorize", "message": "ok"})
+	})
+	router.POST("/api/v1/oidc/callback", func(c *gin.Context) {
+		if binding, _ := c.Cookie("oidc_binding"); binding != "binding-1" {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "state was issued to another browser"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"access": "access-1", "refresh": "refresh-1", "message": "ok"})
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+	ctx := context.Background()
+
+	// the callback is sent with the cookie of the login, the default HTTP client has no cookie jar
+	c := client.New(server.URL)
+	loginURL, err := c.OIDCLoginURL(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "https://idp.example.com/authorize", loginURL)
+	tokens, err := c.OIDCCallback(ctx, "code", "state")
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", tokens.Access)
+
+	// another client did not start the login
+	_, err = client.New(server.URL).OIDCCallback(ctx, "code", "state")
+	assert.ErrorIs(t, err, client.ErrUnauthenticated)
+}
+
+func TestClientEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	var lastEventID string
+	router.GET("/api/v2/events", func(c *gin.Context) {
+		lastEventID = c.GetHeader("Last-Event-ID")
+		c.Header("Content-Type", "text/event-stream")
+		c.String(http.StatusOK, "event: reset\ndata: {}\n\n: ping\n\n"+
+			"id: 7\nevent: finished\ndata: {\"id\":\"7\",\"type\":\"finished\",\"expression\":{\"id\":3,\"ready\":2}}\n\n")
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	stream, err := client.New(server.URL).Events(context.Background(), "5")
+	require.NoError(t, err)
+	defer stream.Close()
+	assert.Equal(t, "5", lastEventID)
+	require.True(t, stream.Next())
+	assert.Equal(t, client.EventReset, stream.Event().Type)
+	require.True(t, stream.Next())
+	assert.Equal(t, client.Event{ID: "7", Type: client.EventFinished,
+		Expression: client.Expression{ID: 3, Status: client.StatusReady}}, stream.Event())
+	assert.Equal(t, "7", stream.LastEventID())
+	assert.False(t, stream.Next())
+	assert.NoError(t, stream.Err())
+}
+
+func TestClient(t *testing.T) {
+	t.Setenv("ACCESS_TOKEN_LIFETIME", "1")
+	d, a := CreateApi(t)
+	server := httptest.NewServer(a.Start())
+	defer server.Close()
+	c := client.New(server.URL)
+	ctx := context.Background()
+
+	require.NoError(t, c.Ping(ctx))
+	kinds, err := c.ErrorCatalogue(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, kinds)
+
+	login := fmt.Sprintf("client%v", time.Now().UnixNano())
+	tokens, err := c.Register(ctx, login, testPassword)
+	require.NoError(t, err)
+	assert.False(t, tokens.NeedsTwoFactor())
+	_, err = client.New(server.URL).Login(ctx, login, "wrong-password")
+	assert.ErrorIs(t, err, client.ErrUnauthenticated)
+	tokens, err = c.Login(ctx, login, testPassword)
+	require.NoError(t, err)
+	assert.Equal(t, tokens.Access, c.Tokens().Access)
+
+	ids := make([]int, 0)
+	for _, value := range []string{"1+1", "2+2", "3+3"} {
+		id, err := c.PostExpression(ctx, client.NewExpression{Expression: value})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	_, err = c.PostExpression(ctx, client.NewExpression{})
+	assert.ErrorIs(t, err, client.ErrInvalidArgument)
+
+	// the iterator follows cursors of pages
+	it := c.Expressions(ctx, client.ExpressionsQuery{Limit: 1, Sort: "creation_time"})
+	listed := make([]int, 0)
+	for it.Next() {
+		listed = append(listed, it.Expression().ID)
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, ids, listed)
+
+	expression, err := c.CancelExpression(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, client.StatusCancelled, expression.Status)
+	expression, err = c.WaitExpression(ctx, ids[0])
+	require.NoError(t, err)
+	assert.True(t, expression.Finished())
+
+	_, err = c.Expression(ctx, -1)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	// the access token expires, the client refreshes it
+	time.Sleep(2 * time.Second)
+	expression, err = c.Expression(ctx, ids[1])
+	require.NoError(t, err)
+	assert.Equal(t, "2+2", expression.Value)
+	assert.NotEqual(t, tokens.Access, c.Tokens().Access)
+	assert.NotEqual(t, tokens.Refresh, c.Tokens().Refresh)
+
+	export, err := c.ExportExpressions(ctx, client.FormatJSONL, client.ExpressionsQuery{})
+	require.NoError(t, err)
+	data, err := io.ReadAll(export)
+	require.NoError(t, err)
+	require.NoError(t, export.Close())
+	assert.Equal(t, 3, strings.Count(string(data), "\n"))
+
+	// the request is cancelled with the context
+	cancelled, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, _, err = c.ExpressionResult(cancelled, ids[2], 30*time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	user, err := d.GetUserByUsername(login)
+	require.NoError(t, err)
+	for _, id := range ids {
+		require.NoError(t, c.DeleteExpression(ctx, id))
+	}
+	require.NoError(t, c.Logout(ctx))
+	assert.Equal(t, client.Tokens{}, c.Tokens())
+	_, err = c.Expression(ctx, ids[1])
+	assert.ErrorIs(t, err, client.ErrUnauthenticated)
+
+	require.NoError(t, d.DeleteByUserId(user.ID))
+	require.NoError(t, d.DeleteUser(user.ID))
+}
@@ -2,7 +2,10 @@ package tests
 
 import (
 	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
 	"storage/internal/cryptPasswords"
+	"strings"
 	"testing"
 )
 
@@ -19,3 +22,33 @@ func TestCreateAndCompare(t *testing.T) {
 	hash, err = cryptPasswords.GeneratePasswordHash("passwordpasswordpasswordpasswordpasswordpasswordpasswordpasswordpasswordpasswordpasswordpasswordpasswordpasswordpassword")
 	require.Error(t, err)
 }
+
+func TestTokens(t *testing.T) {
+	token, err := cryptPasswords.GenerateToken()
+	require.NoError(t, err)
+	other, err := cryptPasswords.GenerateToken()
+	require.NoError(t, err)
+	require.NotEqual(t, token, other)
+
+	require.Equal(t, cryptPasswords.HashToken(token), cryptPasswords.HashToken(token))
+	require.NotEqual(t, cryptPasswords.HashToken(token), cryptPasswords.HashToken(other))
+	require.NotContains(t, cryptPasswords.HashToken(token), token)
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := cryptPasswords.NewPasswordPolicy(0, []string{"Tr0ub4dor&3"})
+	require.Equal(t, cryptPasswords.DefaultMinPasswordLength, policy.MinLength)
+
+	require.NoError(t, policy.Check("user", "correct-horse-battery"))
+	require.ErrorIs(t, policy.Check("user", "a"), cryptPasswords.ErrWeakPassword)
+	require.ErrorIs(t, policy.Check("user", "Password123"), cryptPasswords.ErrWeakPassword)
+	require.ErrorIs(t, policy.Check("user", "tr0ub4dor&3"), cryptPasswords.ErrWeakPassword)
+	require.ErrorIs(t, policy.Check("long-login", "Long-Login"), cryptPasswords.ErrWeakPassword)
+	require.ErrorIs(t, policy.Check("user", strings.Repeat("a", 73)), cryptPasswords.ErrWeakPassword)
+
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("first-password\n\n  second-password  \n"), 0o600))
+	denylist, err := cryptPasswords.LoadDenylist(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"first-password", "second-password"}, denylist)
+}
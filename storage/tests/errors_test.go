@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"storage/internal/api"
+	"storage/internal/apierrors"
+	"storage/internal/expressionstorage"
+	"storage/internal/gRPCServer"
+	"testing"
+)
+
+func TestErrorCatalogue(t *testing.T) {
+	assert.Equal(t, http.StatusNotFound, apierrors.Lookup(apierrors.CodeNotFound).HTTPStatus)
+	assert.Equal(t, apierrors.CodeInternal, apierrors.Lookup("unknown").Code)
+	assert.Equal(t, apierrors.CodeRateLimited, apierrors.ForHTTPStatus(http.StatusTooManyRequests).Code)
+	assert.Equal(t, apierrors.CodeInvalidArgument, apierrors.ForHTTPStatus(http.StatusTeapot).Code)
+	assert.Equal(t, apierrors.CodeInternal, apierrors.ForHTTPStatus(http.StatusBadGateway).Code)
+	assert.True(t, apierrors.Lookup(apierrors.CodeUnavailable).Retryable)
+	assert.False(t, apierrors.Lookup(apierrors.CodeInvalidArgument).Retryable)
+
+	wrapped := fmt.Errorf("cancel: %w", expressionstorage.ErrFinished)
+	assert.Equal(t, apierrors.CodeConflict, apierrors.CodeOf(wrapped))
+	assert.Equal(t, apierrors.CodeInternal, apierrors.CodeOf(errors.New("pq: connection refused")))
+
+	st := expressionstorage.ErrNotFound.GRPCStatus()
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, expressionstorage.ErrNotFound.Error(), st.Message())
+	require.Len(t, st.Details(), 1)
+	info := st.Details()[0].(*errdetails.ErrorInfo)
+	assert.Equal(t, string(apierrors.CodeNotFound), info.Reason)
+	assert.Equal(t, apierrors.Domain, info.Domain)
+	assert.Equal(t, "false", info.Metadata["retryable"])
+
+	assert.True(t, apierrors.IsValidCorrelationID(apierrors.NewCorrelationID()))
+	assert.False(t, apierrors.IsValidCorrelationID("id\nwith newline"))
+	assert.False(t, apierrors.IsValidCorrelationID(""))
+}
+
+func TestProblemDetails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use((&api.API{}).Problems)
+	router.GET("/ok", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+	router.GET("/invalid", func(c *gin.Context) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "value is required", "items": []int{1, 2}})
+	})
+	router.GET("/internal", func(c *gin.Context) {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "pq: relation users does not exist"})
+	})
+	router.GET("/aborted", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusForbidden)
+	})
+	router.GET("/typed", func(c *gin.Context) {
+		_ = c.Error(apierrors.New(apierrors.CodeRequestInProgress, "being handled"))
+		c.JSON(http.StatusConflict, gin.H{"message": "being handled"})
+	})
+	router.GET("/untyped", func(c *gin.Context) {
+		err := errors.New("pq: duplicate key value violates unique constraint")
+		_ = c.Error(err)
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+	})
+	get := func(path string, correlationID string) (*httptest.ResponseRecorder, map[string]any) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		if correlationID != "" {
+			req.Header.Set(api.HeaderCorrelationID, correlationID)
+		}
+		router.ServeHTTP(w, req)
+		problem := make(map[string]any)
+		_ = json.Unmarshal(w.Body.Bytes(), &problem)
+		return w, problem
+	}
+
+	w, body := get("/ok", "client-id-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "client-id-1", w.Header().Get(api.HeaderCorrelationID))
+	assert.Equal(t, map[string]any{"message": "ok"}, body)
+
+	w, body = get("/invalid", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.NotEmpty(t, w.Header().Get(api.HeaderCorrelationID))
+	assert.Equal(t, "/api/v1/errors#invalid_argument", body["type"])
+	assert.Equal(t, "invalid_argument", body["code"])
+	assert.Equal(t, float64(http.StatusBadRequest), body["status"])
+	assert.Equal(t, "value is required", body["detail"])
+	assert.Equal(t, "value is required", body["message"])
+	assert.Equal(t, "/invalid", body["instance"])
+	assert.Equal(t, false, body["retryable"])
+	assert.Equal(t, []any{float64(1), float64(2)}, body["items"])
+	assert.NotContains(t, body, "correlation_id")
+
+	w, body = get("/internal", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "internal", body["code"])
+	assert.NotContains(t, w.Body.String(), "pq:")
+	assert.Equal(t, w.Header().Get(api.HeaderCorrelationID), body["correlation_id"])
+
+	w, body = get("/aborted", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "permission_denied", body["code"])
+	assert.Equal(t, http.StatusText(http.StatusForbidden), body["detail"])
+
+	_, body = get("/typed", "")
+	assert.Equal(t, "request_in_progress", body["code"])
+	assert.Equal(t, true, body["retryable"])
+
+	// errors without code are not sent in 4xx either
+	w, body = get("/untyped", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "conflict", body["code"])
+	assert.Equal(t, "Conflict", body["detail"])
+	assert.NotContains(t, w.Body.String(), "pq:")
+
+	w, body = get("/unknown", "bad id\twith tab")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "not_found", body["code"])
+	assert.NotEqual(t, "bad id\twith tab", w.Header().Get(api.HeaderCorrelationID))
+}
+
+func TestGRPCErrors(t *testing.T) {
+	call := func(err error) *status.Status {
+		_, err = gRPCServer.ErrorsUnaryInterceptor(context.Background(), nil,
+			&grpc.UnaryServerInfo{FullMethod: "/test"}, func(context.Context, interface{}) (interface{}, error) {
+				return nil, err
+			})
+		require.Error(t, err)
+		st, ok := status.FromError(err)
+		require.True(t, ok)
+		return st
+	}
+	errorInfo := func(st *status.Status) *errdetails.ErrorInfo {
+		require.Len(t, st.Details(), 1)
+		return st.Details()[0].(*errdetails.ErrorInfo)
+	}
+
+	st := call(expressionstorage.ErrNotFound)
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, string(apierrors.CodeNotFound), errorInfo(st).Reason)
+
+	st = call(status.Error(codes.Unauthenticated, "credential is not valid"))
+	assert.Equal(t, codes.Unauthenticated, st.Code())
+	assert.Equal(t, "credential is not valid", st.Message())
+	assert.Equal(t, string(apierrors.CodeUnauthenticated), errorInfo(st).Reason)
+
+	st = call(context.DeadlineExceeded)
+	assert.Equal(t, codes.DeadlineExceeded, st.Code())
+	assert.Equal(t, "true", errorInfo(st).Metadata["retryable"])
+
+	st = call(errors.New("pq: password authentication failed"))
+	assert.Equal(t, codes.Internal, st.Code())
+	assert.NotContains(t, st.Message(), "pq:")
+	assert.True(t, apierrors.IsValidCorrelationID(errorInfo(st).Metadata["correlation_id"]))
+}
@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"storage/internal/api"
+	"storage/internal/db"
+	"storage/internal/events"
+	"storage/internal/expressionstorage"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventsBroker(t *testing.T) {
+	broker := events.New(3)
+	first := broker.Publish(expressionstorage.EventCreated, db.Expression{ID: 1})
+	subscription, missed, complete := broker.Subscribe("")
+	assert.Empty(t, missed)
+	assert.True(t, complete)
+
+	second := broker.Publish(expressionstorage.EventStatus, db.Expression{ID: 1, Status: db.ExpressionWorking})
+	received := <-subscription.C
+	assert.Equal(t, second.ID, received.ID)
+	assert.Equal(t, expressionstorage.EventStatus, received.Type)
+	subscription.Close()
+	_, ok := <-subscription.C
+	assert.False(t, ok)
+
+	// resume after the first event
+	subscription, missed, complete = broker.Subscribe(first.ID)
+	assert.True(t, complete)
+	require.Len(t, missed, 1)
+	assert.Equal(t, second.ID, missed[0].ID)
+	subscription.Close()
+
+	// the first event is not kept anymore
+	for i := 0; i < 3; i++ {
+		broker.Publish(expressionstorage.EventProgress, db.Expression{ID: 1})
+	}
+	_, missed, complete = broker.Subscribe(first.ID)
+	assert.False(t, complete)
+	assert.Empty(t, missed)
+	_, _, complete = broker.Subscribe(second.ID)
+	assert.True(t, complete)
+
+	// events of another start of storage
+	_, _, complete = events.New(3).Subscribe(second.ID)
+	assert.False(t, complete)
+	_, _, complete = broker.Subscribe("unknown")
+	assert.False(t, complete)
+
+	// slow subscriber is disconnected
+	slow, _, _ := broker.Subscribe("")
+	for i := 0; i < 100; i++ {
+		broker.Publish(expressionstorage.EventProgress, db.Expression{ID: 1})
+	}
+	count := 0
+	for range slow.C {
+		count++
+	}
+	assert.Less(t, count, 100)
+}
+
+// sseEvent is an event of text/event-stream.
+type sseEvent struct {
+	id   string
+	name string
+	data string
+}
+
+func readEvent(t *testing.T, reader *bufio.Reader) sseEvent {
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && event.name != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestExpressionEvents(t *testing.T) {
+	d, a := CreateApi(t)
+	router := a.Start()
+	server := httptest.NewServer(router)
+	defer server.Close()
+	access := CreateRegisteredUser(t, router)
+	otherAccess := CreateRegisteredUser(t, router)
+
+	stream := func(access string, lastEventID string) (*bufio.Reader, func()) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/events", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+access)
+		req.Header.Set("Last-Event-ID", lastEventID)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		return bufio.NewReader(resp.Body), func() { _ = resp.Body.Close() }
+	}
+	request := func(method, url string, access string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	reader, closeStream := stream(access, "")
+	// the stream is subscribed when headers are sent, events of other users are not sent
+	w := request(http.MethodPost, "/api/v2/expressions", otherAccess, `{"expression":"1+1"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var other api.OutPostExpression
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &other))
+	w = request(http.MethodPost, "/api/v2/expressions", access, `{"expression":"2+2"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var posted api.OutPostExpression
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &posted))
+
+	created := readEvent(t, reader)
+	assert.Equal(t, expressionstorage.EventCreated, created.name)
+	var event events.Event
+	require.NoError(t, json.Unmarshal([]byte(created.data), &event))
+	assert.Equal(t, posted.ID, event.Expression.ID)
+	assert.Equal(t, created.id, event.ID)
+
+	require.Equal(t, http.StatusOK,
+		request(http.MethodPost, fmt.Sprintf("/api/v2/expressions/%v/cancel", posted.ID), access, "").Code)
+	finished := readEvent(t, reader)
+	assert.Equal(t, expressionstorage.EventFinished, finished.name)
+	require.NoError(t, json.Unmarshal([]byte(finished.data), &event))
+	assert.Equal(t, db.ExpressionCancelled, event.Expression.Status)
+	closeStream()
+
+	// missed events are sent after reconnect
+	require.Equal(t, http.StatusNoContent,
+		request(http.MethodDelete, fmt.Sprintf("/api/v2/expressions/%v", posted.ID), access, "").Code)
+	reader, closeStream = stream(access, finished.id)
+	deleted := readEvent(t, reader)
+	assert.Equal(t, expressionstorage.EventDeleted, deleted.name)
+	closeStream()
+
+	reader, closeStream = stream(access, "unknown-1")
+	assert.Equal(t, "reset", readEvent(t, reader).name)
+	closeStream()
+
+	// wait until streams are closed by the server
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, d.DeleteExpression(other.ID))
+	for _, token := range []string{access, otherAccess} {
+		var out api.OutGetUser
+		require.NoError(t, json.Unmarshal(request(http.MethodGet, "/api/v1/getUser", token, "").Body.Bytes(), &out))
+		user, err := d.GetUserByUsername(out.Login)
+		require.NoError(t, err)
+		require.NoError(t, d.DeleteByUserId(user.ID))
+		require.NoError(t, d.DeleteUser(user.ID))
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"storage/internal/api"
+	"storage/internal/db"
+	"strings"
+	"testing"
+)
+
+func TestExportAndImportExpressions(t *testing.T) {
+	d, a := CreateApi(t)
+	router := a.Start()
+	access := CreateRegisteredUser(t, router)
+	request := func(method, url string, contentType string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+access)
+		req.Header.Set("Content-Type", contentType)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/v1/expressions/import", "text/csv",
+		"priority,value\n1,2+2\nhigh,3+3\n0,\n-5,4+4\n\"5,\"5+5\"\n")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var imported api.OutImportExpressions
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &imported))
+	assert.NotZero(t, imported.BatchID)
+	assert.Equal(t, 1, imported.Imported)
+	lines := make([]int, 0)
+	for _, importErr := range imported.Errors {
+		lines = append(lines, importErr.Line)
+	}
+	assert.Equal(t, []int{3, 4, 5, 6}, lines)
+
+	w = request(http.MethodPost, "/api/v1/expressions/import?format=jsonl", "",
+		"{\"value\":\"6+6\",\"ready\":2,\"answer\":12}\n\n{\"value\":7}\n{\"value\":\"7+7\",\"priority\":2}\n")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &imported))
+	assert.Equal(t, 2, imported.Imported)
+	require.Len(t, imported.Errors, 1)
+	assert.Equal(t, 3, imported.Errors[0].Line)
+
+	assert.Equal(t, http.StatusBadRequest,
+		request(http.MethodPost, "/api/v1/expressions/import", "text/plain", "value\n1+1\n").Code)
+	assert.Equal(t, http.StatusBadRequest,
+		request(http.MethodPost, "/api/v1/expressions/import", "text/csv", "expression\n1+1\n").Code)
+	assert.Equal(t, http.StatusBadRequest,
+		request(http.MethodPost, "/api/v1/expressions/import", "text/csv", "value\n\n").Code)
+
+	w = request(http.MethodGet, "/api/v1/expressions/export?sort=creation_time&q=%2B", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+	records, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, "id", records[0][0])
+	values := make([]string, 0)
+	for _, record := range records[1:] {
+		values = append(values, record[1])
+		assert.Equal(t, fmt.Sprint(db.ExpressionNotReady), record[4])
+	}
+	assert.ElementsMatch(t, []string{"2+2", "6+6", "7+7"}, values)
+
+	w = request(http.MethodGet, "/api/v1/expressions/export?format=jsonl&q=7", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	scanner := bufio.NewScanner(w.Body)
+	expressions := make([]db.Expression, 0)
+	for scanner.Scan() {
+		var expression db.Expression
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &expression))
+		expressions = append(expressions, expression)
+	}
+	require.Len(t, expressions, 1)
+	assert.Equal(t, 2, expressions[0].Priority)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/api/v1/expressions/export?format=xml", "", "").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/api/v1/expressions/export?status=9", "", "").Code)
+
+	var out api.OutGetAllExpressions
+	require.NoError(t, json.Unmarshal(request(http.MethodGet, "/api/v1/expression", "", "").Body.Bytes(), &out))
+	for _, expression := range out.Expressions {
+		require.NoError(t, d.DeleteExpression(expression.ID))
+	}
+	var user api.OutGetUser
+	require.NoError(t, json.Unmarshal(request(http.MethodGet, "/api/v1/getUser", "", "").Body.Bytes(), &user))
+	dbUser, err := d.GetUserByUsername(user.Login)
+	require.NoError(t, err)
+	require.NoError(t, d.DeleteByUserId(dbUser.ID))
+	require.NoError(t, d.DeleteUser(dbUser.ID))
+}
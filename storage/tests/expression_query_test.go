@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"storage/internal/api"
+	"storage/internal/db"
+	"storage/internal/expressionstorage"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelectExpressions(t *testing.T) {
+	expressions := []db.Expression{
+		{ID: 1, Value: "2+2", Status: db.ExpressionReady, CreationTime: "2024-01-01 10:00:00",
+			EndCalculationTime: "2024-01-01 10:00:30", Servername: "a"},
+		{ID: 2, Value: "3*3", Status: db.ExpressionError, CreationTime: "2024-01-01 11:00:00",
+			EndCalculationTime: "2024-01-01 11:00:05", Servername: "b"},
+		{ID: 3, Value: "2+5", Status: db.ExpressionNotReady, CreationTime: "2024-01-01 12:00:00"},
+		{ID: 4, Value: "7-2", Status: db.ExpressionReady, CreationTime: "2024-01-01 12:00:00",
+			EndCalculationTime: "2024-01-01 12:01:00", Servername: "a"},
+	}
+	ids := func(page expressionstorage.Page) []int {
+		return expressionIDs(page.Expressions)
+	}
+
+	// all pages together give every expression once, expressions that are not finished have no end time
+	for sort, all := range map[string][]int{
+		expressionstorage.SortCreationTime: {1, 2, 3, 4},
+		expressionstorage.SortEndTime:      {1, 2, 4},
+		expressionstorage.SortDuration:     {1, 2, 4},
+	} {
+		for _, descending := range []bool{false, true} {
+			query := expressionstorage.Query{Sort: sort, Descending: descending, Limit: 2}
+			first, err := expressionstorage.Select(expressions, query)
+			require.NoError(t, err)
+			require.Len(t, first.Expressions, 2)
+			require.NotEmpty(t, first.NextCursor)
+			query.Cursor = first.NextCursor
+			second, err := expressionstorage.Select(expressions, query)
+			require.NoError(t, err)
+			assert.Empty(t, second.NextCursor)
+			assert.ElementsMatch(t, all, append(ids(first), ids(second)...))
+			assert.Equal(t, len(all), second.Total)
+		}
+	}
+
+	page, err := expressionstorage.Select(expressions, expressionstorage.Query{Sort: expressionstorage.SortDuration})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 1, 4}, ids(page))
+	page, err = expressionstorage.Select(expressions, expressionstorage.Query{Descending: true})
+	require.NoError(t, err)
+	assert.Equal(t, []int{4, 3, 2, 1}, ids(page))
+
+	// counts are not filtered by status
+	page, err = expressionstorage.Select(expressions, expressionstorage.Query{
+		Statuses: []int{db.ExpressionReady},
+		Contains: "2",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 4}, ids(page))
+	assert.Equal(t, map[int]int{db.ExpressionReady: 2, db.ExpressionNotReady: 1}, page.Counts)
+	assert.Equal(t, 2, page.Total)
+
+	after, err := time.ParseInLocation("2006-01-02 15:04:05", "2024-01-01 11:00:00", time.Local)
+	require.NoError(t, err)
+	page, err = expressionstorage.Select(expressions, expressionstorage.Query{
+		CreatedAfter:  after,
+		CreatedBefore: after.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, ids(page))
+	page, err = expressionstorage.Select(expressions, expressionstorage.Query{Server: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 4}, ids(page))
+
+	// cursor of another order is not accepted
+	first, err := expressionstorage.Select(expressions, expressionstorage.Query{Limit: 1})
+	require.NoError(t, err)
+	_, err = expressionstorage.Select(expressions, expressionstorage.Query{Descending: true, Cursor: first.NextCursor})
+	assert.ErrorIs(t, err, expressionstorage.ErrInvalidCursor)
+	_, err = expressionstorage.Select(expressions, expressionstorage.Query{Cursor: "???"})
+	assert.ErrorIs(t, err, expressionstorage.ErrInvalidCursor)
+	_, err = expressionstorage.Select(expressions, expressionstorage.Query{Sort: "value"})
+	assert.ErrorIs(t, err, expressionstorage.ErrInvalidSort)
+}
+
+func TestListExpressions(t *testing.T) {
+	d, a := CreateApi(t)
+	router := a.Start()
+	access := CreateRegisteredUser(t, router)
+	list := func(url string) (int, api.OutGetAllExpressions) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		var out api.OutGetAllExpressions
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		return w.Code, out
+	}
+	ids := make([]int, 0)
+	for _, expression := range []string{"1+1", "2+2", "3+3"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v2/expressions",
+			strings.NewReader(`{"expression":"`+expression+`"}`))
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+		var posted api.OutPostExpression
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &posted))
+		ids = append(ids, posted.ID)
+	}
+
+	code, out := list("/api/v2/expressions?limit=2&sort=creation_time")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, out.Expressions, 2)
+	assert.Equal(t, "1+1", out.Expressions[0].Value)
+	assert.Equal(t, 3, out.Total)
+	assert.Equal(t, map[int]int{db.ExpressionNotReady: 3}, out.Counts)
+	code, out = list("/api/v2/expressions?limit=2&sort=creation_time&cursor=" + out.NextCursor)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, out.Expressions, 1)
+	assert.Equal(t, "3+3", out.Expressions[0].Value)
+	assert.Empty(t, out.NextCursor)
+
+	code, out = list("/api/v1/expression?q=2%2B2&status=0")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, out.Expressions, 1)
+	assert.Equal(t, "2+2", out.Expressions[0].Value)
+
+	for _, url := range []string{"/api/v2/expressions?status=9", "/api/v2/expressions?limit=0",
+		"/api/v2/expressions?sort=value", "/api/v2/expressions?cursor=abc",
+		"/api/v2/expressions?created_after=yesterday"} {
+		code, _ = list(url)
+		assert.Equal(t, http.StatusBadRequest, code, url)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/getUser", nil)
+	req.Header.Set("Authorization", "Bearer "+access)
+	router.ServeHTTP(w, req)
+	var got api.OutGetUser
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	user, err := d.GetUserByUsername(got.Login)
+	require.NoError(t, err)
+	for _, id := range ids {
+		require.NoError(t, d.DeleteExpression(id))
+	}
+	require.NoError(t, d.DeleteByUserId(user.ID))
+	require.NoError(t, d.DeleteUser(user.ID))
+}
@@ -348,3 +348,70 @@ func TestKeepAliveExpressions(t *testing.T) {
 	err = d.DeleteUser(newUser)
 	require.NoError(t, err)
 }
+
+func TestAbandonAndRequeueExpression(t *testing.T) {
+	d, err := db.New()
+	require.NoError(t, err)
+
+	servers := &sync.Map{}
+	servers.Store("server", "")
+
+	e := expressionstorage.New(d, 1, servers)
+	require.Error(t, e.SetMaxAttempts(0))
+	require.NoError(t, e.SetMaxAttempts(2))
+
+	newUser := CreateTestUser(t, d)
+
+	newID, err := e.Add(db.Expression{
+		Value:          "2 + 2",
+		Status:         db.ExpressionWorking,
+		User:           newUser,
+		Servername:     "server",
+		AliveExpiresAt: int(time.Now().Unix()),
+	})
+	require.NoError(t, err)
+
+	// first lease expires, expression is pending again
+	time.Sleep(1 * time.Second)
+	expression, err := e.GetByID(newID)
+	require.NoError(t, err)
+	assert.Equal(t, db.ExpressionNotReady, expression.Status)
+	assert.Equal(t, 1, expression.Attempts)
+
+	_, err = e.Requeue(newUser, newID)
+	require.Error(t, err)
+
+	// second lease expires, expression is abandoned
+	expression.Status = db.ExpressionWorking
+	expression.Servername = "server2"
+	expression.AliveExpiresAt = int(time.Now().Unix())
+	require.NoError(t, e.UpdateExpression(expression))
+
+	time.Sleep(1 * time.Second)
+	expression, err = e.GetByID(newID)
+	require.NoError(t, err)
+	assert.Equal(t, db.ExpressionAbandoned, expression.Status)
+	assert.Equal(t, 2, expression.Attempts)
+	assert.Equal(t, "server, server2", expression.FailedServers)
+	assert.NotEmpty(t, e.GetAll(newUser))
+	for _, pending := range e.GetNotWorkingExpressions() {
+		assert.NotEqual(t, newID, pending.ID)
+	}
+
+	fromDB, err := d.GetExpressionByID(newID)
+	require.NoError(t, err)
+	assert.Equal(t, db.ExpressionAbandoned, fromDB.Status)
+
+	_, err = e.Requeue(newUser+1, newID)
+	require.Error(t, err)
+
+	expression, err = e.Requeue(newUser, newID)
+	require.NoError(t, err)
+	assert.Equal(t, db.ExpressionNotReady, expression.Status)
+	assert.Equal(t, 0, expression.Attempts)
+
+	err = e.Delete(newID)
+	require.NoError(t, err)
+	err = d.DeleteUser(newUser)
+	require.NoError(t, err)
+}
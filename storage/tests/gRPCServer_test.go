@@ -161,29 +161,54 @@ func TestPostResult(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	res, err := client.PostResult(context.Background(), &gRPCServer.Expression{
-		Id:     int64(newExp),
-		UserId: int64(newUser),
-		Answer: 2,
-	})
+	result := &gRPCServer.Expression{
+		Id:         int64(newExp),
+		UserId:     int64(newUser),
+		Answer:     2,
+		Status:     db.ExpressionReady,
+		ServerName: "server",
+	}
+	res, err := client.PostResult(context.Background(), result)
 	require.NoError(t, err)
 	assert.NotEqual(t, "ok", res.Message)
 
 	_, err = client.ConfirmStartCalculating(context.Background(), &gRPCServer.Expression{
-		Id:     int64(newExp),
-		UserId: int64(newUser),
+		Id:         int64(newExp),
+		UserId:     int64(newUser),
+		ServerName: "server",
 	})
 	require.NoError(t, err)
 
+	// only the server that holds the lease can post the result
 	res, err = client.PostResult(context.Background(), &gRPCServer.Expression{
-		Id:     int64(newExp),
-		UserId: int64(newUser),
-		Answer: 2,
+		Id:         int64(newExp),
+		UserId:     int64(newUser),
+		Answer:     3,
+		Status:     db.ExpressionReady,
+		ServerName: "other",
 	})
 	require.NoError(t, err)
+	assert.NotEqual(t, "ok", res.Message)
 
+	// the result must be finished
+	_, err = client.PostResult(context.Background(), &gRPCServer.Expression{
+		Id:         int64(newExp),
+		UserId:     int64(newUser),
+		Status:     db.ExpressionNotReady,
+		ServerName: "server",
+	})
+	assert.Error(t, err)
+
+	res, err = client.PostResult(context.Background(), result)
+	require.NoError(t, err)
 	assert.Equal(t, "ok", res.Message)
 
+	expression, err := d.GetExpressionByID(newExp)
+	require.NoError(t, err)
+	assert.Equal(t, db.ExpressionReady, expression.Status)
+	assert.Equal(t, 2.0, expression.Answer)
+	assert.Equal(t, "server", expression.Servername)
+
 	err = d.DeleteExpression(newExp)
 	require.NoError(t, err)
 	err = d.DeleteUser(newUser)
@@ -271,3 +296,116 @@ func TestGetOperationsAndTimesForClient(t *testing.T) {
 	err = d.DeleteUser(newUser)
 	require.NoError(t, err)
 }
+
+func TestKeepAliveCancelled(t *testing.T) {
+	server, expressions, d := setupgRPCServer(t)
+	defer server.Stop()
+
+	client, conn := setupgRPCClient(t)
+	defer conn.Close()
+
+	newUser := createNewUser(t, d)
+
+	newExp, err := expressions.Add(db.Expression{
+		Value: "1+123",
+		User:  newUser,
+	})
+	require.NoError(t, err)
+
+	_, err = client.ConfirmStartCalculating(context.Background(), &gRPCServer.Expression{
+		Id:         int64(newExp),
+		UserId:     int64(newUser),
+		ServerName: "server",
+	})
+	require.NoError(t, err)
+
+	msg := &gRPCServer.KeepAliveMsg{
+		Expression: &gRPCServer.Expression{
+			Id:         int64(newExp),
+			UserId:     int64(newUser),
+			ServerName: "server",
+		},
+		StatusWorkers: "ok",
+	}
+	res, err := client.KeepAlive(context.Background(), msg)
+	require.NoError(t, err)
+	assert.False(t, res.Cancel)
+
+	_, err = expressions.Cancel(newUser, newExp)
+	require.NoError(t, err)
+
+	res, err = client.KeepAlive(context.Background(), msg)
+	require.NoError(t, err)
+	assert.True(t, res.Cancel)
+
+	err = expressions.Delete(newExp)
+	require.NoError(t, err)
+
+	res, err = client.KeepAlive(context.Background(), msg)
+	require.NoError(t, err)
+	assert.True(t, res.Cancel)
+
+	err = d.DeleteUser(newUser)
+	require.NoError(t, err)
+}
+
+func TestCancelWhilePostResult(t *testing.T) {
+	server, expressions, d := setupgRPCServer(t)
+	defer server.Stop()
+
+	client, conn := setupgRPCClient(t)
+	defer conn.Close()
+
+	newUser := createNewUser(t, d)
+
+	newExp, err := expressions.Add(db.Expression{
+		Value: "1+123",
+		User:  newUser,
+	})
+	require.NoError(t, err)
+
+	_, err = client.ConfirmStartCalculating(context.Background(), &gRPCServer.Expression{
+		Id:         int64(newExp),
+		UserId:     int64(newUser),
+		ServerName: "server",
+	})
+	require.NoError(t, err)
+
+	// either the cancel or the result wins, the other one does not overwrite it
+	var wg sync.WaitGroup
+	var cancelErr error
+	var res *gRPCServer.Message
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, cancelErr = expressions.Cancel(newUser, newExp)
+	}()
+	go func() {
+		defer wg.Done()
+		res, err = client.PostResult(context.Background(), &gRPCServer.Expression{
+			Id:         int64(newExp),
+			UserId:     int64(newUser),
+			ServerName: "server",
+			Answer:     124,
+			Status:     db.ExpressionReady,
+		})
+	}()
+	wg.Wait()
+	require.NoError(t, err)
+
+	expression, err := d.GetExpressionByID(newExp)
+	require.NoError(t, err)
+	if cancelErr == nil {
+		assert.NotEqual(t, "ok", res.Message)
+		assert.Equal(t, db.ExpressionCancelled, expression.Status)
+	} else {
+		assert.ErrorIs(t, cancelErr, expressionstorage.ErrFinished)
+		assert.Equal(t, "ok", res.Message)
+		assert.Equal(t, db.ExpressionReady, expression.Status)
+	}
+
+	err = d.DeleteExpression(newExp)
+	require.NoError(t, err)
+	err = d.DeleteUser(newUser)
+	require.NoError(t, err)
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"storage/internal/api"
+	"storage/internal/db"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	d, a := CreateApi(t)
+	router := a.Start()
+	access := CreateRegisteredUser(t, router)
+	post := func(url string, key string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+access)
+		req.Header.Set("Idempotency-Key", key)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post("/api/v1/expression", "key-1", `{"expression":"2+2"}`)
+	require.Equal(t, http.StatusOK, first.Code)
+	var posted api.OutPostExpression
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &posted))
+
+	// retry gets the same response, the expression is not added again
+	retried := post("/api/v1/expression", "key-1", `{"expression":"2+2"}`)
+	require.Equal(t, http.StatusOK, retried.Code)
+	assert.Equal(t, first.Body.String(), retried.Body.String())
+	assert.Equal(t, "true", retried.Header().Get("Idempotent-Replayed"))
+
+	assert.Equal(t, http.StatusConflict, post("/api/v1/expression", "key-1", `{"expression":"3+3"}`).Code)
+	assert.Equal(t, http.StatusConflict, post("/api/v2/expressions", "key-1", `{"expression":"2+2"}`).Code)
+	assert.Equal(t, http.StatusBadRequest,
+		post("/api/v1/expression", strings.Repeat("k", 256), `{"expression":"2+2"}`).Code)
+
+	// Location of v2 is replayed too
+	created := post("/api/v2/expressions", "key-2", `{"expression":"4+4"}`)
+	require.Equal(t, http.StatusCreated, created.Code)
+	replayed := post("/api/v2/expressions", "key-2", `{"expression":"4+4"}`)
+	assert.Equal(t, http.StatusCreated, replayed.Code)
+	assert.Equal(t, created.Header().Get("Location"), replayed.Header().Get("Location"))
+	var createdV2 api.OutPostExpression
+	require.NoError(t, json.Unmarshal(created.Body.Bytes(), &createdV2))
+
+	// errors of the request are replayed as well, the key is bound to the request
+	invalid := post("/api/v1/expression", "key-3", `{}`)
+	require.Equal(t, http.StatusBadRequest, invalid.Code)
+	replayedInvalid := post("/api/v1/expression", "key-3", `{}`)
+	assert.Equal(t, http.StatusBadRequest, replayedInvalid.Code)
+	assert.Equal(t, invalid.Body.String(), replayedInvalid.Body.String())
+	assert.Equal(t, "application/problem+json", replayedInvalid.Header().Get("Content-Type"))
+	// the problem details that were sent are saved, not the answer of the handler
+	expression, err := d.GetExpressionByID(posted.ID)
+	require.NoError(t, err)
+	saved, err := d.GetIdempotencyKey(expression.User, "key-3")
+	require.NoError(t, err)
+	assert.Equal(t, invalid.Body.String(), saved.Response)
+
+	// keys of different users do not conflict
+	otherAccess := CreateRegisteredUser(t, router)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/expression", strings.NewReader(`{"expression":"5+5"}`))
+	req.Header.Set("Authorization", "Bearer "+otherAccess)
+	req.Header.Set("Idempotency-Key", "key-1")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var otherPosted api.OutPostExpression
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &otherPosted))
+	assert.NotEqual(t, posted.ID, otherPosted.ID)
+
+	for _, cleanup := range []struct {
+		access string
+		ids    []int
+	}{{access, []int{posted.ID, createdV2.ID}}, {otherAccess, []int{otherPosted.ID}}} {
+		for _, id := range cleanup.ids {
+			require.NoError(t, d.DeleteExpression(id))
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/getUser", nil)
+		req.Header.Set("Authorization", "Bearer "+cleanup.access)
+		router.ServeHTTP(w, req)
+		var out api.OutGetUser
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		user, err := d.GetUserByUsername(out.Login)
+		require.NoError(t, err)
+		require.NoError(t, d.DeleteByUserId(user.ID))
+		require.NoError(t, d.DeleteUser(user.ID))
+	}
+}
+
+func TestIdempotencyKeyBodySize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/expression", func(c *gin.Context) {
+		c.Set("user", db.User{ID: 1})
+	}, (&api.API{}).Idempotent, func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/expression", strings.NewReader(strings.Repeat("1", 2<<20)))
+	req.Header.Set("Idempotency-Key", "key")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestIdempotencyKeyPanic(t *testing.T) {
+	d, a := CreateApi(t)
+	userID, err := d.AddUser(db.User{Login: fmt.Sprintf("%vidempotencypanic", time.Now().UnixNano())})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.Recovery())
+	panicked := false
+	router.POST("/expression", func(c *gin.Context) {
+		c.Set("user", db.User{ID: userID})
+	}, a.Idempotent, func(c *gin.Context) {
+		if !panicked {
+			panicked = true
+			panic("handler failed")
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+	post := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/expression", strings.NewReader(`{"expression":"2+2"}`))
+		req.Header.Set("Idempotency-Key", "key")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// the key is released after the panic, the retry is handled instead of being "in progress"
+	assert.Equal(t, http.StatusInternalServerError, post().Code)
+	assert.Equal(t, http.StatusOK, post().Code)
+
+	require.NoError(t, d.DeleteByUserId(userID))
+	require.NoError(t, d.DeleteUser(userID))
+}
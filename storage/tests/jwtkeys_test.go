@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"storage/internal/api"
+	"storage/internal/db"
+	"storage/internal/jwtkeys"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSigningKeyJWK(t *testing.T) {
+	for _, algorithm := range []string{jwtkeys.RS256, jwtkeys.EdDSA} {
+		key, err := jwtkeys.GenerateKey(algorithm)
+		require.NoError(t, err)
+		token, err := key.Sign(jwt.MapClaims{"sub": "1", "exp": time.Now().Add(time.Minute).Unix()})
+		require.NoError(t, err)
+
+		// private key is saved and loaded
+		data, err := jwtkeys.MarshalPrivateKey(key.Private)
+		require.NoError(t, err)
+		private, err := jwtkeys.ParsePrivateKey(data)
+		require.NoError(t, err)
+		assert.Equal(t, key.Private.Public(), private.Public())
+
+		// token is verified with the published key
+		var published jwtkeys.JWK
+		body, err := json.Marshal(key.JWK())
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &published))
+		assert.Equal(t, algorithm, published.Alg)
+		public, err := published.PublicKey()
+		require.NoError(t, err)
+		parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+			assert.Equal(t, key.ID, token.Header["kid"])
+			return public, nil
+		}, jwt.WithValidMethods([]string{algorithm}))
+		require.NoError(t, err)
+		assert.True(t, parsed.Valid)
+	}
+
+	_, err := jwtkeys.GenerateKey("HS256")
+	assert.ErrorIs(t, err, jwtkeys.ErrUnsupportedAlgorithm)
+}
+
+// signingKeysEncryptionKey returns the key of the server, keys in database are shared with API tests.
+func signingKeysEncryptionKey(t *testing.T) []byte {
+	key, err := base64.StdEncoding.DecodeString(os.Getenv("JWT_KEY_ENCRYPTION_KEY"))
+	require.NoError(t, err)
+	return key
+}
+
+func TestSigningKeysEncryption(t *testing.T) {
+	_, err := jwtkeys.New(nil, jwtkeys.RS256, time.Hour, []byte("short"))
+	assert.ErrorIs(t, err, jwtkeys.ErrEncryptionKeySize)
+
+	d, err := db.New()
+	require.NoError(t, err)
+	// key saved without encryption by an older version
+	old, err := jwtkeys.GenerateKey(jwtkeys.EdDSA)
+	require.NoError(t, err)
+	private, err := jwtkeys.MarshalPrivateKey(old.Private)
+	require.NoError(t, err)
+	_, err = d.AddSigningKey(db.SigningKey{KID: old.ID, Algorithm: old.Algorithm, PrivateKey: private,
+		CreationTime: time.Now().Format("2006-01-02 15:04:05"), RetiredAt: int(time.Now().Unix())})
+	require.NoError(t, err)
+
+	keys, err := jwtkeys.New(d, jwtkeys.RS256, time.Hour, signingKeysEncryptionKey(t))
+	require.NoError(t, err)
+	stored, err := d.GetSigningKeys()
+	require.NoError(t, err)
+	require.NotEmpty(t, stored)
+	for _, key := range stored {
+		assert.NotContains(t, key.PrivateKey, "PRIVATE KEY")
+	}
+	published := false
+	for _, key := range keys.JWKS().Keys {
+		published = published || key.Kid == old.ID
+	}
+	assert.True(t, published)
+
+	_, err = jwtkeys.New(d, jwtkeys.RS256, time.Hour, bytes.Repeat([]byte{1}, jwtkeys.EncryptionKeySize))
+	assert.ErrorIs(t, err, jwtkeys.ErrDecryption)
+}
+
+func TestKeySetRotation(t *testing.T) {
+	d, err := db.New()
+	require.NoError(t, err)
+	keys, err := jwtkeys.New(d, jwtkeys.RS256, time.Hour, signingKeysEncryptionKey(t))
+	require.NoError(t, err)
+	now := time.Now()
+	keys.SetClock(func() time.Time { return now })
+
+	verify := func(token string) error {
+		_, err := jwt.Parse(token, keys.Keyfunc, jwt.WithValidMethods([]string{jwtkeys.RS256, jwtkeys.EdDSA}))
+		return err
+	}
+	kid := func(token string) string {
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		require.NoError(t, err)
+		return parsed.Header["kid"].(string)
+	}
+	published := func(id string) bool {
+		for _, key := range keys.JWKS().Keys {
+			if key.Kid == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	old, err := keys.Sign(jwt.MapClaims{"sub": "1"})
+	require.NoError(t, err)
+	require.NoError(t, verify(old))
+
+	key, err := keys.Rotate(jwtkeys.EdDSA)
+	require.NoError(t, err)
+	token, err := keys.Sign(jwt.MapClaims{"sub": "1"})
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, kid(token))
+	assert.NotEqual(t, kid(old), kid(token))
+
+	// retired key verifies tokens for the grace period
+	require.NoError(t, verify(old))
+	require.NoError(t, verify(token))
+	assert.True(t, published(kid(old)))
+	assert.ErrorIs(t, keys.Retire(kid(old)), jwtkeys.ErrUnknownKey)
+	assert.ErrorIs(t, keys.Retire(key.ID), jwtkeys.ErrLastKey)
+
+	now = now.Add(time.Hour + time.Minute)
+	assert.ErrorIs(t, verify(old), jwtkeys.ErrUnknownKey)
+	assert.False(t, published(kid(old)))
+	require.NoError(t, verify(token))
+
+	// token signed with the key, but with another algorithm
+	parts := strings.Split(token, ".")
+	header, err := json.Marshal(map[string]string{"alg": jwtkeys.RS256, "kid": key.ID, "typ": "JWT"})
+	require.NoError(t, err)
+	assert.Error(t, verify(base64.RawURLEncoding.EncodeToString(header)+"."+parts[1]+"."+parts[2]))
+}
+
+func TestJWKSEndpoint(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "jwks-admin-token")
+	d, a := CreateApi(t)
+	router := a.Start()
+	request := func(method, url, access string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, nil)
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	verify := func(access string) error {
+		w := request(http.MethodGet, "/.well-known/jwks.json", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var set jwtkeys.JWKSet
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &set))
+		_, err := jwt.Parse(access, func(token *jwt.Token) (interface{}, error) {
+			for _, key := range set.Keys {
+				if key.Kid == token.Header["kid"] {
+					return key.PublicKey()
+				}
+			}
+			return nil, jwtkeys.ErrUnknownKey
+		})
+		return err
+	}
+
+	access := CreateRegisteredUser(t, router)
+	require.NoError(t, verify(access))
+
+	w := request(http.MethodPost, "/api/v1/admin/signingKeys/rotate", "jwks-admin-token")
+	require.Equal(t, http.StatusOK, w.Code)
+	var rotated api.OutRotateSigningKeys
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rotated))
+
+	// tokens signed before rotation are still accepted
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/v1/getUser", access).Code)
+	require.NoError(t, verify(access))
+	newAccess := CreateRegisteredUser(t, router)
+	require.NoError(t, verify(newAccess))
+	parsed, _, err := jwt.NewParser().ParseUnverified(newAccess, jwt.MapClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, rotated.KID, parsed.Header["kid"])
+
+	w = request(http.MethodPost, "/api/v1/admin/signingKeys/"+rotated.KID+"/retire", "jwks-admin-token")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = request(http.MethodGet, "/api/v1/admin/signingKeys", "jwks-admin-token")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), rotated.KID)
+	assert.NotContains(t, w.Body.String(), "PRIVATE KEY")
+
+	// tokens signed with the shared secret are not accepted anymore
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "1"}).
+		SignedString([]byte("noSecretSignature"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/v1/getUser", legacy).Code)
+
+	for _, token := range []string{access, newAccess} {
+		var out api.OutGetUser
+		require.NoError(t, json.Unmarshal(request(http.MethodGet, "/api/v1/getUser", token).Body.Bytes(), &out))
+		user, err := d.GetUserByUsername(out.Login)
+		require.NoError(t, err)
+		require.NoError(t, d.DeleteByUserId(user.ID))
+		require.NoError(t, d.DeleteUser(user.ID))
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"storage/internal/loginlimiter"
+	"testing"
+	"time"
+)
+
+func TestLoginLimiterBackoff(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := loginlimiter.New(loginlimiter.Config{
+		FreeAttempts:    2,
+		BaseDelay:       time.Second,
+		MaxDelay:        4 * time.Second,
+		LockoutAttempts: 6,
+		LockoutDuration: time.Minute,
+	})
+	l.SetClock(func() time.Time { return now })
+
+	// free attempts
+	for i := 0; i < 2; i++ {
+		ok, _ := l.Allow("user")
+		require.True(t, ok)
+		require.False(t, l.Fail("user"))
+	}
+	ok, _ := l.Allow("user")
+	require.True(t, ok)
+
+	// delay doubles up to MaxDelay
+	for _, delay := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		require.False(t, l.Fail("user"))
+		ok, wait := l.Allow("user")
+		assert.False(t, ok)
+		assert.Equal(t, delay, wait)
+		now = now.Add(delay)
+		ok, _ = l.Allow("user")
+		assert.True(t, ok)
+	}
+
+	// other keys are not affected
+	ok, _ = l.Allow("other")
+	assert.True(t, ok)
+
+	// lockout
+	require.True(t, l.Fail("user"))
+	ok, wait := l.Allow("user")
+	assert.False(t, ok)
+	assert.Equal(t, time.Minute, wait)
+
+	// failures are forgotten after lockout
+	now = now.Add(time.Minute + time.Second)
+	ok, _ = l.Allow("user")
+	assert.True(t, ok)
+	require.False(t, l.Fail("user"))
+	ok, _ = l.Allow("user")
+	assert.True(t, ok)
+}
+
+func TestLoginLimiterSuccess(t *testing.T) {
+	l := loginlimiter.New(loginlimiter.Config{
+		FreeAttempts:    0,
+		BaseDelay:       time.Hour,
+		MaxDelay:        time.Hour,
+		LockoutDuration: time.Hour,
+	})
+	l.Fail("user")
+	ok, _ := l.Allow("user")
+	require.False(t, ok)
+
+	l.Success("user")
+	ok, _ = l.Allow("user")
+	require.True(t, ok)
+}
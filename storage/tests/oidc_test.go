@@ -0,0 +1,390 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"storage/internal/api"
+	"storage/internal/oidc"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeGrant struct {
+	challenge   string
+	nonce       string
+	redirectURI string
+}
+
+// fakeIdP is an OpenID Connect provider that logs in Subject without asking anything.
+type fakeIdP struct {
+	server       *httptest.Server
+	key          *rsa.PrivateKey
+	clientID     string
+	clientSecret string
+
+	mu                sync.Mutex
+	grants            map[string]fakeGrant
+	Subject           string
+	PreferredUsername string
+	Audience          string // client ID if empty
+	Kid               string // kid of the signing key if empty
+	jwksRequests      int
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := &fakeIdP{
+		key:          key,
+		clientID:     "calculator",
+		clientSecret: "client-secret",
+		grants:       make(map[string]fakeGrant),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	mux.HandleFunc("/jwks", idp.jwks)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *fakeIdP) discovery(w http.ResponseWriter, _ *http.Request) {
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 idp.server.URL,
+		"authorization_endpoint": idp.server.URL + "/authorize",
+		"token_endpoint":         idp.server.URL + "/token",
+		"jwks_uri":               idp.server.URL + "/jwks",
+	})
+}
+
+func (idp *fakeIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("response_type") != "code" || query.Get("client_id") != idp.clientID ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" ||
+		!strings.Contains(query.Get("scope"), "openid") {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	code := fmt.Sprint(time.Now().UnixNano())
+	idp.mu.Lock()
+	idp.grants[code] = fakeGrant{
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		redirectURI: query.Get("redirect_uri"),
+	}
+	idp.mu.Unlock()
+	redirect := query.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (idp *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	tokenError := func(code string) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != idp.clientID || secret != idp.clientSecret {
+		tokenError("invalid_client")
+		return
+	}
+	idp.mu.Lock()
+	grant, ok := idp.grants[r.PostFormValue("code")]
+	delete(idp.grants, r.PostFormValue("code"))
+	subject, username, audience, kid := idp.Subject, idp.PreferredUsername, idp.Audience, idp.Kid
+	idp.mu.Unlock()
+	hash := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || r.PostFormValue("grant_type") != "authorization_code" ||
+		r.PostFormValue("redirect_uri") != grant.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(hash[:]) != grant.challenge {
+		tokenError("invalid_grant")
+		return
+	}
+
+	if audience == "" {
+		audience = idp.clientID
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                idp.server.URL,
+		"sub":                subject,
+		"aud":                audience,
+		"exp":                now.Add(time.Minute).Unix(),
+		"iat":                now.Unix(),
+		"nonce":              grant.nonce,
+		"preferred_username": username,
+		"email":              username + "@example.com",
+	})
+	if kid == "" {
+		kid = "test-key"
+	}
+	token.Header["kid"] = kid
+	idToken, err := token.SignedString(idp.key)
+	if err != nil {
+		tokenError("server_error")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "unused",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func (idp *fakeIdP) jwks(w http.ResponseWriter, _ *http.Request) {
+	idp.mu.Lock()
+	idp.jwksRequests++
+	idp.mu.Unlock()
+	_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "test-key",
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+	}}})
+}
+
+// login follows the authorization URL like a browser and returns code and state from the redirect.
+func (idp *fakeIdP) login(t *testing.T, authorizationURL string) (string, string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authorizationURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestOIDCClient(t *testing.T) {
+	idp := newFakeIdP(t)
+	idp.Subject = "subject-1"
+	idp.PreferredUsername = "alice"
+	client := oidc.New(oidc.Config{
+		Issuer:       idp.server.URL,
+		ClientID:     idp.clientID,
+		ClientSecret: idp.clientSecret,
+		RedirectURL:  "http://localhost:3000/oidc/callback",
+		Scopes:       []string{"email"},
+	}, nil)
+	ctx := context.Background()
+
+	authorizationURL, binding, err := client.Begin(ctx)
+	require.NoError(t, err)
+	parsed, err := url.Parse(authorizationURL)
+	require.NoError(t, err)
+	assert.Equal(t, "openid email", parsed.Query().Get("scope"))
+	code, state := idp.login(t, authorizationURL)
+	claims, err := client.Finish(ctx, state, binding, code)
+	require.NoError(t, err)
+	assert.Equal(t, "subject-1", claims.Subject)
+	assert.Equal(t, "alice", claims.PreferredUsername)
+
+	// the state can be used once
+	_, err = client.Finish(ctx, state, binding, code)
+	assert.ErrorIs(t, err, oidc.ErrUnknownState)
+
+	// the state can be used only by the browser that started the login
+	authorizationURL, _, err = client.Begin(ctx)
+	require.NoError(t, err)
+	code, state = idp.login(t, authorizationURL)
+	_, err = client.Finish(ctx, state, binding, code)
+	assert.ErrorIs(t, err, oidc.ErrForeignState)
+	_, err = client.Finish(ctx, state, "", code)
+	assert.ErrorIs(t, err, oidc.ErrUnknownState)
+
+	// the code can not be exchanged without the verifier of the state
+	first, _, err := client.Begin(ctx)
+	require.NoError(t, err)
+	second, secondBinding, err := client.Begin(ctx)
+	require.NoError(t, err)
+	code, _ = idp.login(t, first)
+	_, state = idp.login(t, second)
+	_, err = client.Finish(ctx, state, secondBinding, code)
+	assert.Error(t, err)
+
+	// token for another client
+	idp.Audience = "another-client"
+	authorizationURL, binding, err = client.Begin(ctx)
+	require.NoError(t, err)
+	code, state = idp.login(t, authorizationURL)
+	_, err = client.Finish(ctx, state, binding, code)
+	assert.ErrorIs(t, err, oidc.ErrInvalidToken)
+	idp.Audience = ""
+
+	// keys are not fetched again for every token with unknown kid
+	idp.mu.Lock()
+	idp.Kid = "unknown-key"
+	jwksRequests := idp.jwksRequests
+	idp.mu.Unlock()
+	for i := 0; i < 3; i++ {
+		authorizationURL, binding, err = client.Begin(ctx)
+		require.NoError(t, err)
+		code, state = idp.login(t, authorizationURL)
+		_, err = client.Finish(ctx, state, binding, code)
+		assert.ErrorIs(t, err, oidc.ErrInvalidToken)
+	}
+	idp.mu.Lock()
+	assert.Equal(t, jwksRequests, idp.jwksRequests)
+	idp.Kid = ""
+	idp.mu.Unlock()
+
+	// but they are fetched after KeysRefreshInterval
+	now := time.Now().Add(oidc.KeysRefreshInterval)
+	client.SetClock(func() time.Time { return now })
+	idp.mu.Lock()
+	idp.Kid = "rotated-key"
+	idp.mu.Unlock()
+	authorizationURL, binding, err = client.Begin(ctx)
+	require.NoError(t, err)
+	code, state = idp.login(t, authorizationURL)
+	_, err = client.Finish(ctx, state, binding, code)
+	assert.ErrorIs(t, err, oidc.ErrInvalidToken)
+	idp.mu.Lock()
+	assert.Equal(t, jwksRequests+1, idp.jwksRequests)
+	idp.Kid = ""
+	idp.mu.Unlock()
+
+	// states expire
+	authorizationURL, binding, err = client.Begin(ctx)
+	require.NoError(t, err)
+	code, state = idp.login(t, authorizationURL)
+	now = now.Add(oidc.StateLifetime + time.Second)
+	_, err = client.Finish(ctx, state, binding, code)
+	assert.ErrorIs(t, err, oidc.ErrUnknownState)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := newFakeIdP(t)
+	t.Setenv("OIDC_ISSUER", idp.server.URL)
+	t.Setenv("OIDC_CLIENT_ID", idp.clientID)
+	t.Setenv("OIDC_CLIENT_SECRET", idp.clientSecret)
+	t.Setenv("OIDC_REDIRECT_URL", "http://localhost:3000/oidc/callback")
+	d, a := CreateApi(t)
+	router := a.Start()
+
+	login := fmt.Sprintf("%voidc", time.Now().UnixNano())
+	idp.Subject = "sub-" + login
+	idp.PreferredUsername = login
+
+	singleSignOn := func() (int, api.OutLogin) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/oidc/login", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var started api.OutOIDCLogin
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &started))
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.True(t, cookies[0].HttpOnly)
+
+		code, state := idp.login(t, started.URL)
+		body, _ := json.Marshal(api.InOIDCCallback{Code: code, State: state})
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodPost, "/api/v1/oidc/callback", strings.NewReader(string(body)))
+		req.AddCookie(cookies[0])
+		router.ServeHTTP(w, req)
+		var out api.OutLogin
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		return w.Code, out
+	}
+	getUser := func(access string) string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/getUser", nil)
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var out api.OutGetUser
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		return out.Login
+	}
+
+	// the user is created on the first login and found on the next ones
+	status, out := singleSignOn()
+	require.Equal(t, http.StatusOK, status, out.Message)
+	assert.Equal(t, login, getUser(out.Access))
+	user, err := d.GetUserByUsername(login)
+	require.NoError(t, err)
+	status, out = singleSignOn()
+	require.Equal(t, http.StatusOK, status, out.Message)
+	assert.Equal(t, login, getUser(out.Access))
+	identity, err := d.GetOIDCIdentity(idp.server.URL, idp.Subject)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, identity.User)
+
+	// user of the provider can not log in with a password
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/login",
+		strings.NewReader(`{"login":"`+login+`","password":"`+testPassword+`"}`))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// but can set the first password without the old one, then the old password is required
+	updateUser := func(body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/updateUser", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+out.Access)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	require.Equal(t, http.StatusOK, updateUser(`{"password":"`+testPassword+`"}`))
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/login",
+		strings.NewReader(`{"login":"`+login+`","password":"`+testPassword+`"}`))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusBadRequest, updateUser(`{"password":"another-`+testPassword+`"}`))
+
+	// another subject with the login of a local user is not linked to it
+	local := CreateRegisteredUser(t, router)
+	localUser := getUser(local)
+	idp.Subject = "sub-other-" + login
+	idp.PreferredUsername = localUser
+	status, _ = singleSignOn()
+	assert.Equal(t, http.StatusConflict, status)
+
+	// the callback without the cookie of the browser that started the login
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/oidc/login", nil)
+	router.ServeHTTP(w, req)
+	var started api.OutOIDCLogin
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &started))
+	code, state := idp.login(t, started.URL)
+	body, _ := json.Marshal(api.InOIDCCallback{Code: code, State: state})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/oidc/callback", strings.NewReader(string(body)))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// wrong state
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/oidc/callback", strings.NewReader(`{"code":"1","state":"2"}`))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	for _, l := range []string{login, localUser} {
+		user, err := d.GetUserByUsername(l)
+		require.NoError(t, err)
+		require.NoError(t, d.DeleteByUserId(user.ID))
+		require.NoError(t, d.DeleteUser(user.ID))
+	}
+}
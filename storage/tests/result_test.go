@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"storage/internal/api"
+	"storage/internal/db"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetExpressionResult(t *testing.T) {
+	d, a := CreateApi(t)
+	router := a.Start()
+	access := CreateRegisteredUser(t, router)
+	request := func(method, url string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/v2/expressions", `{"expression":"2+2"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var posted api.OutPostExpression
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &posted))
+	resultURL := fmt.Sprintf("/api/v1/expressions/%v/result", posted.ID)
+
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, resultURL+"?wait=soon", "").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, resultURL+"?wait=-1s", "").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/api/v1/expressions/-1/result?wait=1s", "").Code)
+
+	// the wait is over before the expression is finished
+	start := time.Now()
+	w = request(http.MethodGet, resultURL+"?wait=100ms", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	var out api.OutGetExpressionResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	assert.False(t, out.Finished)
+	assert.Equal(t, db.ExpressionNotReady, out.Expression.Status)
+
+	// the waiter is woken when the expression is finished
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		request(http.MethodPost, fmt.Sprintf("/api/v2/expressions/%v/cancel", posted.ID), "")
+	}()
+	start = time.Now()
+	w = request(http.MethodGet, fmt.Sprintf("/api/v2/expressions/%v/result?wait=30", posted.ID), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	assert.True(t, out.Finished)
+	assert.Equal(t, db.ExpressionCancelled, out.Expression.Status)
+
+	// finished expressions are returned at once
+	w = request(http.MethodGet, resultURL+"?wait=30s", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	assert.True(t, out.Finished)
+
+	require.NoError(t, d.DeleteExpression(posted.ID))
+	var user api.OutGetUser
+	require.NoError(t, json.Unmarshal(request(http.MethodGet, "/api/v1/getUser", "").Body.Bytes(), &user))
+	dbUser, err := d.GetUserByUsername(user.Login)
+	require.NoError(t, err)
+	require.NoError(t, d.DeleteByUserId(dbUser.ID))
+	require.NoError(t, d.DeleteUser(dbUser.ID))
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"storage/internal/db"
+	"storage/internal/expressionstorage"
+	"testing"
+)
+
+func expressionIDs(expressions []db.Expression) []int {
+	ids := make([]int, 0, len(expressions))
+	for _, expression := range expressions {
+		ids = append(ids, expression.ID)
+	}
+	return ids
+}
+
+func TestSchedulerOrder(t *testing.T) {
+	type element struct {
+		name    string
+		pending []db.Expression
+		working map[int]int
+		out     []int
+	}
+	tests := []element{
+		{name: "empty", pending: []db.Expression{}, out: []int{}},
+		{name: "fifo for one user", pending: []db.Expression{
+			{ID: 3, User: 1},
+			{ID: 1, User: 1},
+			{ID: 2, User: 1},
+		}, out: []int{1, 2, 3}},
+		{name: "priority first", pending: []db.Expression{
+			{ID: 1, User: 1},
+			{ID: 2, User: 1, Priority: 5},
+			{ID: 3, User: 2, Priority: 10},
+		}, out: []int{3, 2, 1}},
+		{name: "round-robin across users", pending: []db.Expression{
+			{ID: 1, User: 1},
+			{ID: 2, User: 1},
+			{ID: 3, User: 1},
+			{ID: 4, User: 1},
+			{ID: 5, User: 2},
+			{ID: 6, User: 2},
+			{ID: 7, User: 3},
+		}, out: []int{1, 5, 7, 2, 6, 3, 4}},
+		{name: "users with working expressions go back", pending: []db.Expression{
+			{ID: 1, User: 1},
+			{ID: 2, User: 1},
+			{ID: 3, User: 2},
+			{ID: 4, User: 2},
+		}, working: map[int]int{1: 1}, out: []int{3, 1, 4, 2}},
+		{name: "round-robin inside priority", pending: []db.Expression{
+			{ID: 1, User: 1, Priority: 1},
+			{ID: 2, User: 1, Priority: 1},
+			{ID: 3, User: 1},
+			{ID: 4, User: 2},
+			{ID: 5, User: 2, Priority: 1},
+		}, out: []int{1, 5, 2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := expressionstorage.NewScheduler()
+			working := tt.working
+			if working == nil {
+				working = map[int]int{}
+			}
+
+			actual := s.Order(tt.pending, working)
+			assert.Equal(t, tt.out, expressionIDs(actual))
+
+			// the order must not depend on the order of input
+			reversed := make([]db.Expression, 0, len(tt.pending))
+			for i := len(tt.pending) - 1; i >= 0; i-- {
+				reversed = append(reversed, tt.pending[i])
+			}
+			assert.Equal(t, tt.out, expressionIDs(s.Order(reversed, working)))
+		})
+	}
+}
+
+func TestIsPriorityCorrect(t *testing.T) {
+	require.NoError(t, expressionstorage.IsPriorityCorrect(expressionstorage.MinPriority))
+	require.NoError(t, expressionstorage.IsPriorityCorrect(expressionstorage.MaxPriority))
+	require.Error(t, expressionstorage.IsPriorityCorrect(-1))
+	require.Error(t, expressionstorage.IsPriorityCorrect(expressionstorage.MaxPriority+1))
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"storage/internal/api"
+	"storage/internal/availableservers"
+	"storage/internal/db"
+	"storage/internal/expressionstorage"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTeams(t *testing.T) {
+	d, err := db.New()
+	require.NoError(t, err)
+	statusWorkers := &sync.Map{}
+	expressions := expressionstorage.New(d, time.Minute, statusWorkers)
+	servers := availableservers.New(expressions)
+	timeConfig := &api.ExecTimeConfig{TimeAdd: 1, TimeSubtract: 1, TimeDivide: 1, TimeMultiply: 1}
+	a := api.New(d, expressions, statusWorkers, servers, timeConfig)
+	router := a.Start()
+
+	request := func(method, url, access string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	register := func(login string) (db.User, string) {
+		w := request(http.MethodPost, "/api/v1/register", "", `{"login":"`+login+`","password":"`+testPassword+`"}`)
+		var out api.OutRegister
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		require.Equal(t, "ok", out.Message)
+		user, err := d.GetUserByUsername(login)
+		require.NoError(t, err)
+		return user, out.Access
+	}
+
+	prefix := fmt.Sprintf("%vteam", time.Now().UnixNano())
+	owner, ownerAccess := register(prefix + "o")
+	member, memberAccess := register(prefix + "m")
+	stranger, strangerAccess := register(prefix + "s")
+
+	w := request(http.MethodPost, "/api/v1/teams", ownerAccess, `{"name":"`+prefix+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var team api.OutAddTeam
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &team))
+	teamURL := fmt.Sprintf("/api/v1/teams/%v", team.Team.ID)
+
+	// only owners add members
+	w = request(http.MethodPost, teamURL+"/members", ownerAccess, `{"login":"`+member.Login+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = request(http.MethodPost, teamURL+"/members", memberAccess, `{"login":"`+stranger.Login+`"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, teamURL+"/members", strangerAccess, "").Code)
+
+	w = request(http.MethodGet, "/api/v1/teams", memberAccess, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var teams api.OutGetTeams
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &teams))
+	require.Len(t, teams.Teams, 1)
+	assert.Equal(t, db.TeamRoleMember, teams.Teams[0].Role)
+
+	// expressions of the team are shared
+	w = request(http.MethodPost, "/api/v1/expression", strangerAccess,
+		fmt.Sprintf(`{"expression":"1+1","team":%v}`, team.Team.ID))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = request(http.MethodPost, "/api/v1/expression", ownerAccess,
+		fmt.Sprintf(`{"expression":"1+1","team":%v}`, team.Team.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	var posted api.OutPostExpression
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &posted))
+
+	assert.Contains(t, expressionIDs(expressions.GetAll(member.ID)), posted.ID)
+	assert.NotContains(t, expressionIDs(expressions.GetAll(stranger.ID)), posted.ID)
+	_, err = expressions.GetByUserAndID(stranger.ID, posted.ID)
+	assert.Error(t, err)
+	w = request(http.MethodPost, fmt.Sprintf("/api/v1/expression/%v/cancel", posted.ID), strangerAccess, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = request(http.MethodDelete, fmt.Sprintf("/api/v1/expression/%v", posted.ID), strangerAccess, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// members can cancel, but only the author or owners can delete
+	w = request(http.MethodDelete, fmt.Sprintf("/api/v1/expression/%v", posted.ID), memberAccess, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = request(http.MethodPost, fmt.Sprintf("/api/v1/expression/%v/cancel", posted.ID), memberAccess, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	expression, err := expressions.GetByID(posted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.ExpressionCancelled, expression.Status)
+
+	// operation times of the team
+	w = request(http.MethodPost, teamURL+"/operationsAndTimes", memberAccess, `{"+":7}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = request(http.MethodPost, teamURL+"/operationsAndTimes", ownerAccess, `{"+":7}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = request(http.MethodGet, teamURL+"/operationsAndTimes", memberAccess, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var operations api.OutGetOperationsAndTimes
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &operations))
+	assert.Equal(t, 7, operations.Data["+"])
+
+	// leaving the team hides its expressions
+	w = request(http.MethodDelete, fmt.Sprintf("%v/members/%v", teamURL, owner.ID), memberAccess, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = request(http.MethodDelete, fmt.Sprintf("%v/members/%v", teamURL, member.ID), memberAccess, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, expressionIDs(expressions.GetAll(member.ID)), posted.ID)
+
+	// deleted team gives expressions back to authors
+	assert.Equal(t, http.StatusNotFound, request(http.MethodDelete, teamURL, memberAccess, "").Code)
+	require.Equal(t, http.StatusOK, request(http.MethodDelete, teamURL, ownerAccess, "").Code)
+	expression, err = expressions.GetByUserAndID(owner.ID, posted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, expression.Team)
+	expression, err = d.GetExpressionByID(posted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, expression.Team)
+
+	require.NoError(t, expressions.Delete(posted.ID))
+	for _, user := range []db.User{owner, member, stranger} {
+		require.NoError(t, d.DeleteByUserId(user.ID))
+		require.NoError(t, d.DeleteUser(user.ID))
+	}
+}
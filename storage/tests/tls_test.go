@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"storage/internal/gRPCServer"
+	"testing"
+	"time"
+)
+
+type certificate struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+var certificateSerial int64 = 1
+
+// createCertificate creates certificate signed by parent (self-signed if parent is nil) and writes it to dir.
+func createCertificate(t *testing.T, dir string, name string, dnsNames []string, isCA bool,
+	parent *certificate) *certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	certificateSerial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(certificateSerial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if isCA {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	res := &certificate{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	err = os.WriteFile(res.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	require.NoError(t, err)
+	err = os.WriteFile(res.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600)
+	require.NoError(t, err)
+	return res
+}
+
+type confirmAllServer struct {
+	gRPCServer.UnimplementedExpressionsServiceServer
+}
+
+func (s *confirmAllServer) ConfirmStartCalculating(_ context.Context, _ *gRPCServer.Expression) (*gRPCServer.Confirm, error) {
+	return &gRPCServer.Confirm{Confirm: true}, nil
+}
+
+func (s *confirmAllServer) Enroll(_ context.Context, _ *gRPCServer.EnrollRequest) (*gRPCServer.EnrollAnswer, error) {
+	return &gRPCServer.EnrollAnswer{}, nil
+}
+
+func setupTLSServer(t *testing.T, config *tls.Config) (*grpc.Server, *bufconn.Listener) {
+	tlsLis := bufconn.Listen(bufSize)
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(config)),
+		grpc.ChainUnaryInterceptor(gRPCServer.ServerNameInterceptor))
+	gRPCServer.RegisterExpressionsServiceServer(server, &confirmAllServer{})
+	go func() {
+		_ = server.Serve(tlsLis)
+	}()
+	return server, tlsLis
+}
+
+func dialTLS(t *testing.T, tlsLis *bufconn.Listener, config *tls.Config) gRPCServer.ExpressionsServiceClient {
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return tlsLis.Dial()
+		}),
+		grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return gRPCServer.NewExpressionsServiceClient(conn)
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := createCertificate(t, dir, "ca", nil, true, nil)
+	storageCert := createCertificate(t, dir, "storage", []string{"storage"}, false, ca)
+	workerCert := createCertificate(t, dir, "worker1", []string{"worker1.local"}, false, ca)
+	otherCA := createCertificate(t, dir, "other-ca", nil, true, nil)
+	strangerCert := createCertificate(t, dir, "stranger", nil, false, otherCA)
+
+	config, err := gRPCServer.LoadTLSConfig(storageCert.certFile, storageCert.keyFile, ca.certFile)
+	require.NoError(t, err)
+	server, tlsLis := setupTLSServer(t, config)
+	defer server.Stop()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientConfig := func(cert *certificate) *tls.Config {
+		res := &tls.Config{RootCAs: roots, ServerName: "storage", MinVersion: tls.VersionTLS12}
+		if cert != nil {
+			pair, err := tls.LoadX509KeyPair(cert.certFile, cert.keyFile)
+			require.NoError(t, err)
+			res.Certificates = []tls.Certificate{pair}
+		}
+		return res
+	}
+
+	// name from CN and SAN
+	client := dialTLS(t, tlsLis, clientConfig(workerCert))
+	for _, name := range []string{"worker1", "worker1.local"} {
+		res, err := client.ConfirmStartCalculating(context.Background(), &gRPCServer.Expression{ServerName: name})
+		require.NoError(t, err)
+		assert.True(t, res.Confirm)
+	}
+
+	// somebody else's name
+	_, err = client.ConfirmStartCalculating(context.Background(), &gRPCServer.Expression{ServerName: "worker2"})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// enrollment with somebody else's name
+	_, err = client.Enroll(context.Background(), &gRPCServer.EnrollRequest{ServerName: "worker1"})
+	require.NoError(t, err)
+	_, err = client.Enroll(context.Background(), &gRPCServer.EnrollRequest{ServerName: "worker2"})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// no client certificate
+	client = dialTLS(t, tlsLis, clientConfig(nil))
+	_, err = client.ConfirmStartCalculating(context.Background(), &gRPCServer.Expression{ServerName: "worker1"})
+	require.Error(t, err)
+
+	// certificate from unknown CA
+	client = dialTLS(t, tlsLis, clientConfig(strangerCert))
+	_, err = client.ConfirmStartCalculating(context.Background(), &gRPCServer.Expression{ServerName: "stranger"})
+	require.Error(t, err)
+}
+
+func TestServerOnlyTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := createCertificate(t, dir, "ca", nil, true, nil)
+	storageCert := createCertificate(t, dir, "storage", []string{"storage"}, false, ca)
+
+	config, err := gRPCServer.LoadTLSConfig(storageCert.certFile, storageCert.keyFile, "")
+	require.NoError(t, err)
+	server, tlsLis := setupTLSServer(t, config)
+	defer server.Stop()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := dialTLS(t, tlsLis, &tls.Config{RootCAs: roots, ServerName: "storage", MinVersion: tls.VersionTLS12})
+	res, err := client.ConfirmStartCalculating(context.Background(), &gRPCServer.Expression{ServerName: "any"})
+	require.NoError(t, err)
+	assert.True(t, res.Confirm)
+
+	// storage is not trusted
+	client = dialTLS(t, tlsLis, &tls.Config{RootCAs: x509.NewCertPool(), ServerName: "storage",
+		MinVersion: tls.VersionTLS12})
+	_, err = client.ConfirmStartCalculating(context.Background(), &gRPCServer.Expression{ServerName: "any"})
+	require.Error(t, err)
+
+	_, err = gRPCServer.LoadTLSConfig(storageCert.certFile, storageCert.keyFile, filepath.Join(dir, "nofile"))
+	require.Error(t, err)
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"storage/internal/api"
+	"storage/internal/totp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// base32 of "12345678901234567890", the secret of RFC 6238 test vectors
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodes(t *testing.T) {
+	// RFC 6238 SHA1 test vectors (last 6 digits)
+	for unix, expected := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		code, err := totp.Code(rfcSecret, time.Unix(unix, 0))
+		require.NoError(t, err)
+		assert.Equal(t, expected, code, unix)
+	}
+
+	now := time.Unix(1234567890, 0)
+	step, ok := totp.Validate(rfcSecret, "005924", now)
+	assert.True(t, ok)
+	assert.Equal(t, totp.Step(now), step)
+
+	// codes of the previous and the next periods are accepted
+	previous, err := totp.Code(rfcSecret, now.Add(-totp.Period))
+	require.NoError(t, err)
+	_, ok = totp.Validate(rfcSecret, previous, now)
+	assert.True(t, ok)
+	old, err := totp.Code(rfcSecret, now.Add(-3*totp.Period))
+	require.NoError(t, err)
+	_, ok = totp.Validate(rfcSecret, old, now)
+	assert.False(t, ok)
+	_, ok = totp.Validate(rfcSecret, "12345", now)
+	assert.False(t, ok)
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	uri, err := url.Parse(totp.ProvisioningURI("Calc", "user@example.com", secret))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Calc:user@example.com", uri.Path)
+	assert.Equal(t, secret, uri.Query().Get("secret"))
+	assert.Equal(t, "Calc", uri.Query().Get("issuer"))
+
+	codes, err := totp.GenerateRecoveryCodes(10)
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+	assert.Regexp(t, `^[a-z2-7]{4}-[a-z2-7]{4}$`, codes[0])
+	assert.Equal(t, strings.ReplaceAll(codes[0], "-", ""), totp.NormalizeRecoveryCode(strings.ToUpper(codes[0])))
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	d, a := CreateApi(t)
+	router := a.Start()
+	now := time.Now()
+	a.SetLoginClock(func() time.Time { return now })
+
+	request := func(method, url, access string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	login := fmt.Sprintf("%vtotp", time.Now().UnixNano())
+	credentials := `{"login":"` + login + `","password":"` + testPassword + `"}`
+	w := request(http.MethodPost, "/api/v1/register", "", credentials)
+	require.Equal(t, http.StatusOK, w.Code)
+	var registered api.OutRegister
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &registered))
+	access := registered.Access
+
+	// enroll and confirm
+	w = request(http.MethodPost, "/api/v1/twoFactor/enroll", access, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var enrolled api.OutEnrollTwoFactor
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &enrolled))
+	assert.Contains(t, enrolled.ProvisioningURI, "secret="+enrolled.Secret)
+
+	code := func() string {
+		code, err := totp.Code(enrolled.Secret, now)
+		require.NoError(t, err)
+		return code
+	}
+	w = request(http.MethodPost, "/api/v1/twoFactor/confirm", access, `{"code":"000000"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = request(http.MethodPost, "/api/v1/twoFactor/confirm", access, `{"code":"`+code()+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var recovery api.OutRecoveryCodes
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &recovery))
+	require.Len(t, recovery.RecoveryCodes, 10)
+
+	// login gives a challenge instead of tokens
+	challenge := func() string {
+		w := request(http.MethodPost, "/api/v1/login", "", credentials)
+		require.Equal(t, http.StatusOK, w.Code)
+		var out api.OutLogin
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		assert.Empty(t, out.Access)
+		require.NotEmpty(t, out.Challenge)
+		return out.Challenge
+	}
+	ch := challenge()
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/v1/getUser", ch, "").Code)
+
+	// the code of the same period can not be used twice
+	w = request(http.MethodPost, "/api/v1/login/twoFactor", "", `{"challenge":"`+ch+`","code":"`+code()+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	now = now.Add(totp.Period)
+	w = request(http.MethodPost, "/api/v1/login/twoFactor", "", `{"challenge":"`+ch+`","code":"`+code()+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var logged api.OutLogin
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &logged))
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/v1/getUser", logged.Access, "").Code)
+
+	// recovery codes are used once
+	ch = challenge()
+	body := `{"challenge":"` + ch + `","code":"` + strings.ToUpper(recovery.RecoveryCodes[0]) + `"}`
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/api/v1/login/twoFactor", "", body).Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "/api/v1/login/twoFactor", "", body).Code)
+	w = request(http.MethodGet, "/api/v1/twoFactor", logged.Access, "")
+	var status api.OutGetTwoFactor
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.True(t, status.Enabled)
+	assert.Equal(t, 9, status.RecoveryCodes)
+
+	// challenge expires
+	ch = challenge()
+	now = now.Add(10 * time.Minute)
+	w = request(http.MethodPost, "/api/v1/login/twoFactor", "", `{"challenge":"`+ch+`","code":"`+code()+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// team can require second factor
+	other := fmt.Sprintf("%vtotpother", time.Now().UnixNano())
+	w = request(http.MethodPost, "/api/v1/register", "", `{"login":"`+other+`","password":"`+testPassword+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var otherRegistered api.OutRegister
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &otherRegistered))
+	w = request(http.MethodPost, "/api/v1/teams", logged.Access, `{"name":"`+login+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var team api.OutAddTeam
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &team))
+	teamURL := fmt.Sprintf("/api/v1/teams/%v", team.Team.ID)
+	w = request(http.MethodPost, teamURL+"/members", logged.Access, `{"login":"`+other+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = request(http.MethodPost, teamURL+"/requireTwoFactor", logged.Access, `{"required":true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = request(http.MethodGet, teamURL+"/members", otherRegistered.Access, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = request(http.MethodPost, "/api/v1/expression", otherRegistered.Access,
+		fmt.Sprintf(`{"expression":"1+1","team":%v}`, team.Team.ID))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	require.Equal(t, http.StatusOK, request(http.MethodDelete, teamURL, logged.Access, "").Code)
+
+	// disable
+	now = now.Add(totp.Period)
+	w = request(http.MethodPost, "/api/v1/twoFactor/disable", logged.Access, `{"code":"`+code()+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = request(http.MethodPost, "/api/v1/login", "", credentials)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &logged))
+	assert.NotEmpty(t, logged.Access)
+
+	for _, l := range []string{login, other} {
+		user, err := d.GetUserByUsername(l)
+		require.NoError(t, err)
+		require.NoError(t, d.DeleteByUserId(user.ID))
+		require.NoError(t, d.DeleteUser(user.ID))
+	}
+}
@@ -38,12 +38,15 @@ func CreateApi(t *testing.T) (*db.APIDb, *api.API) {
 	return d, api.New(d, e, statusWorkers, servers, timeConfig)
 }
 
+// testPassword satisfies the default password policy.
+const testPassword = "correct-horse-battery"
+
 var RegisteredCounter = 0
 
 func CreateRegisteredUser(t *testing.T, r *gin.Engine) string {
 	userData := api.InRegister{
 		Login:    fmt.Sprintf("%v%v", time.Now().Unix(), RegisteredCounter),
-		Password: "test",
+		Password: testPassword,
 	}
 	RegisteredCounter++
 
@@ -79,7 +82,7 @@ func TestRegisterLoginUpdate(t *testing.T) {
 
 	userData := api.InRegister{
 		Login:    fmt.Sprintf("%vnosuchuser", time.Now().Unix()),
-		Password: "test",
+		Password: testPassword,
 	}
 
 	body, _ := json.Marshal(userData)
@@ -137,8 +140,8 @@ func TestRegisterLoginUpdate(t *testing.T) {
 	// update
 	userUpdate := api.InUpdateUser{
 		Login:       fmt.Sprintf("%vnewlogin", time.Now().Unix()),
-		OldPassword: "test",
-		NewPassword: "testnew",
+		OldPassword: testPassword,
+		NewPassword: testPassword + "-new",
 	}
 
 	body, _ = json.Marshal(userUpdate)
@@ -310,3 +313,445 @@ func TestGetComputingPowers(t *testing.T) {
 
 	assert.Equal(t, "ok", out.Message)
 }
+
+func TestCancelDeleteExpression(t *testing.T) {
+	_, a := CreateApi(t)
+	router := a.Start()
+
+	token := CreateRegisteredUser(t, router)
+
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(api.InPostExpression{
+		Expression: "2+2",
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/expression", strings.NewReader(string(body)))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, req)
+	require.Equal(t, 200, w.Code)
+
+	var out1 api.OutPostExpression
+	err := json.Unmarshal(w.Body.Bytes(), &out1)
+	require.NoError(t, err)
+
+	// cancel
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/expression/%d/cancel", out1.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	var out2 api.OutCancelExpression
+	err = json.Unmarshal(w.Body.Bytes(), &out2)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", out2.Message)
+	assert.Equal(t, db.ExpressionCancelled, out2.Expression.Status)
+
+	// already cancelled
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/expression/%d/cancel", out1.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// delete
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/expression/%d", out1.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/expression/%d", out1.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRetryExpression(t *testing.T) {
+	d, a := CreateApi(t)
+	router := a.Start()
+
+	token := CreateRegisteredUser(t, router)
+
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(api.InPostExpression{
+		Expression: "2+2",
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/expression", strings.NewReader(string(body)))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, req)
+	require.Equal(t, 200, w.Code)
+
+	var out1 api.OutPostExpression
+	err := json.Unmarshal(w.Body.Bytes(), &out1)
+	require.NoError(t, err)
+
+	// not finished yet
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/expression/%d/retry", out1.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/expression/%d/cancel", out1.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, req)
+	require.Equal(t, 200, w.Code)
+
+	// retry with new time for +
+	body, _ = json.Marshal(api.InRetryExpression{
+		Operations: map[string]int{"+": 123},
+	})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/expression/%d/retry", out1.ID),
+		strings.NewReader(string(body)))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	var out2 api.OutRetryExpression
+	err = json.Unmarshal(w.Body.Bytes(), &out2)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", out2.Message)
+	assert.Equal(t, out1.ID, out2.Expression.ID)
+	assert.Equal(t, db.ExpressionNotReady, out2.Expression.Status)
+
+	operations, err := d.GetExpressionOperations(out1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 123, operations.TimeAdd)
+
+	// history
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/expression/%d/history", out1.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	var out3 api.OutGetExpressionHistory
+	err = json.Unmarshal(w.Body.Bytes(), &out3)
+	require.NoError(t, err)
+	require.Len(t, out3.Runs, 1)
+	assert.Equal(t, db.ExpressionCancelled, out3.Runs[0].Status)
+
+	// unknown expression
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/expression/0/retry", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRefreshToken(t *testing.T) {
+	t.Setenv("ACCESS_TOKEN_LIFETIME", "1")
+	d, a := CreateApi(t)
+	router := a.Start()
+
+	userData := api.InRegister{
+		Login:    fmt.Sprintf("%vrefresh", time.Now().UnixNano()),
+		Password: testPassword,
+	}
+	body, _ := json.Marshal(userData)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/register", strings.NewReader(string(body)))
+	router.ServeHTTP(w, req)
+	var registered api.OutRegister
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &registered))
+	require.NotEmpty(t, registered.Refresh)
+
+	getUser := func(access string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/getUser", nil)
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	refresh := func(token string) (int, api.OutRefresh) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/refresh",
+			strings.NewReader(`{"refresh":"`+token+`"}`))
+		router.ServeHTTP(w, req)
+		var out api.OutRefresh
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		return w.Code, out
+	}
+
+	// access token expires
+	assert.Equal(t, http.StatusOK, getUser(registered.Access))
+	time.Sleep(2100 * time.Millisecond)
+	assert.Equal(t, http.StatusUnauthorized, getUser(registered.Access))
+
+	code, refreshed := refresh(registered.Refresh)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, http.StatusOK, getUser(refreshed.Access))
+	assert.NotEqual(t, registered.Refresh, refreshed.Refresh)
+
+	code, refreshedAgain := refresh(refreshed.Refresh)
+	require.Equal(t, http.StatusOK, code)
+
+	// reuse of the old token revokes the family
+	code, _ = refresh(refreshed.Refresh)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = refresh(refreshedAgain.Refresh)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// login starts a new family
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(string(body)))
+	router.ServeHTTP(w, req)
+	var logged api.OutLogin
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &logged))
+	code, refreshed = refresh(logged.Refresh)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = refresh("wrong")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// disabled users get no new tokens
+	user, err := d.GetUserByUsername(userData.Login)
+	require.NoError(t, err)
+	require.NoError(t, d.SetUserRole(user.ID, user.Role, true))
+	code, _ = refresh(refreshed.Refresh)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	require.NoError(t, d.DeleteByUserId(user.ID))
+	require.NoError(t, d.DeleteUser(user.ID))
+}
+
+func TestLogoutAndSessions(t *testing.T) {
+	_, a := CreateApi(t)
+	router := a.Start()
+
+	userData := api.InRegister{
+		Login:    fmt.Sprintf("%vsessions", time.Now().UnixNano()),
+		Password: testPassword,
+	}
+	body, _ := json.Marshal(userData)
+	login := func() api.OutLogin {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(string(body)))
+		router.ServeHTTP(w, req)
+		var out api.OutLogin
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		require.Equal(t, "ok", out.Message)
+		return out
+	}
+	request := func(method, url, access string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, nil)
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/register", strings.NewReader(string(body)))
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	first := login()
+	second := login()
+	third := login()
+
+	w = request(http.MethodGet, "/api/v1/sessions", first.Access)
+	require.Equal(t, http.StatusOK, w.Code)
+	var sessions api.OutGetSessions
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sessions))
+	require.Len(t, sessions.Sessions, 4)
+	assert.True(t, sessions.Sessions[1].Current)
+	assert.False(t, sessions.Sessions[2].Current)
+
+	// logout revokes only the current session and its refresh token
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/api/v1/logout", first.Access).Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/v1/getUser", first.Access).Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/v1/getUser", second.Access).Code)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/refresh", strings.NewReader(`{"refresh":"`+first.Refresh+`"}`))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// revoke another session
+	w = request(http.MethodDelete, fmt.Sprintf("/api/v1/sessions/%v", sessions.Sessions[2].ID), third.Access)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/v1/getUser", second.Access).Code)
+	w = request(http.MethodDelete, fmt.Sprintf("/api/v1/sessions/%v", sessions.Sessions[2].ID), third.Access)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// renaming does not break tokens
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/updateUser",
+		strings.NewReader(`{"login":"`+userData.Login+`renamed","old_password":"`+testPassword+`"}`))
+	req.Header.Set("Authorization", "Bearer "+third.Access)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/v1/getUser", third.Access).Code)
+
+	// logout everywhere
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/api/v1/logoutEverywhere", third.Access).Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/v1/getUser", third.Access).Code)
+}
+
+func TestAPIKeys(t *testing.T) {
+	_, a := CreateApi(t)
+	router := a.Start()
+	token := CreateRegisteredUser(t, router)
+
+	request := func(method, url string, header string, value string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set(header, value)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	addKey := func(in api.InAddAPIKey) (int, api.OutAddAPIKey) {
+		body, _ := json.Marshal(in)
+		w := request(http.MethodPost, "/api/v1/apiKeys", "Authorization", "Bearer "+token, string(body))
+		var out api.OutAddAPIKey
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		return w.Code, out
+	}
+
+	code, _ := addKey(api.InAddAPIKey{Name: "ci", Scopes: []string{"everything"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, readKey := addKey(api.InAddAPIKey{Name: "read", Scopes: []string{api.ScopeExpressionsRead}})
+	require.Equal(t, http.StatusOK, code)
+	code, writeKey := addKey(api.InAddAPIKey{Name: "write",
+		Scopes: []string{api.ScopeExpressionsRead, api.ScopeExpressionsWrite}, ExpiresIn: 3600})
+	require.Equal(t, http.StatusOK, code)
+	assert.NotZero(t, writeKey.APIKey.ExpiresAt)
+
+	// scopes
+	w := request(http.MethodGet, "/api/v1/expression", "X-API-Key", readKey.Key, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = request(http.MethodPost, "/api/v1/expression", "X-API-Key", readKey.Key, `{"expression":"1+1"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = request(http.MethodPost, "/api/v1/expression", "X-API-Key", writeKey.Key, `{"expression":"1+1"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = request(http.MethodPost, "/api/v1/postOperationsAndTimes", "X-API-Key", writeKey.Key, `{"+":1}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// keys can not manage the account
+	w = request(http.MethodGet, "/api/v1/apiKeys", "X-API-Key", writeKey.Key, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = request(http.MethodGet, "/api/v1/apiKeys", "Authorization", "Bearer "+token, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var keys api.OutGetAPIKeys
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &keys))
+	require.Len(t, keys.APIKeys, 2)
+	assert.NotContains(t, w.Body.String(), readKey.Key)
+
+	// revoke
+	w = request(http.MethodDelete, fmt.Sprintf("/api/v1/apiKeys/%v", readKey.APIKey.ID), "Authorization",
+		"Bearer "+token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = request(http.MethodGet, "/api/v1/expression", "X-API-Key", readKey.Key, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = request(http.MethodDelete, fmt.Sprintf("/api/v1/apiKeys/%v", readKey.APIKey.ID), "Authorization",
+		"Bearer "+token, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = request(http.MethodGet, "/api/v1/expression", "X-API-Key", "wrong", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLoginThrottling(t *testing.T) {
+	t.Setenv("LOGIN_LOCKOUT_ATTEMPTS", "5")
+	d, a := CreateApi(t)
+	router := a.Start()
+	now := time.Now()
+	a.SetLoginClock(func() time.Time { return now })
+
+	login := func(login, password string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/login",
+			strings.NewReader(`{"login":"`+login+`","password":"`+password+`"}`))
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// password policy
+	userData := api.InRegister{Login: fmt.Sprintf("%vthrottle", time.Now().UnixNano()), Password: "a"}
+	body, _ := json.Marshal(userData)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/register", strings.NewReader(string(body)))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	userData.Password = testPassword
+	body, _ = json.Marshal(userData)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/register", strings.NewReader(string(body)))
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// unknown login and wrong password look the same
+	unknown := login(userData.Login+"unknown", testPassword)
+	wrong := login(userData.Login, "wrong-password")
+	assert.Equal(t, http.StatusUnauthorized, unknown.Code)
+	assert.Equal(t, unknown.Code, wrong.Code)
+	assert.Equal(t, unknown.Body.String(), wrong.Body.String())
+
+	// backoff after 3 free attempts
+	assert.Equal(t, http.StatusUnauthorized, login(userData.Login, "wrong-password").Code)
+	assert.Equal(t, http.StatusUnauthorized, login(userData.Login, "wrong-password").Code)
+	assert.Equal(t, http.StatusUnauthorized, login(userData.Login, "wrong-password").Code)
+	w = login(userData.Login, testPassword)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	now = now.Add(time.Second)
+
+	// lockout after 5 attempts
+	assert.Equal(t, http.StatusUnauthorized, login(userData.Login, "wrong-password").Code)
+	w = login(userData.Login, testPassword)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "900", w.Header().Get("Retry-After"))
+	now = now.Add(15*time.Minute + time.Second)
+	assert.Equal(t, http.StatusOK, login(userData.Login, testPassword).Code)
+
+	// audit
+	attempts, err := d.GetLoginAttempts(userData.Login, 100)
+	require.NoError(t, err)
+	reasons := make([]string, 0)
+	for _, attempt := range attempts {
+		reasons = append(reasons, attempt.Reason)
+	}
+	assert.Contains(t, reasons, "invalid credentials")
+	assert.Contains(t, reasons, "throttled")
+	assert.Contains(t, reasons, "locked out")
+
+	user, err := d.GetUserByUsername(userData.Login)
+	require.NoError(t, err)
+	require.NoError(t, d.DeleteByUserId(user.ID))
+	require.NoError(t, d.DeleteUser(user.ID))
+}
+
+func TestLoginThrottlingForwardedFor(t *testing.T) {
+	t.Setenv("LOGIN_IP_LOCKOUT_ATTEMPTS", "2")
+
+	login := func(router http.Handler, n int) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/login",
+			strings.NewReader(fmt.Sprintf(`{"login":"%vforwarded%v","password":"wrong-password"}`,
+				time.Now().UnixNano(), n)))
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%v", n))
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// the header is spoofed, the limit of the IP of the connection is not reset
+	_, a := CreateApi(t)
+	router := a.Start()
+	assert.Equal(t, http.StatusUnauthorized, login(router, 1))
+	assert.Equal(t, http.StatusUnauthorized, login(router, 2))
+	assert.Equal(t, http.StatusTooManyRequests, login(router, 3))
+
+	// behind a trusted proxy every forwarded IP has its own limit
+	t.Setenv("TRUSTED_PROXIES", "192.0.2.0/24")
+	_, a = CreateApi(t)
+	router = a.Start()
+	assert.Equal(t, http.StatusUnauthorized, login(router, 1))
+	assert.Equal(t, http.StatusUnauthorized, login(router, 2))
+	assert.Equal(t, http.StatusUnauthorized, login(router, 3))
+}
@@ -0,0 +1,242 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"storage/internal/api"
+	"storage/internal/db"
+	"storage/internal/webhooks"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	secret, err := webhooks.NewSecret()
+	require.NoError(t, err)
+	body := []byte(`{"event":"expression.finished"}`)
+	signature := webhooks.Sign(secret, 1700000000, body)
+	assert.True(t, strings.HasPrefix(signature, "sha256="))
+	assert.True(t, webhooks.Verify(secret, 1700000000, body, signature))
+	assert.False(t, webhooks.Verify(secret, 1700000001, body, signature))
+	assert.False(t, webhooks.Verify(secret, 1700000000, []byte(`{}`), signature))
+	assert.False(t, webhooks.Verify("whsec_other", 1700000000, body, signature))
+}
+
+// webhookReceiver is an in-process receiver of webhooks that fails the first attempts.
+type webhookReceiver struct {
+	mu        sync.Mutex
+	secret    string
+	failFirst int
+	attempts  int
+	payloads  []webhooks.Payload
+	verified  bool
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+	if r.attempts <= r.failFirst {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := io.ReadAll(req.Body)
+	timestamp, _ := strconv.ParseInt(req.Header.Get(webhooks.HeaderTimestamp), 10, 64)
+	r.verified = webhooks.Verify(r.secret, timestamp, body, req.Header.Get(webhooks.HeaderSignature))
+	var payload webhooks.Payload
+	_ = json.Unmarshal(body, &payload)
+	r.payloads = append(r.payloads, payload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *webhookReceiver) received() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.payloads)
+}
+
+func TestWebhooks(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_HTTP", "TRUE")
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE", "TRUE")
+	t.Setenv("WEBHOOK_RETRY_DELAY", "1")
+	d, a := CreateApi(t)
+	router := a.Start()
+	access := CreateRegisteredUser(t, router)
+	receiver := &webhookReceiver{failFirst: 1}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	request := func(method, url string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/v1/webhooks", `{"url":"ftp://example.com"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = request(http.MethodPost, "/api/v1/webhooks", `{"url":"`+server.URL+`","expression_id":-1}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = request(http.MethodPost, "/api/v1/webhooks", `{"url":"`+server.URL+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var added api.OutAddWebhook
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &added))
+	require.True(t, strings.HasPrefix(added.Secret, "whsec_"))
+	receiver.mu.Lock()
+	receiver.secret = added.Secret
+	receiver.mu.Unlock()
+
+	w = request(http.MethodGet, "/api/v1/webhooks", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), added.Secret)
+	var list api.OutGetWebhooks
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Webhooks, 1)
+	assert.Equal(t, server.URL, list.Webhooks[0].URL)
+
+	w = request(http.MethodPost, "/api/v2/expressions", `{"expression":"2+2"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var posted api.OutPostExpression
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &posted))
+	require.Equal(t, http.StatusOK,
+		request(http.MethodPost, fmt.Sprintf("/api/v2/expressions/%v/cancel", posted.ID), "").Code)
+
+	// the first attempt fails, the second one is delivered after the retry delay
+	require.Eventually(t, func() bool { return receiver.received() == 1 }, 10*time.Second, 50*time.Millisecond)
+	receiver.mu.Lock()
+	assert.True(t, receiver.verified)
+	assert.Equal(t, webhooks.EventFinished, receiver.payloads[0].Event)
+	assert.Equal(t, posted.ID, receiver.payloads[0].Expression.ID)
+	assert.Equal(t, db.ExpressionCancelled, receiver.payloads[0].Expression.Status)
+	receiver.mu.Unlock()
+
+	deliveriesURL := fmt.Sprintf("/api/v1/webhooks/%v/deliveries", added.ID)
+	var deliveries api.OutGetWebhookDeliveries
+	require.Eventually(t, func() bool {
+		w = request(http.MethodGet, deliveriesURL, "")
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &deliveries))
+		return len(deliveries.Deliveries) == 1 && deliveries.Deliveries[0].Status == db.DeliveryDelivered
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, 2, deliveries.Deliveries[0].Attempts)
+	assert.Equal(t, http.StatusNoContent, deliveries.Deliveries[0].ResponseStatus)
+
+	// a result of an attempt whose lease expired does not overwrite the newer result
+	stale := deliveries.Deliveries[0]
+	stale.Status = db.DeliveryFailed
+	updated, err := d.UpdateWebhookDelivery(stale, stale.NextAttemptAt-1)
+	require.NoError(t, err)
+	assert.False(t, updated)
+
+	w = request(http.MethodPost, fmt.Sprintf("%v/%v/redeliver", deliveriesURL, deliveries.Deliveries[0].ID), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Eventually(t, func() bool { return receiver.received() == 2 }, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, deliveriesURL+"/0/redeliver", "").Code)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodDelete, fmt.Sprintf("/api/v1/webhooks/%v", added.ID), "").Code)
+	assert.Equal(t, http.StatusNotFound,
+		request(http.MethodDelete, fmt.Sprintf("/api/v1/webhooks/%v", added.ID), "").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, deliveriesURL, "").Code)
+
+	require.NoError(t, d.DeleteExpression(posted.ID))
+	var out api.OutGetUser
+	require.NoError(t, json.Unmarshal(request(http.MethodGet, "/api/v1/getUser", "").Body.Bytes(), &out))
+	user, err := d.GetUserByUsername(out.Login)
+	require.NoError(t, err)
+	require.NoError(t, d.DeleteByUserId(user.ID))
+	require.NoError(t, d.DeleteUser(user.ID))
+}
+
+func TestWebhookAddresses(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_HTTP", "TRUE")
+	d, a := CreateApi(t)
+	router := a.Start()
+	access := CreateRegisteredUser(t, router)
+	request := func(method, url string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// addresses of the network of storage can not be used
+	for _, url := range []string{"http://127.0.0.1:8080/hook", "http://localhost/hook", "http://[::1]/hook",
+		"http://10.0.0.1/hook", "http://169.254.169.254/latest/meta-data", "http://0.0.0.0/hook"} {
+		w := request(http.MethodPost, "/api/v1/webhooks", `{"url":"`+url+`"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		assert.Contains(t, w.Body.String(), webhooks.ErrPrivateAddress.Error(), url)
+	}
+
+	var out api.OutGetUser
+	require.NoError(t, json.Unmarshal(request(http.MethodGet, "/api/v1/getUser", "").Body.Bytes(), &out))
+	user, err := d.GetUserByUsername(out.Login)
+	require.NoError(t, err)
+	require.NoError(t, d.DeleteByUserId(user.ID))
+	require.NoError(t, d.DeleteUser(user.ID))
+}
+
+func TestWebhookRedirect(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_HTTP", "TRUE")
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE", "TRUE")
+	d, a := CreateApi(t)
+	router := a.Start()
+	access := CreateRegisteredUser(t, router)
+	receiver := &webhookReceiver{}
+	target := httptest.NewServer(receiver)
+	defer target.Close()
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Location", target.URL)
+		w.WriteHeader(http.StatusFound)
+		_, _ = w.Write([]byte("secret of the receiver"))
+	}))
+	defer redirect.Close()
+	request := func(method, url string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+access)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/v1/webhooks", `{"url":"`+redirect.URL+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var added api.OutAddWebhook
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &added))
+	w = request(http.MethodPost, "/api/v2/expressions", `{"expression":"2+2"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var posted api.OutPostExpression
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &posted))
+	require.Equal(t, http.StatusOK,
+		request(http.MethodPost, fmt.Sprintf("/api/v2/expressions/%v/cancel", posted.ID), "").Code)
+
+	// the redirect is not followed, only the status of the answer is saved
+	deliveriesURL := fmt.Sprintf("/api/v1/webhooks/%v/deliveries", added.ID)
+	var deliveries api.OutGetWebhookDeliveries
+	require.Eventually(t, func() bool {
+		w = request(http.MethodGet, deliveriesURL, "")
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &deliveries))
+		return len(deliveries.Deliveries) == 1 && deliveries.Deliveries[0].Attempts > 0
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, http.StatusFound, deliveries.Deliveries[0].ResponseStatus)
+	assert.Equal(t, "webhook answered 302", deliveries.Deliveries[0].LastError)
+	assert.Equal(t, 0, receiver.received())
+
+	require.NoError(t, d.DeleteExpression(posted.ID))
+	var out api.OutGetUser
+	require.NoError(t, json.Unmarshal(request(http.MethodGet, "/api/v1/getUser", "").Body.Bytes(), &out))
+	user, err := d.GetUserByUsername(out.Login)
+	require.NoError(t, err)
+	require.NoError(t, d.DeleteByUserId(user.ID))
+	require.NoError(t, d.DeleteUser(user.ID))
+}
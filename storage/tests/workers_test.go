@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"storage/internal/api"
+	"storage/internal/availableservers"
+	"storage/internal/db"
+	"storage/internal/expressionstorage"
+	"storage/internal/gRPCServer"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerEnrollmentAndRevoke(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "admintoken")
+	d, err := db.New()
+	require.NoError(t, err)
+	statusWorkers := &sync.Map{}
+	expressions := expressionstorage.New(d, time.Minute, statusWorkers)
+	servers := availableservers.New(expressions)
+	timeConfig := &api.ExecTimeConfig{TimeAdd: 1, TimeSubtract: 1, TimeDivide: 1, TimeMultiply: 1}
+	router := api.New(d, expressions, statusWorkers, servers, timeConfig).Start()
+
+	workerAuth := gRPCServer.NewWorkerAuth(d)
+	workersLis := bufconn.Listen(bufSize)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(workerAuth.UnaryInterceptor),
+		grpc.ChainStreamInterceptor(workerAuth.StreamInterceptor))
+	gRPCServer.RegisterExpressionsServiceServer(server,
+		gRPCServer.New(expressions, servers, timeConfig, statusWorkers, d))
+	go func() {
+		_ = server.Serve(workersLis)
+	}()
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return workersLis.Dial()
+		}), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	client := gRPCServer.NewExpressionsServiceClient(conn)
+
+	adminRequest := func(method, url, token string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	name := fmt.Sprintf("worker%v", time.Now().UnixNano())
+
+	// only admin can issue tokens
+	w := adminRequest(http.MethodPost, "/api/v1/admin/workers", "wrong", `{"name":"`+name+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = adminRequest(http.MethodPost, "/api/v1/admin/workers", "admintoken", `{"name":"`+name+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var added api.OutAddWorker
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &added))
+	require.NotEmpty(t, added.EnrollmentToken)
+
+	// calls without credential are rejected
+	_, err = client.ConfirmStartCalculating(context.Background(), &gRPCServer.Expression{ServerName: name})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.Enroll(context.Background(), &gRPCServer.EnrollRequest{ServerName: name,
+		EnrollmentToken: "wrong"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	enrolled, err := client.Enroll(context.Background(), &gRPCServer.EnrollRequest{ServerName: name,
+		EnrollmentToken: added.EnrollmentToken})
+	require.NoError(t, err)
+
+	// enrollment token can be used only once
+	_, err = client.Enroll(context.Background(), &gRPCServer.EnrollRequest{ServerName: name,
+		EnrollmentToken: added.EnrollmentToken})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+enrolled.Credential)
+	stream, err := client.GetUpdates(ctx, &gRPCServer.Empty{})
+	require.NoError(t, err)
+	for {
+		_, err = stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+
+	newUser := createNewUser(t, d)
+	newExp, err := expressions.Add(db.Expression{Value: "1+1", User: newUser})
+	require.NoError(t, err)
+
+	// the credential allows only own name
+	_, err = client.ConfirmStartCalculating(ctx, &gRPCServer.Expression{Id: int64(newExp), ServerName: "other"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	res, err := client.ConfirmStartCalculating(ctx, &gRPCServer.Expression{Id: int64(newExp), ServerName: name})
+	require.NoError(t, err)
+	assert.True(t, res.Confirm)
+
+	// revoke returns the expression to pending
+	w = adminRequest(http.MethodDelete, "/api/v1/admin/workers/"+name, "admintoken", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var revoked api.OutRevokeWorker
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &revoked))
+	require.Len(t, revoked.Released, 1)
+	assert.Equal(t, newExp, revoked.Released[0].ID)
+
+	expression, err := expressions.GetByID(newExp)
+	require.NoError(t, err)
+	assert.Equal(t, db.ExpressionNotReady, expression.Status)
+	assert.Equal(t, "", expression.Servername)
+	assert.Equal(t, 0, expression.Attempts)
+
+	_, err = client.KeepAlive(ctx, &gRPCServer.KeepAliveMsg{
+		Expression: &gRPCServer.Expression{Id: int64(newExp), ServerName: name}})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	w = adminRequest(http.MethodDelete, "/api/v1/admin/workers/nosuchworker", "admintoken", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	require.NoError(t, d.DeleteExpression(newExp))
+	require.NoError(t, d.DeleteUser(newUser))
+}
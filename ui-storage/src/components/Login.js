@@ -1,4 +1,4 @@
-import React, {useState} from 'react';
+import React, {useEffect, useState} from 'react';
 import Auth from '../pkg/Auth';
 import Cookies from "js-cookie";
 
@@ -7,17 +7,19 @@ export const Login = () => {
     const [password, setPassword] = useState('');
     const [message, setMessage] = useState('');
     const [error, setError] = useState(false);
+    const [challenge, setChallenge] = useState('');
+    const [code, setCode] = useState('');
 
-    const handleSubmit = async (e) => {
-        e.preventDefault();
-        Auth.axiosInstance.post('/login', {
-            "login": login,
-            "password": password
-        })
+    const handleResponse = (request) => {
+        request
             .then(response => {
-                console.log(response.data.access)
-                if (response.status === 200) {
+                if (response.status === 200 && response.data.challenge) {
+                    setChallenge(response.data.challenge);
+                    setMessage("Enter the code from your authenticator app or a recovery code");
+                    setError(false)
+                } else if (response.status === 200) {
                     Cookies.set('token', response.data.access);
+                    Cookies.set('refresh', response.data.refresh);
                     setMessage("Success");
                     setError(false)
                     window.location = '/'
@@ -25,26 +27,68 @@ export const Login = () => {
             })
             .catch(
                 (error) => {
-                    setMessage("Invalid login or password");
+                    if (error.response && (error.response.status !== 401 || challenge !== "")) {
+                        setMessage(error.response.data.message);
+                    } else {
+                        setMessage("Invalid login or password");
+                    }
                     setError(true)
                 }
             );
     };
 
+    const handleSubmit = async (e) => {
+        e.preventDefault();
+        handleResponse(challenge === "" ?
+            Auth.axiosInstance.post('/login', {"login": login, "password": password}) :
+            Auth.axiosInstance.post('/login/twoFactor', {"challenge": challenge, "code": code}));
+    };
+
+    const handleSingleSignOn = () => {
+        // the answer sets the cookie that binds the login to this browser, it is sent back with the callback
+        Auth.axiosInstance.get('/oidc/login', {withCredentials: true})
+            .then(response => {
+                window.location = response.data.url
+            })
+            .catch((error) => {
+                setMessage(error.response ? error.response.data.message : "Single sign-on is not available");
+                setError(true)
+            });
+    };
+
+    // the provider of single sign-on redirects back to this page with code and state
+    useEffect(() => {
+        const params = new URLSearchParams(window.location.search);
+        if (params.get("code") && params.get("state")) {
+            window.history.replaceState(null, "", window.location.pathname);
+            handleResponse(Auth.axiosInstance.post('/oidc/callback',
+                {"code": params.get("code"), "state": params.get("state")}, {withCredentials: true}));
+        }
+    }, []);
+
     return (
         <div>
             <h1 className="container">Login</h1>
             <form onSubmit={handleSubmit} className="container">
-                <label>
-                    Login:
-                    <input type="text" value={login} onChange={(e) => setLogin(e.target.value)} required/>
-                </label>
-                <label>
-                    Password:
-                    <input type="password" value={password} onChange={(e) => setPassword(e.target.value)} required/>
-                </label>
+                {challenge !== "" ? null : <>
+                    <label>
+                        Login:
+                        <input type="text" value={login} onChange={(e) => setLogin(e.target.value)} required/>
+                    </label>
+                    <label>
+                        Password:
+                        <input type="password" value={password} onChange={(e) => setPassword(e.target.value)} required/>
+                    </label>
+                </>}
+                {challenge === "" ? null : <label>
+                    Code:
+                    <input type="text" value={code} onChange={(e) => setCode(e.target.value)} required/>
+                </label>}
                 <input type="submit" value="Submit"/>
             </form>
+            {challenge !== "" ? null : <div className="container">
+                <button type="button" onClick={handleSingleSignOn}>Login with single sign-on</button>
+            </div>}
             {message === "" ? null : <div className={error ? "alert alert-danger" : "alert alert-success"}>
                 {message}
             </div>}
@@ -1,8 +1,19 @@
 import Cookies from "js-cookie";
+import Auth from "../pkg/Auth";
+import {useEffect} from "react";
 
 export const Logout = () => {
-    Cookies.remove('token');
-    window.location = '/login'
+    useEffect(() => {
+        // revoke the session on the server, so the tokens can not be used anymore
+        Auth.axiosInstance.post('/logout')
+            .catch(() => {
+            })
+            .finally(() => {
+                Cookies.remove('token');
+                Cookies.remove('refresh');
+                window.location = '/login'
+            });
+    }, []);
 
     return (
         <div className="container">
@@ -10,4 +21,4 @@ export const Logout = () => {
             <p>You have been logged out</p>
         </div>
     );
-}
\ No newline at end of file
+}
@@ -18,6 +18,7 @@ export const Register = () => {
                 console.log(response)
                 if (response.status === 200) {
                     Cookies.set('token', response.data.access);
+                    Cookies.set('refresh', response.data.refresh);
                     setMessage("Success");
                     setError(false)
                     window.location = '/'
@@ -29,6 +30,9 @@ export const Register = () => {
                     if (error.response.status === 409) {
                         setMessage("User with such login already exists");
                         setError(true)
+                    } else if (error.response.status === 400) {
+                        setMessage(error.response.data.message);
+                        setError(true)
                     }
                 }
             );
@@ -1,11 +1,17 @@
 import '../App.css'
-import {useEffect, useState} from "react";
+import {useCallback, useEffect, useState} from "react";
 import Auth from "../pkg/Auth";
+import Cookies from "js-cookie";
 
 export const ViewExpressions = () => {
     const [expressions, setExpressions] = useState([])
+    // all expressions are loaded, so numbers of expressions by status are counted from the list
+    const counts = expressions.reduce((counts, expression) => {
+        counts[expression.ready] = (counts[expression.ready] || 0) + 1
+        return counts
+    }, {})
 
-    useEffect(() => {
+    const load = useCallback(() => {
         Auth.axiosInstance.get("/expression")
             .then(response => {
                 response.data.expressions.sort((a, b) => (a.id > b.id) ? -1 : 1)
@@ -16,6 +22,79 @@ export const ViewExpressions = () => {
             });
     }, []);
 
+    useEffect(load, [load]);
+
+    // changes of expressions are streamed by the storage (Server-Sent Events), EventSource can not send the token,
+    // so the stream is read with fetch
+    useEffect(() => {
+        const controller = new AbortController()
+        let lastEventID = ""
+        let retry = null
+
+        const apply = (name, event) => {
+            if (name === "reset") {
+                load()
+                return
+            }
+            const expression = event.expression
+            setExpressions(expressions => {
+                const rest = expressions.filter(e => e.id !== expression.id)
+                return name === "deleted" ? rest : [expression, ...rest].sort((a, b) => (a.id > b.id) ? -1 : 1)
+            })
+        }
+
+        const connect = async () => {
+            try {
+                const headers = {"Authorization": "Bearer " + Cookies.get("token")}
+                if (lastEventID) {
+                    headers["Last-Event-ID"] = lastEventID
+                }
+                const response = await fetch(process.env.REACT_APP_STORAGE_API_URL + "/events",
+                    {headers: headers, signal: controller.signal})
+                if (!response.ok) {
+                    throw new Error("events: " + response.status)
+                }
+                const reader = response.body.getReader()
+                const decoder = new TextDecoder()
+                let buffer = ""
+                for (; ;) {
+                    const {value, done} = await reader.read()
+                    if (done) {
+                        break
+                    }
+                    buffer += decoder.decode(value, {stream: true})
+                    const messages = buffer.split("\n\n")
+                    buffer = messages.pop()
+                    messages.forEach(message => {
+                        let name = "", data = ""
+                        message.split("\n").forEach(line => {
+                            if (line.startsWith("id: ")) lastEventID = line.slice(4)
+                            if (line.startsWith("event: ")) name = line.slice(7)
+                            if (line.startsWith("data: ")) data = line.slice(6)
+                        })
+                        if (name) {
+                            apply(name, JSON.parse(data))
+                        }
+                    })
+                }
+            } catch (err) {
+                if (controller.signal.aborted) {
+                    return
+                }
+                console.log(err)
+                // the access token may be expired, the request of the list refreshes it
+                load()
+            }
+            retry = setTimeout(connect, 3000)
+        }
+
+        connect()
+        return () => {
+            controller.abort()
+            clearTimeout(retry)
+        }
+    }, [load]);
+
     const showReady = (ready) => {
         switch (ready) {
             case 0:
@@ -46,6 +125,20 @@ export const ViewExpressions = () => {
                     </div>
                     <img src={process.env.PUBLIC_URL + '/exclamation-octagon.svg'} alt="error" width="32" height="32"/>
                 </>)
+            case 4:
+                return (<>
+                    <div>
+                        {"Expression is abandoned (servers died calculating it), see logs"}
+                    </div>
+                    <img src={process.env.PUBLIC_URL + '/exclamation-octagon.svg'} alt="abandoned" width="32" height="32"/>
+                </>)
+            case 5:
+                return (<>
+                    <div>
+                        {"Expression is cancelled"}
+                    </div>
+                    <img src={process.env.PUBLIC_URL + '/exclamation-octagon.svg'} alt="cancelled" width="32" height="32"/>
+                </>)
             default:
                 return (<>
                     <div>
@@ -61,6 +154,12 @@ export const ViewExpressions = () => {
         <>
             <div className="scrollable-div">
                 <h1>View All Expressions</h1>
+                <div>
+                    {[["Waiting", 0], ["Calculating", 1], ["Calculated", 2], ["Error", 3], ["Abandoned", 4],
+                        ["Cancelled", 5]].map(([name, status]) =>
+                        <span key={status} className="badge text-bg-secondary me-1">{name}: {counts[status] || 0}</span>
+                    )}
+                </div>
                 <table className="table table-striped-columns">
                     <thead>
                     <tr>
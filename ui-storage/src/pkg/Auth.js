@@ -3,6 +3,7 @@ import axios from 'axios';
 
 class Auth {
     constructor() {
+        this.refreshing = null;
         this.axiosInstance = axios.create({
             baseURL: process.env.REACT_APP_STORAGE_API_URL,
             timeout: 5000,
@@ -28,7 +29,30 @@ class Auth {
             (response) => {
                 return response;
             },
-            (error) => {
+            async (error) => {
+                const request = error.config;
+                const refresh = Cookies.get('refresh');
+                // access token is short-lived, try to get a new one once before logging out
+                if (error.response.status === 401 && refresh && !request._retry && request.url !== '/refresh') {
+                    request._retry = true;
+                    try {
+                        // refresh token can be used only once, parallel requests wait for the same refresh
+                        if (!this.refreshing) {
+                            this.refreshing = this.axiosInstance.post('/refresh', {refresh: refresh})
+                                .then((response) => {
+                                    Cookies.set('token', response.data.access);
+                                    Cookies.set('refresh', response.data.refresh);
+                                })
+                                .finally(() => {
+                                    this.refreshing = null;
+                                });
+                        }
+                        await this.refreshing;
+                        return this.axiosInstance(request);
+                    } catch (refreshError) {
+                        Cookies.remove('refresh');
+                    }
+                }
                 if (error.response.status === 401 && window.location.pathname !== '/login') {
                     window.location = '/login';
                 }